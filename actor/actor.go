@@ -0,0 +1,451 @@
+// Package actor 基于 co 有状态线程池的actor模型
+//
+//	同一实体ID的消息、生命周期回调都派发到 co 线程池中该ID对应的线程串行执行,actor内部状态无需加锁;
+//	集群中的实体经 Remote 按实体ID定位,不在本服务时经rpc转发到实体所在服务
+package actor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"go.uber.org/zap"
+)
+
+// Actor 实体的处理逻辑
+type Actor[M, R any] interface {
+	// OnStart 启动时调用,首次收到消息、Spawn 或 Restart 策略重启时触发,返回错误时本次消息失败,下一条消息时重试
+	//  @param ctx
+	//  @return error
+	OnStart(ctx context.Context) error
+	// Receive 处理消息
+	//  @param ctx
+	//  @param msg
+	//  @return R Request 的回复,Tell 时忽略
+	//  @return error
+	Receive(ctx context.Context, msg M) (R, error)
+	// OnStop 主动停止或空闲钝化时调用,panic 导致的丢弃不会调用
+	//  @param ctx
+	OnStop(ctx context.Context)
+}
+
+// Supervisor Receive panic 时的处理策略
+type Supervisor int
+
+const (
+	SupervisorRestart Supervisor = iota // 丢弃当前实例,立即创建新实例并 OnStart
+	SupervisorResume                    // 保留当前实例,继续处理后续消息
+	SupervisorStop                      // 丢弃当前实例,有新消息时才重新创建
+)
+
+// Kind 一类实体的actor集合,按实体ID将消息投递到各自的邮箱
+//
+//	邮箱即实体ID在 co 线程池中对应线程的任务队列,使用同一线程池的不同 Kind 中ID相同的实体共享线程
+type Kind[M, R any] struct {
+	name     string
+	factory  func(id int64) Actor[M, R]
+	opts     options
+	mu       sync.Mutex
+	cells    map[int64]*cell[M, R]
+	closed   bool
+	stopChan chan struct{}
+}
+
+// cell 实体的运行时数据,actor 只在实体线程中访问
+type cell[M, R any] struct {
+	id         int64
+	actor      Actor[M, R]
+	pending    atomic.Int32 // 邮箱中尚未开始执行的任务数
+	used       atomic.Bool  // 是否执行过任务,派发失败时只移除从未使用的实体
+	lastActive atomic.Int64 // 最后一次处理消息的时间 unix nano
+}
+
+type result[R any] struct {
+	reply R
+	err   error
+}
+
+// NewKind 创建一类actor
+//
+//	@param name 名称,用于日志和 Key
+//	@param factory 按实体ID创建actor实例
+//	@param opts
+//	@return *Kind[M, R]
+func NewKind[M, R any](name string, factory func(id int64) Actor[M, R], opts ...Option) *Kind[M, R] {
+	o := options{poolName: co.DefaultGoPoolName, supervisor: SupervisorRestart}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	k := &Kind[M, R]{
+		name:     name,
+		factory:  factory,
+		opts:     o,
+		cells:    make(map[int64]*cell[M, R]),
+		stopChan: make(chan struct{}),
+	}
+	if o.idleTimeout > 0 {
+		co.Go(k.passivateLoop)
+	}
+	return k
+}
+
+// Name 名称
+func (k *Kind[M, R]) Name() string {
+	return k.name
+}
+
+// Len 当前存活的实体数
+func (k *Kind[M, R]) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.cells)
+}
+
+// Spawn 启动实体并等待 OnStart 完成,实体已启动时直接返回
+//
+//	@receiver k
+//	@param ctx
+//	@param id
+//	@return error
+func (k *Kind[M, R]) Spawn(ctx context.Context, id int64) error {
+	ch := make(chan error, 1)
+	c, done, err := k.dispatch(ctx, id, false, func(ctx context.Context, c *cell[M, R]) {
+		ch <- k.ensureStarted(ctx, c)
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case err := <-ch:
+		return err
+	case <-done:
+		// 任务执行完成时结果已写入
+		select {
+		case err := <-ch:
+			return err
+		default:
+			return k.dropped(c)
+		}
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+// Tell 投递消息,不等待处理结果,实体未启动时自动启动
+//
+//	@receiver k
+//	@param ctx
+//	@param id
+//	@param msg
+//	@return error 邮箱已满、Kind 已关闭或线程池派发失败
+func (k *Kind[M, R]) Tell(ctx context.Context, id int64, msg M) error {
+	_, _, err := k.dispatch(ctx, id, true, func(ctx context.Context, c *cell[M, R]) {
+		if _, err := k.receive(ctx, c, msg); err != nil {
+			logger.Zap.Debug("actor tell failed", zap.String("kind", k.name), zap.Int64("id", id), zap.Error(err))
+		}
+	})
+	return err
+}
+
+// Request 投递消息并等待回复,实体未启动时自动启动
+//
+//	@receiver k
+//	@param ctx
+//	@param id
+//	@param msg
+//	@param timeout 等待回复的超时时间,<=0时只受ctx控制;超时后消息仍会被处理
+//	@return R
+//	@return error
+func (k *Kind[M, R]) Request(ctx context.Context, id int64, msg M, timeout time.Duration) (R, error) {
+	var zero R
+	ch := make(chan result[R], 1)
+	c, done, err := k.dispatch(ctx, id, true, func(ctx context.Context, c *cell[M, R]) {
+		reply, err := k.receive(ctx, c, msg)
+		ch <- result[R]{reply: reply, err: err}
+	})
+	if err != nil {
+		return zero, err
+	}
+	var timeoutChan <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+	select {
+	case res := <-ch:
+		return res.reply, res.err
+	case <-done:
+		select {
+		case res := <-ch:
+			return res.reply, res.err
+		default:
+			return zero, k.dropped(c)
+		}
+	case <-timeoutChan:
+		return zero, errors.WithStack(fmt.Errorf("%w kind=%s,id=%d", constants.ErrActorRequestTimeout, k.name, id))
+	case <-ctx.Done():
+		return zero, errors.WithStack(ctx.Err())
+	}
+}
+
+// Stop 处理完邮箱中已有的消息后停止实体并调用 OnStop
+//
+//	@receiver k
+//	@param ctx
+//	@param id
+//	@return done 停止后关闭,实体不存在时为nil
+func (k *Kind[M, R]) Stop(ctx context.Context, id int64) (done chan struct{}) {
+	k.mu.Lock()
+	c, ok := k.cells[id]
+	k.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return k.stopCell(ctx, c, false)
+}
+
+// Close 停止所有实体,之后的投递返回 constants.ErrActorKindClosed
+//
+//	@receiver k
+//	@param ctx 等待所有实体停止,ctx结束时不再等待
+//	@return error
+func (k *Kind[M, R]) Close(ctx context.Context) error {
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		return nil
+	}
+	k.closed = true
+	close(k.stopChan)
+	cells := make([]*cell[M, R], 0, len(k.cells))
+	for _, c := range k.cells {
+		cells = append(cells, c)
+	}
+	k.mu.Unlock()
+
+	dones := make([]chan struct{}, 0, len(cells))
+	for _, c := range cells {
+		dones = append(dones, k.stopCell(ctx, c, false))
+	}
+	for _, done := range dones {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		}
+	}
+	return nil
+}
+
+// dispatch 将任务投递到实体的邮箱
+//
+//	@return *cell[M, R]
+//	@return done 任务执行完成或被线程池丢弃时关闭
+//	@return error
+func (k *Kind[M, R]) dispatch(ctx context.Context, id int64, isMsg bool, task func(ctx context.Context, c *cell[M, R])) (*cell[M, R], chan struct{}, error) {
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		return nil, nil, errors.WithStack(fmt.Errorf("%w kind=%s", constants.ErrActorKindClosed, k.name))
+	}
+	c, ok := k.cells[id]
+	if !ok {
+		c = &cell[M, R]{id: id}
+		c.lastActive.Store(time.Now().UnixNano())
+		k.cells[id] = c
+	}
+	if isMsg && k.opts.mailboxSize > 0 && int(c.pending.Load()) >= k.opts.mailboxSize {
+		k.mu.Unlock()
+		return nil, nil, errors.WithStack(fmt.Errorf("%w kind=%s,id=%d", constants.ErrActorMailboxFull, k.name, id))
+	}
+	c.pending.Add(1)
+	k.mu.Unlock()
+	done, err := k.submit(ctx, c, isMsg, task)
+	return c, done, err
+}
+
+// submit 派发到实体线程,调用方已增加 pending
+//
+//	任务开始执行时扣除 pending;派发失败或排队后被线程池丢弃时由任务的丢弃回调扣除,不依赖调用方是否仍在等待
+func (k *Kind[M, R]) submit(ctx context.Context, c *cell[M, R], isMsg bool, task func(ctx context.Context, c *cell[M, R])) (chan struct{}, error) {
+	done, err := co.TryGoWithID(ctx, c.id, func(ctx context.Context) {
+		c.used.Store(true)
+		c.pending.Add(-1)
+		if isMsg {
+			c.lastActive.Store(time.Now().UnixNano())
+		}
+		task(ctx, c)
+	}, co.WithPoolName(k.opts.poolName), co.WithOnDrop(func(err error) { k.undispatch(c) }))
+	if err != nil {
+		return nil, err
+	}
+	return done, nil
+}
+
+// undispatch 任务未执行就结束,撤销 pending,实体从未使用过时移除
+func (k *Kind[M, R]) undispatch(c *cell[M, R]) {
+	c.pending.Add(-1)
+	k.mu.Lock()
+	if k.cells[c.id] == c && c.pending.Load() == 0 && !c.used.Load() {
+		delete(k.cells, c.id)
+	}
+	k.mu.Unlock()
+}
+
+// dropped 任务因 co.OverflowDropOldest 被丢弃,pending 已由丢弃回调撤销
+func (k *Kind[M, R]) dropped(c *cell[M, R]) error {
+	return errors.WithStack(fmt.Errorf("%w kind=%s,id=%d", constants.ErrPoolTaskDropped, k.name, c.id))
+}
+
+// ensureStarted 实体未启动时创建实例并 OnStart,在实体线程中调用
+func (k *Kind[M, R]) ensureStarted(ctx context.Context, c *cell[M, R]) (err error) {
+	if c.actor != nil {
+		return nil
+	}
+	a := k.factory(c.id)
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.Zap.Error("actor start panic", zap.String("kind", k.name), zap.Int64("id", c.id), zap.Any("panic", rec), zap.StackSkip("stack", 2))
+			err = errors.WithStack(fmt.Errorf("%w kind=%s,id=%d: %v", constants.ErrActorPanic, k.name, c.id, rec))
+		}
+	}()
+	if err = a.OnStart(ctx); err != nil {
+		return err
+	}
+	c.actor = a
+	return nil
+}
+
+// receive 处理消息,panic时按 Supervisor 策略处理,在实体线程中调用
+func (k *Kind[M, R]) receive(ctx context.Context, c *cell[M, R], msg M) (reply R, err error) {
+	if err = k.ensureStarted(ctx, c); err != nil {
+		return reply, err
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = k.supervise(ctx, c, rec)
+		}
+	}()
+	return c.actor.Receive(ctx, msg)
+}
+
+func (k *Kind[M, R]) supervise(ctx context.Context, c *cell[M, R], rec any) error {
+	logger.Zap.Error("actor receive panic", zap.String("kind", k.name), zap.Int64("id", c.id), zap.Any("panic", rec), zap.StackSkip("stack", 3))
+	if k.opts.panicHandler != nil {
+		k.opts.panicHandler(c.id, rec)
+	}
+	switch k.opts.supervisor {
+	case SupervisorResume:
+	case SupervisorStop:
+		c.actor = nil
+		k.remove(c)
+	default:
+		c.actor = nil
+		if err := k.ensureStarted(ctx, c); err != nil {
+			logger.Zap.Error("actor restart failed", zap.String("kind", k.name), zap.Int64("id", c.id), zap.Error(err))
+		}
+	}
+	return errors.WithStack(fmt.Errorf("%w kind=%s,id=%d: %v", constants.ErrActorPanic, k.name, c.id, rec))
+}
+
+// stopCell 投递停止任务
+//
+//	@param idleOnly 为true时只在实体仍然空闲时停止,用于钝化
+//	@return done 派发失败时为已关闭的chan
+func (k *Kind[M, R]) stopCell(ctx context.Context, c *cell[M, R], idleOnly bool) chan struct{} {
+	c.pending.Add(1)
+	done, err := k.submit(ctx, c, false, func(ctx context.Context, c *cell[M, R]) {
+		if idleOnly && (c.pending.Load() > 0 || time.Since(time.Unix(0, c.lastActive.Load())) < k.opts.idleTimeout) {
+			return
+		}
+		if c.actor != nil {
+			k.safeStop(ctx, c)
+			c.actor = nil
+		}
+		k.remove(c)
+	})
+	if err != nil {
+		logger.Zap.Error("actor stop dispatch failed", zap.String("kind", k.name), zap.Int64("id", c.id), zap.Error(err))
+		done = make(chan struct{})
+		close(done)
+	}
+	return done
+}
+
+func (k *Kind[M, R]) safeStop(ctx context.Context, c *cell[M, R]) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.Zap.Error("actor stop panic", zap.String("kind", k.name), zap.Int64("id", c.id), zap.Any("panic", rec), zap.StackSkip("stack", 2))
+		}
+	}()
+	c.actor.OnStop(ctx)
+}
+
+// remove 邮箱为空时从 Kind 中移除,否则保留,后续消息会重新启动实体
+func (k *Kind[M, R]) remove(c *cell[M, R]) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.cells[c.id] == c && c.pending.Load() == 0 {
+		delete(k.cells, c.id)
+	}
+}
+
+// passivateLoop 定期停止空闲超过 idleTimeout 的实体
+func (k *Kind[M, R]) passivateLoop() {
+	ticker := time.NewTicker(k.opts.idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-k.stopChan:
+			return
+		case <-ticker.C:
+		}
+		deadline := time.Now().Add(-k.opts.idleTimeout).UnixNano()
+		k.mu.Lock()
+		idle := make([]*cell[M, R], 0)
+		for _, c := range k.cells {
+			if c.pending.Load() == 0 && c.lastActive.Load() < deadline {
+				idle = append(idle, c)
+			}
+		}
+		k.mu.Unlock()
+		for _, c := range idle {
+			k.stopCell(context.Background(), c, true)
+		}
+	}
+}
+
+// Key 实体在集群中的唯一标识,用于一致性哈希定位
+//
+//	@param kind
+//	@param id
+//	@return string
+func Key(kind string, id int64) string {
+	return kind + ":" + strconv.FormatInt(id, 10)
+}
+
+// Locate 定位实体所在的服务,按 Key 在 serverType 的一致性哈希环上选择服务
+//
+//	只负责定位,服务变化时定位结果随哈希环变化;Kind 只处理本服务内的投递,跨服务投递使用 Remote
+//	@param sd
+//	@param serverType
+//	@param kind
+//	@param id
+//	@return string 服务ID
+//	@return error
+func Locate(sd cluster.ServiceDiscovery, serverType, kind string, id int64) (string, error) {
+	// 有界负载的分配各服务可能不同,实体只按哈希环定位
+	if ring, ok := sd.(cluster.ConsistentHashRing); ok {
+		return ring.GetRingNode(serverType, Key(kind, id))
+	}
+	return sd.GetConsistentHashNode(serverType, Key(kind, id))
+}
@@ -0,0 +1,262 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+)
+
+func TestMain(m *testing.M) {
+	pools := map[string]config.GoPool{
+		"actorDrop": {TaskBuffer: 1, Overflow: co.OverflowDropOldest, DisableTimeoutWatch: true},
+	}
+	if err := co.NewStatefulPoolsModule(pools, nil).Init(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+type counterMsg struct {
+	add   int
+	panic bool
+	block chan struct{}
+}
+
+type counter struct {
+	id      int64
+	total   int
+	started *atomic.Int32
+	stopped *atomic.Int32
+}
+
+func (c *counter) OnStart(ctx context.Context) error {
+	c.started.Add(1)
+	return nil
+}
+
+func (c *counter) Receive(ctx context.Context, msg counterMsg) (int, error) {
+	if msg.block != nil {
+		<-msg.block
+	}
+	if msg.panic {
+		panic("boom")
+	}
+	c.total += msg.add
+	return c.total, nil
+}
+
+func (c *counter) OnStop(ctx context.Context) {
+	c.stopped.Add(1)
+}
+
+func newCounterKind(opts ...Option) (*Kind[counterMsg, int], *atomic.Int32, *atomic.Int32) {
+	started, stopped := &atomic.Int32{}, &atomic.Int32{}
+	k := NewKind[counterMsg, int]("counter", func(id int64) Actor[counterMsg, int] {
+		return &counter{id: id, started: started, stopped: stopped}
+	}, opts...)
+	return k, started, stopped
+}
+
+func TestKindTellAndRequestOrdered(t *testing.T) {
+	k, started, _ := newCounterKind()
+	defer k.Close(context.Background())
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, k.Tell(ctx, 1, counterMsg{add: 1}))
+	}
+	total, err := k.Request(ctx, 1, counterMsg{add: 1}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 101, total)
+	assert.Equal(t, int32(1), started.Load())
+
+	total, err = k.Request(ctx, 2, counterMsg{add: 5}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, 2, k.Len())
+}
+
+func TestKindRequestTimeout(t *testing.T) {
+	k, _, _ := newCounterKind()
+	defer k.Close(context.Background())
+	block := make(chan struct{})
+	_, err := k.Request(context.Background(), 1, counterMsg{block: block}, 50*time.Millisecond)
+	assert.True(t, errors.Is(err, constants.ErrActorRequestTimeout))
+	close(block)
+}
+
+func TestKindMailboxFull(t *testing.T) {
+	k, _, _ := newCounterKind(WithMailboxSize(1))
+	defer k.Close(context.Background())
+	ctx := context.Background()
+	block := make(chan struct{})
+	require.NoError(t, k.Spawn(ctx, 1))
+	require.NoError(t, k.Tell(ctx, 1, counterMsg{block: block}))
+	assert.Eventually(t, func() bool { return k.Tell(ctx, 1, counterMsg{add: 1}) == nil }, time.Second, 5*time.Millisecond)
+	err := k.Tell(ctx, 1, counterMsg{add: 1})
+	assert.True(t, errors.Is(err, constants.ErrActorMailboxFull))
+	close(block)
+}
+
+func TestKindDispatchFailed(t *testing.T) {
+	k, started, _ := newCounterKind(WithPoolName("missing"))
+	defer k.Close(context.Background())
+	ctx := context.Background()
+	// 线程池不存在时派发失败,不能阻塞到超时
+	assert.Error(t, k.Spawn(ctx, 1))
+	assert.Error(t, k.Tell(ctx, 1, counterMsg{add: 1}))
+	_, err := k.Request(ctx, 1, counterMsg{add: 1}, 0)
+	assert.Error(t, err)
+	assert.Equal(t, 0, k.Len())
+	assert.Equal(t, int32(0), started.Load())
+}
+
+func TestKindRequestDropped(t *testing.T) {
+	k, _, _ := newCounterKind(WithPoolName("actorDrop"), WithMailboxSize(2))
+	defer k.Close(context.Background())
+	ctx := context.Background()
+	block := make(chan struct{})
+	require.NoError(t, k.Spawn(ctx, 1))
+	require.NoError(t, k.Tell(ctx, 1, counterMsg{block: block}))
+	cell := func() *cell[counterMsg, int] {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		return k.cells[1]
+	}
+	assert.Eventually(t, func() bool { return cell().pending.Load() == 0 }, time.Second, 5*time.Millisecond)
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := k.Request(ctx, 1, counterMsg{add: 1}, 0)
+		errChan <- err
+	}()
+	assert.Eventually(t, func() bool { return cell().pending.Load() == 1 }, time.Second, 5*time.Millisecond)
+	// 队列已满,最早的 Request 被丢弃
+	require.NoError(t, k.Tell(ctx, 1, counterMsg{add: 2}))
+	assert.ErrorIs(t, <-errChan, constants.ErrPoolTaskDropped)
+	close(block)
+	assert.Eventually(t, func() bool { return cell().pending.Load() == 0 }, time.Second, 5*time.Millisecond)
+
+	reply, err := k.Request(ctx, 1, counterMsg{add: 3}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 5, reply)
+	assert.Equal(t, int32(0), cell().pending.Load())
+}
+
+func TestKindPendingReleasedOnDrop(t *testing.T) {
+	k, _, _ := newCounterKind(WithPoolName("actorDrop"), WithMailboxSize(2))
+	defer k.Close(context.Background())
+	ctx := context.Background()
+	block := make(chan struct{})
+	require.NoError(t, k.Spawn(ctx, 1))
+	require.NoError(t, k.Tell(ctx, 1, counterMsg{block: block}))
+	cell := func() *cell[counterMsg, int] {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		return k.cells[1]
+	}
+	assert.Eventually(t, func() bool { return cell().pending.Load() == 0 }, time.Second, 5*time.Millisecond)
+
+	// 被丢弃的 Tell 没有调用方等待,仍要撤销 pending
+	require.NoError(t, k.Tell(ctx, 1, counterMsg{add: 1}))
+	require.NoError(t, k.Tell(ctx, 1, counterMsg{add: 2}))
+	assert.Equal(t, int32(1), cell().pending.Load())
+	// Request 超时返回后才被丢弃
+	_, err := k.Request(ctx, 1, counterMsg{add: 4}, 10*time.Millisecond)
+	assert.ErrorIs(t, err, constants.ErrActorRequestTimeout)
+	require.NoError(t, k.Tell(ctx, 1, counterMsg{add: 8}))
+	assert.Equal(t, int32(1), cell().pending.Load())
+
+	close(block)
+	assert.Eventually(t, func() bool { return cell().pending.Load() == 0 }, time.Second, 5*time.Millisecond)
+	reply, err := k.Request(ctx, 1, counterMsg{add: 16}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 24, reply)
+	assert.Equal(t, int32(0), cell().pending.Load())
+}
+
+func TestKindSupervisor(t *testing.T) {
+	tables := []struct {
+		name       string
+		supervisor Supervisor
+		total      int
+		started    int32
+		alive      bool
+	}{
+		{"restart", SupervisorRestart, 1, 2, true},
+		{"resume", SupervisorResume, 2, 1, true},
+		{"stop", SupervisorStop, 1, 1, false},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			var panics atomic.Int32
+			k, started, stopped := newCounterKind(WithSupervisor(table.supervisor), WithPanicHandler(func(id int64, recovered any) {
+				panics.Add(1)
+			}))
+			defer k.Close(context.Background())
+			ctx := context.Background()
+
+			_, err := k.Request(ctx, 1, counterMsg{add: 1}, time.Second)
+			require.NoError(t, err)
+			_, err = k.Request(ctx, 1, counterMsg{panic: true}, time.Second)
+			assert.True(t, errors.Is(err, constants.ErrActorPanic))
+			assert.Equal(t, int32(1), panics.Load())
+			assert.Equal(t, table.started, started.Load())
+			assert.Equal(t, table.alive, k.Len() == 1)
+			assert.Equal(t, int32(0), stopped.Load())
+
+			total, err := k.Request(ctx, 1, counterMsg{add: 1}, time.Second)
+			require.NoError(t, err)
+			assert.Equal(t, table.total, total)
+		})
+	}
+}
+
+func TestKindStopAndPassivate(t *testing.T) {
+	k, started, stopped := newCounterKind(WithIdleTimeout(100 * time.Millisecond))
+	defer k.Close(context.Background())
+	ctx := context.Background()
+
+	require.NoError(t, k.Spawn(ctx, 1))
+	<-k.Stop(ctx, 1)
+	assert.Equal(t, int32(1), stopped.Load())
+	assert.Equal(t, 0, k.Len())
+	assert.Nil(t, k.Stop(ctx, 1))
+
+	require.NoError(t, k.Tell(ctx, 2, counterMsg{add: 1}))
+	assert.Eventually(t, func() bool { return k.Len() == 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), started.Load())
+	assert.Equal(t, int32(2), stopped.Load())
+}
+
+func TestKindClose(t *testing.T) {
+	k, _, stopped := newCounterKind()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for id := int64(1); id <= 10; id++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			assert.NoError(t, k.Spawn(ctx, id))
+		}(id)
+	}
+	wg.Wait()
+	require.NoError(t, k.Close(ctx))
+	assert.Equal(t, int32(10), stopped.Load())
+	assert.Equal(t, 0, k.Len())
+	assert.True(t, errors.Is(k.Tell(ctx, 1, counterMsg{}), constants.ErrActorKindClosed))
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "room:42", Key("room", 42))
+}
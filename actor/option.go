@@ -0,0 +1,52 @@
+package actor
+
+import "time"
+
+type options struct {
+	poolName     string
+	mailboxSize  int
+	idleTimeout  time.Duration
+	supervisor   Supervisor
+	panicHandler func(id int64, recovered any)
+}
+
+// Option Kind 的配置
+type Option func(o *options)
+
+// WithPoolName 使用的 co 线程池,默认 co.DefaultGoPoolName
+//
+//	线程池派发失败时 Tell、Request 和 Spawn 返回错误;线程池为 co.OverflowDropOldest 时被丢弃的 Tell 不会通知调用方,
+//	但会从邮箱容量中扣除;需要消息不丢失时应使用 co.OverflowBlock 或 co.OverflowReject 的线程池
+func WithPoolName(name string) Option {
+	return func(o *options) {
+		o.poolName = name
+	}
+}
+
+// WithMailboxSize 每个实体邮箱的容量,超出时 Tell 和 Request 返回 constants.ErrActorMailboxFull,默认不限制
+func WithMailboxSize(size int) Option {
+	return func(o *options) {
+		o.mailboxSize = size
+	}
+}
+
+// WithIdleTimeout 实体空闲超过该时长后钝化(停止并释放),有新消息时重新启动,默认不钝化
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = timeout
+	}
+}
+
+// WithSupervisor Receive panic 时的处理策略,默认 SupervisorRestart
+func WithSupervisor(supervisor Supervisor) Option {
+	return func(o *options) {
+		o.supervisor = supervisor
+	}
+}
+
+// WithPanicHandler Receive panic 时的回调,在实体线程中调用,可用于上报
+func WithPanicHandler(handler func(id int64, recovered any)) Option {
+	return func(o *options) {
+		o.panicHandler = handler
+	}
+}
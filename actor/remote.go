@@ -0,0 +1,147 @@
+package actor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/constants"
+	pcontext "github.com/topfreegames/pitaya/v2/context"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	remoteKeyCtxKey  = "actor.key"  // 转发的实体 Key
+	remoteTellCtxKey = "actor.tell" // 转发的是否为 Tell
+)
+
+// RPCToFunc 发往指定服务的rpc,如 pitaya.Pitaya.RPCTo
+type RPCToFunc func(ctx context.Context, serverID, routeStr string, reply proto.Message, arg proto.Message) error
+
+// Remote 按实体ID路由的actor,实体由 Locate 定位,在本服务时直接投递到 Kind,否则经 RPCTo 转发到实体所在服务
+//
+//	实体所在服务需在 route 对应的remote handler中调用 Handle,投递到该服务的 Kind;
+//	Handle 只投递到本服务,哈希环在各服务间短暂不一致时不会循环转发
+type Remote[M, R proto.Message] struct {
+	kind       *Kind[M, R]
+	sd         cluster.ServiceDiscovery
+	serverType string
+	route      string
+	rpcTo      RPCToFunc
+	newReply   func() R
+}
+
+// NewRemote 创建按实体ID路由的actor
+//
+//	@param kind 本服务的 Kind,各服务使用相同的名称
+//	@param sd
+//	@param serverType 实体所在的服务类型
+//	@param route 实体所在服务调用 Handle 的remote路由
+//	@param rpcTo
+//	@param newReply 创建空的回复,用于接收rpc回复
+//	@return *Remote[M, R]
+func NewRemote[M, R proto.Message](kind *Kind[M, R], sd cluster.ServiceDiscovery, serverType, route string, rpcTo RPCToFunc, newReply func() R) *Remote[M, R] {
+	return &Remote[M, R]{
+		kind:       kind,
+		sd:         sd,
+		serverType: serverType,
+		route:      route,
+		rpcTo:      rpcTo,
+		newReply:   newReply,
+	}
+}
+
+// Kind 本服务的 Kind
+func (r *Remote[M, R]) Kind() *Kind[M, R] {
+	return r.kind
+}
+
+// Tell 投递消息,不等待处理结果;实体在其他服务时等待对方投递完成
+//
+//	@receiver r
+//	@param ctx
+//	@param id
+//	@param msg
+//	@return error
+func (r *Remote[M, R]) Tell(ctx context.Context, id int64, msg M) error {
+	serverID, err := r.locate(id)
+	if err != nil {
+		return err
+	}
+	if serverID == "" {
+		return r.kind.Tell(ctx, id, msg)
+	}
+	ctx = pcontext.AddListToPropagateCtx(ctx, remoteKeyCtxKey, Key(r.kind.name, id), remoteTellCtxKey, true)
+	return r.rpcTo(ctx, serverID, r.route, r.newReply(), msg)
+}
+
+// Request 投递消息并等待回复
+//
+//	@receiver r
+//	@param ctx
+//	@param id
+//	@param msg
+//	@param timeout 等待回复的超时时间,<=0时只受ctx控制;实体在其他服务时作为rpc的超时时间
+//	@return R
+//	@return error
+func (r *Remote[M, R]) Request(ctx context.Context, id int64, msg M, timeout time.Duration) (R, error) {
+	var zero R
+	serverID, err := r.locate(id)
+	if err != nil {
+		return zero, err
+	}
+	if serverID == "" {
+		return r.kind.Request(ctx, id, msg, timeout)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx = pcontext.AddListToPropagateCtx(ctx, remoteKeyCtxKey, Key(r.kind.name, id), remoteTellCtxKey, false)
+	reply := r.newReply()
+	if err := r.rpcTo(ctx, serverID, r.route, reply, msg); err != nil {
+		return zero, err
+	}
+	return reply, nil
+}
+
+// Handle 在 route 的remote handler中调用,按发送方的 Tell 或 Request 投递到本服务的 Kind
+//
+//	@receiver r
+//	@param ctx rpc的ctx,包含发送方传递的实体ID
+//	@param msg
+//	@return R Tell 时为空的回复
+//	@return error
+func (r *Remote[M, R]) Handle(ctx context.Context, msg M) (R, error) {
+	var zero R
+	key, _ := pcontext.GetFromPropagateCtx(ctx, remoteKeyCtxKey).(string)
+	prefix := r.kind.name + ":"
+	if !strings.HasPrefix(key, prefix) {
+		return zero, errors.WithStack(fmt.Errorf("%w kind=%s,key=%s", constants.ErrActorRemoteInvalid, r.kind.name, key))
+	}
+	id, err := strconv.ParseInt(key[len(prefix):], 10, 64)
+	if err != nil {
+		return zero, errors.WithStack(fmt.Errorf("%w kind=%s,key=%s", constants.ErrActorRemoteInvalid, r.kind.name, key))
+	}
+	if tell, _ := pcontext.GetFromPropagateCtx(ctx, remoteTellCtxKey).(bool); tell {
+		return r.newReply(), r.kind.Tell(ctx, id, msg)
+	}
+	return r.kind.Request(ctx, id, msg, 0)
+}
+
+// locate 实体所在的服务,在本服务时返回空
+func (r *Remote[M, R]) locate(id int64) (string, error) {
+	serverID, err := Locate(r.sd, r.serverType, r.kind.name, id)
+	if err != nil {
+		return "", err
+	}
+	if serverID == r.sd.GetSelfServer().ID {
+		return "", nil
+	}
+	return serverID, nil
+}
@@ -0,0 +1,92 @@
+package actor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/cluster/mocks"
+	"github.com/topfreegames/pitaya/v2/constants"
+	pcontext "github.com/topfreegames/pitaya/v2/context"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type sumActor struct {
+	total int64
+}
+
+func (a *sumActor) OnStart(ctx context.Context) error { return nil }
+func (a *sumActor) Receive(ctx context.Context, msg *wrapperspb.Int64Value) (*wrapperspb.Int64Value, error) {
+	a.total += msg.Value
+	return wrapperspb.Int64(a.total), nil
+}
+func (a *sumActor) OnStop(ctx context.Context) {}
+
+func newSumKind() *Kind[*wrapperspb.Int64Value, *wrapperspb.Int64Value] {
+	return NewKind[*wrapperspb.Int64Value, *wrapperspb.Int64Value]("sum", func(id int64) Actor[*wrapperspb.Int64Value, *wrapperspb.Int64Value] {
+		return &sumActor{}
+	})
+}
+
+func TestRemote(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	newSD := func(self string) *mocks.MockServiceDiscovery {
+		sd := mocks.NewMockServiceDiscovery(ctrl)
+		sd.EXPECT().GetSelfServer().Return(cluster.NewServer(self, "room", false)).AnyTimes()
+		// 实体1在 room-1,实体2在 room-2
+		sd.EXPECT().GetConsistentHashNode("room", Key("sum", 1)).Return("room-1", nil).AnyTimes()
+		sd.EXPECT().GetConsistentHashNode("room", Key("sum", 2)).Return("room-2", nil).AnyTimes()
+		return sd
+	}
+	kind1, kind2 := newSumKind(), newSumKind()
+	defer kind1.Close(context.Background())
+	defer kind2.Close(context.Background())
+	newReply := func() *wrapperspb.Int64Value { return &wrapperspb.Int64Value{} }
+
+	var remote2 *Remote[*wrapperspb.Int64Value, *wrapperspb.Int64Value]
+	var calls []string
+	// 模拟rpc:只传递可传播的ctx和序列化后的消息
+	rpcTo := func(ctx context.Context, serverID, routeStr string, reply proto.Message, arg proto.Message) error {
+		calls = append(calls, serverID+"|"+routeStr)
+		encoded, err := pcontext.Encode(ctx)
+		require.NoError(t, err)
+		remoteCtx, err := pcontext.Decode(encoded)
+		require.NoError(t, err)
+		msg := &wrapperspb.Int64Value{}
+		data, err := proto.Marshal(arg)
+		require.NoError(t, err)
+		require.NoError(t, proto.Unmarshal(data, msg))
+		res, err := remote2.Handle(remoteCtx, msg)
+		if err != nil {
+			return err
+		}
+		proto.Merge(reply, res)
+		return nil
+	}
+	remote1 := NewRemote(kind1, newSD("room-1"), "room", "room.sum.handle", rpcTo, newReply)
+	remote2 = NewRemote(kind2, newSD("room-2"), "room", "room.sum.handle", nil, newReply)
+	ctx := context.Background()
+
+	reply, err := remote1.Request(ctx, 1, wrapperspb.Int64(1), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), reply.Value)
+	assert.Empty(t, calls)
+	assert.Equal(t, 1, kind1.Len())
+
+	require.NoError(t, remote1.Tell(ctx, 2, wrapperspb.Int64(2)))
+	reply, err = remote1.Request(ctx, 2, wrapperspb.Int64(3), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), reply.Value)
+	assert.Equal(t, []string{"room-2|room.sum.handle", "room-2|room.sum.handle"}, calls)
+	assert.Equal(t, 1, kind1.Len())
+	assert.Equal(t, 1, kind2.Len())
+
+	// 不是经 Remote 转发的消息
+	_, err = remote2.Handle(ctx, wrapperspb.Int64(1))
+	assert.ErrorIs(t, err, constants.ErrActorRemoteInvalid)
+}
@@ -42,6 +42,7 @@ import (
 
 	"github.com/alkaid/goerrors/apierrors"
 
+	"github.com/topfreegames/pitaya/v2/clock"
 	"github.com/topfreegames/pitaya/v2/co"
 	"github.com/topfreegames/pitaya/v2/conn/codec"
 	"github.com/topfreegames/pitaya/v2/conn/message"
@@ -91,6 +92,9 @@ type (
 		metricsReporters     []metrics.Reporter
 		serializer           serialize.Serializer // message serializer
 		state                int32                // current agent state
+		ackTimeout           time.Duration        // PushWithAck 等待确认的超时时间
+		ackRetries           int                  // PushWithAck 最大重发次数
+		acks                 pushAcks
 	}
 
 	pendingMessage struct {
@@ -112,6 +116,8 @@ type (
 	Agent interface {
 		GetSession() session.Session
 		Push(route string, v interface{}) error
+		PushWithAck(route string, v interface{}) error
+		AckPush(id uint)
 		ResponseMID(ctx context.Context, mid uint, v interface{}, isError ...bool) error
 		Close(callback map[string]string, reason ...session.CloseReason) error
 		RemoteAddr() net.Addr
@@ -144,6 +150,8 @@ type (
 		metricsReporters   []metrics.Reporter
 		serializer         serialize.Serializer // message serializer
 		serverID           string
+		ackTimeout         time.Duration
+		ackRetries         int
 	}
 )
 
@@ -159,6 +167,8 @@ func NewAgentFactory(
 	sessionPool session.SessionPool,
 	metricsReporters []metrics.Reporter,
 	serverID string,
+	ackTimeout time.Duration,
+	ackRetries int,
 ) AgentFactory {
 	return &agentFactoryImpl{
 		appDieChan:         appDieChan,
@@ -171,12 +181,14 @@ func NewAgentFactory(
 		metricsReporters:   metricsReporters,
 		serializer:         serializer,
 		serverID:           serverID,
+		ackTimeout:         ackTimeout,
+		ackRetries:         ackRetries,
 	}
 }
 
 // CreateAgent returns a new agent
 func (f *agentFactoryImpl) CreateAgent(conn net.Conn) Agent {
-	return newAgent(conn, f.decoder, f.encoder, f.serializer, f.heartbeatTimeout, f.messagesBufferSize, f.appDieChan, f.messageEncoder, f.metricsReporters, f.sessionPool, f.serverID)
+	return newAgent(conn, f.decoder, f.encoder, f.serializer, f.heartbeatTimeout, f.messagesBufferSize, f.appDieChan, f.messageEncoder, f.metricsReporters, f.sessionPool, f.serverID, f.ackTimeout, f.ackRetries)
 }
 
 // NewAgent create new agent instance
@@ -192,6 +204,8 @@ func newAgent(
 	metricsReporters []metrics.Reporter,
 	sessionPool session.SessionPool,
 	serverID string,
+	ackTimeout time.Duration,
+	ackRetries int,
 ) Agent {
 	// initialize heartbeat and handshake data on first user connection
 	serializerName := serializer.GetName()
@@ -212,12 +226,14 @@ func newAgent(
 		decoder:              packetDecoder,
 		encoder:              packetEncoder,
 		heartbeatTimeout:     heartbeatTime,
-		lastAt:               time.Now().Unix(),
+		lastAt:               clock.Now().Unix(),
 		serializer:           serializer,
 		state:                constants.StatusStart,
 		messageEncoder:       messageEncoder,
 		metricsReporters:     metricsReporters,
 		sessionPool:          sessionPool,
+		ackTimeout:           ackTimeout,
+		ackRetries:           ackRetries,
 	}
 
 	// binding session
@@ -379,6 +395,9 @@ func (a *agentImpl) Close(callback map[string]string, reason ...session.CloseRea
 	closeReason := session.CloseReasonNormal
 	if len(reason) > 0 {
 		closeReason = reason[0]
+	} else if a.Session.IsMigrating() {
+		// 迁移中的session断开时先视为迁移,超时未被目标网关接管时由源网关补做下线处理
+		closeReason = session.CloseReasonKickMigrate
 	}
 	logger.Zap.Debug("Session closed",
 		zap.Int64("ID", a.Session.ID()), zap.String("UID", a.Session.UID()), zap.Int("reason", closeReason), zap.Stringer("IP", a.conn.RemoteAddr()))
@@ -392,7 +411,8 @@ func (a *agentImpl) Close(callback map[string]string, reason ...session.CloseRea
 		close(a.chStopHeartbeat)
 		close(a.chStopKeepCacheAlive)
 		close(a.chDie)
-		a.onSessionClosed(a.Session, callback, reason...)
+		a.onUnackedPushes(a.Session)
+		a.onSessionClosed(a.Session, callback, closeReason)
 	}
 	// 若是被kick的因为是先agent.close()再session.close(),会造成瞬时不准确,但下一次report时就能准确
 	metrics.ReportNumberOfConnectedClients(a.metricsReporters, a.sessionPool.GetSessionCount())
@@ -448,7 +468,7 @@ func (a *agentImpl) Kick(ctx context.Context, reason ...session.CloseReason) err
 
 // SetLastAt sets the last at to now
 func (a *agentImpl) SetLastAt() {
-	atomic.StoreInt64(&a.lastAt, time.Now().Unix())
+	atomic.StoreInt64(&a.lastAt, clock.Now().Unix())
 }
 
 // SetStatus sets the agent status
@@ -493,7 +513,7 @@ const ttlKeepAliveIntervalRate = 10
 //
 //	@receiver a
 func (a *agentImpl) keepClusterCacheAlive() {
-	ticker := time.NewTicker(a.Session.GetClusterStorage().CacheTTL() / ttlKeepAliveIntervalRate)
+	ticker := clock.NewTicker(a.Session.GetClusterStorage().CacheTTL() / ttlKeepAliveIntervalRate)
 
 	defer func() {
 		ticker.Stop()
@@ -501,7 +521,7 @@ func (a *agentImpl) keepClusterCacheAlive() {
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			if a.Session.UID() == "" {
 				continue
 			}
@@ -519,7 +539,7 @@ func (a *agentImpl) keepClusterCacheAlive() {
 }
 
 func (a *agentImpl) heartbeat() {
-	ticker := time.NewTicker(a.heartbeatTimeout)
+	ticker := clock.NewTicker(a.heartbeatTimeout)
 
 	defer func() {
 		ticker.Stop()
@@ -528,14 +548,14 @@ func (a *agentImpl) heartbeat() {
 
 	for {
 		select {
-		case <-ticker.C:
-			deadline := time.Now().Add(-2 * a.heartbeatTimeout).Unix()
+		case <-ticker.C():
+			deadline := clock.Now().Add(-2 * a.heartbeatTimeout).Unix()
 			if atomic.LoadInt64(&a.lastAt) < deadline {
 				logger.Zap.Debug("Session heartbeat timeout", zap.Int64("LastTime", atomic.LoadInt64(&a.lastAt)), zap.Int64("Deadline", deadline))
 				return
 			}
 			bs := make([]byte, 8)
-			binary.BigEndian.PutUint64(bs, uint64(time.Now().UnixMilli()))
+			binary.BigEndian.PutUint64(bs, uint64(clock.Now().UnixMilli()))
 			hbData, err := a.encoder.Encode(packet.Heartbeat, bs)
 			if err != nil {
 				logger.Zap.Error("encode heartbeat failed", zap.Error(err))
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alkaid/goerrors/apierrors"
+	"github.com/topfreegames/pitaya/v2/clock"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/conn/message"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"github.com/topfreegames/pitaya/v2/session"
+	"github.com/topfreegames/pitaya/v2/util"
+	"go.uber.org/zap"
+)
+
+type (
+	// unackedPush 已发送但未被客户端确认的推送
+	unackedPush struct {
+		route   string
+		data    []byte
+		sentAt  time.Time
+		retries int
+	}
+
+	// pushAcks 确认推送的发送记录
+	pushAcks struct {
+		mu      sync.Mutex
+		nextID  uint
+		pending map[uint]*unackedPush
+	}
+)
+
+// PushWithAck 需要客户端确认的推送,超过 ackTimeout 未确认时重发,最多重发 ackRetries 次
+//
+//	@receiver a
+//	@param route
+//	@param v
+//	@return error
+func (a *agentImpl) PushWithAck(route string, v interface{}) error {
+	if a.GetStatus() == constants.StatusClosed {
+		return apierrors.ClientClosed("ErrBrokenPipe", "agent push error", "").WithCause(constants.ErrBrokenPipe)
+	}
+	data, err := util.SerializeOrRaw(a.serializer, v)
+	if err != nil {
+		return err
+	}
+	a.acks.mu.Lock()
+	if a.acks.pending == nil {
+		a.acks.pending = map[uint]*unackedPush{}
+		co.Go(func() { a.retryPushes() })
+	}
+	a.acks.nextID++
+	id := a.acks.nextID
+	a.acks.pending[id] = &unackedPush{route: route, data: data, sentAt: clock.Now()}
+	a.acks.mu.Unlock()
+	logger.Zap.Debug("Type=AckPush", zap.Int64("ID", a.Session.ID()), zap.String("UID", a.Session.UID()), zap.String("Route", route), zap.Uint("MID", id), zap.Int("DataLen", len(data)))
+	return a.send(pendingMessage{typ: message.AckPush, route: route, mid: id, payload: data})
+}
+
+// AckPush 客户端确认收到推送
+//
+//	@receiver a
+//	@param id
+func (a *agentImpl) AckPush(id uint) {
+	a.acks.mu.Lock()
+	delete(a.acks.pending, id)
+	a.acks.mu.Unlock()
+}
+
+// retryPushes 重发超时未确认的推送,超过重发次数后不再重发,等待session关闭时交给回调
+func (a *agentImpl) retryPushes() {
+	if a.ackTimeout <= 0 {
+		return
+	}
+	ticker := clock.NewTicker(a.ackTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			deadline := clock.Now().Add(-a.ackTimeout)
+			var resend []pendingMessage
+			a.acks.mu.Lock()
+			for id, p := range a.acks.pending {
+				if p.retries >= a.ackRetries || p.sentAt.After(deadline) {
+					continue
+				}
+				p.retries++
+				p.sentAt = clock.Now()
+				resend = append(resend, pendingMessage{typ: message.AckPush, route: p.route, mid: id, payload: p.data})
+			}
+			a.acks.mu.Unlock()
+			// 按ID顺序重发,保持推送顺序
+			sort.Slice(resend, func(i, j int) bool { return resend[i].mid < resend[j].mid })
+			for _, pm := range resend {
+				if err := a.send(pm); err != nil {
+					logger.Zap.Warn("resend ack push error", zap.Int64("ID", a.Session.ID()), zap.Uint("MID", pm.mid), zap.Error(err))
+				}
+			}
+		case <-a.chDie:
+			return
+		}
+	}
+}
+
+// takeUnackedPushes 取出所有未确认的推送,按ID顺序排列
+func (a *agentImpl) takeUnackedPushes() []*session.UnackedPush {
+	a.acks.mu.Lock()
+	defer a.acks.mu.Unlock()
+	if len(a.acks.pending) == 0 {
+		return nil
+	}
+	pushes := make([]*session.UnackedPush, 0, len(a.acks.pending))
+	for id, p := range a.acks.pending {
+		pushes = append(pushes, &session.UnackedPush{ID: id, Route: p.route, Data: p.data})
+	}
+	a.acks.pending = map[uint]*unackedPush{}
+	sort.Slice(pushes, func(i, j int) bool { return pushes[i].ID < pushes[j].ID })
+	return pushes
+}
+
+func (a *agentImpl) onUnackedPushes(s session.Session) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Zap.Error("pitaya/onUnackedPushes", zap.Any("recover", err))
+		}
+	}()
+	pushes := a.takeUnackedPushes()
+	if len(pushes) == 0 {
+		return
+	}
+	for _, fn := range a.sessionPool.GetUnackedPushCallbacks() {
+		fn(s, pushes)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"os/signal"
 	"reflect"
 	"strings"
+	"sync"
 	"syscall"
 
 	"go.uber.org/zap"
@@ -37,19 +38,23 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
 	"github.com/topfreegames/pitaya/v2/co"
 	"github.com/topfreegames/pitaya/v2/pipeline"
 	"github.com/topfreegames/pitaya/v2/protos"
 
 	"github.com/topfreegames/pitaya/v2/acceptor"
+	"github.com/topfreegames/pitaya/v2/clock"
 	"github.com/topfreegames/pitaya/v2/cluster"
 	"github.com/topfreegames/pitaya/v2/component"
 	"github.com/topfreegames/pitaya/v2/config"
 	"github.com/topfreegames/pitaya/v2/conn/message"
 	"github.com/topfreegames/pitaya/v2/constants"
 	pcontext "github.com/topfreegames/pitaya/v2/context"
+	"github.com/topfreegames/pitaya/v2/delaytask"
 	"github.com/topfreegames/pitaya/v2/docgenerator"
 	"github.com/topfreegames/pitaya/v2/groups"
+	"github.com/topfreegames/pitaya/v2/inbox"
 	"github.com/topfreegames/pitaya/v2/interfaces"
 	"github.com/topfreegames/pitaya/v2/logger"
 	"github.com/topfreegames/pitaya/v2/metrics"
@@ -106,10 +111,41 @@ type Pitaya interface {
 	//  @param server
 	//  @return error
 	FlushServer2Cluster(server *cluster.Server) error
+	// SetServerStatus 修改当前服务状态并同步到集群,设置为 draining 后健康检查不再修改状态
+	//  @param status
+	//  @return error
+	SetServerStatus(status cluster.ServerStatus) error
 	// AddServerDiscoveryListener 添加服务发现中服务的生命周期监听
 	//  @receiver app
 	//  @param listener
 	AddServerDiscoveryListener(listener cluster.SDListener)
+	// AddHashRingListener 添加一致性哈希环变化监听,用于有状态缓存按迁移的区间预热或淘汰
+	//  @param listener
+	//  @return error
+	AddHashRingListener(listener cluster.HashRingListener) error
+	// AddElectionListener 添加主节点选举监听,用于在成为主节点时启动、失去主节点时停止的任务
+	//  @param listener
+	//  @return error
+	AddElectionListener(listener cluster.ElectionListener) error
+	// Campaign 参与命名选举,返回可观察主节点变化和辞职的句柄
+	//  @param name 选举名
+	//  @return cluster.Leadership
+	//  @return error
+	Campaign(name string) (cluster.Leadership, error)
+	// Lock 获取分布式锁,锁带有 fencing token
+	//  @param ctx
+	//  @param name 锁名
+	//  @param ttl 锁的租约时长
+	//  @return cluster.Lock
+	//  @return error
+	Lock(ctx context.Context, name string, ttl time.Duration) (cluster.Lock, error)
+	// Schedule 按cron表达式执行任务,表达式格式见 timer.CronSchedule
+	//  @param spec 如 "0 0 5 * * *" 每天5点, "CRON_TZ=Asia/Shanghai 0 0 0 * * MON" 每周一零点
+	//  @param fn
+	//  @param opts timer.WithLeaderOnly 只在主节点执行, timer.WithMissedPolicy 错过执行时间的策略等
+	//  @return *timer.Timer 调用 Stop 取消
+	//  @return error
+	Schedule(spec string, fn timer.Func, opts ...timer.Option) (*timer.Timer, error)
 	// AddConfLoader 添加配置重载回调
 	//  @param loader
 	AddConfLoader(loader config.ConfLoader)
@@ -147,6 +183,16 @@ type Pitaya interface {
 	// SetSessionCache 自定义session缓存设施
 	//  @param cache
 	SetSessionCache(cache session.CacheInterface)
+	// SetInbox 自定义离线消息收件箱,需在 Start 前调用
+	//  @param ib
+	SetInbox(ib inbox.Inbox)
+	// SetDelayTaskStore 自定义延迟任务存储并启用延迟任务,需在 Start 前调用
+	//  @param store
+	SetDelayTaskStore(store delaytask.Store)
+	// DelayTasks 持久化延迟任务队列,用于注册处理器以及添加、取消、重新调度任务
+	//  @return *delaytask.Queue
+	//  @return error 未启用时返回 constants.ErrDelayTaskNotEnabled
+	DelayTasks() (*delaytask.Queue, error)
 	// AddSessionListener 添加session状态监听
 	//  @param listener
 	AddSessionListener(listener cluster.RemoteSessionListener)
@@ -258,6 +304,31 @@ type Pitaya interface {
 	//  @return []string
 	//  @return error
 	SendKickToUsers(uids []string, frontendType string, callback map[string]string) ([]string, error)
+	// SendPersistentPushToUsers 持久化推送,用户离线或推送失败时存入收件箱,在下次 Bind 时按顺序重放
+	//  未启用收件箱时等同于 SendPushToUsers
+	//  @param route
+	//  @param v
+	//  @param uids
+	//  @param frontendType
+	//  @return []string 既未推送也未存入收件箱的uid
+	//  @return error
+	SendPersistentPushToUsers(route string, v interface{}, uids []string, frontendType string) ([]string, error)
+	// MigrateSession 将在线用户迁移到其他frontend,用于扩容后的负载均衡或下线前清空节点
+	//  会给客户端推送 constants.SessionRedirectRoute 重定向消息,地址取自目标frontend metadata的 constants.ClientAddrKey
+	//  session数据和backend绑定关系经由cluster cache交接,目标frontend接管时不会触发绑定和断线广播
+	//  @param ctx
+	//  @param uid
+	//  @param targetFrontendID 目标frontend id
+	//  @return error
+	MigrateSession(ctx context.Context, uid string, targetFrontendID string) error
+	// QuerySessions 查询集群内所有frontend上满足条件的session,用于GM工具和线上排查
+	//  经 Fork 广播给所有frontend,仅支持nats.结果按连接时间排序后统一分页,
+	//  部分frontend查询失败或超时时返回其余结果并在 QueryResult.Errors 中记录
+	//  @param ctx
+	//  @param q 查询条件
+	//  @return *session.QueryResult
+	//  @return error
+	QuerySessions(ctx context.Context, q *session.SessionQuery) (*session.QueryResult, error)
 
 	GroupCreate(ctx context.Context, groupName string) error
 	GroupCreateWithTTL(ctx context.Context, groupName string, ttlTime time.Duration) error
@@ -347,6 +418,12 @@ type Pitaya interface {
 	// @receiver app
 	// @param f
 	OnSessionClose(f session.OnSessionCloseFunc)
+	// OnUnackedPush 设置session关闭时仍有未确认推送的回调,启用收件箱时这些推送会自动存入收件箱
+	//  @param f
+	OnUnackedPush(f session.OnUnackedPushFunc)
+	// OnMigrationAbandoned 设置迁移超时未被目标网关接管且连接已断开的session的回调,用于补做 OnSessionClose 中跳过的 session.CloseReasonKickMigrate 下线处理
+	//  @param f
+	OnMigrationAbandoned(f session.OnMigrationAbandonedFunc)
 	// OnAfterSessionBind 设置本地session bind后的回调
 	//  @param f
 	OnAfterSessionBind(f session.OnSessionBindFunc)
@@ -363,6 +440,7 @@ type App struct {
 	acceptors          []acceptor.Acceptor
 	config             config.PitayaConfig
 	dieChan            chan bool
+	stopChan           chan struct{} // 开始停服时关闭,通知后台任务退出.dieChan 的信号只能被 Start 消费一次
 	heartbeat          time.Duration
 	router             *router.Router
 	rpcClient          cluster.RPCClient
@@ -387,6 +465,11 @@ type App struct {
 	redis              redis.Cmdable
 	conf               *config.Config
 	onStarted          func()
+	sys                *remote.Sys
+	inbox              inbox.Inbox
+	delayTasks         *delaytask.Queue
+	statusLock         sync.Mutex
+	status             cluster.ServerStatus // 当前状态,由 statusLock 保护;app.server 与服务发现共享,不直接修改
 }
 
 // NewApp is the base constructor for a pitaya app instance
@@ -410,6 +493,7 @@ func NewApp(
 ) *App {
 	app := &App{
 		server:           server,
+		status:           server.Status,
 		config:           config,
 		rpcClient:        rpcClient,
 		rpcServer:        rpcServer,
@@ -420,6 +504,7 @@ func NewApp(
 		groups:           groups,
 		startAt:          time.Now(),
 		dieChan:          dieChan,
+		stopChan:         make(chan struct{}),
 		acceptors:        acceptors,
 		metricsReporters: metricsReporters,
 		serverMode:       serverMode,
@@ -518,6 +603,85 @@ func (app *App) AddServerDiscoveryListener(listener cluster.SDListener) {
 	app.serviceDiscovery.AddListener(listener)
 }
 
+// AddHashRingListener
+//
+//	@implement Pitaya.AddHashRingListener
+//	@receiver app
+//	@param listener
+//	@return error
+func (app *App) AddHashRingListener(listener cluster.HashRingListener) error {
+	ring, ok := app.serviceDiscovery.(cluster.ConsistentHashRing)
+	if !ok {
+		return errors.WithStack(constants.ErrHashRingNotSupported)
+	}
+	ring.AddHashRingListener(listener)
+	return nil
+}
+
+// AddElectionListener
+//
+//	@implement Pitaya.AddElectionListener
+//	@receiver app
+//	@param listener
+//	@return error
+func (app *App) AddElectionListener(listener cluster.ElectionListener) error {
+	notifier, ok := app.serviceDiscovery.(cluster.ElectionNotifier)
+	if !ok {
+		return errors.WithStack(constants.ErrElectionListenerNotSupported)
+	}
+	notifier.AddElectionListener(listener)
+	return nil
+}
+
+// Campaign
+//
+//	@implement Pitaya.Campaign
+//	@receiver app
+//	@param name
+//	@return cluster.Leadership
+//	@return error
+func (app *App) Campaign(name string) (cluster.Leadership, error) {
+	coordinator, ok := app.serviceDiscovery.(cluster.Coordinator)
+	if !ok {
+		return nil, errors.WithStack(constants.ErrCoordinationNotSupported)
+	}
+	return coordinator.Campaign(name)
+}
+
+// Lock
+//
+//	@implement Pitaya.Lock
+//	@receiver app
+//	@param ctx
+//	@param name
+//	@param ttl
+//	@return cluster.Lock
+//	@return error
+func (app *App) Lock(ctx context.Context, name string, ttl time.Duration) (cluster.Lock, error) {
+	coordinator, ok := app.serviceDiscovery.(cluster.Coordinator)
+	if !ok {
+		return nil, errors.WithStack(constants.ErrCoordinationNotSupported)
+	}
+	return coordinator.Lock(ctx, name, ttl)
+}
+
+// Schedule
+//
+//	@implement Pitaya.Schedule
+//	@receiver app
+//	@param spec
+//	@param fn
+//	@param opts
+//	@return *timer.Timer
+//	@return error
+func (app *App) Schedule(spec string, fn timer.Func, opts ...timer.Option) (*timer.Timer, error) {
+	if fn == nil {
+		return nil, errors.WithStack(constants.ErrNilTimerFunc)
+	}
+	opts = append([]timer.Option{timer.WithLeaderChecker(app.IsLeader)}, opts...)
+	return timer.NewCronTimer(spec, fn, opts...)
+}
+
 // AddConfLoader
 //
 //	@implement Pitaya.AddConfLoader
@@ -617,6 +781,12 @@ func (app *App) GetLocalSessionByUid(ctx context.Context, uid string) session.Se
 func (app *App) OnSessionClose(f session.OnSessionCloseFunc) {
 	app.sessionPool.OnSessionClose(f)
 }
+func (app *App) OnUnackedPush(f session.OnUnackedPushFunc) {
+	app.sessionPool.OnUnackedPush(f)
+}
+func (app *App) OnMigrationAbandoned(f session.OnMigrationAbandonedFunc) {
+	app.sessionPool.OnMigrationAbandoned(f)
+}
 func (app *App) OnAfterSessionBind(f session.OnSessionBindFunc) {
 	app.sessionPool.OnAfterSessionBind(f)
 }
@@ -628,7 +798,8 @@ func (app *App) OnAfterKickBackend(f session.OnSessionKickBackendFunc) {
 }
 
 func (app *App) initSysRemotes() {
-	sys := remote.NewSys(app.sessionPool, app.server, app.serviceDiscovery, app.rpcClient, app.remoteService)
+	sys := remote.NewSys(app.sessionPool, app.server, app.serviceDiscovery, app.rpcClient, app.remoteService, app.config.Session.MigrateTimeout)
+	app.sys = sys
 	app.RegisterRemote(sys,
 		component.WithName("sys"),
 		component.WithNameFunc(strings.ToLower),
@@ -642,10 +813,27 @@ func (app *App) initSysRemotes() {
 func (app *App) periodicMetrics() {
 	period := app.config.Metrics.Period
 	co.Go(func() { metrics.ReportSysMetrics(app.metricsReporters, period) })
+	co.Go(func() {
+		for {
+			metrics.ReportScopedTimers(app.metricsReporters, timer.ScopedTimers())
+			co.ReportQueueDepth()
+			time.Sleep(period)
+		}
+	})
 
 	if app.worker.Started() {
 		co.Go(func() { worker.Report(app.metricsReporters, period) })
 	}
+
+	if freezer, ok := app.serviceDiscovery.(cluster.TopologyFreezer); ok {
+		co.Go(func() {
+			for {
+				frozen, staleness := freezer.Frozen()
+				metrics.ReportServiceDiscoveryFrozen(app.metricsReporters, frozen, staleness)
+				time.Sleep(period)
+			}
+		})
+	}
 }
 
 func (app *App) OnStarted(fun func()) {
@@ -681,6 +869,8 @@ func (app *App) Start() {
 		}
 	}
 
+	app.initInbox()
+
 	app.periodicMetrics()
 
 	app.listen()
@@ -706,11 +896,20 @@ func (app *App) Start() {
 		logger.Zap.Warn("the app will shutdown in a few seconds")
 	case s := <-sg:
 		logger.Sugar.Warn("got signal: ", s, ", shutting down...")
+		if s == syscall.SIGQUIT {
+			// 与go运行时的SIGQUIT一致,退出前输出调用栈,并附上线程池中的任务
+			co.Dump(os.Stderr)
+		}
 		close(app.dieChan)
 	}
+	close(app.stopChan)
 
 	logger.Zap.Warn("server is stopping...")
 
+	if err := app.SetServerStatus(cluster.ServerStatusDraining); err != nil {
+		logger.Zap.Error("failed to flush draining status", zap.Error(err))
+	}
+
 	app.sessionPool.CloseAll()
 	app.shutdownModules()
 	app.shutdownComponents()
@@ -720,7 +919,7 @@ func (app *App) listen() {
 	app.startupComponents()
 	// create global ticker instance, timer precision could be customized
 	// by SetTimerPrecision
-	timer.GlobalTicker = time.NewTicker(timer.Precision)
+	timer.GlobalTicker = clock.NewTicker(timer.Precision)
 
 	logger.Sugar.Infof("starting server %s:%s", app.server.Type, app.server.ID)
 	for i := 0; i < app.config.Concurrency.Handler.Dispatch; i++ {
@@ -758,14 +957,38 @@ func (app *App) listen() {
 	}
 	// 注册配置重载回调
 	app.RegisterModuleBefore(config.NewConfigModule(app.conf), "configLoader")
+	if err := co.SetPanicPolicy(app.config.Panic.Policy); err != nil {
+		logger.Zap.Fatal("failed to set panic policy", zap.Error(err))
+	}
+	co.SetSessionKiller(func(ctx context.Context, info co.PanicInfo) error {
+		s, ok := ctx.Value(constants.SessionCtxKey).(session.SessPublic)
+		if !ok {
+			return nil
+		}
+		return s.Kick(ctx, nil, session.CloseReasonKickPanic)
+	})
 	statefulGoPool := co.NewStatefulPoolsModule(app.config.GoPools, app.metricsReporters)
+	if app.conf != nil {
+		app.conf.AddLoader(statefulGoPool)
+	}
 
 	app.RegisterModuleBefore(statefulGoPool, "statefulGoPool")
+	if app.conf != nil {
+		if adminConf := config.NewAdminConfig(app.conf); adminConf.Enable {
+			admin := mods.NewAdmin(*adminConf, app.serviceDiscovery, app.handlerService, app.remoteService, statefulGoPool, app.conf)
+			app.RegisterModule(admin, "admin")
+		}
+	}
+	if app.delayTasks != nil {
+		app.RegisterModule(app.delayTasks, "delayTasks")
+	}
 
 	app.startModules()
 
 	logger.Zap.Info("all modules started!")
 
+	app.initHealthCheck()
+
 	app.running = true
 }
 
@@ -915,6 +1138,9 @@ func (app *App) StartWorker() {
 
 // RegisterRPCJob registers rpc job to execute jobs with retries
 func (app *App) RegisterRPCJob(rpcJob worker.RPCJob) error {
+	if app.delayTasks != nil {
+		app.delayTasks.SetRPCJob(rpcJob)
+	}
 	err := app.worker.RegisterRPCJob(rpcJob)
 	return err
 }
@@ -10,7 +10,9 @@ import (
 	"github.com/topfreegames/pitaya/v2/conn/codec"
 	"github.com/topfreegames/pitaya/v2/conn/message"
 	"github.com/topfreegames/pitaya/v2/defaultpipelines"
+	"github.com/topfreegames/pitaya/v2/delaytask"
 	"github.com/topfreegames/pitaya/v2/groups"
+	"github.com/topfreegames/pitaya/v2/inbox"
 	"github.com/topfreegames/pitaya/v2/logger"
 	"github.com/topfreegames/pitaya/v2/metrics"
 	"github.com/topfreegames/pitaya/v2/metrics/models"
@@ -47,6 +49,8 @@ type Builder struct {
 	HandlerHooks     *pipeline.HandlerHooks
 	RemoteHooks      *pipeline.HandlerHooks
 	Redis            redis.Cmdable
+	Inbox            inbox.Inbox
+	DelayTasks       *delaytask.Queue
 	conf             *config.Config
 }
 
@@ -80,7 +84,7 @@ func NewBuilderWithConfigs(
 		logger.Sugar = logger.Manager.Sugar
 		logger.Log = logger.Sugar
 	}))
-	b := NewBuilder(
+	b := newBuilder(
 		isFrontend,
 		serverType,
 		serverMode,
@@ -89,7 +93,7 @@ func NewBuilderWithConfigs(
 		*customMetrics,
 		*prometheusConfig,
 		*statsdConfig,
-		*etcdSDConfig,
+		serviceDiscoveryFactory(conf, *etcdSDConfig),
 		*natsRPCServerConfig,
 		*natsRPCClientConfig,
 		*workerConfig,
@@ -98,9 +102,61 @@ func NewBuilderWithConfigs(
 		*redisConfig,
 	)
 	b.conf = conf
+	apiVersion := config.NewAPIVersionConfig(conf)
+	b.Server.APIVersion = cluster.VersionRange{Min: apiVersion.Min, Max: apiVersion.Max}
+	if serverMode == Cluster {
+		if ring, ok := b.ServiceDiscovery.(cluster.ConsistentHashRing); ok {
+			ring.SetConsistentHashConfig(*config.NewConsistentHashConfig(conf))
+		}
+	}
 	return b
 }
 
+// sdFactory 创建服务发现.在创建其他组件之前调用,可按需修改当前服务(如服务ID)
+type sdFactory func(server *cluster.Server, dieChan chan bool) (cluster.ServiceDiscovery, error)
+
+// etcdSDFactory 创建默认的etcd服务发现
+//
+//	@param etcdSDConfig
+//	@return sdFactory
+func etcdSDFactory(etcdSDConfig config.EtcdServiceDiscoveryConfig) sdFactory {
+	return func(server *cluster.Server, dieChan chan bool) (cluster.ServiceDiscovery, error) {
+		return cluster.NewEtcdServiceDiscovery(etcdSDConfig, server, dieChan)
+	}
+}
+
+// serviceDiscoveryFactory 按 pitaya.cluster.sd.type 选择服务发现
+//
+//	@param conf
+//	@param etcdSDConfig
+//	@return sdFactory
+func serviceDiscoveryFactory(conf *config.Config, etcdSDConfig config.EtcdServiceDiscoveryConfig) sdFactory {
+	switch sdType := config.NewServiceDiscoveryType(conf); sdType {
+	case config.ServiceDiscoveryEtcd:
+		return etcdSDFactory(etcdSDConfig)
+	case config.ServiceDiscoveryStatic:
+		return func(server *cluster.Server, dieChan chan bool) (cluster.ServiceDiscovery, error) {
+			return cluster.NewStaticServiceDiscovery(*config.NewStaticServiceDiscoveryConfig(conf), server)
+		}
+	case config.ServiceDiscoveryDNS:
+		return func(server *cluster.Server, dieChan chan bool) (cluster.ServiceDiscovery, error) {
+			// SRV记录中的服务以 target:port 区分,显式配置时当前服务ID与之一致.此时其他组件尚未创建,不会持有旧ID
+			dnsConf := config.NewDNSServiceDiscoveryConfig(conf)
+			if dnsConf.ServerID != "" {
+				server.ID = dnsConf.ServerID
+			}
+			return cluster.NewDNSServiceDiscovery(*dnsConf, server)
+		}
+	case config.ServiceDiscoveryNats:
+		return func(server *cluster.Server, dieChan chan bool) (cluster.ServiceDiscovery, error) {
+			return cluster.NewNatsServiceDiscovery(*config.NewNatsServiceDiscoveryConfig(conf), server, dieChan)
+		}
+	default:
+		logger.Zap.Fatal("unknown service discovery type", zap.String("type", sdType))
+		return nil
+	}
+}
+
 // NewDefaultBuilder return a builder instance with default dependency instances for a pitaya App,
 // with default configs
 func NewDefaultBuilder(isFrontend bool, serverType string, serverMode ServerMode, serverMetadata map[string]string, builderConfig config.BuilderConfig) *Builder {
@@ -150,10 +206,55 @@ func NewBuilder(isFrontend bool,
 	enqueueOpts config.EnqueueOpts,
 	groupServiceConfig config.MemoryGroupConfig,
 	redisConfig config.RedisConfig,
+) *Builder {
+	return newBuilder(
+		isFrontend,
+		serverType,
+		serverMode,
+		serverMetadata,
+		config,
+		customMetrics,
+		prometheusConfig,
+		statsdConfig,
+		etcdSDFactory(etcdSDConfig),
+		natsRPCServerConfig,
+		natsRPCClientConfig,
+		workerConfig,
+		enqueueOpts,
+		groupServiceConfig,
+		redisConfig,
+	)
+}
+
+func newBuilder(isFrontend bool,
+	serverType string,
+	serverMode ServerMode,
+	serverMetadata map[string]string,
+	config config.BuilderConfig,
+	customMetrics models.CustomMetricsSpec,
+	prometheusConfig config.PrometheusConfig,
+	statsdConfig config.StatsdConfig,
+	newSD sdFactory,
+	natsRPCServerConfig config.NatsRPCServerConfig,
+	natsRPCClientConfig config.NatsRPCClientConfig,
+	workerConfig config.WorkerConfig,
+	enqueueOpts config.EnqueueOpts,
+	groupServiceConfig config.MemoryGroupConfig,
+	redisConfig config.RedisConfig,
 ) *Builder {
 	server := cluster.NewServer(util.NanoID(8), serverType, isFrontend, serverMetadata)
 	dieChan := make(chan bool)
 
+	// 服务发现最先创建,其他组件创建时服务ID已确定
+	var serviceDiscovery cluster.ServiceDiscovery
+	if serverMode == Cluster {
+		var err error
+		serviceDiscovery, err = newSD(server, dieChan)
+		if err != nil {
+			logger.Zap.Fatal("error creating cluster service discovery component", zap.Error(err))
+		}
+	}
+
 	metricsReporters := []metrics.Reporter{}
 	if config.Metrics.Prometheus.Enabled {
 		metricsReporters = addDefaultPrometheus(prometheusConfig, customMetrics, metricsReporters, serverType)
@@ -183,16 +284,10 @@ func NewBuilder(isFrontend bool,
 	sessionPool := session.NewSessionPool()
 	sessionPool.SetClusterCache(sessionCache)
 
-	var serviceDiscovery cluster.ServiceDiscovery
 	var rpcServer cluster.RPCServer
 	var rpcClient cluster.RPCClient
 	if serverMode == Cluster {
 		var err error
-		serviceDiscovery, err = cluster.NewEtcdServiceDiscovery(etcdSDConfig, server, dieChan)
-		if err != nil {
-			logger.Zap.Fatal("error creating default cluster service discovery component", zap.Error(err))
-		}
-
 		rpcServer, err = cluster.NewNatsRPCServer(natsRPCServerConfig, server, metricsReporters, dieChan, sessionPool)
 		if err != nil {
 			logger.Zap.Fatal("error setting default cluster rpc server component", zap.Error(err))
@@ -209,6 +304,20 @@ func NewBuilder(isFrontend bool,
 		logger.Zap.Fatal("error creating default worker", zap.Error(err))
 	}
 
+	var ib inbox.Inbox
+	if config.Pitaya.Inbox.Enabled {
+		ib = inbox.NewRedisInbox(redisClient, config.Pitaya.Inbox)
+	}
+
+	var delayTasks *delaytask.Queue
+	if config.Pitaya.DelayTask.Enabled {
+		namespace := config.Pitaya.DelayTask.Namespace
+		if namespace == "" {
+			namespace = serverType
+		}
+		delayTasks = delaytask.NewQueue(delaytask.NewRedisStore(redisClient, namespace), config.Pitaya.DelayTask)
+	}
+
 	gsi := groups.NewMemoryGroupService(groupServiceConfig)
 	if err != nil {
 		panic(err)
@@ -235,6 +344,8 @@ func NewBuilder(isFrontend bool,
 		SessionPool:      sessionPool,
 		Worker:           worker,
 		Redis:            redisClient,
+		Inbox:            ib,
+		DelayTasks:       delayTasks,
 	}
 }
 
@@ -290,6 +401,8 @@ func (builder *Builder) Build() Pitaya {
 		builder.SessionPool,
 		builder.MetricsReporters,
 		builder.Server.ID,
+		builder.Config.Pitaya.Push.AckTimeout,
+		builder.Config.Pitaya.Push.AckRetries,
 	)
 
 	handlerService := service.NewHandlerService(
@@ -324,6 +437,8 @@ func (builder *Builder) Build() Pitaya {
 		builder.Config.Pitaya,
 	)
 	app.conf = builder.conf
+	app.inbox = builder.Inbox
+	app.delayTasks = builder.DelayTasks
 	return app
 }
 
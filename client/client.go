@@ -23,6 +23,7 @@ package client
 import (
 	"bytes"
 	"crypto/tls"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -87,8 +88,15 @@ type Client struct {
 	writeMutex          sync.Mutex
 	lastAt              time.Time
 	connMutex           sync.Mutex
+	ackedPushes         map[uint]struct{} // 最近确认的 message.AckPush ID,用于过滤服务器重发的重复推送
+	ackedPushRing       []uint            // 按确认顺序记录 ackedPushes 中的ID,满 ackedPushWindow 后淘汰最早的
+	ackedPushPos        int               // ackedPushRing 下一个写入位置
 }
 
+// ackedPushWindow 记录的已确认推送ID数量.服务器按ID顺序重发未确认的推送且重发次数有限,
+// 重复推送只会出现在最近确认的ID中,更早的ID无需保留
+const ackedPushWindow = 1024
+
 // MsgChannel return the incoming message channel
 func (c *Client) MsgChannel() chan *message.Message {
 	return c.IncomingMsgChan
@@ -271,6 +279,9 @@ func (c *Client) handlePackets() {
 					}
 					c.pendingReqMutex.Unlock()
 				}
+				if m.Type == message.AckPush && !c.ackPush(m.ID) {
+					continue // 服务器重发的推送只确认不重复处理
+				}
 				c.IncomingMsgChan <- m
 			case packet.Kick:
 				Log.Warn("got kick packet from the server! disconnecting...")
@@ -282,6 +293,31 @@ func (c *Client) handlePackets() {
 	}
 }
 
+// ackPush 确认服务器推送,返回是否首次收到
+func (c *Client) ackPush(id uint) bool {
+	bs := make([]byte, 8)
+	binary.BigEndian.PutUint64(bs, uint64(id))
+	p, err := c.packetEncoder.Encode(packet.PushAck, bs)
+	if err == nil {
+		_, err = c.SafeWrite(p)
+	}
+	if err != nil {
+		Log.Error("error sending push ack to server", zap.Uint("id", id), zap.Error(err))
+	}
+	if _, ok := c.ackedPushes[id]; ok {
+		return false
+	}
+	if len(c.ackedPushRing) < ackedPushWindow {
+		c.ackedPushRing = append(c.ackedPushRing, id)
+	} else {
+		delete(c.ackedPushes, c.ackedPushRing[c.ackedPushPos])
+		c.ackedPushRing[c.ackedPushPos] = id
+		c.ackedPushPos = (c.ackedPushPos + 1) % ackedPushWindow
+	}
+	c.ackedPushes[id] = struct{}{}
+	return true
+}
+
 func (c *Client) readPackets(buf *bytes.Buffer) ([]*packet.Packet, error) {
 	// listen for sv messages
 	data := make([]byte, 1024)
@@ -417,6 +453,9 @@ func (c *Client) ConnectTo(uri string, tlsConfig ...*tls.Config) error {
 		return err
 	}
 	c.IncomingMsgChan = make(chan *message.Message, 10)
+	c.ackedPushes = make(map[uint]struct{}, ackedPushWindow)
+	c.ackedPushRing = make([]uint, 0, ackedPushWindow)
+	c.ackedPushPos = 0
 
 	if err = c.handleHandshake(); err != nil {
 		return err
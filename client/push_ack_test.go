@@ -0,0 +1,30 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya/v2/mocks"
+)
+
+func TestAckPushWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockConn := mocks.NewMockPlayerConn(ctrl)
+	mockConn.EXPECT().Write(gomock.Any()).AnyTimes()
+	mockConn.EXPECT().SetWriteDeadline(gomock.Any()).AnyTimes()
+	c := New()
+	c.conn = mockConn
+	c.ackedPushes = make(map[uint]struct{})
+
+	assert.True(t, c.ackPush(1))
+	assert.False(t, c.ackPush(1))
+	for id := uint(2); id <= ackedPushWindow+1; id++ {
+		assert.True(t, c.ackPush(id))
+	}
+	assert.Len(t, c.ackedPushes, ackedPushWindow)
+	// 最早的ID已淘汰,最近的仍能过滤重复
+	assert.NotContains(t, c.ackedPushes, uint(1))
+	assert.False(t, c.ackPush(ackedPushWindow+1))
+	assert.False(t, c.ackPush(2))
+}
@@ -0,0 +1,104 @@
+// Package clock 可替换的时钟,框架内读取当前时间和等待时间都应通过该包,测试时可用 helpers.FakeClock 替换为虚拟时钟
+package clock
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Clock 时钟
+	Clock interface {
+		Now() time.Time
+		Since(t time.Time) time.Duration
+		After(d time.Duration) <-chan time.Time
+		Sleep(d time.Duration)
+		NewTicker(d time.Duration) Ticker
+		NewTimer(d time.Duration) Timer
+		// AfterFunc d 后执行 f,真实时钟在新的goroutine中执行,虚拟时钟在推进时间的goroutine中执行
+		AfterFunc(d time.Duration, f func()) Timer
+	}
+
+	// Ticker 对应 time.Ticker
+	Ticker interface {
+		C() <-chan time.Time
+		Stop()
+		Reset(d time.Duration)
+	}
+
+	// Timer 对应 time.Timer, AfterFunc 创建的 Timer C 返回nil
+	Timer interface {
+		C() <-chan time.Time
+		Stop() bool
+		Reset(d time.Duration) bool
+	}
+)
+
+type holder struct{ clock Clock }
+
+var current atomic.Pointer[holder]
+
+func init() {
+	current.Store(&holder{clock: Real{}})
+}
+
+// Default 当前使用的时钟,默认为 Real
+//
+//	@return Clock
+func Default() Clock {
+	return current.Load().clock
+}
+
+// SetDefault 替换时钟,仅用于测试,已创建的 Ticker Timer 不受影响
+//
+//	@param c nil时恢复为 Real
+//	@return restore 恢复为替换前的时钟
+func SetDefault(c Clock) (restore func()) {
+	if c == nil {
+		c = Real{}
+	}
+	old := current.Swap(&holder{clock: c})
+	return func() { current.Store(old) }
+}
+
+// Now 当前时间
+func Now() time.Time { return Default().Now() }
+
+// Since 距t经过的时间
+func Since(t time.Time) time.Duration { return Default().Since(t) }
+
+// After d 后发送当前时间
+func After(d time.Duration) <-chan time.Time { return Default().After(d) }
+
+// Sleep 等待d
+func Sleep(d time.Duration) { Default().Sleep(d) }
+
+// NewTicker 每隔d发送当前时间
+func NewTicker(d time.Duration) Ticker { return Default().NewTicker(d) }
+
+// NewTimer d 后发送当前时间
+func NewTimer(d time.Duration) Timer { return Default().NewTimer(d) }
+
+// AfterFunc d 后执行f
+func AfterFunc(d time.Duration, f func()) Timer { return Default().AfterFunc(d, f) }
+
+// Real 基于 time 包的真实时钟
+type Real struct{}
+
+func (Real) Now() time.Time                         { return time.Now() }
+func (Real) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (Real) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (Real) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (Real) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+func (Real) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (Real) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
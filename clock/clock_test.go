@@ -0,0 +1,70 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya/v2/clock"
+	"github.com/topfreegames/pitaya/v2/helpers"
+)
+
+func TestSetDefault(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	restore := clock.SetDefault(helpers.NewFakeClock(start))
+	assert.Equal(t, start, clock.Now())
+	restore()
+	assert.IsType(t, clock.Real{}, clock.Default())
+}
+
+func TestFakeClockAdvance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := helpers.UseFakeClock(t, start)
+
+	var fired []string
+	clock.AfterFunc(3*time.Second, func() { fired = append(fired, "after3") })
+	clock.AfterFunc(time.Second, func() {
+		fired = append(fired, "after1")
+		// 回调中创建的已到期定时器在本次推进中触发
+		clock.AfterFunc(time.Second, func() { fired = append(fired, "nested") })
+	})
+	stopped := clock.AfterFunc(2*time.Second, func() { fired = append(fired, "stopped") })
+	assert.True(t, stopped.Stop())
+	ticker := clock.NewTicker(time.Second)
+	timer := clock.NewTimer(10 * time.Second)
+	assert.Equal(t, 4, c.Waiters())
+
+	c.Advance(3 * time.Second)
+	assert.Equal(t, []string{"after1", "nested", "after3"}, fired)
+	assert.Equal(t, start.Add(3*time.Second), c.Now())
+	// 未读取的tick被丢弃,与 time.Ticker 一致
+	assert.Equal(t, start.Add(time.Second), <-ticker.C())
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker should drop ticks for slow receivers")
+	default:
+	}
+
+	ticker.Reset(5 * time.Second)
+	c.Advance(5 * time.Second)
+	assert.Equal(t, start.Add(8*time.Second), <-ticker.C())
+	ticker.Stop()
+
+	assert.True(t, timer.Reset(time.Second))
+	c.Advance(time.Second)
+	assert.Equal(t, start.Add(9*time.Second), <-timer.C())
+	assert.False(t, timer.Stop())
+	assert.Equal(t, 0, c.Waiters())
+}
+
+func TestFakeClockSleep(t *testing.T) {
+	c := helpers.NewFakeClock(time.Now())
+	done := make(chan bool, 1)
+	go func() {
+		c.Sleep(time.Minute)
+		done <- true
+	}()
+	helpers.ShouldEventuallyReturn(t, c.Waiters, 1)
+	c.Advance(time.Minute)
+	helpers.ShouldEventuallyReceive(t, done)
+}
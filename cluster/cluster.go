@@ -71,6 +71,27 @@ type SDListener interface {
 	ModifyServer(sv *Server, old *Server)
 }
 
+// ElectionListener 主节点选举监听,回调与 SDListener 的回调串行执行
+//
+//	主节点为其他服务时,OnLeaderChanged 在该服务的 AddServer 之后通知,该服务 RemoveServer 之后通知主节点为空
+//	回调中可以再次触发服务发现的通知,新的通知在当前回调返回后执行
+type ElectionListener interface {
+	// OnElected 当前服务成为主节点
+	//  @param ctx 失去主节点时取消
+	OnElected(ctx context.Context)
+	// OnRevoked 当前服务失去主节点
+	OnRevoked()
+	// OnLeaderChanged 主节点变化
+	//  @param leaderID 为空表示暂无主节点
+	OnLeaderChanged(leaderID string)
+}
+
+// ElectionNotifier 支持主节点选举监听的服务发现
+type ElectionNotifier interface {
+	// AddElectionListener 添加主节点选举监听
+	AddElectionListener(listener ElectionListener)
+}
+
 // RemoteBindingListener listens to session bindings in remote servers
 //
 // 框架内部使用
@@ -189,6 +210,5 @@ func GoWithRequest(ctx context.Context, req *protos.Request, task func(ctx conte
 		co.GoWithUser(ctx, uidInt, task)
 		return
 	}
-	sid := session.FrontendSessHash(req.FrontendID, req.Session.Id)
-	co.GoWithID(ctx, sid, task, co.WithPoolName(co.SessionGoPoolName))
+	co.GoWithKey(ctx, session.FrontendSessKey(req.FrontendID, req.Session.Id), task, co.WithPoolName(co.SessionGoPoolName))
 }
@@ -0,0 +1,50 @@
+package cluster
+
+import (
+	"context"
+	"time"
+)
+
+// Leadership 命名选举的句柄
+type Leadership interface {
+	// Name 选举名
+	Name() string
+	// LeaderID 当前主节点ID,为空表示暂无主节点
+	LeaderID() string
+	// IsLeader 当前服务是否为主节点
+	IsLeader() bool
+	// Changes 主节点变化通知,值为新的主节点ID;未消费的通知被最新值替换,只保证收到最新的主节点ID;Resign 后关闭
+	Changes() <-chan string
+	// Resign 退出竞选,为主节点时辞职
+	Resign(ctx context.Context) error
+}
+
+// Lock 分布式锁的句柄
+type Lock interface {
+	// Name 锁名
+	Name() string
+	// Token fencing token,每次加锁单调递增,下游存储可据此拒绝过期持有者的写入
+	Token() int64
+	// Done 锁因会话过期而丢失时关闭
+	Done() <-chan struct{}
+	// Unlock 释放锁
+	Unlock(ctx context.Context) error
+}
+
+// Coordinator 支持命名选举和分布式锁的服务发现
+type Coordinator interface {
+	// Campaign 参与命名选举,立即返回句柄,竞选在后台进行
+	//  @param name 选举名
+	//  @return Leadership
+	//  @return error
+	Campaign(name string) (Leadership, error)
+	// Lock 获取分布式锁,阻塞直到获取成功或ctx结束
+	//  @param ctx
+	//  @param name 锁名
+	//  @param ttl 锁的租约时长,持有者失联超过ttl后锁被释放,<=0时与服务注册共用租约(服务心跳TTL)
+	//  @return Lock
+	//  @return error
+	Lock(ctx context.Context, name string, ttl time.Duration) (Lock, error)
+	// Leaders 当前服务参与的命名选举及其主节点 map[name]leaderID
+	Leaders() map[string]string
+}
@@ -0,0 +1,201 @@
+package cluster
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"go.uber.org/zap"
+)
+
+// dnsServiceDiscovery 基于DNS SRV记录的服务发现,定时轮询每种服务类型的SRV记录
+//
+//	每条SRV记录对应一个服务,服务ID为 target:port,如 game-0.game.default.svc.cluster.local. 3434 的ID为 game-0.game.default.svc.cluster.local:3434
+//	记录的target和port写入 metadata 的 grpcHost 和 grpcPort;当前服务的记录按ID或 grpcHost、grpcPort 识别;
+//	没有选举机制,同类型中ID最小的服务为主节点
+type dnsServiceDiscovery struct {
+	*serverRegistry
+	services []config.DNSService
+	interval time.Duration
+	timeout  time.Duration
+	resolver srvResolver
+	stopChan chan struct{}
+}
+
+// srvResolver 查询SRV记录,默认为 *net.Resolver
+type srvResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// NewDNSServiceDiscovery ctor
+func NewDNSServiceDiscovery(conf config.DNSServiceDiscoveryConfig, server *Server) (ServiceDiscovery, error) {
+	if len(conf.Services) == 0 {
+		return nil, errors.New("dns service discovery has no services")
+	}
+	var resolver srvResolver = net.DefaultResolver
+	if conf.Resolver != "" {
+		addr := conf.Resolver
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+	}
+	return &dnsServiceDiscovery{
+		serverRegistry: newServerRegistry(server),
+		services:       conf.Services,
+		interval:       conf.Interval,
+		timeout:        conf.Timeout,
+		resolver:       resolver,
+		stopChan:       make(chan struct{}),
+	}, nil
+}
+
+// DNSServerID 由SRV记录的target和port得到服务ID
+//
+//	主机名的第一段在不同命名空间或不同端口的服务间可能重复,使用完整的target和port
+//	@param target
+//	@param port
+//	@return string
+func DNSServerID(target string, port uint16) string {
+	return net.JoinHostPort(strings.TrimSuffix(target, "."), strconv.Itoa(int(port)))
+}
+
+// Init 同步SRV记录并开始轮询
+//
+//	@receiver sd
+//	@return error
+func (sd *dnsServiceDiscovery) Init() error {
+	sd.addServer(sd.GetSelfServer())
+	if err := sd.SyncServers(true); err != nil {
+		return err
+	}
+	if sd.interval > 0 {
+		co.Go(func() { sd.poll() })
+	}
+	return nil
+}
+
+func (sd *dnsServiceDiscovery) poll() {
+	ticker := time.NewTicker(sd.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := sd.SyncServers(false); err != nil {
+				logger.Zap.Error("dns service discovery sync error", zap.Error(err))
+			}
+		case <-sd.stopChan:
+			return
+		}
+	}
+}
+
+// SyncServers 查询所有服务类型的SRV记录并同步本地列表
+//
+//	任一类型查询失败时不修改本地列表,避免DNS短暂故障导致服务被误删
+//	@receiver sd
+//	@param firstSync
+//	@return error
+func (sd *dnsServiceDiscovery) SyncServers(firstSync bool) error {
+	servers := make([]*Server, 0)
+	for _, svc := range sd.services {
+		svs, err := sd.lookup(svc)
+		if err != nil {
+			return err
+		}
+		servers = append(servers, svs...)
+	}
+	sd.syncServers(servers)
+	sd.reportLeader(sd.LeaderID())
+	return nil
+}
+
+func (sd *dnsServiceDiscovery) lookup(svc config.DNSService) ([]*Server, error) {
+	ctx := context.Background()
+	if sd.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sd.timeout)
+		defer cancel()
+	}
+	_, addrs, err := sd.resolver.LookupSRV(ctx, "", "", svc.Name)
+	if err != nil {
+		// 记录不存在视为该类型暂无服务
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+	self := sd.GetSelfServer()
+	servers := make([]*Server, 0, len(addrs))
+	for _, addr := range addrs {
+		host := strings.TrimSuffix(addr.Target, ".")
+		id := DNSServerID(host, addr.Port)
+		if svc.Type == self.Type && self.Metadata[constants.GRPCHostKey] == host && self.Metadata[constants.GRPCPortKey] == strconv.Itoa(int(addr.Port)) {
+			// 当前服务的记录,同步时跳过
+			id = self.ID
+		}
+		metadata := make(map[string]string, len(svc.Metadata)+2)
+		for k, v := range svc.Metadata {
+			metadata[k] = v
+		}
+		metadata[constants.GRPCHostKey] = host
+		metadata[constants.GRPCPortKey] = strconv.Itoa(int(addr.Port))
+		servers = append(servers, &Server{
+			ID:                id,
+			Type:              svc.Type,
+			Metadata:          metadata,
+			Frontend:          svc.Frontend,
+			Hostname:          host,
+			SessionStickiness: svc.SessionStickiness,
+		})
+	}
+	return servers, nil
+}
+
+// FlushServer2Cluster DNS记录无法写回,仅更新本地数据
+//
+//	@receiver sd
+//	@param server
+//	@return error
+func (sd *dnsServiceDiscovery) FlushServer2Cluster(server *Server) error {
+	sd.setSelfServer(server)
+	sd.addServer(server)
+	return nil
+}
+
+// LeaderID 同类型中ID最小的服务
+//
+//	@receiver sd
+//	@return string
+func (sd *dnsServiceDiscovery) LeaderID() string {
+	return sd.minServerIDOfType(sd.server.Type)
+}
+
+func (sd *dnsServiceDiscovery) IsLeader() bool {
+	return sd.LeaderID() == sd.server.ID
+}
+
+// AfterInit executes after Init
+func (sd *dnsServiceDiscovery) AfterInit() {
+}
+
+// BeforeShutdown executes before shutting down
+func (sd *dnsServiceDiscovery) BeforeShutdown() {
+}
+
+// Shutdown stops polling
+func (sd *dnsServiceDiscovery) Shutdown() error {
+	close(sd.stopChan)
+	return nil
+}
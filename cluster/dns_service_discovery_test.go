@@ -0,0 +1,137 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/helpers"
+)
+
+// fakeSRVResolver 按记录名返回预设的SRV记录
+type fakeSRVResolver struct {
+	mu      sync.Mutex
+	records map[string][]*net.SRV
+	err     error
+}
+
+func (r *fakeSRVResolver) set(name string, records []*net.SRV, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[name] = records
+	r.err = err
+}
+
+func (r *fakeSRVResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	records, ok := r.records[name]
+	if !ok {
+		return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return name, records, nil
+}
+
+func newTestDNSSD(t *testing.T, resolver *fakeSRVResolver, self *Server) *dnsServiceDiscovery {
+	t.Helper()
+	conf := config.DNSServiceDiscoveryConfig{Services: []config.DNSService{
+		{Type: "connector", Name: "_connector._tcp.svc", Frontend: true},
+		{Type: "game", Name: "_game._tcp.svc", SessionStickiness: true, Metadata: map[string]string{"region": "cn"}},
+	}}
+	sd, err := NewDNSServiceDiscovery(conf, self)
+	require.NoError(t, err)
+	ret := sd.(*dnsServiceDiscovery)
+	ret.resolver = resolver
+	return ret
+}
+
+func TestDNSServerID(t *testing.T) {
+	assert.Equal(t, "game-0.game.default.svc.cluster.local:3434", DNSServerID("game-0.game.default.svc.cluster.local.", 3434))
+	assert.Equal(t, "game-0:3434", DNSServerID("game-0", 3434))
+	// 主机名第一段相同的服务ID不同
+	assert.NotEqual(t, DNSServerID("game-0.game.ns1.svc.", 3434), DNSServerID("game-0.game.ns2.svc.", 3434))
+}
+
+func TestDNSSDSync(t *testing.T) {
+	resolver := &fakeSRVResolver{records: map[string][]*net.SRV{}}
+	resolver.set("_connector._tcp.svc", []*net.SRV{{Target: "connector-0.connector.svc.", Port: 3250}}, nil)
+	resolver.set("_game._tcp.svc", []*net.SRV{
+		{Target: "game-0.game.svc.", Port: 3434},
+		{Target: "game-1.game.svc.", Port: 3434},
+	}, nil)
+	sd := newTestDNSSD(t, resolver, NewServer("game-1.game.svc:3434", "game", false))
+	l := newRecordSDListener()
+	sd.AddListener(l)
+	require.NoError(t, sd.Init())
+	defer sd.Shutdown()
+	for i := 0; i < 2; i++ {
+		helpers.ShouldEventuallyReceive(t, l.added)
+	}
+
+	sv, err := sd.GetServer("game-0.game.svc:3434")
+	require.NoError(t, err)
+	assert.Equal(t, "game", sv.Type)
+	assert.True(t, sv.SessionStickiness)
+	assert.Equal(t, "game-0.game.svc", sv.Hostname)
+	assert.Equal(t, "game-0.game.svc", sv.Metadata[constants.GRPCHostKey])
+	assert.Equal(t, "3434", sv.Metadata[constants.GRPCPortKey])
+	assert.Equal(t, "cn", sv.Metadata["region"])
+	sv, err = sd.GetServer("connector-0.connector.svc:3250")
+	require.NoError(t, err)
+	assert.True(t, sv.Frontend)
+	assert.Equal(t, "game-0.game.svc:3434", sd.LeaderID())
+	assert.False(t, sd.IsLeader())
+
+	// game-0 下线
+	resolver.set("_game._tcp.svc", []*net.SRV{{Target: "game-1.game.svc.", Port: 3434}}, nil)
+	require.NoError(t, sd.SyncServers(false))
+	assert.Equal(t, "game-0.game.svc:3434", helpers.ShouldEventuallyReceive(t, l.removed).(*Server).ID)
+	assert.True(t, sd.IsLeader())
+
+	// 查询失败时保留本地列表
+	resolver.set("_game._tcp.svc", nil, errors.New("dns timeout"))
+	assert.Error(t, sd.SyncServers(false))
+	assert.Len(t, sd.GetServers(), 2)
+
+	// 记录不存在视为该类型暂无服务,当前服务始终存在
+	resolver.set("_connector._tcp.svc", nil, nil)
+	delete(resolver.records, "_game._tcp.svc")
+	require.NoError(t, sd.SyncServers(false))
+	assert.Equal(t, "connector-0.connector.svc:3250", helpers.ShouldEventuallyReceive(t, l.removed).(*Server).ID)
+	_, err = sd.GetServer("game-1.game.svc:3434")
+	assert.NoError(t, err)
+}
+
+func TestDNSSDSelfByGRPCAddress(t *testing.T) {
+	resolver := &fakeSRVResolver{records: map[string][]*net.SRV{}}
+	resolver.set("_game._tcp.svc", []*net.SRV{
+		{Target: "game-0.game.svc.", Port: 3434},
+		{Target: "game-1.game.svc.", Port: 3434},
+	}, nil)
+	// 未配置ID时保留生成的ID,按grpc地址识别自己的记录
+	self := NewServer("generated-id", "game", false)
+	self.Metadata[constants.GRPCHostKey] = "game-1.game.svc"
+	self.Metadata[constants.GRPCPortKey] = "3434"
+	sd := newTestDNSSD(t, resolver, self)
+	require.NoError(t, sd.Init())
+	defer sd.Shutdown()
+	assert.Len(t, sd.GetServers(), 2)
+	_, err := sd.GetServer("generated-id")
+	assert.NoError(t, err)
+	_, err = sd.GetServer("game-1.game.svc:3434")
+	assert.Error(t, err)
+}
+
+func TestNewDNSServiceDiscoveryRequiresServices(t *testing.T) {
+	_, err := NewDNSServiceDiscovery(config.DNSServiceDiscoveryConfig{}, NewServer("game-1", "game", false))
+	assert.Error(t, err)
+}
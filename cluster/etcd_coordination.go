@@ -0,0 +1,359 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.uber.org/zap"
+)
+
+const (
+	// coordinationElectionPrefix 命名选举的key前缀,与服务发现选主的 "election/" 区分,避免同名时互相竞选
+	coordinationElectionPrefix = "coordination/election/"
+	coordinationLockPrefix     = "coordination/lock/"
+)
+
+// etcdCoordinator 基于etcd concurrency的命名选举和分布式锁
+//
+//	选举和默认ttl的锁共用服务注册的租约,不额外创建租约;租约过期时其上的选举和锁全部丢失
+//	指定了其他ttl的锁按ttl共享独立的会话
+type etcdCoordinator struct {
+	mu          sync.Mutex
+	session     *concurrency.Session         // 绑定服务注册租约的会话
+	sessions    map[int]*concurrency.Session // key:ttl秒
+	leaderships map[*etcdLeadership]struct{}
+}
+
+// etcdLeadership 命名选举,租约会话过期后重新竞选
+type etcdLeadership struct {
+	sd       *etcdServiceDiscovery
+	name     string
+	lock     sync.RWMutex
+	leaderID string
+	changes  chan string // 容量1,只保留最新的主节点ID
+	closed   bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func (l *etcdLeadership) Name() string {
+	return l.name
+}
+
+func (l *etcdLeadership) LeaderID() string {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.leaderID
+}
+
+func (l *etcdLeadership) IsLeader() bool {
+	return l.LeaderID() == l.sd.server.ID
+}
+
+func (l *etcdLeadership) Changes() <-chan string {
+	return l.changes
+}
+
+// Resign 退出竞选,为主节点时删除竞选记录
+//
+//	@receiver l
+//	@param ctx
+//	@return error
+func (l *etcdLeadership) Resign(ctx context.Context) error {
+	l.cancel()
+	select {
+	case <-l.done:
+		l.sd.coordinator().removeLeadership(l)
+		return nil
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+func (l *etcdLeadership) setLeader(id string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.leaderID == id {
+		return
+	}
+	l.leaderID = id
+	logger.Zap.Debug("etcd named election leader change", zap.String("name", l.name), zap.String("leaderID", id))
+	if l.closed {
+		return
+	}
+	// 未被消费的旧通知替换为最新的主节点ID
+	select {
+	case l.changes <- id:
+	default:
+		select {
+		case <-l.changes:
+		default:
+		}
+		l.changes <- id
+	}
+}
+
+func (l *etcdLeadership) closeChanges() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.closed = true
+	close(l.changes)
+}
+
+func (l *etcdLeadership) run(ctx context.Context) {
+	defer close(l.done)
+	defer l.closeChanges()
+	for ctx.Err() == nil {
+		if err := l.campaignOnce(ctx); err != nil {
+			logger.Zap.Error("etcd named election error", zap.String("name", l.name), zap.Error(err))
+		}
+		l.setLeader("")
+		select {
+		case <-ctx.Done():
+		case <-time.After(l.sd.reconnectBackOff):
+		}
+	}
+}
+
+// campaignOnce 在租约会话内竞选并观察主节点变化,会话过期或ctx结束时返回
+//
+//	会话与服务注册共用,不能关闭;ctx结束时主动辞职删除竞选记录
+func (l *etcdLeadership) campaignOnce(ctx context.Context) error {
+	session, err := l.sd.leaseSession()
+	if err != nil {
+		return err
+	}
+	election := concurrency.NewElection(session, coordinationElectionPrefix+l.name)
+
+	observeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	co.Go(func() {
+		for resp := range election.Observe(observeCtx) {
+			if len(resp.Kvs) > 0 {
+				l.setLeader(string(resp.Kvs[0].Value))
+			}
+		}
+	})
+
+	errChan := make(chan error, 1)
+	co.Go(func() { errChan <- election.Campaign(ctx, l.sd.server.ID) })
+	select {
+	case err = <-errChan:
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.WithStack(err)
+		}
+		l.setLeader(l.sd.server.ID)
+	case <-session.Done():
+		return errors.New("etcd session expired while campaigning")
+	case <-ctx.Done():
+		// Campaign 在ctx结束时自行清理竞选记录
+		return nil
+	}
+
+	select {
+	case <-session.Done():
+		return errors.New("etcd session expired, leadership lost")
+	case <-ctx.Done():
+		resignCtx, cancel := context.WithTimeout(context.Background(), l.sd.revokeTimeout)
+		defer cancel()
+		return errors.WithStack(election.Resign(resignCtx))
+	}
+}
+
+// etcdLock 基于 concurrency.Mutex 的分布式锁
+type etcdLock struct {
+	name    string
+	mutex   *concurrency.Mutex
+	session *concurrency.Session
+	token   int64
+}
+
+func (l *etcdLock) Name() string {
+	return l.name
+}
+
+func (l *etcdLock) Token() int64 {
+	return l.token
+}
+
+func (l *etcdLock) Done() <-chan struct{} {
+	return l.session.Done()
+}
+
+func (l *etcdLock) Unlock(ctx context.Context) error {
+	return errors.WithStack(l.mutex.Unlock(ctx))
+}
+
+func (sd *etcdServiceDiscovery) coordinator() *etcdCoordinator {
+	sd.coordinatorOnce.Do(func() {
+		sd.coord = &etcdCoordinator{
+			sessions:    make(map[int]*concurrency.Session),
+			leaderships: make(map[*etcdLeadership]struct{}),
+		}
+	})
+	return sd.coord
+}
+
+func (c *etcdCoordinator) removeLeadership(l *etcdLeadership) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.leaderships, l)
+}
+
+// Campaign
+//
+//	@implement Coordinator.Campaign
+func (sd *etcdServiceDiscovery) Campaign(name string) (Leadership, error) {
+	if sd.cli == nil {
+		return nil, errors.WithStack(constants.ErrCoordinationNotReady)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &etcdLeadership{
+		sd:      sd,
+		name:    name,
+		changes: make(chan string, 1),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	c := sd.coordinator()
+	c.mu.Lock()
+	c.leaderships[l] = struct{}{}
+	c.mu.Unlock()
+	co.Go(func() { l.run(ctx) })
+	return l, nil
+}
+
+// Lock
+//
+//	@implement Coordinator.Lock
+func (sd *etcdServiceDiscovery) Lock(ctx context.Context, name string, ttl time.Duration) (Lock, error) {
+	if sd.cli == nil {
+		return nil, errors.WithStack(constants.ErrCoordinationNotReady)
+	}
+	session, err := sd.lockSession(ttl)
+	if err != nil {
+		return nil, err
+	}
+	mutex := concurrency.NewMutex(session, coordinationLockPrefix+name)
+	if err = mutex.Lock(ctx); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// 锁key的创建revision在每次加锁时单调递增,作为fencing token
+	resp, err := sd.cli.Get(ctx, mutex.Key())
+	if err != nil || len(resp.Kvs) == 0 {
+		unlockCtx, cancel := context.WithTimeout(context.Background(), sd.revokeTimeout)
+		mutex.Unlock(unlockCtx)
+		cancel()
+		if err == nil {
+			err = constants.ErrLockLost
+		}
+		return nil, errors.WithStack(err)
+	}
+	return &etcdLock{name: name, mutex: mutex, session: session, token: resp.Kvs[0].CreateRevision}, nil
+}
+
+// leaseSession 获取绑定服务注册租约的会话,会话过期后使用当前租约重新创建
+//
+//	会话只复用租约,关闭会话会撤销服务注册的租约,因此只能 Orphan
+func (sd *etcdServiceDiscovery) leaseSession() (*concurrency.Session, error) {
+	c := sd.coordinator()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	leaseID := sd.getLeaseID()
+	if leaseID == clientv3.NoLease {
+		return nil, errors.WithStack(constants.ErrCoordinationNotReady)
+	}
+	if c.session != nil {
+		select {
+		case <-c.session.Done():
+			c.session.Orphan()
+		default:
+			return c.session, nil
+		}
+	}
+	session, err := concurrency.NewSession(sd.cli, concurrency.WithLease(leaseID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c.session = session
+	return session, nil
+}
+
+// lockSession 获取ttl对应的会话,默认ttl使用服务注册租约,会话过期后重新创建
+func (sd *etcdServiceDiscovery) lockSession(ttl time.Duration) (*concurrency.Session, error) {
+	seconds := int(ttl.Seconds())
+	if seconds <= 0 || seconds == int(sd.heartbeatTTL.Seconds()) {
+		return sd.leaseSession()
+	}
+	c := sd.coordinator()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if session, ok := c.sessions[seconds]; ok {
+		select {
+		case <-session.Done():
+		default:
+			return session, nil
+		}
+	}
+	session, err := concurrency.NewSession(sd.cli, concurrency.WithTTL(seconds))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c.sessions[seconds] = session
+	return session, nil
+}
+
+// Leaders
+//
+//	@implement Coordinator.Leaders
+func (sd *etcdServiceDiscovery) Leaders() map[string]string {
+	c := sd.coordinator()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	leaders := make(map[string]string, len(c.leaderships))
+	for l := range c.leaderships {
+		leaders[l.name] = l.LeaderID()
+	}
+	return leaders
+}
+
+// closeCoordination 退出所有选举并关闭锁会话,会话关闭时持有的锁被释放
+//
+//	租约会话上的锁随服务注册租约撤销而释放
+func (sd *etcdServiceDiscovery) closeCoordination() {
+	c := sd.coordinator()
+	c.mu.Lock()
+	leaderships := make([]*etcdLeadership, 0, len(c.leaderships))
+	for l := range c.leaderships {
+		leaderships = append(leaderships, l)
+	}
+	sessions := c.sessions
+	c.sessions = make(map[int]*concurrency.Session)
+	session := c.session
+	c.session = nil
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), sd.revokeTimeout)
+	defer cancel()
+	for _, l := range leaderships {
+		if err := l.Resign(ctx); err != nil {
+			logger.Zap.Warn("failed to resign named election", zap.String("name", l.name), zap.Error(err))
+		}
+	}
+	for _, session := range sessions {
+		session.Close()
+	}
+	if session != nil {
+		session.Orphan()
+	}
+}
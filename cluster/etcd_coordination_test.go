@@ -0,0 +1,137 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.etcd.io/etcd/tests/v3/integration"
+)
+
+// getCoordinationTestEtcd 同 helpers.GetTestEtcd,但不检测goroutine泄漏.
+// 选举和锁经 co.Go 派发到无状态线程池,池中空闲的worker和包初始化时创建的常驻goroutine会在测试结束后继续存在,
+// 泄漏检测会误报为泄漏;本测试自身的goroutine由 closeCoordination 和 Terminate 回收
+func getCoordinationTestEtcd(t *testing.T) (*integration.ClusterV3, *clientv3.Client) {
+	t.Helper()
+	integration.BeforeTest(t, integration.WithoutGoLeakDetection())
+	c := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	return c, c.RandClient()
+}
+
+func newCoordinationTestSD(t *testing.T, id string, cli *clientv3.Client) *etcdServiceDiscovery {
+	t.Helper()
+	conf := config.NewDefaultEtcdServiceDiscoveryConfig()
+	conf.Heartbeat.TTL = 5 * time.Second
+	sd, err := NewEtcdServiceDiscovery(*conf, NewServer(id, "game", false), make(chan bool), cli)
+	require.NoError(t, err)
+	e := sd.(*etcdServiceDiscovery)
+	require.NoError(t, e.grantLease())
+	return e
+}
+
+// closeCoordinationTestSD 退出选举后撤销服务注册租约
+func closeCoordinationTestSD(t *testing.T, sd *etcdServiceDiscovery) {
+	t.Helper()
+	sd.closeCoordination()
+	require.NoError(t, sd.revoke())
+}
+
+func waitLeader(t *testing.T, l Leadership, id string) {
+	t.Helper()
+	assert.Eventually(t, func() bool { return l.LeaderID() == id }, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestEtcdCampaign(t *testing.T) {
+	c, cli := getCoordinationTestEtcd(t)
+	defer c.Terminate(t)
+	sd1 := newCoordinationTestSD(t, "game-1", cli)
+	sd2 := newCoordinationTestSD(t, "game-2", cli)
+
+	l1, err := sd1.Campaign("job")
+	require.NoError(t, err)
+	waitLeader(t, l1, "game-1")
+	assert.True(t, l1.IsLeader())
+	assert.Equal(t, "game-1", <-l1.Changes())
+
+	l2, err := sd2.Campaign("job")
+	require.NoError(t, err)
+	waitLeader(t, l2, "game-1")
+	assert.False(t, l2.IsLeader())
+
+	require.NoError(t, l1.Resign(context.Background()))
+	waitLeader(t, l2, "game-2")
+	assert.True(t, l2.IsLeader())
+	for range l1.Changes() {
+	}
+	// 选举复用服务注册租约,不额外创建会话租约
+	session, err := sd2.leaseSession()
+	require.NoError(t, err)
+	assert.Equal(t, sd2.getLeaseID(), session.Lease())
+	closeCoordinationTestSD(t, sd1)
+	closeCoordinationTestSD(t, sd2)
+}
+
+func TestEtcdCampaignSeparatedFromDiscoveryElection(t *testing.T) {
+	c, cli := getCoordinationTestEtcd(t)
+	defer c.Terminate(t)
+	sd1 := newCoordinationTestSD(t, "game-1", cli)
+	sd2 := newCoordinationTestSD(t, "game-2", cli)
+
+	// 服务发现按服务类型在 "election/game" 选主,与同名的命名选举互不影响
+	session, err := sd2.leaseSession()
+	require.NoError(t, err)
+	require.NoError(t, concurrency.NewElection(session, "election/game").Campaign(context.Background(), "game-2"))
+
+	l1, err := sd1.Campaign("game")
+	require.NoError(t, err)
+	waitLeader(t, l1, "game-1")
+	assert.True(t, l1.IsLeader())
+	closeCoordinationTestSD(t, sd1)
+	closeCoordinationTestSD(t, sd2)
+}
+
+func TestEtcdLeadershipChangesCoalesce(t *testing.T) {
+	l := &etcdLeadership{sd: &etcdServiceDiscovery{serverRegistry: newServerRegistry(NewServer("game-1", "game", false))}, name: "job", changes: make(chan string, 1)}
+	l.setLeader("game-1")
+	l.setLeader("game-2")
+	l.setLeader("game-3")
+	assert.Equal(t, "game-3", <-l.Changes())
+	assert.Empty(t, l.Changes())
+
+	l.closeChanges()
+	l.setLeader("game-4")
+	_, ok := <-l.Changes()
+	assert.False(t, ok)
+	assert.Equal(t, "game-4", l.LeaderID())
+}
+
+func TestEtcdLockFencingToken(t *testing.T) {
+	c, cli := getCoordinationTestEtcd(t)
+	defer c.Terminate(t)
+	sd1 := newCoordinationTestSD(t, "game-1", cli)
+	sd2 := newCoordinationTestSD(t, "game-2", cli)
+	ctx := context.Background()
+
+	lock1, err := sd1.Lock(ctx, "res", time.Second*5)
+	require.NoError(t, err)
+	assert.Equal(t, "res", lock1.Name())
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	_, err = sd2.Lock(timeoutCtx, "res", time.Second*5)
+	cancel()
+	assert.Error(t, err)
+
+	require.NoError(t, lock1.Unlock(ctx))
+	lock2, err := sd2.Lock(ctx, "res", 0)
+	require.NoError(t, err)
+	assert.Greater(t, lock2.Token(), lock1.Token())
+	require.NoError(t, lock2.Unlock(ctx))
+
+	closeCoordinationTestSD(t, sd1)
+	closeCoordinationTestSD(t, sd2)
+}
@@ -38,34 +38,29 @@ import (
 	"github.com/topfreegames/pitaya/v2/config"
 	"github.com/topfreegames/pitaya/v2/constants"
 	"github.com/topfreegames/pitaya/v2/logger"
-	"github.com/topfreegames/pitaya/v2/util"
-	"github.com/zeromicro/go-zero/core/hash"
 	logutil "go.etcd.io/etcd/client/pkg/v3/logutil"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/namespace"
 )
 
 type etcdServiceDiscovery struct {
+	*serverRegistry
 	cli                    *clientv3.Client
 	syncServersInterval    time.Duration
 	heartbeatTTL           time.Duration
 	logHeartbeat           bool
 	lastHeartbeatTime      time.Time
-	leaseID                clientv3.LeaseID
-	mapByTypeLock          sync.RWMutex
-	serverMapByType        map[string]map[string]*Server
-	serverMapByID          sync.Map
+	leaseLock              sync.RWMutex
+	leaseID                clientv3.LeaseID // 由 leaseLock 保护,续约失败重新申请时会被改写
 	etcdEndpoints          []string
 	etcdUser               string
 	etcdPass               string
 	etcdPrefix             string
 	etcdDialTimeout        time.Duration
 	running                bool
-	server                 *Server
 	stopChan               chan bool
 	stopLeaseChan          chan bool
-	lastSyncTime           time.Time
-	listeners              []SDListener
+	lastSyncTime           time.Time // 由 frozenLock 保护
 	revokeTimeout          time.Duration
 	grantLeaseTimeout      time.Duration
 	grantLeaseMaxRetries   int
@@ -75,7 +70,6 @@ type etcdServiceDiscovery struct {
 	serverTypesBlacklist   []string
 	syncServersParallelism int
 	syncServersRunning     chan bool
-	consistentHash         map[string]*hash.ConsistentHash
 
 	// 选主相关
 	electionEnable   bool   // 是否开启选举
@@ -86,6 +80,17 @@ type etcdServiceDiscovery struct {
 	election         *concurrency.Election
 	electionCancel   context.CancelFunc // 选举取消
 	leaderID         string
+
+	// 快照相关
+	snapshotEnable bool
+	snapshotFile   *sdSnapshotFile // 为nil时不落盘
+	maxStaleness   time.Duration
+	frozenLock     sync.RWMutex
+	frozen         bool // 冻结拓扑,etcd不可用期间不删除服务
+
+	// 命名选举和分布式锁
+	coordinatorOnce sync.Once
+	coord           *etcdCoordinator
 }
 
 // NewEtcdServiceDiscovery ctor
@@ -101,15 +106,12 @@ func NewEtcdServiceDiscovery(
 	}
 	sd := &etcdServiceDiscovery{
 		running:            false,
-		server:             server,
-		serverMapByType:    make(map[string]map[string]*Server),
-		listeners:          make([]SDListener, 0),
+		serverRegistry:     newServerRegistry(server),
 		stopChan:           make(chan bool),
 		stopLeaseChan:      make(chan bool),
 		appDieChan:         appDieChan,
 		cli:                client,
 		syncServersRunning: make(chan bool),
-		consistentHash:     make(map[string]*hash.ConsistentHash),
 		resumeLeader:       true,
 		reconnectBackOff:   time.Second * 2,
 		electionName:       server.Type,
@@ -120,14 +122,6 @@ func NewEtcdServiceDiscovery(
 	return sd, nil
 }
 
-// GetSelfServer @implement ServiceDiscovery.GetSelfServer
-//
-//	@receiver sd
-//	@return *Server
-func (sd etcdServiceDiscovery) GetSelfServer() *Server {
-	return sd.server
-}
-
 func (sd *etcdServiceDiscovery) configure(config config.EtcdServiceDiscoveryConfig) {
 	sd.etcdEndpoints = config.Endpoints
 	sd.etcdUser = config.User
@@ -149,6 +143,11 @@ func (sd *etcdServiceDiscovery) configure(config config.EtcdServiceDiscoveryConf
 		sd.electionName = config.Election.Name
 	}
 	sd.electionName = "election/" + sd.electionName
+	sd.snapshotEnable = config.Snapshot.Enable
+	if config.Snapshot.File != "" {
+		sd.snapshotFile = &sdSnapshotFile{path: config.Snapshot.File}
+	}
+	sd.maxStaleness = config.Snapshot.MaxStaleness
 }
 
 func (sd *etcdServiceDiscovery) watchLeaseChan(c <-chan *clientv3.LeaseKeepAliveResponse) {
@@ -178,16 +177,12 @@ func (sd *etcdServiceDiscovery) watchLeaseChan(c <-chan *clientv3.LeaseKeepAlive
 					failedGrantLeaseAttempts = failedGrantLeaseAttempts + 1
 					if err == constants.ErrEtcdGrantLeaseTimeout {
 						logger.Log.Warn("sd: timed out trying to grant etcd lease")
-						if sd.appDieChan != nil {
-							sd.appDieChan <- true
-						}
+						sd.onEtcdUnavailable()
 						return
 					}
 					if failedGrantLeaseAttempts >= sd.grantLeaseMaxRetries {
 						logger.Log.Warn("sd: exceeded max attempts to renew etcd lease")
-						if sd.appDieChan != nil {
-							sd.appDieChan <- true
-						}
+						sd.onEtcdUnavailable()
 						return
 					}
 					logger.Log.Warnf("sd: error granting etcd lease, will retry in %d seconds", uint64(sd.grantLeaseInterval.Seconds()))
@@ -211,7 +206,7 @@ func (sd *etcdServiceDiscovery) renewLease() error {
 			c <- err
 			return
 		}
-		err = sd.bootstrapServer(sd.server)
+		err = sd.bootstrapServer(sd.GetSelfServer())
 		c <- err
 	})
 	select {
@@ -224,15 +219,19 @@ func (sd *etcdServiceDiscovery) renewLease() error {
 
 func (sd *etcdServiceDiscovery) grantLease() error {
 	// grab lease
-	l, err := sd.cli.Grant(context.TODO(), int64(sd.heartbeatTTL.Seconds()))
+	ctx, cancel := context.WithTimeout(context.Background(), sd.grantLeaseTimeout)
+	defer cancel()
+	l, err := sd.cli.Grant(ctx, int64(sd.heartbeatTTL.Seconds()))
 	if err != nil {
 		return err
 	}
+	sd.leaseLock.Lock()
 	sd.leaseID = l.ID
+	sd.leaseLock.Unlock()
 	logger.Zap.Debug("sd: got leaseID", zap.Int64("leaseID", int64(l.ID)))
 	// this will keep alive forever, when channel c is closed
 	// it means we probably have to rebootstrap the lease
-	c, err := sd.cli.KeepAlive(context.TODO(), sd.leaseID)
+	c, err := sd.cli.KeepAlive(context.TODO(), l.ID)
 	if err != nil {
 		return err
 	}
@@ -249,6 +248,7 @@ func (sd *etcdServiceDiscovery) grantLease() error {
 //	@param server
 //	@return error
 func (sd *etcdServiceDiscovery) FlushServer2Cluster(server *Server) error {
+	sd.setSelfServer(server)
 	return sd.addServerIntoEtcd(server)
 }
 
@@ -257,11 +257,18 @@ func (sd *etcdServiceDiscovery) addServerIntoEtcd(server *Server) error {
 		context.TODO(),
 		getKey(server.ID, server.Type),
 		server.AsJSONString(),
-		clientv3.WithLease(sd.leaseID),
+		clientv3.WithLease(sd.getLeaseID()),
 	)
 	return err
 }
 
+// getLeaseID 当前服务注册使用的租约
+func (sd *etcdServiceDiscovery) getLeaseID() clientv3.LeaseID {
+	sd.leaseLock.RLock()
+	defer sd.leaseLock.RUnlock()
+	return sd.leaseID
+}
+
 func (sd *etcdServiceDiscovery) bootstrapServer(server *Server) error {
 	if err := sd.addServerIntoEtcd(server); err != nil {
 		return err
@@ -271,60 +278,10 @@ func (sd *etcdServiceDiscovery) bootstrapServer(server *Server) error {
 	return nil
 }
 
-// AddListener adds a listener to etcd service discovery
-func (sd *etcdServiceDiscovery) AddListener(listener SDListener) {
-	sd.listeners = append(sd.listeners, listener)
-}
-
 // AfterInit executes after Init
 func (sd *etcdServiceDiscovery) AfterInit() {
 }
 
-func (sd *etcdServiceDiscovery) notifyListeners(act Action, sv *Server, old ...*Server) {
-	for _, l := range sd.listeners {
-		if act == DEL {
-			l.RemoveServer(sv)
-		} else if act == ADD {
-			l.AddServer(sv)
-		} else if act == Modify {
-			l.ModifyServer(sv, old[0])
-		}
-	}
-}
-
-func (sd *etcdServiceDiscovery) writeLockScope(f func()) {
-	sd.mapByTypeLock.Lock()
-	defer sd.mapByTypeLock.Unlock()
-	f()
-}
-
-func (sd *etcdServiceDiscovery) deleteServer(serverID string) {
-	if actual, ok := sd.serverMapByID.Load(serverID); ok {
-		sv := actual.(*Server)
-		sd.serverMapByID.Delete(sv.ID)
-		sd.writeLockScope(func() {
-			if svMap, ok := sd.serverMapByType[sv.Type]; ok {
-				delete(svMap, sv.ID)
-			}
-		})
-		if sd.consistentHash[sv.Type] != nil {
-			sd.consistentHash[sv.Type].Remove(sv.ID)
-		}
-		sd.notifyListeners(DEL, sv)
-	}
-}
-
-func (sd *etcdServiceDiscovery) deleteLocalInvalidServers(actualServers []string) {
-	sd.serverMapByID.Range(func(key interface{}, value interface{}) bool {
-		k := key.(string)
-		if !util.SliceContainsString(actualServers, k) {
-			logger.Sugar.Warnf("deleting invalid local server %s", k)
-			sd.deleteServer(k)
-		}
-		return true
-	})
-}
-
 func getKey(serverID, serverType string) string {
 	return fmt.Sprintf("servers/%s/%s", serverType, serverID)
 }
@@ -341,77 +298,6 @@ func getServerFromEtcd(cli *clientv3.Client, serverType, serverID string) (*Serv
 	return parseServer(svEInfo.Kvs[0].Value)
 }
 
-// GetServersByType returns a slice with all the servers of a certain type
-func (sd *etcdServiceDiscovery) GetServersByType(serverType string) (map[string]*Server, error) {
-	sd.mapByTypeLock.RLock()
-	defer sd.mapByTypeLock.RUnlock()
-	if m, ok := sd.serverMapByType[serverType]; ok && len(m) > 0 {
-		// Create a new map to avoid concurrent read and write access to the
-		// map, this also prevents accidental changes to the list of servers
-		// kept by the service discovery.
-		ret := make(map[string]*Server, len(sd.serverMapByType[serverType]))
-		for k, v := range sd.serverMapByType[serverType] {
-			ret[k] = v
-		}
-		return ret, nil
-	}
-	return nil, constants.ErrNoServersAvailableOfType
-}
-
-func (sd *etcdServiceDiscovery) GetConsistentHashNode(serverType string, sessionID string) (string, error) {
-	ha := sd.consistentHash[serverType]
-	if ha == nil {
-		return "", errors.WithStack(fmt.Errorf("%w server=%s,sid=%s", constants.ErrServerNotFound, serverType, sessionID))
-	}
-	node, ok := sd.consistentHash[serverType].Get(sessionID)
-	if !ok {
-		return "", errors.WithStack(fmt.Errorf("%w server=%s,sid=%s", constants.ErrServerNotFound, serverType, sessionID))
-	}
-	return node.(string), nil
-}
-
-// GetServers returns a slice with all the servers
-func (sd *etcdServiceDiscovery) GetServers() []*Server {
-	ret := make([]*Server, 0)
-	sd.serverMapByID.Range(func(k, v interface{}) bool {
-		ret = append(ret, v.(*Server))
-		return true
-	})
-	return ret
-}
-
-func (sd *etcdServiceDiscovery) GetAnyFrontend() (*Server, error) {
-	var frontend *Server
-	sd.serverMapByID.Range(func(k, v interface{}) bool {
-		sv := v.(*Server)
-		if sv.Frontend {
-			frontend = sv
-			return false
-		}
-		return true
-	})
-	if frontend == nil {
-		return nil, errors.New("not found any frontend")
-	}
-	return frontend, nil
-}
-
-// GetServerTypes
-//
-//	@implement ServiceDiscovery.GetServerTypes
-func (sd *etcdServiceDiscovery) GetServerTypes() map[string]*Server {
-	sd.mapByTypeLock.RLock()
-	defer sd.mapByTypeLock.RUnlock()
-	ret := make(map[string]*Server, len(sd.serverMapByType))
-	for t, m := range sd.serverMapByType {
-		for _, server := range m {
-			ret[t] = server
-			break
-		}
-	}
-	return ret
-}
-
 func (sd *etcdServiceDiscovery) bootstrap() error {
 	if err := sd.grantLease(); err != nil {
 		return err
@@ -428,27 +314,20 @@ func (sd *etcdServiceDiscovery) bootstrap() error {
 		co.Go(func() {
 			for leader := range leaderChan {
 				sd.leaderID = leader
-				// TODO 抛出监听给业务层
+				sd.reportLeader(leader)
 			}
+			sd.reportLeader("")
 		})
 
 	}
 
-	if err := sd.bootstrapServer(sd.server); err != nil {
+	if err := sd.bootstrapServer(sd.GetSelfServer()); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// GetServer returns a server given it's id
-func (sd *etcdServiceDiscovery) GetServer(id string) (*Server, error) {
-	if sv, ok := sd.serverMapByID.Load(id); ok {
-		return sv.(*Server), nil
-	}
-	return nil, errors.WithStack(fmt.Errorf("%w:%s", constants.ErrNoServerWithID, id))
-}
-
 func (sd *etcdServiceDiscovery) InitETCDClient() error {
 	logger.Zap.Info("Initializing ETCD client")
 	var cli *clientv3.Client
@@ -495,7 +374,9 @@ func (sd *etcdServiceDiscovery) Init() error {
 	co.Go(func() { sd.watchEtcdChanges() })
 
 	if err = sd.bootstrap(); err != nil {
-		return err
+		if err = sd.bootFromSnapshot(err); err != nil {
+			return err
+		}
 	}
 
 	// update servers
@@ -653,6 +534,7 @@ func (sd *etcdServiceDiscovery) SyncServers(firstSync bool) error {
 	}
 	if err != nil {
 		logger.Zap.Error("Error querying etcd server", zap.Error(err))
+		sd.onEtcdError(err)
 		return err
 	}
 
@@ -695,10 +577,12 @@ func (sd *etcdServiceDiscovery) SyncServers(firstSync bool) error {
 		sd.addServer(server)
 	}
 
-	sd.deleteLocalInvalidServers(allIds)
+	if !sd.isFrozen() {
+		sd.deleteLocalInvalidServers(allIds)
+	}
 
 	// sd.printServers()
-	sd.lastSyncTime = time.Now()
+	sd.markSynced()
 	// elapsed := time.Since(start)
 	// logger.Zap.Info("SyncServers took", zap.Duration("elapsed", elapsed))
 	return nil
@@ -706,6 +590,7 @@ func (sd *etcdServiceDiscovery) SyncServers(firstSync bool) error {
 
 // BeforeShutdown executes before shutting down and will remove the server from the list
 func (sd *etcdServiceDiscovery) BeforeShutdown() {
+	sd.closeCoordination()
 	sd.revoke()
 	if sd.electionCancel != nil {
 		sd.electionCancel()
@@ -727,7 +612,7 @@ func (sd *etcdServiceDiscovery) revoke() error {
 	defer close(c)
 	co.Go(func() {
 		logger.Zap.Debug("waiting for etcd revoke")
-		_, err := sd.cli.Revoke(context.TODO(), sd.leaseID)
+		_, err := sd.cli.Revoke(context.TODO(), sd.getLeaseID())
 		c <- err
 		logger.Zap.Debug("finished waiting for etcd revoke")
 	})
@@ -740,30 +625,6 @@ func (sd *etcdServiceDiscovery) revoke() error {
 	}
 }
 
-func (sd *etcdServiceDiscovery) addServer(sv *Server) {
-	old, loaded := sd.serverMapByID.Load(sv.ID)
-	sd.writeLockScope(func() {
-		sd.serverMapByID.Store(sv.ID, sv)
-		mapSvByType, ok := sd.serverMapByType[sv.Type]
-		if !ok {
-			mapSvByType = make(map[string]*Server)
-			sd.serverMapByType[sv.Type] = mapSvByType
-		}
-		mapSvByType[sv.ID] = sv
-	})
-	if sv.ID != sd.server.ID {
-		if !loaded {
-			if sd.consistentHash[sv.Type] == nil {
-				sd.consistentHash[sv.Type] = hash.NewConsistentHash()
-			}
-			sd.consistentHash[sv.Type].Add(sv.ID)
-			sd.notifyListeners(ADD, sv)
-		} else {
-			sd.notifyListeners(Modify, sv, old.(*Server))
-		}
-	}
-}
-
 func (sd *etcdServiceDiscovery) watchEtcdChanges() {
 	w := sd.cli.Watch(context.Background(), "servers/", clientv3.WithPrefix())
 	failedWatchAttempts := 0
@@ -778,10 +639,12 @@ func (sd *etcdServiceDiscovery) watchEtcdChanges() {
 			case wResp, ok := <-chn:
 				if wResp.Err() != nil {
 					logger.Zap.Warn("etcd watcher response error", zap.Error(wResp.Err()))
+					sd.onEtcdError(wResp.Err())
 					time.Sleep(100 * time.Millisecond)
 				}
 				if !ok {
 					logger.Zap.Error("etcd watcher died, retrying to watch in 1 second")
+					sd.onEtcdError(errors.New("etcd watcher closed"))
 					failedWatchAttempts++
 					time.Sleep(1000 * time.Millisecond)
 					if failedWatchAttempts > 10 {
@@ -819,11 +682,18 @@ func (sd *etcdServiceDiscovery) watchEtcdChanges() {
 						logger.Log.Debugf("server %s added by watcher", ev.Kv.Key)
 						sd.printServers()
 					case clientv3.EventTypeDelete:
+						if sd.isFrozen() {
+							logger.Log.Debugf("server %s delete ignored by frozen topology", svID)
+							continue
+						}
 						sd.deleteServer(svID)
 						logger.Log.Debugf("server %s deleted by watcher", svID)
 						sd.printServers()
 					}
 				}
+				if len(wResp.Events) > 0 {
+					sd.saveSnapshot()
+				}
 			case <-sd.stopChan:
 				return
 			}
@@ -859,7 +729,7 @@ func (sd *etcdServiceDiscovery) runElection(ctx context.Context) (<-chan string,
 		leaderChan <- id
 	}
 
-	if err = sd.newSession(ctx, sd.leaseID); err != nil {
+	if err = sd.newSession(ctx, sd.getLeaseID()); err != nil {
 		return nil, errors.Wrap(err, "while creating initial session")
 	}
 
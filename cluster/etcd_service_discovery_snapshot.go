@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// Frozen
+//
+//	@implement TopologyFreezer.Frozen
+func (sd *etcdServiceDiscovery) Frozen() (bool, time.Duration) {
+	sd.frozenLock.RLock()
+	defer sd.frozenLock.RUnlock()
+	if sd.lastSyncTime.IsZero() {
+		return sd.frozen, 0
+	}
+	return sd.frozen, time.Since(sd.lastSyncTime)
+}
+
+func (sd *etcdServiceDiscovery) isFrozen() bool {
+	sd.frozenLock.RLock()
+	defer sd.frozenLock.RUnlock()
+	return sd.frozen
+}
+
+func (sd *etcdServiceDiscovery) setFrozen(frozen bool) {
+	sd.frozenLock.Lock()
+	defer sd.frozenLock.Unlock()
+	sd.frozen = frozen
+}
+
+// tryFreeze 冻结拓扑,已处于冻结状态时返回false
+func (sd *etcdServiceDiscovery) tryFreeze() bool {
+	sd.frozenLock.Lock()
+	defer sd.frozenLock.Unlock()
+	if sd.frozen {
+		return false
+	}
+	sd.frozen = true
+	return true
+}
+
+// lastSynced 最后一次同步成功的时间
+func (sd *etcdServiceDiscovery) lastSynced() time.Time {
+	sd.frozenLock.RLock()
+	defer sd.frozenLock.RUnlock()
+	return sd.lastSyncTime
+}
+
+// markSynced 记录同步成功的时间并保存快照
+func (sd *etcdServiceDiscovery) markSynced() {
+	sd.frozenLock.Lock()
+	sd.lastSyncTime = time.Now()
+	sd.frozenLock.Unlock()
+	sd.saveSnapshot()
+}
+
+// saveSnapshot 冻结期间的服务列表可能已过时,不覆盖快照
+func (sd *etcdServiceDiscovery) saveSnapshot() {
+	if sd.snapshotFile == nil || sd.isFrozen() {
+		return
+	}
+	if err := sd.snapshotFile.save(sd.GetServers(), sd.lastSynced()); err != nil {
+		logger.Zap.Warn("sd: failed to save servers snapshot", zap.String("file", sd.snapshotFile.path), zap.Error(err))
+	}
+}
+
+// onEtcdUnavailable 租约无法恢复时,开启快照则冻结拓扑并在后台重连,否则退出
+func (sd *etcdServiceDiscovery) onEtcdUnavailable() {
+	if !sd.snapshotEnable {
+		if sd.appDieChan != nil {
+			sd.appDieChan <- true
+		}
+		return
+	}
+	logger.Zap.Warn("sd: etcd unavailable, entering frozen topology mode", zap.Duration("maxStaleness", sd.maxStaleness))
+	sd.setFrozen(true)
+	co.Go(func() { sd.recoverFrozen(sd.renewLease) })
+}
+
+// onEtcdError 同步或监听服务列表失败时,开启快照则冻结拓扑,在后台确认etcd恢复后重新同步
+//
+//	@receiver sd
+//	@param cause
+func (sd *etcdServiceDiscovery) onEtcdError(cause error) {
+	if !sd.snapshotEnable || !sd.tryFreeze() {
+		return
+	}
+	logger.Zap.Warn("sd: failed to sync or watch servers, entering frozen topology mode", zap.Error(cause), zap.Duration("maxStaleness", sd.maxStaleness))
+	co.Go(func() { sd.recoverFrozen(sd.checkEtcd) })
+}
+
+// checkEtcd 检查etcd是否可读
+func (sd *etcdServiceDiscovery) checkEtcd() error {
+	ctx, cancel := context.WithTimeout(context.Background(), sd.grantLeaseTimeout)
+	defer cancel()
+	_, err := sd.cli.Get(ctx, "servers/", clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithLimit(1))
+	return errors.WithStack(err)
+}
+
+// bootFromSnapshot 启动时etcd不可用,从快照文件加载服务列表并冻结拓扑
+//
+//	@receiver sd
+//	@param cause 启动失败的原因
+//	@return error 无法从快照启动时返回 cause
+func (sd *etcdServiceDiscovery) bootFromSnapshot(cause error) error {
+	if !sd.snapshotEnable || sd.snapshotFile == nil {
+		return cause
+	}
+	snap, err := sd.snapshotFile.load()
+	if err != nil {
+		logger.Zap.Warn("sd: failed to load servers snapshot", zap.String("file", sd.snapshotFile.path), zap.Error(err))
+		return cause
+	}
+	syncAt := time.UnixMilli(snap.SavedAt)
+	if sd.maxStaleness > 0 && time.Since(syncAt) > sd.maxStaleness {
+		logger.Zap.Warn("sd: servers snapshot is too stale", zap.Time("savedAt", syncAt))
+		return cause
+	}
+	logger.Zap.Warn("sd: etcd unavailable on boot, loading servers from snapshot",
+		zap.Error(cause), zap.Int("servers", len(snap.Servers)), zap.Time("savedAt", syncAt))
+
+	sd.frozenLock.Lock()
+	sd.frozen = true
+	sd.lastSyncTime = syncAt
+	sd.frozenLock.Unlock()
+	sd.addServer(sd.GetSelfServer())
+	for _, sv := range snap.Servers {
+		if sv.ID == sd.server.ID || sd.isServerTypeBlacklisted(sv.Type) {
+			continue
+		}
+		sd.addServer(sv)
+	}
+	co.Go(func() { sd.recoverFrozen(sd.bootstrap) })
+	return nil
+}
+
+// recoverFrozen 定时重连etcd,成功后解除冻结并重新同步服务列表;冻结超过 maxStaleness 时退出
+//
+//	@receiver sd
+//	@param reconnect 重新获取租约并注册当前服务
+func (sd *etcdServiceDiscovery) recoverFrozen(reconnect func() error) {
+	ticker := time.NewTicker(sd.grantLeaseInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sd.stopChan:
+			return
+		case <-ticker.C:
+		}
+		if _, staleness := sd.Frozen(); sd.maxStaleness > 0 && staleness > sd.maxStaleness {
+			logger.Zap.Error("sd: frozen topology exceeded max staleness", zap.Duration("staleness", staleness))
+			if sd.appDieChan != nil {
+				sd.appDieChan <- true
+			}
+			return
+		}
+		if err := reconnect(); err != nil {
+			logger.Zap.Warn("sd: etcd still unavailable, keeping frozen topology", zap.Error(err))
+			continue
+		}
+		sd.setFrozen(false)
+		// 同步失败时由 onEtcdError 重新冻结
+		if err := sd.SyncServers(true); err != nil {
+			logger.Zap.Error("sd: failed to reconcile servers after etcd recovered", zap.Error(errors.WithStack(err)))
+			return
+		}
+		logger.Zap.Info("sd: etcd recovered, leaving frozen topology mode")
+		return
+	}
+}
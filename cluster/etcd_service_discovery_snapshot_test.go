@@ -0,0 +1,104 @@
+package cluster
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+)
+
+func newSnapshotTestSD(t *testing.T, file string, dieChan chan bool) *etcdServiceDiscovery {
+	t.Helper()
+	conf := config.NewDefaultEtcdServiceDiscoveryConfig()
+	conf.Snapshot.Enable = true
+	conf.Snapshot.File = file
+	conf.Snapshot.MaxStaleness = time.Minute
+	conf.GrantLease.RetryInterval = 10 * time.Millisecond
+	sd, err := NewEtcdServiceDiscovery(*conf, NewServer("frontend-1", "connector", true), dieChan)
+	require.NoError(t, err)
+	return sd.(*etcdServiceDiscovery)
+}
+
+func TestSDSnapshotFileSaveLoad(t *testing.T) {
+	f := &sdSnapshotFile{path: filepath.Join(t.TempDir(), "sd.json")}
+	now := time.Now()
+	servers := []*Server{NewServer("backend-1", "game", false, map[string]string{"k": "v"})}
+	require.NoError(t, f.save(servers, now))
+
+	snap, err := f.load()
+	require.NoError(t, err)
+	assert.Equal(t, now.UnixMilli(), snap.SavedAt)
+	assert.Equal(t, servers, snap.Servers)
+}
+
+func TestEtcdSDBootFromSnapshot(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sd.json")
+	f := &sdSnapshotFile{path: file}
+	require.NoError(t, f.save([]*Server{
+		NewServer("backend-1", "game", false),
+		NewServer("frontend-1", "connector", true),
+	}, time.Now().Add(-time.Second)))
+
+	sd := newSnapshotTestSD(t, file, nil)
+	defer close(sd.stopChan)
+	cause := errors.New("etcd down")
+	require.NoError(t, sd.bootFromSnapshot(cause))
+
+	frozen, staleness := sd.Frozen()
+	assert.True(t, frozen)
+	assert.GreaterOrEqual(t, staleness, time.Second)
+	sv, err := sd.GetServer("backend-1")
+	require.NoError(t, err)
+	assert.Equal(t, "game", sv.Type)
+	assert.Len(t, sd.GetServers(), 2)
+}
+
+func TestEtcdSDBootFromSnapshotTooStale(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sd.json")
+	f := &sdSnapshotFile{path: file}
+	require.NoError(t, f.save([]*Server{NewServer("backend-1", "game", false)}, time.Now().Add(-time.Hour)))
+
+	sd := newSnapshotTestSD(t, file, nil)
+	cause := errors.New("etcd down")
+	assert.Equal(t, cause, sd.bootFromSnapshot(cause))
+	frozen, _ := sd.Frozen()
+	assert.False(t, frozen)
+}
+
+func TestEtcdSDFrozenExceedsMaxStaleness(t *testing.T) {
+	dieChan := make(chan bool, 1)
+	sd := newSnapshotTestSD(t, "", dieChan)
+	defer close(sd.stopChan)
+	sd.maxStaleness = 50 * time.Millisecond
+	sd.markSynced()
+	sd.setFrozen(true)
+
+	go sd.recoverFrozen(func() error { return errors.New("etcd down") })
+	select {
+	case <-dieChan:
+	case <-time.After(time.Second):
+		t.Fatal("app die not triggered")
+	}
+}
+
+func TestEtcdSDFreezeOnError(t *testing.T) {
+	sd := newSnapshotTestSD(t, "", nil)
+	defer close(sd.stopChan)
+	// 不触发后台重连
+	sd.grantLeaseInterval = time.Hour
+
+	sd.onEtcdError(errors.New("watch failed"))
+	frozen, _ := sd.Frozen()
+	assert.True(t, frozen)
+	assert.False(t, sd.tryFreeze())
+
+	sd.setFrozen(false)
+	sd.snapshotEnable = false
+	sd.onEtcdError(errors.New("sync failed"))
+	frozen, _ = sd.Frozen()
+	assert.False(t, frozen)
+}
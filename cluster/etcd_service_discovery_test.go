@@ -286,7 +286,7 @@ func TestEtcdSDInit(t *testing.T) {
 			time.Sleep(50 * time.Millisecond)
 			// TODO may be flaky
 			helpers.ShouldEventuallyReturn(t, func() bool {
-				return math.Abs(float64(time.Now().Unix()-e.lastSyncTime.Unix())) < 5
+				return math.Abs(float64(time.Now().Unix()-e.lastSynced().Unix())) < 5
 			}, true, 50*time.Millisecond, 2*time.Second)
 		})
 	}
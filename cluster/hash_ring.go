@@ -0,0 +1,367 @@
+package cluster
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/zeromicro/go-zero/core/hash"
+)
+
+const (
+	defaultHashRingReplicas = 100
+	defaultHashRingLoadTTL  = 10 * time.Minute
+	defaultServerWeight     = 100
+)
+
+// HashRingListener 一致性哈希环变化监听
+type HashRingListener interface {
+	// OnHashRingChanged 服务增删或权重、状态变化导致哈希环变化时回调,按变化顺序在更新服务列表的锁内同步回调,不能再修改服务列表
+	//  @param serverType
+	//  @param moved 归属发生变化的哈希区间
+	OnHashRingChanged(serverType string, moved []MovedRange)
+}
+
+// ConsistentHashRing 支持一致性哈希配置和哈希环变化监听的服务发现
+type ConsistentHashRing interface {
+	// SetConsistentHashConfig 设置一致性哈希配置,需在服务发现初始化前调用
+	SetConsistentHashConfig(conf config.ConsistentHashConfig)
+	// AddHashRingListener 添加一致性哈希环变化监听
+	AddHashRingListener(listener HashRingListener)
+	// HashRingMembers 各类型哈希环中的节点及权重 map[serverType]map[serverID]weight
+	HashRingMembers() map[string]map[string]int
+	// GetRingNode 仅按哈希环获取key对应的节点,忽略有界负载.
+	//  各服务的哈希环一致时结果一致,用于定位有状态的实体
+	//  @param serverType
+	//  @param key
+	//  @return string
+	//  @return error
+	GetRingNode(serverType string, key string) (string, error)
+	// GetRingNodeMatch 从key在哈希环上的位置顺时针查找第一个满足match的节点,忽略有界负载.
+	//  用于一致性哈希选中的节点不可用于某个路由时,依次选择环上的下一个节点
+	//  @param serverType
+	//  @param key
+	//  @param match
+	//  @return string
+	//  @return error
+	GetRingNodeMatch(serverType string, key string, match func(serverID string) bool) (string, error)
+}
+
+// MovedRange 哈希环上归属变化的区间 (Start, End],Start > End 时表示跨越环的起点
+//
+//	有界负载只影响单个key的分配,不体现在区间中
+type MovedRange struct {
+	Start uint64
+	End   uint64
+	From  string // 原节点,为空表示原先没有节点
+	To    string // 新节点,为空表示当前没有节点
+}
+
+// Contains 哈希值是否在区间内
+//
+//	@receiver r
+//	@param h HashKey 的结果
+//	@return bool
+func (r MovedRange) Contains(h uint64) bool {
+	if r.Start < r.End {
+		return h > r.Start && h <= r.End
+	}
+	return h > r.Start || h <= r.End
+}
+
+// HashKey 一致性哈希使用的key哈希值,可用于判断key是否在 MovedRange 中
+//
+//	与 go-zero hash.ConsistentHash 相同,key和虚拟节点(节点ID+序号)均使用 hash.Hash.
+//	权重为默认值100时环上的位置与 go-zero 一致,升级前后key的分配不变;
+//	go-zero 的虚拟节点数不超过 Replicas,这里按权重等比缩放,权重大于100时会多出虚拟节点
+//
+//	@param key
+//	@return uint64
+func HashKey(key string) uint64 {
+	return hash.Hash([]byte(key))
+}
+
+// serverWeight 从metadata读取权重,默认100
+func serverWeight(sv *Server) int {
+	if w, err := strconv.Atoi(sv.Metadata[constants.ServerWeightKey]); err == nil && w > 0 {
+		return w
+	}
+	return defaultServerWeight
+}
+
+// hashAssignment 有界负载下key的分配
+type hashAssignment struct {
+	node     string
+	lastSeen time.Time
+}
+
+// hashRing 带虚拟节点和权重的一致性哈希环,LoadFactor>1时为有界负载一致性哈希
+//
+//	有界负载: 节点分配的key数上限为 ceil(LoadFactor*(总数+1)*权重/总权重),超过上限时顺时针选择下一个节点
+//	分配结果在key有访问期间保持不变,超过 LoadTTL 无访问后释放.
+//	负载只在本进程内统计,不同服务对同一key的选择可能不同,仅适用于不要求各服务路由一致的无状态服务;
+//	session粘性的服务类型不开启有界负载,定位有状态实体需使用 locate
+type hashRing struct {
+	mu          sync.Mutex
+	replicas    int
+	loadFactor  float64
+	loadTTL     time.Duration
+	weights     map[string]int
+	totalWeight int
+	keys        []uint64
+	owners      map[uint64]string
+	assigned    map[string]*hashAssignment
+	loads       map[string]int
+	lastSweep   time.Time
+}
+
+func newHashRing(conf config.HashRingConfig) *hashRing {
+	r := &hashRing{
+		replicas:   conf.Replicas,
+		loadFactor: conf.LoadFactor,
+		loadTTL:    conf.LoadTTL,
+		weights:    make(map[string]int),
+		owners:     make(map[uint64]string),
+		assigned:   make(map[string]*hashAssignment),
+		loads:      make(map[string]int),
+		lastSweep:  time.Now(),
+	}
+	if r.replicas <= 0 {
+		r.replicas = defaultHashRingReplicas
+	}
+	if r.loadTTL <= 0 {
+		r.loadTTL = defaultHashRingLoadTTL
+	}
+	return r
+}
+
+func (r *hashRing) bounded() bool {
+	return r.loadFactor > 1
+}
+
+// set 添加节点或修改权重
+//
+//	@receiver r
+//	@return []MovedRange 归属变化的区间
+func (r *hashRing) set(node string, weight int) []MovedRange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if w, ok := r.weights[node]; ok && w == weight {
+		return nil
+	}
+	oldKeys, oldOwners := r.keys, r.owners
+	r.weights[node] = weight
+	r.rebuild()
+	return diffHashRing(oldKeys, oldOwners, r.keys, r.owners)
+}
+
+// remove 移除节点,分配到该节点的key被释放
+//
+//	@receiver r
+//	@return []MovedRange 归属变化的区间
+func (r *hashRing) remove(node string) []MovedRange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.weights[node]; !ok {
+		return nil
+	}
+	oldKeys, oldOwners := r.keys, r.owners
+	delete(r.weights, node)
+	delete(r.loads, node)
+	for key, a := range r.assigned {
+		if a.node == node {
+			delete(r.assigned, key)
+		}
+	}
+	r.rebuild()
+	return diffHashRing(oldKeys, oldOwners, r.keys, r.owners)
+}
+
+// members 环中的节点及权重
+func (r *hashRing) members() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	members := make(map[string]int, len(r.weights))
+	for node, weight := range r.weights {
+		members[node] = weight
+	}
+	return members
+}
+
+func (r *hashRing) rebuild() {
+	r.totalWeight = 0
+	keys := make([]uint64, 0, len(r.keys))
+	owners := make(map[uint64]string, len(r.owners))
+	for node, weight := range r.weights {
+		r.totalWeight += weight
+		n := r.replicas * weight / defaultServerWeight
+		if n < 1 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			h := HashKey(node + strconv.Itoa(i))
+			// 哈希冲突时保留字典序较小的节点,保证各服务的环一致
+			if exist, ok := owners[h]; ok && exist < node {
+				continue
+			} else if !ok {
+				keys = append(keys, h)
+			}
+			owners[h] = node
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	r.keys, r.owners = keys, owners
+}
+
+// ownerIndex h 归属的虚拟节点下标
+func ownerIndex(keys []uint64, h uint64) int {
+	idx := sort.Search(len(keys), func(i int) bool { return keys[i] >= h })
+	if idx == len(keys) {
+		idx = 0
+	}
+	return idx
+}
+
+func ownerOf(keys []uint64, owners map[uint64]string, h uint64) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	return owners[keys[ownerIndex(keys, h)]]
+}
+
+// get 获取key对应的节点
+//
+//	@receiver r
+//	@param key
+//	@return string
+//	@return bool
+func (r *hashRing) get(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.keys) == 0 {
+		return "", false
+	}
+	h := HashKey(key)
+	if !r.bounded() {
+		return ownerOf(r.keys, r.owners, h), true
+	}
+
+	now := time.Now()
+	r.sweep(now)
+	if a, ok := r.assigned[key]; ok {
+		a.lastSeen = now
+		return a.node, true
+	}
+	total := len(r.assigned) + 1
+	idx := ownerIndex(r.keys, h)
+	node := r.owners[r.keys[idx]]
+	for i := 0; i < len(r.keys); i++ {
+		candidate := r.owners[r.keys[(idx+i)%len(r.keys)]]
+		capacity := int(math.Ceil(r.loadFactor * float64(total) * float64(r.weights[candidate]) / float64(r.totalWeight)))
+		if r.loads[candidate] < capacity {
+			node = candidate
+			break
+		}
+	}
+	r.assigned[key] = &hashAssignment{node: node, lastSeen: now}
+	r.loads[node]++
+	return node, true
+}
+
+// locate 仅按哈希环获取key对应的节点,不受有界负载影响
+//
+//	@receiver r
+//	@param key
+//	@return string
+//	@return bool
+func (r *hashRing) locate(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.keys) == 0 {
+		return "", false
+	}
+	return ownerOf(r.keys, r.owners, HashKey(key)), true
+}
+
+// next 从key的位置顺时针查找第一个满足match的节点,不受有界负载影响
+//
+//	@receiver r
+//	@param key
+//	@param match
+//	@return string
+//	@return bool 没有满足的节点时为false
+func (r *hashRing) next(key string, match func(node string) bool) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.keys) == 0 {
+		return "", false
+	}
+	idx := ownerIndex(r.keys, HashKey(key))
+	checked := make(map[string]bool, len(r.weights))
+	for i := 0; i < len(r.keys) && len(checked) < len(r.weights); i++ {
+		node := r.owners[r.keys[(idx+i)%len(r.keys)]]
+		if checked[node] {
+			continue
+		}
+		checked[node] = true
+		if match(node) {
+			return node, true
+		}
+	}
+	return "", false
+}
+
+// sweep 释放超过 loadTTL 无访问的key
+func (r *hashRing) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < r.loadTTL/2 {
+		return
+	}
+	r.lastSweep = now
+	for key, a := range r.assigned {
+		if now.Sub(a.lastSeen) > r.loadTTL {
+			delete(r.assigned, key)
+			r.loads[a.node]--
+		}
+	}
+}
+
+// diffHashRing 比较新旧哈希环,返回归属变化的区间,相邻且变化相同的区间合并
+func diffHashRing(oldKeys []uint64, oldOwners map[uint64]string, newKeys []uint64, newOwners map[uint64]string) []MovedRange {
+	points := make([]uint64, 0, len(oldKeys)+len(newKeys))
+	points = append(points, oldKeys...)
+	points = append(points, newKeys...)
+	if len(points) == 0 {
+		return nil
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+	uniq := points[:1]
+	for _, p := range points[1:] {
+		if p != uniq[len(uniq)-1] {
+			uniq = append(uniq, p)
+		}
+	}
+
+	var moved []MovedRange
+	for i, end := range uniq {
+		// 区间 (上一个点, end] 的归属即 end 所在虚拟节点的归属,第一个区间跨越环的起点
+		start := uniq[len(uniq)-1]
+		if i > 0 {
+			start = uniq[i-1]
+		}
+		from := ownerOf(oldKeys, oldOwners, end)
+		to := ownerOf(newKeys, newOwners, end)
+		if from == to {
+			continue
+		}
+		if n := len(moved); n > 0 && i > 0 && moved[n-1].End == start && moved[n-1].From == from && moved[n-1].To == to {
+			moved[n-1].End = end
+			continue
+		}
+		moved = append(moved, MovedRange{Start: start, End: end, From: from, To: to})
+	}
+	return moved
+}
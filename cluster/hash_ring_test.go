@@ -0,0 +1,199 @@
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/zeromicro/go-zero/core/hash"
+)
+
+func TestHashRingWeight(t *testing.T) {
+	r := newHashRing(config.HashRingConfig{Replicas: 200})
+	r.set("a", 100)
+	r.set("b", 300)
+	counts := map[string]int{}
+	for i := 0; i < 20000; i++ {
+		node, ok := r.get(fmt.Sprintf("uid-%d", i))
+		require.True(t, ok)
+		counts[node]++
+	}
+	ratio := float64(counts["b"]) / float64(counts["a"])
+	assert.InDelta(t, 3, ratio, 1)
+}
+
+func TestHashRingBoundedLoad(t *testing.T) {
+	r := newHashRing(config.HashRingConfig{Replicas: 10, LoadFactor: 1.25, LoadTTL: time.Minute})
+	for _, n := range []string{"a", "b", "c", "d"} {
+		r.set(n, 100)
+	}
+	const keys = 4000
+	for i := 0; i < keys; i++ {
+		_, ok := r.get(fmt.Sprintf("uid-%d", i))
+		require.True(t, ok)
+	}
+	limit := int(math.Ceil(1.25 * keys / 4))
+	for n, load := range r.loads {
+		assert.LessOrEqual(t, load, limit, n)
+	}
+
+	// 已分配的key保持不变
+	first, _ := r.get("uid-1")
+	again, _ := r.get("uid-1")
+	assert.Equal(t, first, again)
+	assert.Len(t, r.assigned, keys)
+}
+
+func TestHashRingCompatibleWithGoZero(t *testing.T) {
+	// 默认权重和虚拟节点数时与原先使用的 go-zero 一致性哈希分配相同
+	r := newHashRing(config.HashRingConfig{})
+	old := hash.NewConsistentHash()
+	for _, n := range []string{"game-1", "game-2", "game-3"} {
+		r.set(n, defaultServerWeight)
+		old.Add(n)
+	}
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("uid-%d", i)
+		node, ok := r.get(key)
+		require.True(t, ok)
+		expected, _ := old.Get(key)
+		require.Equal(t, expected, node, key)
+	}
+}
+
+func TestHashRingLocateIgnoresBoundedLoad(t *testing.T) {
+	// 有界负载的分配依赖本进程的访问历史,locate 只取决于哈希环
+	r1 := newHashRing(config.HashRingConfig{Replicas: 10, LoadFactor: 1.01, LoadTTL: time.Minute})
+	r2 := newHashRing(config.HashRingConfig{Replicas: 10, LoadFactor: 1.01, LoadTTL: time.Minute})
+	for _, n := range []string{"a", "b", "c"} {
+		r1.set(n, 100)
+		r2.set(n, 100)
+	}
+	for i := 0; i < 1000; i++ {
+		r1.get(fmt.Sprintf("uid-%d", i))
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("uid-%d", i)
+		n1, _ := r1.locate(key)
+		n2, _ := r2.locate(key)
+		require.Equal(t, n1, n2, key)
+		expected := ownerOf(r2.keys, r2.owners, HashKey(key))
+		require.Equal(t, expected, n1, key)
+	}
+}
+
+func TestHashRingNext(t *testing.T) {
+	r := newHashRing(config.HashRingConfig{Replicas: 10})
+	_, ok := r.next("uid", func(string) bool { return true })
+	assert.False(t, ok)
+	for _, n := range []string{"a", "b", "c"} {
+		r.set(n, 100)
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("uid-%d", i)
+		owner, _ := r.locate(key)
+		node, ok := r.next(key, func(string) bool { return true })
+		require.True(t, ok)
+		require.Equal(t, owner, node, key)
+		// 跳过原节点后与移除该节点的环结果一致
+		node, ok = r.next(key, func(n string) bool { return n != owner })
+		require.True(t, ok)
+		other := newHashRing(config.HashRingConfig{Replicas: 10})
+		for _, n := range []string{"a", "b", "c"} {
+			if n != owner {
+				other.set(n, 100)
+			}
+		}
+		expected, _ := other.locate(key)
+		require.Equal(t, expected, node, key)
+	}
+	_, ok = r.next("uid", func(string) bool { return false })
+	assert.False(t, ok)
+}
+
+func TestHashRingBoundedLoadSweep(t *testing.T) {
+	r := newHashRing(config.HashRingConfig{LoadFactor: 1.25, LoadTTL: 10 * time.Millisecond})
+	r.set("a", 100)
+	r.get("uid-1")
+	time.Sleep(20 * time.Millisecond)
+	r.get("uid-2")
+	assert.Len(t, r.assigned, 1)
+	assert.Equal(t, 1, r.loads["a"])
+}
+
+func TestHashRingMovedRanges(t *testing.T) {
+	r := newHashRing(config.HashRingConfig{Replicas: 50})
+	r.set("a", 100)
+	r.set("b", 100)
+	before := map[string]string{}
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("uid-%d", i)
+		before[key], _ = r.get(key)
+	}
+
+	moved := r.set("c", 100)
+	require.NotEmpty(t, moved)
+	for key, old := range before {
+		now, _ := r.get(key)
+		h := HashKey(key)
+		inRange := false
+		for _, m := range moved {
+			if m.Contains(h) {
+				inRange = true
+				assert.Equal(t, old, m.From)
+				assert.Equal(t, now, m.To)
+			}
+		}
+		assert.Equal(t, old != now, inRange, key)
+	}
+
+	assert.Empty(t, r.set("c", 100))
+	for _, m := range r.remove("c") {
+		assert.Equal(t, "c", m.From)
+	}
+}
+
+type recordRingListener struct {
+	moved map[string][]MovedRange
+}
+
+func (l *recordRingListener) OnHashRingChanged(serverType string, moved []MovedRange) {
+	l.moved[serverType] = append(l.moved[serverType], moved...)
+}
+
+func TestServerRegistryHashRingConfig(t *testing.T) {
+	r := newServerRegistry(NewServer("frontend-1", "connector", true))
+	conf := config.NewDefaultConsistentHashConfig()
+	conf.Types["game"] = config.HashRingConfig{Replicas: 10, LoadFactor: 1.5}
+	r.SetConsistentHashConfig(*conf)
+	l := &recordRingListener{moved: map[string][]MovedRange{}}
+	r.AddHashRingListener(l)
+
+	r.addServer(&Server{ID: "game-1", Type: "game", Metadata: map[string]string{constants.ServerWeightKey: "200"}})
+	require.NotEmpty(t, l.moved["game"])
+	assert.Equal(t, "game-1", l.moved["game"][0].To)
+	ring := r.hashRings["game"]
+	assert.True(t, ring.bounded())
+	assert.Len(t, ring.keys, 20)
+
+	node, err := r.GetRingNode("game", "uid")
+	require.NoError(t, err)
+	assert.Equal(t, "game-1", node)
+
+	r.deleteServer("game-1")
+	_, err = r.GetConsistentHashNode("game", "uid")
+	assert.Error(t, err)
+	_, err = r.GetRingNode("game", "uid")
+	assert.Error(t, err)
+
+	// session粘性的类型不开启有界负载
+	conf.Types["room"] = config.HashRingConfig{LoadFactor: 1.5}
+	r.SetConsistentHashConfig(*conf)
+	r.addServer(&Server{ID: "room-1", Type: "room", SessionStickiness: true})
+	assert.False(t, r.hashRings["room"].bounded())
+}
@@ -1,7 +1,7 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: cluster/service_discovery.go
 
-// Package mock_cluster is a generated GoMock package.
+// Package mocks is a generated GoMock package.
 package mocks
 
 import (
@@ -70,6 +70,64 @@ func (mr *MockServiceDiscoveryMockRecorder) BeforeShutdown() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeforeShutdown", reflect.TypeOf((*MockServiceDiscovery)(nil).BeforeShutdown))
 }
 
+// FlushServer2Cluster mocks base method.
+func (m *MockServiceDiscovery) FlushServer2Cluster(server *cluster.Server) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlushServer2Cluster", server)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FlushServer2Cluster indicates an expected call of FlushServer2Cluster.
+func (mr *MockServiceDiscoveryMockRecorder) FlushServer2Cluster(server interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushServer2Cluster", reflect.TypeOf((*MockServiceDiscovery)(nil).FlushServer2Cluster), server)
+}
+
+// GetAnyFrontend mocks base method.
+func (m *MockServiceDiscovery) GetAnyFrontend() (*cluster.Server, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAnyFrontend")
+	ret0, _ := ret[0].(*cluster.Server)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAnyFrontend indicates an expected call of GetAnyFrontend.
+func (mr *MockServiceDiscoveryMockRecorder) GetAnyFrontend() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAnyFrontend", reflect.TypeOf((*MockServiceDiscovery)(nil).GetAnyFrontend))
+}
+
+// GetConsistentHashNode mocks base method.
+func (m *MockServiceDiscovery) GetConsistentHashNode(serverType, sessionID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsistentHashNode", serverType, sessionID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsistentHashNode indicates an expected call of GetConsistentHashNode.
+func (mr *MockServiceDiscoveryMockRecorder) GetConsistentHashNode(serverType, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsistentHashNode", reflect.TypeOf((*MockServiceDiscovery)(nil).GetConsistentHashNode), serverType, sessionID)
+}
+
+// GetSelfServer mocks base method.
+func (m *MockServiceDiscovery) GetSelfServer() *cluster.Server {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSelfServer")
+	ret0, _ := ret[0].(*cluster.Server)
+	return ret0
+}
+
+// GetSelfServer indicates an expected call of GetSelfServer.
+func (mr *MockServiceDiscoveryMockRecorder) GetSelfServer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSelfServer", reflect.TypeOf((*MockServiceDiscovery)(nil).GetSelfServer))
+}
+
 // GetServer mocks base method.
 func (m *MockServiceDiscovery) GetServer(id string) (*cluster.Server, error) {
 	m.ctrl.T.Helper()
@@ -85,6 +143,20 @@ func (mr *MockServiceDiscoveryMockRecorder) GetServer(id interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServer", reflect.TypeOf((*MockServiceDiscovery)(nil).GetServer), id)
 }
 
+// GetServerTypes mocks base method.
+func (m *MockServiceDiscovery) GetServerTypes() map[string]*cluster.Server {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServerTypes")
+	ret0, _ := ret[0].(map[string]*cluster.Server)
+	return ret0
+}
+
+// GetServerTypes indicates an expected call of GetServerTypes.
+func (mr *MockServiceDiscoveryMockRecorder) GetServerTypes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServerTypes", reflect.TypeOf((*MockServiceDiscovery)(nil).GetServerTypes))
+}
+
 // GetServers mocks base method.
 func (m *MockServiceDiscovery) GetServers() []*cluster.Server {
 	m.ctrl.T.Helper()
@@ -128,6 +200,34 @@ func (mr *MockServiceDiscoveryMockRecorder) Init() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Init", reflect.TypeOf((*MockServiceDiscovery)(nil).Init))
 }
 
+// IsLeader mocks base method.
+func (m *MockServiceDiscovery) IsLeader() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsLeader")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsLeader indicates an expected call of IsLeader.
+func (mr *MockServiceDiscoveryMockRecorder) IsLeader() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsLeader", reflect.TypeOf((*MockServiceDiscovery)(nil).IsLeader))
+}
+
+// LeaderID mocks base method.
+func (m *MockServiceDiscovery) LeaderID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LeaderID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// LeaderID indicates an expected call of LeaderID.
+func (mr *MockServiceDiscoveryMockRecorder) LeaderID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaderID", reflect.TypeOf((*MockServiceDiscovery)(nil).LeaderID))
+}
+
 // Shutdown mocks base method.
 func (m *MockServiceDiscovery) Shutdown() error {
 	m.ctrl.T.Helper()
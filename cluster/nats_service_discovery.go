@@ -0,0 +1,427 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	natsSDServersPrefix  = "servers."
+	natsSDElectionPrefix = "election."
+)
+
+// natsServiceDiscovery 基于NATS JetStream KV的服务发现
+//
+//	服务记录 servers.{type}.{id} 由各服务定时续写,超过 Heartbeat.TTL 未续写视为下线,bucket的TTL同样设置为 Heartbeat.TTL 用于清理存储.
+//	KV记录TTL过期时不会推送删除通知,由心跳时 sweepExpired 按最近续写时间清理本地列表
+//	选举使用 election.{name} 记录,通过 Create 和按revision的 Update 实现CAS,leader定时续写,其他节点发现记录过期后按revision抢占
+type natsServiceDiscovery struct {
+	*serverRegistry
+	connectString          string
+	maxReconnectionRetries int
+	connectionTimeout      time.Duration
+	bucket                 string
+	replicas               int
+	heartbeatTTL           time.Duration
+	syncServersInterval    time.Duration
+	appDieChan             chan bool
+	conn                   *nats.Conn
+	ownConn                bool // 连接由服务发现创建,关闭时需断开
+	kv                     nats.KeyValue
+	watcher                nats.KeyWatcher
+	stopChan               chan struct{}
+	stopOnce               sync.Once
+	renewedAt              sync.Map // 其他服务最近一次续写的时间 key:serverID value:time.Time
+
+	// 选主相关
+	electionEnable bool
+	electionName   string
+	campaignChan   chan struct{}
+	leaderLock     sync.RWMutex
+	leaderID       string
+	leaderRevision uint64 // 当前节点为leader时选举记录的revision
+}
+
+// NewNatsServiceDiscovery ctor
+func NewNatsServiceDiscovery(
+	config config.NatsServiceDiscoveryConfig,
+	server *Server,
+	appDieChan chan bool,
+	conn ...*nats.Conn,
+) (ServiceDiscovery, error) {
+	sd := &natsServiceDiscovery{
+		serverRegistry:         newServerRegistry(server),
+		connectString:          config.Connect,
+		maxReconnectionRetries: config.MaxReconnectionRetries,
+		connectionTimeout:      config.ConnectionTimeout,
+		bucket:                 config.Bucket,
+		replicas:               config.Replicas,
+		heartbeatTTL:           config.Heartbeat.TTL,
+		syncServersInterval:    config.SyncServers.Interval,
+		appDieChan:             appDieChan,
+		stopChan:               make(chan struct{}),
+		electionEnable:         config.Election.Enable,
+		electionName:           config.Election.Name,
+		campaignChan:           make(chan struct{}, 1),
+	}
+	if sd.heartbeatTTL <= 0 {
+		return nil, errors.New("nats service discovery heartbeat ttl must be positive")
+	}
+	if sd.electionName == "" {
+		sd.electionName = server.Type
+	}
+	if len(conn) > 0 {
+		sd.conn = conn[0]
+	}
+	return sd, nil
+}
+
+func natsSDServerKey(serverType, serverID string) string {
+	return fmt.Sprintf("%s%s.%s", natsSDServersPrefix, serverType, serverID)
+}
+
+// parseNatsSDServerKey 解析服务记录key
+//
+//	@param key
+//	@return serverType
+//	@return serverID
+//	@return error
+func parseNatsSDServerKey(key string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(key, natsSDServersPrefix), ".", 2)
+	if !strings.HasPrefix(key, natsSDServersPrefix) || len(parts) != 2 {
+		return "", "", fmt.Errorf("error parsing nats sd key %s", key)
+	}
+	return parts[0], parts[1], nil
+}
+
+func (sd *natsServiceDiscovery) electionKey() string {
+	return natsSDElectionPrefix + sd.electionName
+}
+
+// Init 连接nats,注册当前服务并开始监听
+//
+//	@receiver sd
+//	@return error
+func (sd *natsServiceDiscovery) Init() error {
+	if sd.conn == nil {
+		conn, err := setupNatsConn(
+			sd.connectString,
+			sd.appDieChan,
+			nats.MaxReconnects(sd.maxReconnectionRetries),
+			nats.Timeout(sd.connectionTimeout),
+		)
+		if err != nil {
+			return err
+		}
+		sd.conn = conn
+		sd.ownConn = true
+	}
+	js, err := sd.conn.JetStream()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sd.kv, err = js.KeyValue(sd.bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		sd.kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:   sd.bucket,
+			History:  1,
+			TTL:      sd.heartbeatTTL,
+			Replicas: sd.replicas,
+		})
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	sd.addServer(sd.GetSelfServer())
+	if err = sd.FlushServer2Cluster(sd.GetSelfServer()); err != nil {
+		return err
+	}
+	if err = sd.SyncServers(true); err != nil {
+		return err
+	}
+	if sd.watcher, err = sd.kv.WatchAll(); err != nil {
+		return errors.WithStack(err)
+	}
+	co.Go(func() { sd.watchChanges() })
+	if sd.electionEnable {
+		sd.campaign()
+	}
+	co.Go(func() { sd.loop() })
+	return nil
+}
+
+// loop 续写当前服务和选举记录,定时全量同步
+func (sd *natsServiceDiscovery) loop() {
+	heartbeat := time.NewTicker(sd.heartbeatTTL / 3)
+	defer heartbeat.Stop()
+	var syncC <-chan time.Time
+	if sd.syncServersInterval > 0 {
+		syncTicker := time.NewTicker(sd.syncServersInterval)
+		defer syncTicker.Stop()
+		syncC = syncTicker.C
+	}
+	for {
+		select {
+		case <-heartbeat.C:
+			if err := sd.FlushServer2Cluster(sd.GetSelfServer()); err != nil {
+				logger.Zap.Error("nats sd heartbeat error", zap.Error(err))
+			}
+			sd.sweepExpired()
+			if sd.electionEnable {
+				sd.campaign()
+			}
+		case <-sd.campaignChan:
+			if sd.electionEnable {
+				sd.campaign()
+			}
+		case <-syncC:
+			if err := sd.SyncServers(false); err != nil {
+				logger.Zap.Error("nats sd sync servers error", zap.Error(err))
+			}
+		case <-sd.stopChan:
+			return
+		}
+	}
+}
+
+func (sd *natsServiceDiscovery) watchChanges() {
+	for {
+		select {
+		case entry, ok := <-sd.watcher.Updates():
+			if !ok {
+				return
+			}
+			// nil 表示初始数据已全部推送
+			if entry == nil {
+				continue
+			}
+			sd.applyEntry(entry)
+		case <-sd.stopChan:
+			return
+		}
+	}
+}
+
+func (sd *natsServiceDiscovery) applyEntry(entry nats.KeyValueEntry) {
+	key := entry.Key()
+	if strings.HasPrefix(key, natsSDElectionPrefix) {
+		if key != sd.electionKey() {
+			return
+		}
+		if entry.Operation() == nats.KeyValuePut {
+			sd.setLeader(string(entry.Value()), 0)
+		} else {
+			sd.setLeader("", 0)
+			select {
+			case sd.campaignChan <- struct{}{}:
+			default:
+			}
+		}
+		return
+	}
+	_, id, err := parseNatsSDServerKey(key)
+	if err != nil {
+		logger.Zap.Warn("nats sd ignore key", zap.Error(err))
+		return
+	}
+	if id == sd.server.ID {
+		return
+	}
+	if entry.Operation() != nats.KeyValuePut {
+		sd.renewedAt.Delete(id)
+		sd.deleteServer(id)
+		return
+	}
+	sv, err := parseServer(entry.Value())
+	if err != nil {
+		logger.Zap.Error("nats sd parse server error", zap.String("key", key), zap.Error(err))
+		return
+	}
+	sd.renewedAt.Store(sv.ID, entry.Created())
+	if old, ok := sd.serverMapByID.Load(sv.ID); ok && serverEqual(old.(*Server), sv) {
+		return
+	}
+	sd.addServer(sv)
+}
+
+// SyncServers 全量同步服务列表,超过 Heartbeat.TTL 未续写的服务视为下线
+//
+//	@receiver sd
+//	@param firstSync
+//	@return error
+func (sd *natsServiceDiscovery) SyncServers(firstSync bool) error {
+	keys, err := sd.kv.Keys()
+	if err != nil && !errors.Is(err, nats.ErrNoKeysFound) {
+		return errors.WithStack(err)
+	}
+	servers := make([]*Server, 0, len(keys))
+	for _, key := range keys {
+		if !strings.HasPrefix(key, natsSDServersPrefix) {
+			continue
+		}
+		entry, err := sd.kv.Get(key)
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if time.Since(entry.Created()) > sd.heartbeatTTL {
+			continue
+		}
+		sv, err := parseServer(entry.Value())
+		if err != nil {
+			logger.Zap.Error("nats sd parse server error", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		if sv.ID != sd.server.ID {
+			sd.renewedAt.Store(sv.ID, entry.Created())
+		}
+		servers = append(servers, sv)
+	}
+	sd.syncServers(servers)
+	return nil
+}
+
+// sweepExpired 删除超过 Heartbeat.TTL 未续写的服务.KV记录因TTL过期被清理时不会推送删除通知
+//
+//	@receiver sd
+func (sd *natsServiceDiscovery) sweepExpired() {
+	sd.renewedAt.Range(func(key, value any) bool {
+		if time.Since(value.(time.Time)) > sd.heartbeatTTL {
+			sd.renewedAt.Delete(key)
+			sd.deleteServer(key.(string))
+		}
+		return true
+	})
+}
+
+// FlushServer2Cluster 写入服务记录,同时作为心跳续期
+//
+//	@receiver sd
+//	@param server
+//	@return error
+func (sd *natsServiceDiscovery) FlushServer2Cluster(server *Server) error {
+	sd.setSelfServer(server)
+	data, err := json.Marshal(server)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err = sd.kv.Put(natsSDServerKey(server.Type, server.ID), data); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// campaign 竞选或续期leader
+//
+//	记录不存在时 Create,记录属于自己或已过期时按revision Update,失败说明被其他节点抢先
+func (sd *natsServiceDiscovery) campaign() {
+	key := sd.electionKey()
+	self := []byte(sd.server.ID)
+	entry, err := sd.kv.Get(key)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		if rev, err := sd.kv.Create(key, self); err == nil {
+			sd.setLeader(sd.server.ID, rev)
+		}
+		return
+	}
+	if err != nil {
+		logger.Zap.Error("nats sd get election error", zap.String("key", key), zap.Error(err))
+		return
+	}
+	leader := string(entry.Value())
+	if leader == sd.server.ID || time.Since(entry.Created()) > sd.heartbeatTTL {
+		if rev, err := sd.kv.Update(key, self, entry.Revision()); err == nil {
+			sd.setLeader(sd.server.ID, rev)
+			return
+		}
+		if leader == sd.server.ID {
+			sd.setLeader("", 0)
+		}
+		return
+	}
+	sd.setLeader(leader, 0)
+}
+
+func (sd *natsServiceDiscovery) setLeader(id string, revision uint64) {
+	sd.leaderLock.Lock()
+	if id == sd.server.ID && revision == 0 {
+		// 来自watch的自身续期,保留续期得到的revision
+		revision = sd.leaderRevision
+	}
+	if sd.leaderID != id {
+		logger.Zap.Info("nats sd leader changed", zap.String("election", sd.electionName), zap.String("leader", id))
+	}
+	sd.leaderID = id
+	sd.leaderRevision = revision
+	sd.leaderLock.Unlock()
+	sd.reportLeader(id)
+}
+
+// LeaderID 获取主节点ID 只有在配置 sd.nats.election.enable 开启时有效
+//
+//	@receiver sd
+//	@return string
+func (sd *natsServiceDiscovery) LeaderID() string {
+	sd.leaderLock.RLock()
+	defer sd.leaderLock.RUnlock()
+	return sd.leaderID
+}
+
+func (sd *natsServiceDiscovery) IsLeader() bool {
+	return sd.LeaderID() == sd.server.ID
+}
+
+// AfterInit executes after Init
+func (sd *natsServiceDiscovery) AfterInit() {
+}
+
+// BeforeShutdown 删除当前服务记录,若为leader则辞职
+func (sd *natsServiceDiscovery) BeforeShutdown() {
+	if sd.kv == nil {
+		return
+	}
+	sd.stop()
+	if err := sd.kv.Delete(natsSDServerKey(sd.server.Type, sd.server.ID)); err != nil {
+		logger.Zap.Warn("nats sd delete self error", zap.Error(err))
+	}
+	sd.leaderLock.RLock()
+	rev := sd.leaderRevision
+	isLeader := sd.leaderID == sd.server.ID
+	sd.leaderLock.RUnlock()
+	if isLeader && rev > 0 {
+		if err := sd.kv.Delete(sd.electionKey(), nats.LastRevision(rev)); err != nil {
+			logger.Zap.Warn("nats sd resign error", zap.Error(err))
+		}
+	}
+}
+
+// Shutdown executes on shutdown
+func (sd *natsServiceDiscovery) Shutdown() error {
+	sd.stop()
+	if sd.ownConn && sd.conn != nil {
+		sd.conn.Close()
+	}
+	return nil
+}
+
+func (sd *natsServiceDiscovery) stop() {
+	sd.stopOnce.Do(func() {
+		close(sd.stopChan)
+		if sd.watcher != nil {
+			sd.watcher.Stop()
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/helpers"
+)
+
+type recordSDListener struct {
+	added   chan *Server
+	removed chan *Server
+}
+
+func newRecordSDListener() *recordSDListener {
+	return &recordSDListener{added: make(chan *Server, 10), removed: make(chan *Server, 10)}
+}
+
+func (l *recordSDListener) AddServer(sv *Server)         { l.added <- sv }
+func (l *recordSDListener) RemoveServer(sv *Server)      { l.removed <- sv }
+func (l *recordSDListener) ModifyServer(sv, old *Server) {}
+
+func newTestNatsSD(t *testing.T, url string, sv *Server, election bool) *natsServiceDiscovery {
+	t.Helper()
+	conf := config.NewDefaultNatsServiceDiscoveryConfig()
+	conf.Connect = url
+	conf.Heartbeat.TTL = 600 * time.Millisecond
+	conf.SyncServers.Interval = 200 * time.Millisecond
+	conf.Election.Enable = election
+	sv.Status = ServerStatusReady
+	sd, err := NewNatsServiceDiscovery(*conf, sv, nil)
+	require.NoError(t, err)
+	return sd.(*natsServiceDiscovery)
+}
+
+func TestNatsSDParseServerKey(t *testing.T) {
+	svType, id, err := parseNatsSDServerKey(natsSDServerKey("connector", "c-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "connector", svType)
+	assert.Equal(t, "c-1", id)
+
+	_, _, err = parseNatsSDServerKey("election.connector")
+	assert.Error(t, err)
+}
+
+func TestNatsSDAddAndRemoveServers(t *testing.T) {
+	s := helpers.GetTestJetStreamServer(t)
+	defer s.Shutdown()
+
+	sd1 := newTestNatsSD(t, s.ClientURL(), NewServer("game-1", "game", false), false)
+	l := newRecordSDListener()
+	sd1.AddListener(l)
+	require.NoError(t, sd1.Init())
+	defer sd1.Shutdown()
+
+	sd2 := newTestNatsSD(t, s.ClientURL(), NewServer("game-2", "game", false), false)
+	require.NoError(t, sd2.Init())
+
+	select {
+	case sv := <-l.added:
+		assert.Equal(t, "game-2", sv.ID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server not added")
+	}
+	node, err := sd1.GetConsistentHashNode("game", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "game-2", node)
+
+	sd2.BeforeShutdown()
+	sd2.Shutdown()
+	select {
+	case sv := <-l.removed:
+		assert.Equal(t, "game-2", sv.ID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server not removed")
+	}
+	_, err = sd1.GetServer("game-2")
+	assert.Error(t, err)
+}
+
+func TestNatsSDExpireServer(t *testing.T) {
+	s := helpers.GetTestJetStreamServer(t)
+	defer s.Shutdown()
+
+	sd1 := newTestNatsSD(t, s.ClientURL(), NewServer("game-1", "game", false), false)
+	l := newRecordSDListener()
+	sd1.AddListener(l)
+	require.NoError(t, sd1.Init())
+	defer sd1.Shutdown()
+
+	// 只写入一次不续期的服务记录
+	stale := NewServer("game-2", "game", false)
+	_, err := sd1.kv.Put(natsSDServerKey(stale.Type, stale.ID), []byte(stale.AsJSONString()))
+	require.NoError(t, err)
+	<-l.added
+
+	select {
+	case sv := <-l.removed:
+		assert.Equal(t, "game-2", sv.ID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("stale server not removed")
+	}
+}
+
+func TestNatsSDExpireServerWithoutSync(t *testing.T) {
+	s := helpers.GetTestJetStreamServer(t)
+	defer s.Shutdown()
+
+	// 不全量同步时,过期的服务由心跳清理
+	sd1 := newTestNatsSD(t, s.ClientURL(), NewServer("game-1", "game", false), false)
+	sd1.syncServersInterval = 0
+	l := newRecordSDListener()
+	sd1.AddListener(l)
+	require.NoError(t, sd1.Init())
+	defer sd1.Shutdown()
+
+	stale := NewServer("game-2", "game", false)
+	_, err := sd1.kv.Put(natsSDServerKey(stale.Type, stale.ID), []byte(stale.AsJSONString()))
+	require.NoError(t, err)
+	<-l.added
+
+	select {
+	case sv := <-l.removed:
+		assert.Equal(t, "game-2", sv.ID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("stale server not removed")
+	}
+}
+
+func TestNatsSDElection(t *testing.T) {
+	s := helpers.GetTestJetStreamServer(t)
+	defer s.Shutdown()
+
+	sd1 := newTestNatsSD(t, s.ClientURL(), NewServer("game-1", "game", false), true)
+	require.NoError(t, sd1.Init())
+	sd2 := newTestNatsSD(t, s.ClientURL(), NewServer("game-2", "game", false), true)
+	require.NoError(t, sd2.Init())
+	defer sd2.Shutdown()
+
+	assert.True(t, sd1.IsLeader())
+	assert.Eventually(t, func() bool { return sd2.LeaderID() == "game-1" }, 2*time.Second, 20*time.Millisecond)
+
+	sd1.BeforeShutdown()
+	sd1.Shutdown()
+	assert.Eventually(t, sd2.IsLeader, 2*time.Second, 20*time.Millisecond)
+
+	// 选举记录的revision被其他节点更新后,旧leader无法续期
+	entry, err := sd2.kv.Get(sd2.electionKey())
+	require.NoError(t, err)
+	_, err = sd2.kv.Update(sd2.electionKey(), []byte("game-3"), entry.Revision()-1)
+	assert.ErrorIs(t, err, nats.ErrKeyExists)
+}
@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TopologyFreezer 支持冻结拓扑的服务发现
+//
+//	服务发现的数据源不可用时冻结拓扑,沿用最后一次同步成功的服务列表,期间不删除服务,恢复后重新同步
+type TopologyFreezer interface {
+	// Frozen 是否处于冻结拓扑状态
+	//  @return frozen
+	//  @return staleness 距最后一次同步成功的时间
+	Frozen() (frozen bool, staleness time.Duration)
+}
+
+// sdSnapshot 服务列表快照
+type sdSnapshot struct {
+	Servers []*Server `json:"servers"`
+	SavedAt int64     `json:"savedAt"` // 对应的同步时间 unix毫秒
+}
+
+// sdSnapshotFile 快照文件,先写临时文件再重命名,避免写入中断导致快照损坏
+type sdSnapshotFile struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (f *sdSnapshotFile) save(servers []*Server, syncAt time.Time) error {
+	data, err := json.Marshal(&sdSnapshot{Servers: servers, SavedAt: syncAt.UnixMilli()})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.WithStack(err)
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp.Name(), f.path))
+}
+
+func (f *sdSnapshotFile) load() (*sdSnapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var snap sdSnapshot
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &snap, nil
+}
@@ -25,17 +25,107 @@ import (
 	"os"
 
 	"github.com/topfreegames/pitaya/v2/logger"
+	"github.com/topfreegames/pitaya/v2/route"
 	"go.uber.org/zap"
 )
 
 // Server struct
 type Server struct {
-	ID                string            `json:"id"`
-	Type              string            `json:"type"`
-	Metadata          map[string]string `json:"metadata"`
-	Frontend          bool              `json:"frontend"`
-	Hostname          string            `json:"hostname"`
-	SessionStickiness bool              `json:"stickiness"` // 是否可以绑定session，绑定后将保持session粘连
+	ID                string                  `json:"id"`
+	Type              string                  `json:"type"`
+	Metadata          map[string]string       `json:"metadata"`
+	Frontend          bool                    `json:"frontend"`
+	Hostname          string                  `json:"hostname"`
+	SessionStickiness bool                    `json:"stickiness"` // 是否可以绑定session，绑定后将保持session粘连
+	Status            ServerStatus            `json:"status,omitempty"`
+	APIVersion        VersionRange            `json:"apiVersion"`              // 服务支持的接口版本
+	RouteVersions     map[string]VersionRange `json:"routeVersions,omitempty"` // 单独声明版本的路由,key为 service 或 service.method
+}
+
+// VersionRange 接口版本区间 [Min, Max],均为0表示未声明,与任意版本兼容;Max为0表示没有上限
+type VersionRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Declared 是否声明了版本
+//
+//	@receiver v
+//	@return bool
+func (v VersionRange) Declared() bool {
+	return v.Min != 0 || v.Max != 0
+}
+
+// Compatible 两个版本区间是否有交集,任一方未声明时视为兼容
+//
+//	@receiver v
+//	@param other
+//	@return bool
+func (v VersionRange) Compatible(other VersionRange) bool {
+	if !v.Declared() || !other.Declared() {
+		return true
+	}
+	return (v.Max == 0 || other.Min <= v.Max) && (other.Max == 0 || v.Min <= other.Max)
+}
+
+// RouteVersion 路由的版本区间,依次查找 service.method、service 的声明,都没有时为服务的版本
+//
+//	@receiver s
+//	@param rt
+//	@return VersionRange
+func (s *Server) RouteVersion(rt *route.Route) VersionRange {
+	if rt != nil {
+		if v, ok := s.RouteVersions[rt.Short()]; ok {
+			return v
+		}
+		if v, ok := s.RouteVersions[rt.Service]; ok {
+			return v
+		}
+	}
+	return s.APIVersion
+}
+
+// CompatibleWith 当前服务是否可以调用目标服务的路由,当前服务的版本与目标路由的版本有交集时兼容
+//
+//	@receiver s
+//	@param target
+//	@param rt 为nil时只比较服务版本
+//	@return bool
+func (s *Server) CompatibleWith(target *Server, rt *route.Route) bool {
+	return s.APIVersion.Compatible(target.RouteVersion(rt))
+}
+
+// SetRouteVersion 声明路由的版本,需在服务注册到服务发现前调用
+//
+//	@receiver s
+//	@param routeKey service 或 service.method
+//	@param v
+func (s *Server) SetRouteVersion(routeKey string, v VersionRange) {
+	if !v.Declared() {
+		return
+	}
+	if s.RouteVersions == nil {
+		s.RouteVersions = make(map[string]VersionRange)
+	}
+	s.RouteVersions[routeKey] = v
+}
+
+// ServerStatus 服务状态,只有 ready 的服务参与随机路由、一致性哈希和 GetAnyFrontend
+type ServerStatus string
+
+const (
+	ServerStatusStarting  ServerStatus = "starting"  // 启动中,模块尚未就绪
+	ServerStatusReady     ServerStatus = "ready"     // 可正常接收请求
+	ServerStatusDraining  ServerStatus = "draining"  // 下线中,不再分配新的请求,已绑定的session继续路由
+	ServerStatusUnhealthy ServerStatus = "unhealthy" // 健康检查未通过
+)
+
+// Available 是否可以分配新的请求,未设置状态的服务视为就绪以兼容旧版本
+//
+//	@receiver s
+//	@return bool
+func (s *Server) Available() bool {
+	return s.Status == "" || s.Status == ServerStatusReady
 }
 
 // NewServer ctor
@@ -54,6 +144,7 @@ func NewServer(id, serverType string, frontend bool, metadata ...map[string]stri
 		Metadata: d,
 		Frontend: frontend,
 		Hostname: h,
+		Status:   ServerStatusStarting,
 	}
 }
 
@@ -0,0 +1,499 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"github.com/topfreegames/pitaya/v2/util"
+	"go.uber.org/zap"
+)
+
+// serverRegistry 服务发现的本地服务列表,维护按类型索引、一致性哈希环并通知 SDListener
+//
+//	各 ServiceDiscovery 实现只负责从各自的数据源同步,增删改统一经由 addServer 和 deleteServer
+type serverRegistry struct {
+	server          *Server                // 当前服务,只读取其中不变的ID、类型等字段
+	self            atomic.Pointer[Server] // 最近一次同步到集群的当前服务,状态变化时替换为新的副本
+	updateLock      sync.Mutex             // 串行 addServer 和 deleteServer,保证本地列表、哈希环和通知顺序一致
+	mapByTypeLock   sync.RWMutex
+	serverMapByType map[string]map[string]*Server
+	serverMapByID   sync.Map
+	hashLock        sync.RWMutex
+	hashRings       map[string]*hashRing
+	hashConfig      config.ConsistentHashConfig
+	listeners       []SDListener
+	ringListeners   []HashRingListener
+
+	// SDListener 和选举的通知在 notifyLock 下排队,在锁外按顺序执行,回调中可以再次触发通知
+	notifyLock        sync.Mutex
+	notifyQueue       []func()
+	notifying         bool // 已有goroutine在执行通知队列
+	electionListeners []ElectionListener
+	pendingLeader     string             // 选举得到的最新主节点
+	notifiedLeader    string             // 已通知的主节点
+	leaderCancel      context.CancelFunc // 失去主节点时取消 OnElected 的ctx
+}
+
+func newServerRegistry(server *Server) *serverRegistry {
+	r := &serverRegistry{
+		server:          server,
+		serverMapByType: make(map[string]map[string]*Server),
+		hashRings:       make(map[string]*hashRing),
+		hashConfig:      *config.NewDefaultConsistentHashConfig(),
+		listeners:       make([]SDListener, 0),
+	}
+	r.self.Store(server)
+	return r
+}
+
+// GetSelfServer @implement ServiceDiscovery.GetSelfServer
+//
+//	返回最近一次 FlushServer2Cluster 的当前服务,注册和续写时使用,保证不会写回旧的状态
+//	@receiver r
+//	@return *Server
+func (r *serverRegistry) GetSelfServer() *Server {
+	return r.self.Load()
+}
+
+// setSelfServer FlushServer2Cluster 时记录当前服务的最新副本,其他服务忽略
+func (r *serverRegistry) setSelfServer(sv *Server) {
+	if sv.ID == r.server.ID {
+		r.self.Store(sv)
+	}
+}
+
+func (r *serverRegistry) AddListener(listener SDListener) {
+	r.notifyLock.Lock()
+	defer r.notifyLock.Unlock()
+	r.listeners = append(r.listeners, listener)
+}
+
+// SetConsistentHashConfig 设置一致性哈希配置,需在服务发现初始化前调用
+//
+//	@receiver r
+//	@param conf
+func (r *serverRegistry) SetConsistentHashConfig(conf config.ConsistentHashConfig) {
+	r.hashLock.Lock()
+	defer r.hashLock.Unlock()
+	r.hashConfig = conf
+}
+
+// AddHashRingListener 添加一致性哈希环变化监听
+//
+//	@receiver r
+//	@param listener
+func (r *serverRegistry) AddHashRingListener(listener HashRingListener) {
+	r.ringListeners = append(r.ringListeners, listener)
+}
+
+// HashRingMembers 各类型哈希环中的节点及权重
+//
+//	@receiver r
+//	@return map[string]map[string]int map[serverType]map[serverID]weight
+func (r *serverRegistry) HashRingMembers() map[string]map[string]int {
+	r.hashLock.RLock()
+	defer r.hashLock.RUnlock()
+	members := make(map[string]map[string]int, len(r.hashRings))
+	for serverType, ring := range r.hashRings {
+		members[serverType] = ring.members()
+	}
+	return members
+}
+
+func (r *serverRegistry) notifyRingListeners(serverType string, moved []MovedRange) {
+	if len(moved) == 0 {
+		return
+	}
+	for _, l := range r.ringListeners {
+		l.OnHashRingChanged(serverType, moved)
+	}
+}
+
+// notifyListeners 通知入队,由调用方在释放 updateLock 后 dispatch
+func (r *serverRegistry) notifyListeners(act Action, sv *Server, old ...*Server) {
+	r.notifyLock.Lock()
+	listeners := append([]SDListener(nil), r.listeners...)
+	r.notifyQueue = append(r.notifyQueue, func() {
+		for _, l := range listeners {
+			if act == DEL {
+				l.RemoveServer(sv)
+			} else if act == ADD {
+				l.AddServer(sv)
+			} else if act == Modify {
+				l.ModifyServer(sv, old[0])
+			}
+		}
+	})
+	if act == ADD && sv.ID == r.pendingLeader {
+		r.flushLeader(r.pendingLeader)
+	} else if act == DEL && sv.ID == r.notifiedLeader {
+		// 主节点服务已下线,新的选举结果到达前暂无主节点
+		r.flushLeader("")
+	}
+	r.notifyLock.Unlock()
+}
+
+// dispatch 在锁外按入队顺序执行通知,已有goroutine在执行时由其负责执行新入队的通知
+//
+//	@receiver r
+func (r *serverRegistry) dispatch() {
+	r.notifyLock.Lock()
+	if r.notifying {
+		r.notifyLock.Unlock()
+		return
+	}
+	r.notifying = true
+	defer func() {
+		r.notifying = false
+		r.notifyLock.Unlock()
+	}()
+	for len(r.notifyQueue) > 0 {
+		f := r.notifyQueue[0]
+		r.notifyQueue[0] = nil
+		r.notifyQueue = r.notifyQueue[1:]
+		r.notifyLock.Unlock()
+		func() {
+			// 回调panic时恢复锁状态,保证 defer 中解锁
+			defer r.notifyLock.Lock()
+			f()
+		}()
+	}
+}
+
+// AddElectionListener 添加主节点选举监听
+//
+//	@receiver r
+//	@param listener
+func (r *serverRegistry) AddElectionListener(listener ElectionListener) {
+	r.notifyLock.Lock()
+	defer r.notifyLock.Unlock()
+	r.electionListeners = append(r.electionListeners, listener)
+}
+
+// reportLeader 上报选举得到的主节点,为空表示暂无主节点
+//
+//	主节点为其他服务且本地尚未收到该服务时,等到 AddServer 通知之后再通知 OnLeaderChanged
+//	@receiver r
+//	@param leaderID
+func (r *serverRegistry) reportLeader(leaderID string) {
+	r.notifyLock.Lock()
+	r.pendingLeader = leaderID
+	r.flushLeader(leaderID)
+	r.notifyLock.Unlock()
+	r.dispatch()
+}
+
+// flushLeader 主节点变化的通知入队,调用方需持有 notifyLock
+//
+//	失去主节点时依次 OnRevoked、OnLeaderChanged,成为主节点时依次 OnLeaderChanged、OnElected
+//	@receiver r
+//	@param leaderID
+func (r *serverRegistry) flushLeader(leaderID string) {
+	if leaderID == r.notifiedLeader {
+		return
+	}
+	if leaderID != "" && leaderID != r.server.ID {
+		if _, ok := r.serverMapByID.Load(leaderID); !ok {
+			return
+		}
+	}
+	listeners := append([]ElectionListener(nil), r.electionListeners...)
+	revoked := r.leaderCancel != nil
+	if revoked {
+		r.leaderCancel()
+		r.leaderCancel = nil
+	}
+	r.notifiedLeader = leaderID
+	var ctx context.Context
+	if leaderID == r.server.ID {
+		ctx, r.leaderCancel = context.WithCancel(context.Background())
+	}
+	r.notifyQueue = append(r.notifyQueue, func() {
+		if revoked {
+			for _, l := range listeners {
+				l.OnRevoked()
+			}
+		}
+		for _, l := range listeners {
+			l.OnLeaderChanged(leaderID)
+		}
+		if ctx != nil {
+			for _, l := range listeners {
+				l.OnElected(ctx)
+			}
+		}
+	})
+}
+
+func (r *serverRegistry) writeLockScope(f func()) {
+	r.mapByTypeLock.Lock()
+	defer r.mapByTypeLock.Unlock()
+	f()
+}
+
+// addServer 添加或更新服务
+//
+//	同步和 FlushServer2Cluster 可能并发修改同一服务,读取旧值到通知入队在 updateLock 内完成,
+//	避免重复的 ADD 或 Modify 的旧值错误;SDListener 在锁外回调,可以再次修改服务列表
+func (r *serverRegistry) addServer(sv *Server) {
+	defer r.dispatch()
+	r.updateLock.Lock()
+	defer r.updateLock.Unlock()
+	old, loaded := r.serverMapByID.Load(sv.ID)
+	r.writeLockScope(func() {
+		r.serverMapByID.Store(sv.ID, sv)
+		mapSvByType, ok := r.serverMapByType[sv.Type]
+		if !ok {
+			mapSvByType = make(map[string]*Server)
+			r.serverMapByType[sv.Type] = mapSvByType
+		}
+		mapSvByType[sv.ID] = sv
+	})
+	if sv.ID != r.server.ID {
+		if !r.server.CompatibleWith(sv, nil) {
+			logger.Zap.Warn("server api version is incompatible, requests will not be routed to it", zap.String("svID", sv.ID), zap.String("svType", sv.Type), zap.Int("min", sv.APIVersion.Min), zap.Int("max", sv.APIVersion.Max))
+		}
+		r.updateConsistentHash(sv)
+		if !loaded {
+			r.notifyListeners(ADD, sv)
+		} else {
+			r.notifyListeners(Modify, sv, old.(*Server))
+		}
+	}
+}
+
+// updateConsistentHash 只有可用且接口版本兼容的服务在哈希环中,状态变化时加入或移出
+func (r *serverRegistry) updateConsistentHash(sv *Server) {
+	r.hashLock.Lock()
+	ring := r.hashRings[sv.Type]
+	if ring == nil {
+		conf := r.hashConfig.Ring(sv.Type)
+		if conf.LoadFactor > 1 && sv.SessionStickiness {
+			// 有界负载的分配只在本进程内有效,各网关的选择可能不同
+			logger.Zap.Warn("bounded load consistent hash is not supported by session stickiness server type, disable it", zap.String("svType", sv.Type))
+			conf.LoadFactor = 0
+		}
+		ring = newHashRing(conf)
+		r.hashRings[sv.Type] = ring
+	}
+	r.hashLock.Unlock()
+	var moved []MovedRange
+	if sv.Available() && r.server.CompatibleWith(sv, nil) {
+		moved = ring.set(sv.ID, serverWeight(sv))
+	} else {
+		moved = ring.remove(sv.ID)
+	}
+	r.notifyRingListeners(sv.Type, moved)
+}
+
+func (r *serverRegistry) deleteServer(serverID string) {
+	defer r.dispatch()
+	r.updateLock.Lock()
+	defer r.updateLock.Unlock()
+	if actual, ok := r.serverMapByID.Load(serverID); ok {
+		sv := actual.(*Server)
+		r.serverMapByID.Delete(sv.ID)
+		r.writeLockScope(func() {
+			if svMap, ok := r.serverMapByType[sv.Type]; ok {
+				delete(svMap, sv.ID)
+			}
+		})
+		r.hashLock.RLock()
+		ring := r.hashRings[sv.Type]
+		r.hashLock.RUnlock()
+		if ring != nil {
+			r.notifyRingListeners(sv.Type, ring.remove(sv.ID))
+		}
+		r.notifyListeners(DEL, sv)
+	}
+}
+
+func (r *serverRegistry) deleteLocalInvalidServers(actualServers []string) {
+	r.serverMapByID.Range(func(key interface{}, value interface{}) bool {
+		k := key.(string)
+		if !util.SliceContainsString(actualServers, k) {
+			logger.Sugar.Warnf("deleting invalid local server %s", k)
+			r.deleteServer(k)
+		}
+		return true
+	})
+}
+
+// syncServers 以完整的服务列表覆盖本地列表,当前服务始终保留
+//
+//	@receiver r
+//	@param servers
+func (r *serverRegistry) syncServers(servers []*Server) {
+	allIds := []string{r.server.ID}
+	for _, sv := range servers {
+		if sv.ID == r.server.ID {
+			continue
+		}
+		allIds = append(allIds, sv.ID)
+		if old, ok := r.serverMapByID.Load(sv.ID); ok && serverEqual(old.(*Server), sv) {
+			continue
+		}
+		r.addServer(sv)
+	}
+	r.deleteLocalInvalidServers(allIds)
+}
+
+// GetServersByType returns a slice with all the servers of a certain type
+func (r *serverRegistry) GetServersByType(serverType string) (map[string]*Server, error) {
+	r.mapByTypeLock.RLock()
+	defer r.mapByTypeLock.RUnlock()
+	if m, ok := r.serverMapByType[serverType]; ok && len(m) > 0 {
+		// Create a new map to avoid concurrent read and write access to the
+		// map, this also prevents accidental changes to the list of servers
+		// kept by the service discovery.
+		ret := make(map[string]*Server, len(r.serverMapByType[serverType]))
+		for k, v := range r.serverMapByType[serverType] {
+			ret[k] = v
+		}
+		return ret, nil
+	}
+	return nil, constants.ErrNoServersAvailableOfType
+}
+
+func (r *serverRegistry) GetConsistentHashNode(serverType string, sessionID string) (string, error) {
+	r.hashLock.RLock()
+	ring := r.hashRings[serverType]
+	r.hashLock.RUnlock()
+	if ring == nil {
+		return "", pkgerrors.WithStack(fmt.Errorf("%w server=%s,sid=%s", constants.ErrServerNotFound, serverType, sessionID))
+	}
+	node, ok := ring.get(sessionID)
+	if !ok {
+		return "", pkgerrors.WithStack(fmt.Errorf("%w server=%s,sid=%s", constants.ErrServerNotFound, serverType, sessionID))
+	}
+	return node, nil
+}
+
+// GetRingNode
+//
+//	@implement ConsistentHashRing.GetRingNode
+func (r *serverRegistry) GetRingNode(serverType string, key string) (string, error) {
+	r.hashLock.RLock()
+	ring := r.hashRings[serverType]
+	r.hashLock.RUnlock()
+	if ring == nil {
+		return "", pkgerrors.WithStack(fmt.Errorf("%w server=%s,key=%s", constants.ErrServerNotFound, serverType, key))
+	}
+	node, ok := ring.locate(key)
+	if !ok {
+		return "", pkgerrors.WithStack(fmt.Errorf("%w server=%s,key=%s", constants.ErrServerNotFound, serverType, key))
+	}
+	return node, nil
+}
+
+// GetRingNodeMatch
+//
+//	@implement ConsistentHashRing.GetRingNodeMatch
+func (r *serverRegistry) GetRingNodeMatch(serverType string, key string, match func(serverID string) bool) (string, error) {
+	r.hashLock.RLock()
+	ring := r.hashRings[serverType]
+	r.hashLock.RUnlock()
+	if ring == nil {
+		return "", pkgerrors.WithStack(fmt.Errorf("%w server=%s,key=%s", constants.ErrServerNotFound, serverType, key))
+	}
+	node, ok := ring.next(key, match)
+	if !ok {
+		return "", pkgerrors.WithStack(fmt.Errorf("%w server=%s,key=%s", constants.ErrServerNotFound, serverType, key))
+	}
+	return node, nil
+}
+
+// GetServers returns a slice with all the servers
+func (r *serverRegistry) GetServers() []*Server {
+	ret := make([]*Server, 0)
+	r.serverMapByID.Range(func(k, v interface{}) bool {
+		ret = append(ret, v.(*Server))
+		return true
+	})
+	return ret
+}
+
+// GetAnyFrontend 获取任意一个可用的frontend
+func (r *serverRegistry) GetAnyFrontend() (*Server, error) {
+	var frontend *Server
+	r.serverMapByID.Range(func(k, v interface{}) bool {
+		sv := v.(*Server)
+		if sv.Frontend && sv.Available() {
+			frontend = sv
+			return false
+		}
+		return true
+	})
+	if frontend == nil {
+		return nil, errors.New("not found any frontend")
+	}
+	return frontend, nil
+}
+
+// GetServerTypes
+//
+//	@implement ServiceDiscovery.GetServerTypes
+func (r *serverRegistry) GetServerTypes() map[string]*Server {
+	r.mapByTypeLock.RLock()
+	defer r.mapByTypeLock.RUnlock()
+	ret := make(map[string]*Server, len(r.serverMapByType))
+	for t, m := range r.serverMapByType {
+		for _, server := range m {
+			ret[t] = server
+			break
+		}
+	}
+	return ret
+}
+
+// GetServer returns a server given it's id
+func (r *serverRegistry) GetServer(id string) (*Server, error) {
+	if sv, ok := r.serverMapByID.Load(id); ok {
+		return sv.(*Server), nil
+	}
+	return nil, pkgerrors.WithStack(fmt.Errorf("%w:%s", constants.ErrNoServerWithID, id))
+}
+
+// minServerIDOfType 同类型服务中ID最小的服务,用于没有选举机制时确定主节点
+func (r *serverRegistry) minServerIDOfType(serverType string) string {
+	r.mapByTypeLock.RLock()
+	defer r.mapByTypeLock.RUnlock()
+	ids := make([]string, 0, len(r.serverMapByType[serverType]))
+	for id := range r.serverMapByType[serverType] {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	sort.Strings(ids)
+	return ids[0]
+}
+
+// serverEqual 判断服务信息是否一致,用于同步时跳过未变化的服务
+func serverEqual(a, b *Server) bool {
+	if a.ID != b.ID || a.Type != b.Type || a.Frontend != b.Frontend || a.Hostname != b.Hostname || a.SessionStickiness != b.SessionStickiness || a.Status != b.Status || a.APIVersion != b.APIVersion {
+		return false
+	}
+	if len(a.Metadata) != len(b.Metadata) || len(a.RouteVersions) != len(b.RouteVersions) {
+		return false
+	}
+	for k, v := range a.Metadata {
+		if bv, ok := b.Metadata[k]; !ok || bv != v {
+			return false
+		}
+	}
+	for k, v := range a.RouteVersions {
+		if bv, ok := b.RouteVersions[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
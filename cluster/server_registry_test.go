@@ -0,0 +1,208 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type statusSDListener struct {
+	modified []*Server
+}
+
+func (l *statusSDListener) AddServer(sv *Server)    {}
+func (l *statusSDListener) RemoveServer(sv *Server) {}
+func (l *statusSDListener) ModifyServer(sv, old *Server) {
+	l.modified = append(l.modified, sv)
+}
+
+func TestServerRegistryStatus(t *testing.T) {
+	r := newServerRegistry(NewServer("frontend-1", "connector", true))
+	l := &statusSDListener{}
+	r.AddListener(l)
+
+	starting := NewServer("backend-1", "game", false)
+	r.addServer(starting)
+	_, err := r.GetConsistentHashNode("game", "uid")
+	assert.Error(t, err)
+
+	ready := *starting
+	ready.Status = ServerStatusReady
+	r.addServer(&ready)
+	node, err := r.GetConsistentHashNode("game", "uid")
+	require.NoError(t, err)
+	assert.Equal(t, "backend-1", node)
+
+	draining := ready
+	draining.Status = ServerStatusDraining
+	r.addServer(&draining)
+	_, err = r.GetConsistentHashNode("game", "uid")
+	assert.Error(t, err)
+	svs, err := r.GetServersByType("game")
+	require.NoError(t, err)
+	assert.Equal(t, ServerStatusDraining, svs["backend-1"].Status)
+
+	require.Len(t, l.modified, 2)
+	assert.Equal(t, ServerStatusReady, l.modified[0].Status)
+	assert.Equal(t, ServerStatusDraining, l.modified[1].Status)
+}
+
+func TestServerRegistryGetAnyFrontendSkipsUnavailable(t *testing.T) {
+	r := newServerRegistry(NewServer("backend-1", "game", false))
+	unhealthy := NewServer("frontend-1", "connector", true)
+	unhealthy.Status = ServerStatusUnhealthy
+	r.addServer(unhealthy)
+	_, err := r.GetAnyFrontend()
+	assert.Error(t, err)
+
+	r.addServer(&Server{ID: "frontend-2", Type: "connector", Frontend: true})
+	sv, err := r.GetAnyFrontend()
+	require.NoError(t, err)
+	assert.Equal(t, "frontend-2", sv.ID)
+}
+
+type recordingElectionListener struct {
+	events []string
+	ctx    context.Context
+}
+
+func (l *recordingElectionListener) AddServer(sv *Server) { l.events = append(l.events, "add:"+sv.ID) }
+func (l *recordingElectionListener) RemoveServer(sv *Server) {
+	l.events = append(l.events, "del:"+sv.ID)
+}
+func (l *recordingElectionListener) ModifyServer(sv, old *Server) {}
+func (l *recordingElectionListener) OnRevoked()                   { l.events = append(l.events, "revoked") }
+func (l *recordingElectionListener) OnElected(ctx context.Context) {
+	l.ctx = ctx
+	l.events = append(l.events, "elected")
+}
+func (l *recordingElectionListener) OnLeaderChanged(leaderID string) {
+	l.events = append(l.events, "leader:"+leaderID)
+}
+
+func TestServerRegistryElectionListener(t *testing.T) {
+	r := newServerRegistry(NewServer("game-2", "game", false))
+	l := &recordingElectionListener{}
+	r.AddListener(l)
+	r.AddElectionListener(l)
+
+	r.reportLeader("game-2")
+	require.NotNil(t, l.ctx)
+	ctx := l.ctx
+
+	// 主节点尚未加入本地列表时,等 AddServer 之后再通知
+	r.reportLeader("game-1")
+	assert.NoError(t, ctx.Err())
+	r.addServer(NewServer("game-1", "game", false))
+	assert.Error(t, ctx.Err())
+
+	// 主节点服务下线时通知暂无主节点,重新加入后恢复
+	r.deleteServer("game-1")
+	r.addServer(NewServer("game-1", "game", false))
+
+	r.reportLeader("")
+	assert.Equal(t, []string{"leader:game-2", "elected", "add:game-1", "revoked", "leader:game-1",
+		"del:game-1", "leader:", "add:game-1", "leader:game-1", "leader:"}, l.events)
+}
+
+// reentrantListener 在回调中再次修改服务列表
+type reentrantListener struct {
+	recordingElectionListener
+	r *serverRegistry
+}
+
+func (l *reentrantListener) AddServer(sv *Server) {
+	l.recordingElectionListener.AddServer(sv)
+	if sv.ID == "game-1" {
+		l.r.addServer(NewServer("game-3", "game", false))
+	}
+}
+
+func (l *reentrantListener) OnLeaderChanged(leaderID string) {
+	l.recordingElectionListener.OnLeaderChanged(leaderID)
+	if leaderID == "game-1" {
+		l.r.deleteServer("game-1")
+	}
+}
+
+func TestServerRegistryReentrantListener(t *testing.T) {
+	r := newServerRegistry(NewServer("game-2", "game", false))
+	l := &reentrantListener{r: r}
+	r.AddListener(l)
+	r.AddElectionListener(l)
+
+	r.reportLeader("game-1")
+	r.addServer(NewServer("game-1", "game", false))
+	assert.Equal(t, []string{"add:game-1", "leader:game-1", "add:game-3", "del:game-1", "leader:"}, l.events)
+	_, err := r.GetServer("game-3")
+	assert.NoError(t, err)
+}
+
+// countingSDListener 统计每个服务的通知次数
+type countingSDListener struct {
+	mu       sync.Mutex
+	added    map[string]int
+	modified map[string]int
+}
+
+func (l *countingSDListener) AddServer(sv *Server) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.added[sv.ID]++
+}
+func (l *countingSDListener) RemoveServer(sv *Server) {}
+func (l *countingSDListener) ModifyServer(sv, old *Server) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.modified[sv.ID]++
+}
+
+func TestServerRegistryConcurrentAdd(t *testing.T) {
+	r := newServerRegistry(NewServer("connector-1", "connector", true))
+	l := &countingSDListener{added: map[string]int{}, modified: map[string]int{}}
+	r.AddListener(l)
+
+	// 同步和 FlushServer2Cluster 并发写入同一服务时只通知一次 ADD,其余为 Modify
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sv := NewServer("game-1", "game", false)
+			sv.Status = ServerStatusReady
+			r.addServer(sv)
+		}()
+	}
+	wg.Wait()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	assert.Equal(t, 1, l.added["game-1"])
+	assert.Equal(t, n-1, l.modified["game-1"])
+	node, err := r.GetConsistentHashNode("game", "uid")
+	require.NoError(t, err)
+	assert.Equal(t, "game-1", node)
+}
+
+func TestServerRegistryExcludesIncompatibleFromHashRing(t *testing.T) {
+	self := NewServer("connector-1", "connector", true)
+	self.APIVersion = VersionRange{Min: 2, Max: 3}
+	r := newServerRegistry(self)
+
+	old := NewServer("game-1", "game", false)
+	old.Status = ServerStatusReady
+	old.APIVersion = VersionRange{Min: 1, Max: 1}
+	r.addServer(old)
+	_, err := r.GetConsistentHashNode("game", "uid")
+	assert.Error(t, err)
+
+	upgraded := *old
+	upgraded.APIVersion = VersionRange{Min: 1, Max: 2}
+	r.addServer(&upgraded)
+	node, err := r.GetConsistentHashNode("game", "uid")
+	require.NoError(t, err)
+	assert.Equal(t, "game-1", node)
+}
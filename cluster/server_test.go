@@ -25,6 +25,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya/v2/route"
 )
 
 var svTestTables = []struct {
@@ -63,3 +64,38 @@ func TestAsJSONString(t *testing.T) {
 		})
 	}
 }
+
+func TestVersionRangeCompatible(t *testing.T) {
+	tables := []struct {
+		name string
+		a, b VersionRange
+		want bool
+	}{
+		{"undeclared", VersionRange{}, VersionRange{Min: 5, Max: 6}, true},
+		{"overlap", VersionRange{Min: 1, Max: 2}, VersionRange{Min: 2, Max: 3}, true},
+		{"disjoint", VersionRange{Min: 1, Max: 1}, VersionRange{Min: 2, Max: 3}, false},
+		{"open max", VersionRange{Min: 2}, VersionRange{Min: 5, Max: 6}, true},
+		{"open max below min", VersionRange{Min: 7}, VersionRange{Min: 5, Max: 6}, false},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			assert.Equal(t, table.want, table.a.Compatible(table.b))
+			assert.Equal(t, table.want, table.b.Compatible(table.a))
+		})
+	}
+}
+
+func TestServerCompatibleWithRoute(t *testing.T) {
+	caller := NewServer("connector-1", "connector", true)
+	caller.APIVersion = VersionRange{Min: 2, Max: 2}
+	target := NewServer("game-1", "game", false)
+	target.APIVersion = VersionRange{Min: 1, Max: 1}
+	target.SetRouteVersion("room", VersionRange{Min: 1, Max: 2})
+	target.SetRouteVersion("room.join", VersionRange{Min: 1, Max: 1})
+	target.SetRouteVersion("room.leave", VersionRange{})
+
+	assert.False(t, caller.CompatibleWith(target, nil))
+	assert.False(t, caller.CompatibleWith(target, route.NewRoute("game", "lobby", "enter")))
+	assert.True(t, caller.CompatibleWith(target, route.NewRoute("game", "room", "leave")))
+	assert.False(t, caller.CompatibleWith(target, route.NewRoute("game", "room", "join")))
+}
@@ -0,0 +1,197 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// staticServer 服务列表文件中的单个服务
+type staticServer struct {
+	ID                string            `json:"id" yaml:"id"`
+	Type              string            `json:"type" yaml:"type"`
+	Metadata          map[string]string `json:"metadata" yaml:"metadata"`
+	Frontend          bool              `json:"frontend" yaml:"frontend"`
+	Hostname          string            `json:"hostname" yaml:"hostname"`
+	SessionStickiness bool              `json:"stickiness" yaml:"stickiness"`
+	Status            ServerStatus      `json:"status" yaml:"status"`
+	APIVersion        VersionRange      `json:"apiVersion" yaml:"apiVersion"`
+}
+
+// staticServerFile 服务列表文件格式
+//
+//	servers:
+//	  - id: connector-1
+//	    type: connector
+//	    frontend: true
+type staticServerFile struct {
+	Servers []*staticServer `json:"servers" yaml:"servers"`
+}
+
+// staticServiceDiscovery 基于静态文件的服务发现,适用于本地开发、CI以及拓扑固定的小规模部署
+//
+//	文件变更后按文件内容增删改服务,当前服务始终存在;没有选举机制,同类型中ID最小的服务为主节点
+type staticServiceDiscovery struct {
+	*serverRegistry
+	file          string
+	watchInterval time.Duration
+	lastModTime   time.Time
+	stopChan      chan struct{}
+}
+
+// NewStaticServiceDiscovery ctor
+func NewStaticServiceDiscovery(conf config.StaticServiceDiscoveryConfig, server *Server) (ServiceDiscovery, error) {
+	if conf.File == "" {
+		return nil, errors.New("static service discovery file is empty")
+	}
+	return &staticServiceDiscovery{
+		serverRegistry: newServerRegistry(server),
+		file:           conf.File,
+		watchInterval:  conf.WatchInterval,
+		stopChan:       make(chan struct{}),
+	}, nil
+}
+
+// Init 加载服务列表并开始监听文件变更
+//
+//	@receiver sd
+//	@return error
+func (sd *staticServiceDiscovery) Init() error {
+	sd.addServer(sd.GetSelfServer())
+	if err := sd.SyncServers(true); err != nil {
+		return err
+	}
+	if sd.watchInterval > 0 {
+		co.Go(func() { sd.watch() })
+	}
+	return nil
+}
+
+func (sd *staticServiceDiscovery) watch() {
+	ticker := time.NewTicker(sd.watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := sd.SyncServers(false); err != nil {
+				logger.Zap.Error("static service discovery sync error", zap.String("file", sd.file), zap.Error(err))
+			}
+		case <-sd.stopChan:
+			return
+		}
+	}
+}
+
+// SyncServers 重新读取服务列表文件,firstSync为false时文件未修改则跳过
+//
+//	@receiver sd
+//	@param firstSync
+//	@return error
+func (sd *staticServiceDiscovery) SyncServers(firstSync bool) error {
+	info, err := os.Stat(sd.file)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !firstSync && info.ModTime().Equal(sd.lastModTime) {
+		return nil
+	}
+	servers, err := loadStaticServers(sd.file)
+	if err != nil {
+		return err
+	}
+	sd.lastModTime = info.ModTime()
+	sd.syncServers(servers)
+	sd.reportLeader(sd.LeaderID())
+	return nil
+}
+
+// loadStaticServers 按扩展名解析服务列表文件
+//
+//	@param file
+//	@return []*Server
+//	@return error
+func loadStaticServers(file string) ([]*Server, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var f staticServerFile
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		err = json.Unmarshal(data, &f)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &f)
+	default:
+		return nil, errors.WithStack(fmt.Errorf("unsupported static service discovery file %s", file))
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	servers := make([]*Server, 0, len(f.Servers))
+	for _, s := range f.Servers {
+		if s.ID == "" || s.Type == "" {
+			return nil, errors.WithStack(fmt.Errorf("invalid server in %s, id and type are required", file))
+		}
+		if s.Metadata == nil {
+			s.Metadata = map[string]string{}
+		}
+		servers = append(servers, &Server{
+			ID:                s.ID,
+			Type:              s.Type,
+			Metadata:          s.Metadata,
+			Frontend:          s.Frontend,
+			Hostname:          s.Hostname,
+			SessionStickiness: s.SessionStickiness,
+			Status:            s.Status,
+			APIVersion:        s.APIVersion,
+		})
+	}
+	return servers, nil
+}
+
+// FlushServer2Cluster 静态服务列表无法写回,仅更新本地数据
+//
+//	@receiver sd
+//	@param server
+//	@return error
+func (sd *staticServiceDiscovery) FlushServer2Cluster(server *Server) error {
+	sd.setSelfServer(server)
+	sd.addServer(server)
+	return nil
+}
+
+// LeaderID 同类型中ID最小的服务
+//
+//	@receiver sd
+//	@return string
+func (sd *staticServiceDiscovery) LeaderID() string {
+	return sd.minServerIDOfType(sd.server.Type)
+}
+
+func (sd *staticServiceDiscovery) IsLeader() bool {
+	return sd.LeaderID() == sd.server.ID
+}
+
+// AfterInit executes after Init
+func (sd *staticServiceDiscovery) AfterInit() {
+}
+
+// BeforeShutdown executes before shutting down
+func (sd *staticServiceDiscovery) BeforeShutdown() {
+}
+
+// Shutdown stops watching the file
+func (sd *staticServiceDiscovery) Shutdown() error {
+	close(sd.stopChan)
+	return nil
+}
@@ -0,0 +1,126 @@
+package cluster
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/helpers"
+)
+
+func writeStaticServers(t *testing.T, file, content string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	// 保证每次改写的修改时间不同
+	require.NoError(t, os.Chtimes(file, modTime, modTime))
+}
+
+func TestLoadStaticServers(t *testing.T) {
+	dir := t.TempDir()
+	tables := []struct {
+		name    string
+		file    string
+		content string
+		ids     []string
+		err     bool
+	}{
+		{"Yaml", "servers.yaml", "servers:\n  - id: connector-1\n    type: connector\n    frontend: true\n  - id: game-1\n    type: game\n    metadata:\n      region: cn\n", []string{"connector-1", "game-1"}, false},
+		{"Yml", "servers.yml", "servers:\n  - id: game-1\n    type: game\n", []string{"game-1"}, false},
+		{"Json", "servers.json", `{"servers":[{"id":"game-1","type":"game","stickiness":true}]}`, []string{"game-1"}, false},
+		{"Empty", "empty.yaml", "", []string{}, false},
+		{"MissingType", "invalid.yaml", "servers:\n  - id: game-1\n", nil, true},
+		{"Malformed", "malformed.json", `{"servers":`, nil, true},
+		{"Unsupported", "servers.toml", "", nil, true},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			file := filepath.Join(dir, table.file)
+			require.NoError(t, os.WriteFile(file, []byte(table.content), 0o644))
+			servers, err := loadStaticServers(file)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			ids := []string{}
+			for _, sv := range servers {
+				ids = append(ids, sv.ID)
+				assert.NotNil(t, sv.Metadata)
+			}
+			assert.Equal(t, table.ids, ids)
+		})
+	}
+
+	servers, err := loadStaticServers(filepath.Join(dir, "servers.yaml"))
+	require.NoError(t, err)
+	assert.True(t, servers[0].Frontend)
+	assert.Equal(t, "cn", servers[1].Metadata["region"])
+	_, err = loadStaticServers(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestStaticSDReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "servers.yaml")
+	now := time.Now()
+	writeStaticServers(t, file, "servers:\n  - id: game-2\n    type: game\n  - id: connector-1\n    type: connector\n    frontend: true\n", now)
+
+	sd, err := NewStaticServiceDiscovery(config.StaticServiceDiscoveryConfig{File: file, WatchInterval: 20 * time.Millisecond}, NewServer("game-3", "game", false))
+	require.NoError(t, err)
+	l := newRecordSDListener()
+	sd.AddListener(l)
+	require.NoError(t, sd.Init())
+	defer sd.Shutdown()
+
+	assert.Len(t, sd.GetServers(), 3)
+	assert.Equal(t, "game-2", sd.LeaderID())
+	assert.False(t, sd.IsLeader())
+	for i := 0; i < 2; i++ {
+		helpers.ShouldEventuallyReceive(t, l.added)
+	}
+
+	// 改写文件:删除game-2,新增game-1
+	writeStaticServers(t, file, "servers:\n  - id: game-1\n    type: game\n  - id: connector-1\n    type: connector\n    frontend: true\n", now.Add(time.Second))
+	assert.Equal(t, "game-1", helpers.ShouldEventuallyReceive(t, l.added).(*Server).ID)
+	assert.Equal(t, "game-2", helpers.ShouldEventuallyReceive(t, l.removed).(*Server).ID)
+	_, err = sd.GetServer("game-2")
+	assert.Error(t, err)
+	assert.Equal(t, "game-1", sd.LeaderID())
+	// 当前服务不在文件中也始终存在
+	_, err = sd.GetServer("game-3")
+	assert.NoError(t, err)
+
+	// 改写为非法内容时保留原列表
+	writeStaticServers(t, file, "servers:\n  - id: game-4\n", now.Add(2*time.Second))
+	helpers.ShouldAlwaysReturn(t, func() int { return len(sd.GetServers()) }, 3, 10*time.Millisecond, 100*time.Millisecond)
+}
+
+func TestNewStaticServiceDiscoveryRequiresFile(t *testing.T) {
+	_, err := NewStaticServiceDiscovery(config.StaticServiceDiscoveryConfig{}, NewServer("game-1", "game", false))
+	assert.Error(t, err)
+}
+
+func TestStaticSDFlushSelfServer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "servers.yaml")
+	writeStaticServers(t, file, "servers:\n  - id: connector-1\n    type: connector\n    frontend: true\n", time.Now())
+
+	self := NewServer("game-3", "game", false)
+	sd, err := NewStaticServiceDiscovery(config.StaticServiceDiscoveryConfig{File: file}, self)
+	require.NoError(t, err)
+	require.NoError(t, sd.Init())
+	defer sd.Shutdown()
+
+	// 状态变化以副本同步,原对象不被修改,重新加载文件后保留最新状态
+	draining := *self
+	draining.Status = ServerStatusDraining
+	require.NoError(t, sd.FlushServer2Cluster(&draining))
+	assert.Equal(t, ServerStatusStarting, self.Status)
+	assert.Equal(t, ServerStatusDraining, sd.GetSelfServer().Status)
+	require.NoError(t, sd.SyncServers(true))
+	sv, err := sd.GetServer("game-3")
+	require.NoError(t, err)
+	assert.Equal(t, ServerStatusDraining, sv.Status)
+}
@@ -4,7 +4,9 @@ package co
 import (
 	"context"
 
+	"github.com/alkaid/goerrors/errors"
 	"github.com/panjf2000/ants/v2"
+	"github.com/topfreegames/pitaya/v2/constants"
 	"github.com/topfreegames/pitaya/v2/logger"
 	"github.com/topfreegames/pitaya/v2/util"
 	"go.uber.org/zap"
@@ -23,7 +25,7 @@ func GoPersist[T int | int32 | int64](ctx context.Context, goID T, task func(ctx
 	for _, opt := range opts {
 		opt(o)
 	}
-	return instance.pools[PersistGoPoolName].Go(ctx, int(goID), task, o.disableTimeoutWatch)
+	return instance.lookup(PersistGoPoolName).Go(o.withContext(ctx), int(goID), task, o.disableTimeoutWatch)
 }
 
 // GoWithID 派发任务到指定线程 若不指定线程池 WithPoolName, 则使用默认线程池 DefaultGoPoolName
@@ -39,7 +41,7 @@ func GoWithID[T int | int32 | int64](ctx context.Context, goID T, task func(ctx
 	for _, opt := range opts {
 		opt(o)
 	}
-	return instance.pools[o.poolName].Go(ctx, int(goID), task, o.disableTimeoutWatch)
+	return instance.lookup(o.poolName).Go(o.withContext(ctx), int(goID), task, o.disableTimeoutWatch)
 }
 
 // WaitWithID 派发任务到指定线程并阻塞等待 若不指定线程池 WithPoolName, 则使用默认线程池 DefaultGoPoolName
@@ -53,10 +55,106 @@ func WaitWithID[T int | int32 | int64](ctx context.Context, goID T, task func(ct
 	for _, opt := range opts {
 		opt(o)
 	}
-	instance.pools[o.poolName].Wait(ctx, int(goID), task, o.disableTimeoutWatch)
+	instance.lookup(o.poolName).Wait(o.withContext(ctx), int(goID), task, o.disableTimeoutWatch)
 }
 
-// GoWithUser 派发到 UserGoPoolName 用户线程
+// TryGoWithID 同 GoWithID,队列已满等派发失败时返回error,可用于回复客户端服务器繁忙
+//
+//	@see StatefulPool.TryGo
+//	@param ctx
+//	@param goID
+//	@param task
+//	@param opts
+//	@return done
+//	@return error
+func TryGoWithID[T int | int32 | int64](ctx context.Context, goID T, task func(ctx context.Context), opts ...Option) (done chan struct{}, err error) {
+	o := &options{poolName: DefaultGoPoolName}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return instance.lookup(o.poolName).TryGo(o.withContext(ctx), int(goID), task, o.disableTimeoutWatch)
+}
+
+// TryWaitWithID 同 WaitWithID,派发失败、任务被丢弃或ctx结束时返回error
+//
+//	@see StatefulPool.TryWait
+//	@param ctx
+//	@param goID
+//	@param task
+//	@param opts
+//	@return error
+func TryWaitWithID[T int | int32 | int64](ctx context.Context, goID T, task func(ctx context.Context), opts ...Option) error {
+	o := &options{poolName: DefaultGoPoolName}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return instance.lookup(o.poolName).TryWait(o.withContext(ctx), int(goID), task, o.disableTimeoutWatch)
+}
+
+// GoWithKey 按字符串key派发任务,同一key的任务按顺序执行,不同key之间不会冲突.
+// 若不指定线程池 WithPoolName, 则使用默认线程池 DefaultGoPoolName
+//
+//	@param ctx
+//	@param key 如房间UUID、公会编码,不能为空
+//	@param task
+//	@param opts
+//	@return done
+func GoWithKey(ctx context.Context, key string, task func(ctx context.Context), opts ...Option) (done chan struct{}) {
+	o := &options{poolName: DefaultGoPoolName}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return instance.lookup(o.poolName).GoWithKey(o.withContext(ctx), key, task, o.disableTimeoutWatch)
+}
+
+// WaitWithKey 按字符串key派发任务并阻塞等待 若不指定线程池 WithPoolName, 则使用默认线程池 DefaultGoPoolName
+//
+//	@param ctx
+//	@param key 不能为空
+//	@param task
+//	@param opts
+func WaitWithKey(ctx context.Context, key string, task func(ctx context.Context), opts ...Option) {
+	o := &options{poolName: DefaultGoPoolName}
+	for _, opt := range opts {
+		opt(o)
+	}
+	instance.lookup(o.poolName).WaitWithKey(o.withContext(ctx), key, task, o.disableTimeoutWatch)
+}
+
+// TryGoWithKey 同 GoWithKey,派发失败时返回error
+//
+//	@see StatefulPool.TryGoWithKey
+//	@param ctx
+//	@param key 不能为空
+//	@param task
+//	@param opts
+//	@return done
+//	@return error
+func TryGoWithKey(ctx context.Context, key string, task func(ctx context.Context), opts ...Option) (done chan struct{}, err error) {
+	o := &options{poolName: DefaultGoPoolName}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return instance.lookup(o.poolName).TryGoWithKey(o.withContext(ctx), key, task, o.disableTimeoutWatch)
+}
+
+// TryWaitWithKey 同 WaitWithKey,派发失败、任务被丢弃或ctx结束时返回error
+//
+//	@see StatefulPool.TryWaitWithKey
+//	@param ctx
+//	@param key 不能为空
+//	@param task
+//	@param opts
+//	@return error
+func TryWaitWithKey(ctx context.Context, key string, task func(ctx context.Context), opts ...Option) error {
+	o := &options{poolName: DefaultGoPoolName}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return instance.lookup(o.poolName).TryWaitWithKey(o.withContext(ctx), key, task, o.disableTimeoutWatch)
+}
+
+// GoWithUser 派发到 UserGoPoolName 用户线程,uid非法时返回的done已关闭
 //
 //	@param ctx
 //	@param uid
@@ -64,9 +162,38 @@ func WaitWithID[T int | int32 | int64](ctx context.Context, goID T, task func(ct
 func GoWithUser(ctx context.Context, uid int64, task func(ctx context.Context)) (done chan struct{}) {
 	if uid <= 0 {
 		util.GetLoggerFromCtx(ctx).Error("uid invalid", zap.Int64("uid", uid))
-		return
+		done = make(chan struct{})
+		close(done)
+		return done
+	}
+	return instance.lookup(UserGoPoolName).Go(ctx, int(uid), task, false)
+}
+
+// TryGoWithUser 同 GoWithUser,派发失败时返回error
+//
+//	@param ctx
+//	@param uid
+//	@param task
+//	@return done
+//	@return error
+func TryGoWithUser(ctx context.Context, uid int64, task func(ctx context.Context)) (done chan struct{}, err error) {
+	if uid <= 0 {
+		return nil, errors.WithStack(constants.ErrIllegalUID)
+	}
+	return instance.lookup(UserGoPoolName).TryGo(ctx, int(uid), task, false)
+}
+
+// TryWaitWithUser 同 WaitWithUser,派发失败、任务被丢弃或ctx结束时返回error
+//
+//	@param ctx
+//	@param uid
+//	@param task
+//	@return error
+func TryWaitWithUser(ctx context.Context, uid int64, task func(ctx context.Context)) error {
+	if uid <= 0 {
+		return errors.WithStack(constants.ErrIllegalUID)
 	}
-	return instance.pools[UserGoPoolName].Go(ctx, int(uid), task, false)
+	return instance.lookup(UserGoPoolName).TryWait(ctx, int(uid), task, false)
 }
 
 // WaitWithUser 派发到 UserGoPoolName 用户线程并阻塞等待
@@ -79,7 +206,7 @@ func WaitWithUser(ctx context.Context, uid int64, task func(ctx context.Context)
 		util.GetLoggerFromCtx(ctx).Error("uid invalid", zap.Int64("uid", uid))
 		return
 	}
-	instance.pools[UserGoPoolName].Wait(ctx, int(uid), task, false)
+	instance.lookup(UserGoPoolName).Wait(ctx, int(uid), task, false)
 }
 
 // GoMain 派发到 PersistGoPoolName 常驻线程池的主线程,线程id为 MainThreadID
@@ -92,7 +219,7 @@ func GoMain(ctx context.Context, task func(ctx context.Context), opts ...Option)
 	for _, opt := range opts {
 		opt(o)
 	}
-	return instance.pools[PersistGoPoolName].Go(ctx, MainThreadID, task, o.disableTimeoutWatch)
+	return instance.lookup(PersistGoPoolName).Go(o.withContext(ctx), MainThreadID, task, o.disableTimeoutWatch)
 }
 
 // WaitMain 派发到 PersistGoPoolName 常驻线程池的主线程并阻塞等待,线程id为 MainThreadID
@@ -105,14 +232,14 @@ func WaitMain(ctx context.Context, task func(ctx context.Context), opts ...Optio
 	for _, opt := range opts {
 		opt(o)
 	}
-	instance.pools[PersistGoPoolName].Wait(ctx, MainThreadID, task, o.disableTimeoutWatch)
+	instance.lookup(PersistGoPoolName).Wait(o.withContext(ctx), MainThreadID, task, o.disableTimeoutWatch)
 }
 
-// Go 从无状态线程池获取一个goroutine并派发任务
+// Go 从无状态线程池获取一个goroutine并派发任务,panic时按 SetPanicPolicy 设置的策略处理
 //
 //	@param task
 func Go(task func()) {
-	err := ants.Submit(task)
+	err := ants.Submit(func() { goSafe(task) })
 	if err != nil {
 		logger.Zap.Error("submit task error", zap.Error(err))
 		return
@@ -1,7 +1,11 @@
 package co
 
 import (
+	"context"
 	"math"
+	"reflect"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/topfreegames/pitaya/v2/config"
@@ -11,7 +15,10 @@ import (
 	"go.uber.org/zap"
 )
 
-var _ interfaces.Module = (*StatefulPoolsModule)(nil)
+var (
+	_ interfaces.Module = (*StatefulPoolsModule)(nil)
+	_ config.ConfLoader = (*StatefulPoolsModule)(nil)
+)
 
 const (
 	SessionGoPoolName = "session"   // session线程池,仅框架内部使用
@@ -25,10 +32,15 @@ var builtinPool = []string{
 	SessionGoPoolName, UserGoPoolName, DefaultGoPoolName, PersistGoPoolName,
 }
 
+// StatefulPoolsModule 有状态线程池模块
+//
+//	实现了 config.ConfLoader,注册到 config.Config 后 pitaya.gopools 变化时无需重启即可生效
 type StatefulPoolsModule struct {
 	reporters []metrics.Reporter
+	mu        sync.RWMutex // 保护 pools cfgMap, Reload 时写入
 	pools     map[string]*StatefulPool
 	cfgMap    map[string]config.GoPool
+	inited    bool
 }
 
 var instance *StatefulPoolsModule
@@ -37,28 +49,33 @@ func NewStatefulPoolsModule(poolsCfg map[string]config.GoPool, reporters []metri
 	module := StatefulPoolsModule{
 		reporters: reporters,
 		pools:     map[string]*StatefulPool{},
-		cfgMap:    poolsCfg,
+		cfgMap:    normalizePoolsConfig(poolsCfg),
 	}
-	for name, pool := range module.cfgMap {
+	instance = &module
+	return &module
+}
+
+// normalizePoolsConfig 填充线程池名并加上没有配置的内建线程池
+func normalizePoolsConfig(poolsCfg map[string]config.GoPool) map[string]config.GoPool {
+	cfgMap := make(map[string]config.GoPool, len(poolsCfg)+len(builtinPool))
+	for name, pool := range poolsCfg {
 		pool.Name = name
-		module.cfgMap[name] = pool
-	}
-	if module.cfgMap == nil {
-		module.cfgMap = map[string]config.GoPool{}
+		cfgMap[name] = pool
 	}
 	// 若没有配置内建线程池,要加上
 	for _, name := range builtinPool {
-		_, ok := module.cfgMap[name]
+		_, ok := cfgMap[name]
 		if !ok {
-			module.cfgMap[name] = config.GoPool{Name: name}
+			cfgMap[name] = config.GoPool{Name: name}
 		}
 	}
-	module.cfgMap[PersistGoPoolName] = config.GoPool{Name: PersistGoPoolName, DisablePurgeRunning: true, Expire: time.Hour}
-	instance = &module
-	return &module
+	cfgMap[PersistGoPoolName] = config.GoPool{Name: PersistGoPoolName, DisablePurgeRunning: true, Expire: time.Hour}
+	return cfgMap
 }
 
 func (p *StatefulPoolsModule) Init() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	for _, cfg := range p.cfgMap {
 		pool, err := NewStatefulPool(cfg, p.reporters)
 		if err != nil {
@@ -67,9 +84,117 @@ func (p *StatefulPoolsModule) Init() error {
 		p.pools[cfg.Name] = pool
 		logger.Zap.Info("stateful pool init success", zap.String("name", cfg.Name))
 	}
+	p.inited = true
 	return nil
 }
 
+// lookup 按名称获取线程池,不存在时返回的线程池拒绝所有任务
+func (p *StatefulPoolsModule) lookup(name string) *StatefulPool {
+	p.mu.RLock()
+	pool := p.pools[name]
+	p.mu.RUnlock()
+	if pool == nil {
+		return newMissingPool(name)
+	}
+	return pool
+}
+
+// Provide
+//
+//	@implement config.ConfLoader.Provide
+//	@receiver p
+//	@return key
+//	@return confStruct
+func (p *StatefulPoolsModule) Provide() (key string, confStruct interface{}) {
+	return "pitaya.gopools", &map[string]config.GoPool{}
+}
+
+// Reload 应用新的线程池配置
+//
+//	已有的线程池调用 StatefulPool.Reconfigure,新增的线程池立即创建,
+//	移除的线程池不再接收新任务,排队中的任务执行完后释放.内建线程池不会被移除
+//	@implement config.ConfLoader.Reload
+//	@receiver p
+//	@param key
+//	@param confStruct
+func (p *StatefulPoolsModule) Reload(key string, confStruct interface{}) {
+	cfgMap := normalizePoolsConfig(*confStruct.(*map[string]config.GoPool))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	old := p.cfgMap
+	p.cfgMap = cfgMap
+	if !p.inited {
+		return
+	}
+	for name, cfg := range cfgMap {
+		pool, ok := p.pools[name]
+		if !ok {
+			pool, err := NewStatefulPool(cfg, p.reporters)
+			if err != nil {
+				logger.Zap.Error("stateful pool create error", zap.String("name", name), zap.Error(err))
+				continue
+			}
+			p.pools[name] = pool
+			logger.Zap.Info("stateful pool created", zap.String("name", name))
+			continue
+		}
+		if reflect.DeepEqual(old[name], cfg) {
+			continue
+		}
+		if err := pool.Reconfigure(cfg); err != nil {
+			logger.Zap.Error("stateful pool reconfigure error", zap.String("name", name), zap.Error(err))
+			// 保留生效中的配置,下次重载时重试
+			cfgMap[name] = old[name]
+			continue
+		}
+		logger.Zap.Info("stateful pool reconfigured", zap.String("name", name), zap.Any("config", pool.Config()))
+	}
+	for name, pool := range p.pools {
+		if _, ok := cfgMap[name]; ok {
+			continue
+		}
+		delete(p.pools, name)
+		go func(name string, pool *StatefulPool) {
+			logger.Zap.Info("stateful pool removed, draining", zap.String("name", name), zap.Int64("queued", pool.queued.Load()))
+			_ = pool.Close(context.Background())
+			logger.Zap.Info("stateful pool drained", zap.String("name", name))
+		}(name, pool)
+	}
+}
+
+// Stats 所有线程池的运行状态
+//
+//	@receiver p
+//	@return []PoolStats 按名称排序
+func (p *StatefulPoolsModule) Stats() []PoolStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	stats := make([]PoolStats, 0, len(p.pools))
+	for _, pool := range p.pools {
+		stats = append(stats, pool.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// ReportQueueDepth 上报所有线程池的队列长度
+//
+//	@receiver p
+func (p *StatefulPoolsModule) ReportQueueDepth() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, pool := range p.pools {
+		pool.ReportQueueDepth()
+	}
+}
+
+// ReportQueueDepth 上报所有线程池的队列长度,线程池模块未创建时忽略
+func ReportQueueDepth() {
+	if instance != nil {
+		instance.ReportQueueDepth()
+	}
+}
+
 func (p *StatefulPoolsModule) AfterInit() {
 }
 
@@ -0,0 +1,45 @@
+package co
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+)
+
+func TestStatefulPoolsModuleReload(t *testing.T) {
+	m := NewStatefulPoolsModule(map[string]config.GoPool{
+		"room": {TaskBuffer: 1},
+	}, nil)
+	require.NoError(t, m.Init())
+
+	key, confStruct := m.Provide()
+	assert.Equal(t, "pitaya.gopools", key)
+	*confStruct.(*map[string]config.GoPool) = map[string]config.GoPool{
+		"guild": {TaskBuffer: 5},
+	}
+	room := m.lookup("room")
+	user := m.lookup(UserGoPoolName)
+	m.Reload(key, confStruct)
+
+	assert.NoError(t, TryWaitWithID(context.Background(), 1, func(ctx context.Context) {}, WithPoolName("guild")))
+	assert.Equal(t, 5, m.lookup("guild").Config().TaskBuffer)
+	// 移除的线程池排空后释放,之后的任务返回错误
+	_, err := TryGoWithID(context.Background(), 1, func(ctx context.Context) {}, WithPoolName("room"))
+	assert.ErrorIs(t, err, constants.ErrPoolNotFound)
+	assert.NoError(t, room.Close(context.Background()))
+	// 内建线程池不会被移除
+	assert.Same(t, user, m.lookup(UserGoPoolName))
+	assert.Equal(t, []string{"default", "guild", "persist", "session", "user"}, poolNames(m))
+}
+
+func poolNames(m *StatefulPoolsModule) []string {
+	names := make([]string, 0)
+	for _, s := range m.Stats() {
+		names = append(names, s.Name)
+	}
+	return names
+}
@@ -1,8 +1,12 @@
 package co
 
+import "context"
+
 type options struct {
 	poolName            string
 	disableTimeoutWatch bool
+	taskName            string
+	onDrop              func(err error)
 }
 
 type Option func(o *options)
@@ -17,3 +21,44 @@ func WithDisableTimeoutWatch(disable bool) Option {
 		o.disableTimeoutWatch = disable
 	}
 }
+
+// WithTaskName 任务名,用于超时日志、任务列表和耗时指标,同 ContextWithTaskName
+func WithTaskName(name string) Option {
+	return func(o *options) {
+		o.taskName = name
+	}
+}
+
+// WithOnDrop 任务未执行就结束时调用,每个任务最多调用一次
+//
+//	包括派发失败、被 OverflowDropOldest 丢弃、runner派发失败时丢弃排队中的任务,调用时任务的done尚未关闭;
+//	适用于按任务计数的调用方,在任务本身上撤销计数,不依赖调用方等待done
+//	@param fn err 为任务未执行的原因
+//	@return Option
+func WithOnDrop(fn func(err error)) Option {
+	return func(o *options) {
+		o.onDrop = fn
+	}
+}
+
+type dropHookKey struct{}
+
+// withContext 将选项中的任务名和丢弃回调写入ctx,丢弃回调在提交时取出,不会传递给任务内再提交的任务
+func (o *options) withContext(ctx context.Context) context.Context {
+	if o.taskName != "" {
+		ctx = ContextWithTaskName(ctx, o.taskName)
+	}
+	if o.onDrop != nil {
+		ctx = context.WithValue(ctx, dropHookKey{}, o.onDrop)
+	}
+	return ctx
+}
+
+// takeDropHook 取出 WithOnDrop 设置的回调,返回的ctx中不再包含
+func takeDropHook(ctx context.Context) (func(err error), context.Context) {
+	onDrop, _ := ctx.Value(dropHookKey{}).(func(err error))
+	if onDrop == nil {
+		return nil, ctx
+	}
+	return onDrop, context.WithValue(ctx, dropHookKey{}, nil)
+}
@@ -2,12 +2,17 @@ package co
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alkaid/goerrors/errors"
 	"github.com/panjf2000/ants/v2"
+	"github.com/topfreegames/pitaya/v2/clock"
 	"github.com/topfreegames/pitaya/v2/config"
 	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/logger"
 	"github.com/topfreegames/pitaya/v2/metrics"
 	"github.com/topfreegames/pitaya/v2/util"
 	"go.uber.org/zap"
@@ -22,15 +27,95 @@ const (
 var DefaultStatefulPoolTimeoutBuckets = []time.Duration{5 * time.Second, 10 * time.Second, 1 * time.Minute, 10 * time.Minute}
 
 // StatefulPool 带状态的线程池
+//
+//	每个goID的任务先进入 goQueue 排队,队列长度不超过 config.GoPool.TaskBuffer,满时按 config.GoPool.Overflow 处理.
+//	每个 lane 同时只有一个派发到ants的runner依次执行队列中的任务,因此重载配置时可以安全地切换ants线程池.
+//	runner空闲时等待新任务而不是退出,空闲超过 config.GoPool.Expire 才结束.
+//	未分片时每个goID一个lane,配置了 config.GoPool.Shards 时goID按哈希分配到固定数量的lane.
+//	字符串key的任务由线程池分配不重复的负数goID,与int goID混用时int goID须非负
 type StatefulPool struct {
-	pool      *ants.PoolWithID
-	config    config.GoPool
+	name      string
 	reporters []metrics.Reporter
+	options   []ants.Option // 创建ants线程池的额外参数,重载时沿用
+	shards    int           // 分片数,创建后不变
+	mu        sync.Mutex
+	config    config.GoPool    // 当前配置,重载时替换
+	pool      *antsPool        // 当前的ants线程池,新的runner派发到这里
+	queues    map[int]*goQueue // goID -> 排队中的任务
+	lanes     map[int]*lane    // laneID -> 有runner的lane
+	keys      map[string]int   // 字符串key -> 分配的goID
+	keyOf     map[int]string   // 分配的goID -> 字符串key
+	nextKeyID int              // 上一个分配给字符串key的goID
+	queued    atomic.Int64     // 所有goID排队中的任务数
+	reported  int              // 上次上报了队列长度的slot数
+	closed    error            // 非nil时拒绝新任务并返回该错误
+	drained   chan struct{}    // Close 后所有队列清空时关闭
+}
+
+// antsPool 一代ants线程池,重载修改了ants的参数时创建新的一代,旧的一代在其上的runner全部结束后释放
+type antsPool struct {
+	*ants.Pool
+	runners atomic.Int64
+	retired atomic.Bool
+	once    sync.Once
+}
+
+func (a *antsPool) acquire() {
+	a.runners.Add(1)
+}
+
+func (a *antsPool) release() {
+	if a.runners.Add(-1) == 0 && a.retired.Load() {
+		a.once.Do(a.Release)
+	}
+}
+
+// retire 不再派发新的runner,已有的runner结束后释放
+func (a *antsPool) retire() {
+	a.retired.Store(true)
+	if a.runners.Load() == 0 {
+		a.once.Do(a.Release)
+	}
 }
 
 func NewStatefulPool(config config.GoPool, reporters []metrics.Reporter, options ...ants.Option) (*StatefulPool, error) {
+	config, err := normalizePoolConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newAntsPool(config, options)
+	if err != nil {
+		return nil, err
+	}
+	return &StatefulPool{
+		name:      config.Name,
+		config:    config,
+		reporters: reporters,
+		options:   options,
+		shards:    config.Shards,
+		pool:      p,
+		queues:    map[int]*goQueue{},
+		lanes:     map[int]*lane{},
+		keys:      map[string]int{},
+		keyOf:     map[int]string{},
+	}, nil
+}
+
+// newMissingPool 未配置的线程池,提交的任务都返回 constants.ErrPoolNotFound
+func newMissingPool(name string) *StatefulPool {
+	return &StatefulPool{
+		name:   name,
+		queues: map[int]*goQueue{},
+		keys:   map[string]int{},
+		keyOf:  map[int]string{},
+		closed: constants.ErrPoolNotFound,
+	}
+}
+
+// normalizePoolConfig 校验配置并填充默认值
+func normalizePoolConfig(config config.GoPool) (config.GoPool, error) {
 	if config.Name == "" {
-		return nil, errors.New("stateful pool name cannot be nil")
+		return config, errors.New("stateful pool name cannot be nil")
 	}
 	if config.Expire == 0 {
 		config.Expire = DefaultStatefulPoolExpire
@@ -41,65 +126,280 @@ func NewStatefulPool(config config.GoPool, reporters []metrics.Reporter, options
 	if len(config.TimeoutBuckets) == 0 {
 		config.TimeoutBuckets = DefaultStatefulPoolTimeoutBuckets
 	}
-	options = append(options,
-		ants.WithTaskBuffer(config.TaskBuffer),
+	switch config.Overflow {
+	case "":
+		config.Overflow = OverflowBlock
+	case OverflowBlock, OverflowReject, OverflowDropOldest:
+	default:
+		return config, errors.NewWithStack(fmt.Sprintf("stateful pool %s unknown overflow policy %s", config.Name, config.Overflow))
+	}
+	if config.PanicPolicy != "" && checkPanicPolicy(config.PanicPolicy) != nil {
+		return config, errors.NewWithStack(fmt.Sprintf("stateful pool %s unknown panic policy %s", config.Name, config.PanicPolicy))
+	}
+	config.TimeoutBuckets = slices.Clone(config.TimeoutBuckets)
+	slices.Sort(config.TimeoutBuckets)
+	return config, nil
+}
+
+// newAntsPool 创建ants线程池,每个lane在ants中最多只有一个runner,同一goID的顺序由lane保证,因此不需要按ID派发,也不需要设置ants的队列长度
+func newAntsPool(config config.GoPool, options []ants.Option) (*antsPool, error) {
+	options = append(slices.Clip(options),
 		ants.WithExpiryDuration(config.Expire),
 		ants.WithDisablePurgeRunning(config.DisablePurgeRunning),
 		ants.WithDisablePurge(config.DisablePurge))
-	p, err := ants.NewPoolWithID(ants.DefaultAntsPoolSize, options...)
+	p, err := ants.NewPool(ants.DefaultAntsPoolSize, options...)
 	if err != nil {
 		return nil, err
 	}
-	slices.Sort(config.TimeoutBuckets)
-	return &StatefulPool{
-		config:    config,
-		reporters: reporters,
-		pool:      p,
-	}, nil
+	return &antsPool{Pool: p}, nil
+}
+
+// Reconfigure 应用新配置,无需重启
+//
+//	TaskBuffer Overflow BlockTimeout TimeoutBuckets 等对之后提交的任务生效,队列变长时唤醒阻塞中的提交者.
+//	Expire 等ants参数变化时创建新的ants线程池,正在执行的goID执行完队列中的任务后才切换到新线程池,同一goID的任务仍按顺序执行
+//	@receiver s
+//	@param cfg 名称固定为当前线程池名
+//	@return error 配置不合法时返回,不影响当前配置
+func (s *StatefulPool) Reconfigure(cfg config.GoPool) error {
+	cfg.Name = s.name
+	cfg, err := normalizePoolConfig(cfg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	old := s.config
+	s.mu.Unlock()
+	if cfg.Shards != s.shards {
+		logger.Zap.Warn("stateful pool shards cannot be changed without restart", zap.String("name", s.name), zap.Int("shards", s.shards), zap.Int("new", cfg.Shards))
+		cfg.Shards = s.shards
+	}
+	var pool *antsPool
+	if old.Expire != cfg.Expire || old.DisablePurge != cfg.DisablePurge || old.DisablePurgeRunning != cfg.DisablePurgeRunning {
+		pool, err = newAntsPool(cfg, s.options)
+		if err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.config = cfg
+	retired := s.pool
+	if pool != nil {
+		s.pool = pool
+	}
+	for _, q := range s.queues {
+		s.wakeAllLocked(q)
+	}
+	if pool != nil {
+		s.wakeLanesLocked()
+	}
+	s.mu.Unlock()
+	if pool != nil {
+		retired.retire()
+	}
+	return nil
+}
+
+// Close 停止接收新任务,等待已排队的任务执行完后释放线程池
+//
+//	@receiver s
+//	@param ctx 结束时不再等待,剩余的任务仍会执行
+//	@return error
+func (s *StatefulPool) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.drained == nil {
+		s.closed = constants.ErrPoolClosed
+		s.drained = make(chan struct{})
+		for _, q := range s.queues {
+			s.wakeAllLocked(q)
+		}
+		s.wakeLanesLocked()
+		s.checkDrainedLocked()
+	}
+	drained := s.drained
+	s.mu.Unlock()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+	s.mu.Lock()
+	pool := s.pool
+	s.mu.Unlock()
+	pool.retire()
+	return nil
+}
+
+// Config 当前配置
+//
+//	@receiver s
+//	@return config.GoPool
+func (s *StatefulPool) Config() config.GoPool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
 }
 
 func (s *StatefulPool) Name() string {
-	return s.config.Name
+	return s.name
 }
 
-// Go 根据指定的goroutineID派发线程
+// PoolStats 线程池运行状态
+type PoolStats struct {
+	Name    string `json:"name"`
+	Running int    `json:"running"` // 运行中的线程数
+	Free    int    `json:"free"`    // 可用的线程数
+	Waiting int    `json:"waiting"` // 等待分配线程的任务数
+	Cap     int    `json:"cap"`     // 容量
+	Queued  int64  `json:"queued"`  // 所有goID排队中的任务数
+}
+
+// Stats 线程池运行状态
+//
+//	@receiver s
+//	@return PoolStats
+func (s *StatefulPool) Stats() PoolStats {
+	s.mu.Lock()
+	pool := s.pool
+	s.mu.Unlock()
+	return PoolStats{
+		Name:    s.name,
+		Running: pool.Running(),
+		Free:    pool.Free(),
+		Waiting: pool.Waiting(),
+		Cap:     pool.Cap(),
+		Queued:  s.queued.Load(),
+	}
+}
+
+// Go 根据指定的goroutineID派发线程,派发失败时记录日志,返回的done已关闭
 //
 //	@receiver h
 //	@param goID
 //	@param task
 func (s *StatefulPool) Go(ctx context.Context, goID int, task func(ctx context.Context), disableTimeoutWatch bool) (done chan struct{}) {
-	done = make(chan struct{})
-	logg := util.GetLoggerFromCtx(ctx).With(zap.String("pool", s.Name())).With(zap.Int("goID", goID))
-	ctx = context.WithValue(ctx, constants.LoggerCtxKey, logg)
-	taskWithDone := func() {
-		task(ctx)
-		close(done)
-	}
-	submitted := false
-	if !s.config.DisableTimeoutWatch && !disableTimeoutWatch {
-		timeoutErr := errors.NewWithStack("goroutine timeout")
-		Go(func() {
-			for _, timeout := range s.config.TimeoutBuckets {
-				select {
-				case <-done:
-					return
-				case <-ctx.Done():
-					return
-				case <-time.After(timeout):
-					logg.Error("", zap.Duration("timeout", timeout), zap.Bool("submitted", submitted), zap.Error(timeoutErr))
-					metrics.ReportPoolGoDeadlines(ctx, s.Name(), int(timeout/time.Second), s.reporters)
-				}
-			}
-		})
+	t, _ := s.submit(ctx, goID, "", task, disableTimeoutWatch)
+	return t.done
+}
+
+// TryGo 同 Go,派发失败时返回error,如队列已满时返回 constants.ErrPoolOverflow,可用于回复客户端服务器繁忙
+//
+//	@receiver s
+//	@param ctx
+//	@param goID
+//	@param task
+//	@param disableTimeoutWatch
+//	@return done 任务执行完成或因 OverflowDropOldest 被丢弃时关闭
+//	@return error
+func (s *StatefulPool) TryGo(ctx context.Context, goID int, task func(ctx context.Context), disableTimeoutWatch bool) (done chan struct{}, err error) {
+	t, err := s.submit(ctx, goID, "", task, disableTimeoutWatch)
+	if err != nil {
+		return nil, err
 	}
+	return t.done, nil
+}
+
+// GoWithKey 同 Go,按字符串key派发,同一key的任务按顺序执行,不同key之间不会冲突
+//
+//	@receiver s
+//	@param ctx
+//	@param key 不能为空
+//	@param task
+//	@param disableTimeoutWatch
+//	@return done
+func (s *StatefulPool) GoWithKey(ctx context.Context, key string, task func(ctx context.Context), disableTimeoutWatch bool) (done chan struct{}) {
+	t, _ := s.submitKey(ctx, key, task, disableTimeoutWatch)
+	return t.done
+}
+
+// TryGoWithKey 同 TryGo,按字符串key派发
+//
+//	@receiver s
+//	@param ctx
+//	@param key 不能为空
+//	@param task
+//	@param disableTimeoutWatch
+//	@return done
+//	@return error
+func (s *StatefulPool) TryGoWithKey(ctx context.Context, key string, task func(ctx context.Context), disableTimeoutWatch bool) (done chan struct{}, err error) {
+	t, err := s.submitKey(ctx, key, task, disableTimeoutWatch)
+	if err != nil {
+		return nil, err
+	}
+	return t.done, nil
+}
+
+// submitKey 校验key后 submit
+func (s *StatefulPool) submitKey(ctx context.Context, key string, task func(ctx context.Context), disableTimeoutWatch bool) (*poolTask, error) {
+	if key == "" {
+		err := errors.WithStack(constants.ErrEmptyGoKey)
+		util.GetLoggerFromCtx(ctx).Error("submit task with key error", zap.String("pool", s.Name()), zap.Error(err))
+		t := &poolTask{done: make(chan struct{})}
+		t.finish(err)
+		return t, err
+	}
+	return s.submit(ctx, 0, key, task, disableTimeoutWatch)
+}
+
+// submit 任务进入goID或key的队列,所在lane没有runner时派发一个,失败时返回的任务已结束
+func (s *StatefulPool) submit(ctx context.Context, goID int, key string, task func(ctx context.Context), disableTimeoutWatch bool) (*poolTask, error) {
+	logg := util.GetLoggerFromCtx(ctx).With(zap.String("pool", s.Name()))
+	if key != "" {
+		logg = logg.With(zap.String("goKey", key))
+	} else {
+		logg = logg.With(zap.Int("goID", goID))
+	}
+	onDrop, ctx := takeDropHook(ctx)
+	ctx = context.WithValue(ctx, constants.LoggerCtxKey, logg)
+	t := newPoolTask(ctx, goID, key, task, logg)
+	t.onDrop = onDrop
 	logg.Debug("submit")
-	err := s.pool.Submit(goID, taskWithDone)
+	cfg, runner, laneID, err := s.enqueue(ctx, t)
+	if err != nil {
+		logg.Error("submit task with id error", zap.Error(err))
+		t.finish(err)
+		return t, err
+	}
+	submitted := &atomic.Bool{}
+	if !cfg.DisableTimeoutWatch && !disableTimeoutWatch {
+		s.watchTimeout(ctx, t, submitted, cfg.TimeoutBuckets)
+	}
+	if runner == nil {
+		// lane已有runner,由其依次执行
+		submitted.Store(true)
+		return t, nil
+	}
+	err = runner.Submit(func() { s.drain(laneID, runner) })
 	if err != nil {
 		logg.Error("submit task with id error", zap.Error(err))
+		s.abort(laneID, runner, err)
+		return t, err
+	}
+	submitted.Store(true)
+	return t, nil
+}
+
+// watchTimeout 依次等待 buckets 中的时长,任务仍未结束时记录日志和指标
+//
+//	使用 clock.AfterFunc 而非常驻goroutine,测试时可由虚拟时钟触发;任务结束时停止定时器
+func (s *StatefulPool) watchTimeout(ctx context.Context, t *poolTask, submitted *atomic.Bool, buckets []time.Duration) {
+	if len(buckets) == 0 {
 		return
 	}
-	submitted = true
-	return done
+	timeout := buckets[0]
+	t.setWatch(clock.AfterFunc(timeout, func() {
+		select {
+		case <-t.done:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+		fields := append(t.fields(), zap.Duration("timeout", timeout), zap.Bool("submitted", submitted.Load()), zap.Bool("running", t.startedAt.Load() > 0))
+		t.logg.Error("", append(fields, zap.Error(errors.NewWithStack("goroutine timeout")))...)
+		metrics.ReportPoolGoDeadlines(ctx, s.Name(), int(timeout/time.Second), s.reporters)
+		s.watchTimeout(ctx, t, submitted, buckets[1:])
+	}))
 }
 
 func (s *StatefulPool) Wait(ctx context.Context, goID int, task func(ctx context.Context), disableTimeoutWatch bool) {
@@ -109,3 +409,57 @@ func (s *StatefulPool) Wait(ctx context.Context, goID int, task func(ctx context
 	case <-ctx.Done():
 	}
 }
+
+// WaitWithKey 同 Wait,按字符串key派发
+//
+//	@receiver s
+//	@param ctx
+//	@param key 不能为空
+//	@param task
+//	@param disableTimeoutWatch
+func (s *StatefulPool) WaitWithKey(ctx context.Context, key string, task func(ctx context.Context), disableTimeoutWatch bool) {
+	done := s.GoWithKey(ctx, key, task, disableTimeoutWatch)
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// TryWait 同 Wait,派发失败、任务被丢弃或ctx结束时返回error
+//
+//	@receiver s
+//	@param ctx
+//	@param goID
+//	@param task
+//	@param disableTimeoutWatch
+//	@return error
+func (s *StatefulPool) TryWait(ctx context.Context, goID int, task func(ctx context.Context), disableTimeoutWatch bool) error {
+	t, err := s.submit(ctx, goID, "", task, disableTimeoutWatch)
+	return waitTask(ctx, t, err)
+}
+
+// TryWaitWithKey 同 TryWait,按字符串key派发
+//
+//	@receiver s
+//	@param ctx
+//	@param key 不能为空
+//	@param task
+//	@param disableTimeoutWatch
+//	@return error
+func (s *StatefulPool) TryWaitWithKey(ctx context.Context, key string, task func(ctx context.Context), disableTimeoutWatch bool) error {
+	t, err := s.submitKey(ctx, key, task, disableTimeoutWatch)
+	return waitTask(ctx, t, err)
+}
+
+// waitTask 等待提交的任务结束
+func waitTask(ctx context.Context, t *poolTask, err error) error {
+	if err != nil {
+		return err
+	}
+	select {
+	case <-t.done:
+		return t.err
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
@@ -2,11 +2,20 @@ package co
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/golang/mock/gomock"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/helpers"
+	"github.com/topfreegames/pitaya/v2/metrics"
+	"github.com/topfreegames/pitaya/v2/metrics/mocks"
 )
 
 func newPool() *StatefulPool {
@@ -33,3 +42,433 @@ func TestStatefulPool_Go(t *testing.T) {
 	wg.Wait()
 	time.Sleep(time.Second * 5)
 }
+
+func TestStatefulPoolTimeoutWatch(t *testing.T) {
+	c := helpers.UseFakeClock(t, time.Now())
+	p, err := NewStatefulPool(config.GoPool{
+		Name:           "watch",
+		TimeoutBuckets: []time.Duration{10 * time.Minute, 5 * time.Second},
+	}, nil)
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	done := p.Go(context.Background(), 1, func(ctx context.Context) { <-release }, false)
+	assert.Equal(t, 1, c.Waiters())
+	// 档位按时长排序,第一档超时后等待下一档
+	c.Advance(5 * time.Second)
+	assert.Equal(t, 1, c.Waiters())
+	close(release)
+	<-done
+	// 任务结束时停止定时器
+	assert.Equal(t, 0, c.Waiters())
+
+	p.Go(context.Background(), 1, func(ctx context.Context) {}, true)
+	assert.Equal(t, 0, c.Waiters())
+}
+
+// blockGoID 占住goID的线程,返回释放函数
+func blockGoID(t *testing.T, p *StatefulPool, goID int) func() {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	p.Go(context.Background(), goID, func(ctx context.Context) {
+		close(started)
+		<-release
+	}, true)
+	<-started
+	return func() { close(release) }
+}
+
+func newOverflowPool(t *testing.T, overflow string, blockTimeout time.Duration, reporters ...metrics.Reporter) *StatefulPool {
+	p, err := NewStatefulPool(config.GoPool{
+		Name:                "overflow",
+		TaskBuffer:          2,
+		Overflow:            overflow,
+		BlockTimeout:        blockTimeout,
+		DisableTimeoutWatch: true,
+	}, reporters)
+	require.NoError(t, err)
+	return p
+}
+
+func TestStatefulPoolOverflowReject(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	reporter := mocks.NewMockReporter(ctrl)
+	reporter.EXPECT().ReportHistogram(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	p := newOverflowPool(t, OverflowReject, 0, reporter)
+	release := blockGoID(t, p, 1)
+
+	var ran []int
+	for i := 0; i < 2; i++ {
+		i := i
+		_, err := p.TryGo(context.Background(), 1, func(ctx context.Context) { ran = append(ran, i) }, true)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, p.QueueDepth(1))
+	assert.Equal(t, int64(2), p.Stats().Queued)
+
+	reporter.EXPECT().ReportCount(metrics.PoolOverflows, map[string]string{"pool": "overflow", "policy": OverflowReject}, float64(1))
+	_, err := p.TryGo(context.Background(), 1, func(ctx context.Context) { ran = append(ran, 2) }, true)
+	assert.ErrorIs(t, err, constants.ErrPoolOverflow)
+	// 其他goID不受影响
+	require.NoError(t, p.TryWait(context.Background(), 2, func(ctx context.Context) {}, true))
+
+	reporter.EXPECT().ReportGauge(metrics.PoolQueueDepth, map[string]string{"pool": "overflow"}, float64(2))
+	reporter.EXPECT().ReportGauge(metrics.PoolGoIDQueueDepth, map[string]string{"pool": "overflow", "slot": "0"}, float64(2))
+	p.ReportQueueDepth()
+
+	release()
+	helpers.ShouldEventuallyReturn(t, func() int { return p.QueueDepth(1) }, 0)
+	require.NoError(t, p.TryWait(context.Background(), 1, func(ctx context.Context) {}, true))
+	assert.Equal(t, []int{0, 1}, ran)
+	reporter.EXPECT().ReportGauge(metrics.PoolQueueDepth, map[string]string{"pool": "overflow"}, float64(0))
+	reporter.EXPECT().ReportGauge(metrics.PoolGoIDQueueDepth, map[string]string{"pool": "overflow", "slot": "0"}, float64(0))
+	p.ReportQueueDepth()
+}
+
+func TestStatefulPoolOverflowDropOldest(t *testing.T) {
+	p := newOverflowPool(t, OverflowDropOldest, 0)
+	release := blockGoID(t, p, 1)
+
+	var mu sync.Mutex
+	var ran []int
+	waits := make([]chan error, 4)
+	for i := 0; i < 4; i++ {
+		i := i
+		waits[i] = make(chan error, 1)
+		go func() {
+			waits[i] <- p.TryWait(context.Background(), 1, func(ctx context.Context) {
+				mu.Lock()
+				defer mu.Unlock()
+				ran = append(ran, i)
+			}, true)
+		}()
+		// 逐个提交以保证顺序
+		if i < 2 {
+			helpers.ShouldEventuallyReturn(t, func() int { return p.QueueDepth(1) }, i+1)
+		} else {
+			assert.ErrorIs(t, <-waits[i-2], constants.ErrPoolTaskDropped, "oldest task should be dropped")
+		}
+	}
+	assert.Equal(t, 2, p.QueueDepth(1))
+
+	release()
+	assert.NoError(t, <-waits[2])
+	assert.NoError(t, <-waits[3])
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{2, 3}, ran)
+}
+
+func TestStatefulPoolOnDrop(t *testing.T) {
+	p := newOverflowPool(t, OverflowDropOldest, 0)
+	release := blockGoID(t, p, 1)
+
+	var dropped []error
+	var mu sync.Mutex
+	withOnDrop := func() context.Context {
+		o := &options{onDrop: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dropped = append(dropped, err)
+		}}
+		return o.withContext(context.Background())
+	}
+	inherited := make(chan bool, 2)
+	for i := 0; i < 3; i++ {
+		_, err := p.TryGo(withOnDrop(), 1, func(ctx context.Context) {
+			// 丢弃回调只属于提交的任务,不传递给任务的ctx
+			onDrop, _ := takeDropHook(ctx)
+			inherited <- onDrop != nil
+		}, true)
+		require.NoError(t, err)
+	}
+	mu.Lock()
+	require.Len(t, dropped, 1)
+	assert.ErrorIs(t, dropped[0], constants.ErrPoolTaskDropped)
+	mu.Unlock()
+
+	release()
+	assert.False(t, <-inherited)
+	assert.False(t, <-inherited)
+	mu.Lock()
+	assert.Len(t, dropped, 1)
+	mu.Unlock()
+
+	// 派发失败时同样调用
+	_, err := newMissingPool("missing").TryGo(withOnDrop(), 1, func(ctx context.Context) {}, true)
+	assert.ErrorIs(t, err, constants.ErrPoolNotFound)
+	mu.Lock()
+	assert.Len(t, dropped, 2)
+	mu.Unlock()
+}
+
+func TestStatefulPoolOverflowBlock(t *testing.T) {
+	p := newOverflowPool(t, OverflowBlock, 50*time.Millisecond)
+	release := blockGoID(t, p, 1)
+	for i := 0; i < 2; i++ {
+		p.Go(context.Background(), 1, func(ctx context.Context) {}, true)
+	}
+
+	start := time.Now()
+	_, err := p.TryGo(context.Background(), 1, func(ctx context.Context) {}, true)
+	assert.ErrorIs(t, err, constants.ErrPoolOverflow)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	// 提交失败时 Go 返回已关闭的done
+	select {
+	case <-p.Go(context.Background(), 1, func(ctx context.Context) {}, true):
+	default:
+		t.Fatal("done should be closed when submit fails")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = p.TryGo(ctx, 1, func(ctx context.Context) {}, true)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := p.TryGo(context.Background(), 1, func(ctx context.Context) {}, true)
+		blocked <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	release()
+	assert.NoError(t, <-blocked)
+	require.NoError(t, p.TryWait(context.Background(), 1, func(ctx context.Context) {}, true))
+	assert.Equal(t, 0, p.QueueDepth(1))
+}
+
+func TestStatefulPoolUnknownOverflow(t *testing.T) {
+	_, err := NewStatefulPool(config.GoPool{Name: "bad", Overflow: "drop"}, nil)
+	assert.Error(t, err)
+}
+
+func TestStatefulPoolReconfigure(t *testing.T) {
+	p := newOverflowPool(t, OverflowReject, 0)
+	release := blockGoID(t, p, 1)
+	oldAnts := p.pool
+
+	var mu sync.Mutex
+	var ran []int
+	record := func(i int) func(ctx context.Context) {
+		return func(ctx context.Context) {
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, i)
+		}
+	}
+	dones := make([]chan struct{}, 0, 3)
+	for i := 0; i < 2; i++ {
+		done, err := p.TryGo(context.Background(), 1, record(i), true)
+		require.NoError(t, err)
+		dones = append(dones, done)
+	}
+	_, err := p.TryGo(context.Background(), 1, record(2), true)
+	assert.ErrorIs(t, err, constants.ErrPoolOverflow)
+
+	assert.Error(t, p.Reconfigure(config.GoPool{Overflow: "drop"}), "invalid config should be rejected")
+	assert.Equal(t, 2, p.Config().TaskBuffer)
+
+	// Expire变化时切换ants线程池,goID 1 仍在旧线程池中按顺序执行
+	require.NoError(t, p.Reconfigure(config.GoPool{TaskBuffer: 3, Overflow: OverflowReject, Expire: time.Minute, DisableTimeoutWatch: true}))
+	assert.Equal(t, "overflow", p.Config().Name)
+	assert.NotSame(t, oldAnts, p.pool)
+	done, err := p.TryGo(context.Background(), 1, record(2), true)
+	require.NoError(t, err)
+	dones = append(dones, done)
+	require.NoError(t, p.TryWait(context.Background(), 2, func(ctx context.Context) {}, true))
+	assert.False(t, oldAnts.IsClosed())
+
+	release()
+	for _, done := range dones {
+		<-done
+	}
+	mu.Lock()
+	assert.Equal(t, []int{0, 1, 2}, ran)
+	mu.Unlock()
+	helpers.ShouldEventuallyReturn(t, oldAnts.IsClosed, true)
+}
+
+func TestStatefulPoolReconfigureWakesBlocked(t *testing.T) {
+	p := newOverflowPool(t, OverflowBlock, 0)
+	release := blockGoID(t, p, 1)
+	defer release()
+	for i := 0; i < 2; i++ {
+		p.Go(context.Background(), 1, func(ctx context.Context) {}, true)
+	}
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := p.TryGo(context.Background(), 1, func(ctx context.Context) {}, true)
+		blocked <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, p.Reconfigure(config.GoPool{TaskBuffer: 3, DisableTimeoutWatch: true}))
+	assert.NoError(t, <-blocked)
+	assert.Equal(t, 3, p.QueueDepth(1))
+}
+
+func TestStatefulPoolClose(t *testing.T) {
+	p := newOverflowPool(t, OverflowBlock, 0)
+	release := blockGoID(t, p, 1)
+	var ran bool
+	done := p.Go(context.Background(), 1, func(ctx context.Context) { ran = true }, true)
+
+	closed := make(chan error, 1)
+	go func() { closed <- p.Close(context.Background()) }()
+	helpers.ShouldEventuallyReturn(t, func() bool {
+		_, err := p.TryGo(context.Background(), 2, func(ctx context.Context) {}, true)
+		return errors.Is(err, constants.ErrPoolClosed)
+	}, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.Close(ctx), context.DeadlineExceeded)
+
+	// 排队中的任务在关闭后仍会执行
+	release()
+	<-done
+	assert.True(t, ran)
+	assert.NoError(t, <-closed)
+	helpers.ShouldEventuallyReturn(t, p.pool.IsClosed, true)
+}
+
+func TestStatefulPoolParkedRunner(t *testing.T) {
+	p, err := NewStatefulPool(config.GoPool{Name: "parked", Expire: 200 * time.Millisecond, DisableTimeoutWatch: true}, nil)
+	require.NoError(t, err)
+	lane := func() *lane {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.lanes[1]
+	}
+	// 每批任务结束后runner空闲等待,之后的任务由同一个runner执行,不重新派发到ants
+	for i := 0; i < 3; i++ {
+		require.NoError(t, p.TryWait(context.Background(), 1, func(ctx context.Context) {}, true))
+	}
+	require.NotNil(t, lane())
+	assert.Equal(t, 1, p.pool.Running())
+
+	// 空闲超过Expire时runner结束
+	helpers.ShouldEventuallyReturn(t, func() bool { return lane() == nil }, true)
+	require.NoError(t, p.TryWait(context.Background(), 1, func(ctx context.Context) {}, true))
+	require.NotNil(t, lane())
+
+	// 关闭时唤醒空闲的runner,全部结束后释放ants线程池
+	require.NoError(t, p.Close(context.Background()))
+	helpers.ShouldEventuallyReturn(t, p.pool.IsClosed, true)
+	assert.Nil(t, lane())
+}
+
+func TestStatefulPoolKeys(t *testing.T) {
+	p := newPool()
+	var mu sync.Mutex
+	ran := map[string][]int{}
+	var wg sync.WaitGroup
+	keys := []string{"room-a", "room-b", "guild-c"}
+	for i := 0; i < 100; i++ {
+		for _, key := range keys {
+			i, key := i, key
+			wg.Add(1)
+			p.GoWithKey(context.Background(), key, func(ctx context.Context) {
+				defer wg.Done()
+				mu.Lock()
+				defer mu.Unlock()
+				ran[key] = append(ran[key], i)
+			}, true)
+		}
+	}
+	wg.Wait()
+	for _, key := range keys {
+		require.Len(t, ran[key], 100)
+		for i, v := range ran[key] {
+			assert.Equal(t, i, v, "tasks of %s should run in order", key)
+		}
+	}
+
+	// 同时存在的key分配到不同的goID
+	release := make(chan struct{})
+	p.GoWithKey(context.Background(), "room-a", func(ctx context.Context) { <-release }, true)
+	p.GoWithKey(context.Background(), "room-b", func(ctx context.Context) { <-release }, true)
+	helpers.ShouldEventuallyReturn(t, func() int { return len(p.Tasks()) }, 2)
+	tasks := p.Tasks()
+	assert.NotEqual(t, tasks[0].GoID, tasks[1].GoID)
+	assert.ElementsMatch(t, []string{"room-a", "room-b"}, []string{tasks[0].Key, tasks[1].Key})
+	close(release)
+
+	_, err := p.TryGoWithKey(context.Background(), "", func(ctx context.Context) {}, true)
+	assert.ErrorIs(t, err, constants.ErrEmptyGoKey)
+	assert.ErrorIs(t, p.TryWaitWithKey(context.Background(), "", func(ctx context.Context) {}, true), constants.ErrEmptyGoKey)
+}
+
+func TestStatefulPoolKeyReusesLane(t *testing.T) {
+	p, err := NewStatefulPool(config.GoPool{Name: "keyLane", Expire: 200 * time.Millisecond, DisableTimeoutWatch: true}, nil)
+	require.NoError(t, err)
+	count := func() (keys, lanes int) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return len(p.keys) + len(p.keyOf), len(p.lanes)
+	}
+	// lane结束前重复提交的key沿用同一goID,只有一个runner
+	for i := 0; i < 200; i++ {
+		require.NoError(t, p.TryWaitWithKey(context.Background(), "room-1", func(ctx context.Context) {}, true))
+	}
+	keys, lanes := count()
+	assert.Equal(t, 2, keys)
+	assert.Equal(t, 1, lanes)
+	assert.Equal(t, 1, p.pool.Running())
+
+	// runner空闲结束后释放key
+	helpers.ShouldEventuallyReturn(t, func() int {
+		keys, lanes := count()
+		return keys + lanes
+	}, 0)
+	require.NoError(t, p.Close(context.Background()))
+}
+
+func TestStatefulPoolShards(t *testing.T) {
+	p, err := NewStatefulPool(config.GoPool{Name: "shards", Shards: 4, DisableTimeoutWatch: true}, nil)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	ran := map[int][]int{}
+	running, maxRunning := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		for goID := 1; goID <= 50; goID++ {
+			i, goID := i, goID
+			wg.Add(1)
+			p.Go(context.Background(), goID, func(ctx context.Context) {
+				defer wg.Done()
+				mu.Lock()
+				running++
+				maxRunning = lo.Max([]int{maxRunning, running})
+				ran[goID] = append(ran[goID], i)
+				mu.Unlock()
+				time.Sleep(10 * time.Microsecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}, true)
+		}
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxRunning, 4)
+	for goID, seq := range ran {
+		require.Len(t, seq, 20)
+		for i, v := range seq {
+			assert.Equal(t, i, v, "tasks of goID %d should run in order", goID)
+		}
+	}
+	// 队列清空后runner空闲等待,不重新派发
+	helpers.ShouldEventuallyReturn(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		parked := len(p.queues) == 0 && len(p.lanes) <= 4
+		for _, ln := range p.lanes {
+			parked = parked && ln.parked && len(ln.ready) == 0
+		}
+		return parked
+	}, true)
+
+	// 分片数不能在运行时修改
+	require.NoError(t, p.Reconfigure(config.GoPool{Shards: 8}))
+	assert.Equal(t, 4, p.Config().Shards)
+}
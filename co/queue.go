@@ -0,0 +1,497 @@
+package co
+
+import (
+	"context"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alkaid/goerrors/errors"
+	"github.com/topfreegames/pitaya/v2/clock"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/metrics"
+	"go.uber.org/zap"
+)
+
+// 队列满时的策略 config.GoPool.Overflow
+const (
+	OverflowBlock      = "block"      // 阻塞等待队列有空位,超过 config.GoPool.BlockTimeout 时返回 constants.ErrPoolOverflow
+	OverflowReject     = "reject"     // 直接返回 constants.ErrPoolOverflow
+	OverflowDropOldest = "dropOldest" // 丢弃最早排队的任务,被丢弃任务的done关闭, TryWait 返回 constants.ErrPoolTaskDropped
+)
+
+// reportTopGoIDs 每个线程池上报队列长度的goID数量,只上报最长的几个,按排名使用固定的slot标签,避免指标基数过大
+const reportTopGoIDs = 10
+
+// poolTask 排队中的任务
+type poolTask struct {
+	ctx        context.Context
+	run        func()
+	done       chan struct{}
+	err        error // 未执行的原因,done关闭前设置
+	logg       *zap.Logger
+	goID       int
+	key        string          // 字符串key,非空时goID由key分配
+	name       string          // 任务名 WithTaskName
+	route      string          // 提交任务的请求路由
+	caller     string          // 任务函数名
+	enqueuedAt time.Time       // 提交时间
+	startedAt  atomic.Int64    // 开始执行的 UnixNano,0为排队中
+	watchMu    sync.Mutex      // 保护 watch closed
+	watch      clock.Timer     // 超时监控的定时器,任务结束时停止
+	closed     bool            // 任务是否已结束
+	onDrop     func(err error) // WithOnDrop,未执行就结束时调用
+}
+
+// finish 任务未执行就结束
+func (t *poolTask) finish(err error) {
+	t.err = err
+	if t.onDrop != nil {
+		t.onDrop(err)
+	}
+	t.close()
+}
+
+// close 停止超时监控的定时器并结束任务
+func (t *poolTask) close() {
+	t.watchMu.Lock()
+	t.closed = true
+	if t.watch != nil {
+		t.watch.Stop()
+		t.watch = nil
+	}
+	t.watchMu.Unlock()
+	close(t.done)
+}
+
+// setWatch 记录超时监控的定时器,任务已结束时直接停止
+func (t *poolTask) setWatch(timer clock.Timer) {
+	t.watchMu.Lock()
+	defer t.watchMu.Unlock()
+	if t.closed {
+		timer.Stop()
+		return
+	}
+	t.watch = timer
+}
+
+// exec 执行任务,panic时按策略处理,除 PanicCrash 外runner继续执行后续任务
+func (s *StatefulPool) exec(t *poolTask, policy string) {
+	defer func() {
+		if r := recover(); r != nil {
+			handlePanic(t.ctx, t.logg, s.reporters, policy, PanicInfo{
+				Pool:  s.name,
+				GoID:  t.goID,
+				Key:   t.key,
+				Task:  t.label(),
+				Route: t.route,
+				Value: r,
+				Stack: string(debug.Stack()),
+			})
+		}
+		t.close()
+	}()
+	t.run()
+}
+
+// label 指标中的任务标签,依次取任务名、路由、任务函数名
+func (t *poolTask) label() string {
+	if t.name != "" {
+		return t.name
+	}
+	if t.route != "" {
+		return t.route
+	}
+	return t.caller
+}
+
+// fields 日志中的任务信息
+func (t *poolTask) fields() []zap.Field {
+	fields := []zap.Field{
+		zap.String("task", t.name),
+		zap.String("route", t.route),
+		zap.String("caller", t.caller),
+		zap.Duration("wait", clock.Since(t.enqueuedAt)),
+	}
+	if started := t.startedAt.Load(); started > 0 {
+		fields = append(fields, zap.Duration("elapsed", clock.Since(time.Unix(0, started))))
+	}
+	return fields
+}
+
+// goQueue 一个goID排队中的任务
+type goQueue struct {
+	tasks   []*poolTask
+	waiters []chan struct{} // OverflowBlock 时等待空位的提交者
+	ready   bool            // 已在lane的就绪列表中或有任务执行中
+	current *poolTask       // 执行中的任务
+}
+
+// lane 一个派发到ants的runner StatefulPool.drain 及其依次执行的goID.
+// 未分片时每个goID一个lane,分片时同一分片的goID共用一个lane,轮流执行各goID的队首任务.
+// runner空闲时不退出,等待 wake 继续执行之后入队的任务
+type lane struct {
+	ready  []int         // 有任务待执行的goID
+	runner *antsPool     // 执行中的runner
+	parked bool          // runner空闲等待中
+	wake   chan struct{} // 唤醒空闲的runner
+}
+
+// wakeLocked 唤醒空闲的runner
+func (ln *lane) wakeLocked() {
+	if ln.parked {
+		ln.parked = false
+		ln.wake <- struct{}{}
+	}
+}
+
+// enqueue 任务进入goID的队列,队列满时按 config.GoPool.Overflow 处理
+//
+//	@return cfg 入队时的配置
+//	@return runner 非nil时lane还没有runner,调用方须向其派发 StatefulPool.drain;已有runner时唤醒空闲的runner
+//	@return laneID
+//	@return err
+func (s *StatefulPool) enqueue(ctx context.Context, t *poolTask) (cfg config.GoPool, runner *antsPool, laneID int, err error) {
+	var timeout <-chan struct{}
+	s.mu.Lock()
+	for {
+		cfg = s.config
+		if t.key != "" {
+			t.goID = s.keyIDLocked(t.key)
+		}
+		goID := t.goID
+		q := s.queues[goID]
+		if s.closed != nil {
+			if q != nil {
+				// 被 Close 唤醒的等待者创建的空队列
+				s.cleanLocked(goID, q)
+			} else {
+				s.releaseKeyLocked(goID)
+			}
+			s.mu.Unlock()
+			return cfg, nil, 0, errors.WithStack(s.closed)
+		}
+		if q == nil {
+			q = &goQueue{}
+			s.queues[goID] = q
+		}
+		if len(q.tasks) < cfg.TaskBuffer {
+			q.tasks = append(q.tasks, t)
+			s.queued.Add(1)
+			if !q.ready {
+				q.ready = true
+				laneID = s.laneID(goID)
+				ln := s.lanes[laneID]
+				if ln == nil {
+					ln = &lane{wake: make(chan struct{}, 1)}
+					s.lanes[laneID] = ln
+				}
+				ln.ready = append(ln.ready, goID)
+				if ln.runner == nil {
+					ln.runner = s.pool
+					ln.runner.acquire()
+					runner = ln.runner
+				} else {
+					ln.wakeLocked()
+				}
+			}
+			s.mu.Unlock()
+			return cfg, runner, laneID, nil
+		}
+		switch cfg.Overflow {
+		case OverflowReject:
+			s.mu.Unlock()
+			metrics.ReportPoolOverflow(s.reporters, s.Name(), cfg.Overflow)
+			return cfg, nil, 0, errors.WithStack(constants.ErrPoolOverflow)
+		case OverflowDropOldest:
+			// 队列非空时一定已在lane中
+			dropped := q.tasks[0]
+			q.tasks = append(q.tasks[1:], t)
+			s.mu.Unlock()
+			metrics.ReportPoolOverflow(s.reporters, s.Name(), cfg.Overflow)
+			dropped.finish(errors.WithStack(constants.ErrPoolTaskDropped))
+			return cfg, nil, 0, nil
+		}
+		if timeout == nil && cfg.BlockTimeout > 0 {
+			timer := make(chan struct{})
+			stop := clock.AfterFunc(cfg.BlockTimeout, func() { close(timer) })
+			defer stop.Stop()
+			timeout = timer
+		}
+		ready := make(chan struct{})
+		q.waiters = append(q.waiters, ready)
+		s.mu.Unlock()
+		select {
+		case <-ready:
+		case <-timeout:
+			err = errors.WithStack(constants.ErrPoolOverflow)
+		case <-ctx.Done():
+			err = errors.WithStack(ctx.Err())
+		}
+		s.mu.Lock()
+		if err != nil {
+			s.removeWaiterLocked(goID, ready)
+			s.mu.Unlock()
+			metrics.ReportPoolOverflow(s.reporters, s.Name(), cfg.Overflow)
+			return cfg, nil, 0, err
+		}
+	}
+}
+
+// laneID goID所在的lane,分片时按goID的哈希分配,创建后分片数不变,保证同一goID始终在同一分片
+func (s *StatefulPool) laneID(goID int) int {
+	if s.shards <= 0 {
+		return goID
+	}
+	// splitmix64,避免规律的goID集中在少数分片
+	x := uint64(goID) + 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return int(x % uint64(s.shards))
+}
+
+// keyIDLocked 字符串key对应的goID,没有时分配一个新的负数goID.
+// 未分片时key的lane结束后才释放,期间重复提交的key沿用同一goID和runner;分片时队列清空后即释放.
+// 释放后再提交时重新分配,不同key同时存在时goID一定不同
+func (s *StatefulPool) keyIDLocked(key string) int {
+	if goID, ok := s.keys[key]; ok {
+		return goID
+	}
+	s.nextKeyID--
+	s.keys[key] = s.nextKeyID
+	s.keyOf[s.nextKeyID] = key
+	return s.nextKeyID
+}
+
+// releaseKeyLocked 释放goID对应的key,未分片时goID的lane仍在时保留
+func (s *StatefulPool) releaseKeyLocked(goID int) {
+	if s.shards <= 0 && s.lanes[goID] != nil {
+		return
+	}
+	if key, ok := s.keyOf[goID]; ok {
+		delete(s.keyOf, goID)
+		delete(s.keys, key)
+	}
+}
+
+// removeWaiterLocked 放弃等待,已被唤醒时把空位让给下一个等待者
+func (s *StatefulPool) removeWaiterLocked(goID int, ready chan struct{}) {
+	q := s.queues[goID]
+	if q == nil {
+		return
+	}
+	for i, w := range q.waiters {
+		if w == ready {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			s.cleanLocked(goID, q)
+			return
+		}
+	}
+	s.wakeLocked(q)
+}
+
+// drain 在lane的线程中轮流执行各goID的队首任务
+//
+//	没有待执行的goID时不退出,等待之后入队的任务,避免每批任务都重新派发到ants.
+//	空闲超过 config.GoPool.Expire、线程池关闭或已切换到新的ants线程池时结束
+func (s *StatefulPool) drain(laneID int, runner *antsPool) {
+	defer runner.release()
+	var goID int
+	var q *goQueue
+	for {
+		s.mu.Lock()
+		ln := s.lanes[laneID]
+		if q != nil {
+			q.current = nil
+			if len(q.tasks) > 0 {
+				ln.ready = append(ln.ready, goID)
+			} else {
+				q.ready = false
+				s.cleanLocked(goID, q)
+			}
+			q = nil
+		}
+		if len(ln.ready) == 0 {
+			if s.closed != nil || runner != s.pool {
+				s.removeLaneLocked(laneID)
+				s.mu.Unlock()
+				return
+			}
+			ln.parked = true
+			cfg := s.config
+			s.mu.Unlock()
+			if !s.park(laneID, ln, cfg) {
+				return
+			}
+			continue
+		}
+		goID = ln.ready[0]
+		ln.ready = ln.ready[1:]
+		q = s.queues[goID]
+		t := q.tasks[0]
+		q.tasks[0] = nil
+		q.tasks = q.tasks[1:]
+		q.current = t
+		s.queued.Add(-1)
+		s.wakeLocked(q)
+		started := clock.Now()
+		t.startedAt.Store(started.UnixNano())
+		policy := s.config.PanicPolicy
+		s.mu.Unlock()
+		s.exec(t, policy)
+		metrics.ReportPoolTaskTiming(s.reporters, s.Name(), t.label(), started.Sub(t.enqueuedAt), clock.Since(started))
+	}
+}
+
+// park runner空闲时等待唤醒,超过 config.GoPool.Expire 仍没有新任务时移除lane并返回false
+func (s *StatefulPool) park(laneID int, ln *lane, cfg config.GoPool) bool {
+	if cfg.DisablePurge {
+		// 不回收空闲的线程
+		<-ln.wake
+		return true
+	}
+	// 与ants回收空闲worker一致使用真实时间
+	timer := time.NewTimer(cfg.Expire)
+	defer timer.Stop()
+	select {
+	case <-ln.wake:
+		return true
+	case <-timer.C:
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !ln.parked {
+		// 超时的同时被唤醒,取走唤醒信号
+		<-ln.wake
+		return true
+	}
+	s.removeLaneLocked(laneID)
+	return false
+}
+
+// removeLaneLocked runner结束时移除lane,未分片时释放lane上已没有队列的key
+func (s *StatefulPool) removeLaneLocked(laneID int) {
+	delete(s.lanes, laneID)
+	if s.shards <= 0 && s.queues[laneID] == nil {
+		s.releaseKeyLocked(laneID)
+	}
+}
+
+// wakeLanesLocked 唤醒所有空闲的runner,用于线程池关闭或切换ants线程池后让其结束
+func (s *StatefulPool) wakeLanesLocked() {
+	for _, ln := range s.lanes {
+		ln.wakeLocked()
+	}
+}
+
+// abort runner派发失败,结束lane中所有goID排队中的任务
+func (s *StatefulPool) abort(laneID int, runner *antsPool, err error) {
+	var tasks []*poolTask
+	s.mu.Lock()
+	ln := s.lanes[laneID]
+	s.removeLaneLocked(laneID)
+	for _, goID := range ln.ready {
+		q := s.queues[goID]
+		tasks = append(tasks, q.tasks...)
+		s.queued.Add(-int64(len(q.tasks)))
+		q.tasks = nil
+		q.ready = false
+		s.wakeAllLocked(q)
+		s.cleanLocked(goID, q)
+	}
+	s.mu.Unlock()
+	runner.release()
+	for _, t := range tasks {
+		t.finish(err)
+	}
+}
+
+func (s *StatefulPool) wakeLocked(q *goQueue) {
+	if len(q.waiters) > 0 {
+		close(q.waiters[0])
+		q.waiters = q.waiters[1:]
+	}
+}
+
+// wakeAllLocked 唤醒所有等待者重新检查队列,用于配置变化或线程池关闭
+func (s *StatefulPool) wakeAllLocked(q *goQueue) {
+	for _, w := range q.waiters {
+		close(w)
+	}
+	q.waiters = nil
+}
+
+func (s *StatefulPool) cleanLocked(goID int, q *goQueue) {
+	if len(q.tasks) == 0 && len(q.waiters) == 0 && !q.ready {
+		delete(s.queues, goID)
+		s.releaseKeyLocked(goID)
+		s.checkDrainedLocked()
+	}
+}
+
+// checkDrainedLocked Close 后所有队列都已清空时通知
+func (s *StatefulPool) checkDrainedLocked() {
+	if s.drained == nil || len(s.queues) > 0 {
+		return
+	}
+	select {
+	case <-s.drained:
+	default:
+		close(s.drained)
+	}
+}
+
+// QueueDepth goID排队中的任务数
+//
+//	@receiver s
+//	@param goID
+//	@return int
+func (s *StatefulPool) QueueDepth(goID int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q := s.queues[goID]; q != nil {
+		return len(q.tasks)
+	}
+	return 0
+}
+
+// ReportQueueDepth 上报线程池排队中的任务总数和排队最多的几个goID的任务数
+//
+//	goID的任务数按排名上报到固定的slot标签,不以goID作为标签,避免指标基数随goID增长
+//	@receiver s
+func (s *StatefulPool) ReportQueueDepth() {
+	if len(s.reporters) == 0 {
+		return
+	}
+	s.mu.Lock()
+	depths := make([]int, 0, len(s.queues))
+	for _, q := range s.queues {
+		if len(q.tasks) > 0 {
+			depths = append(depths, len(q.tasks))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(depths)))
+	if len(depths) > reportTopGoIDs {
+		depths = depths[:reportTopGoIDs]
+	}
+	// 上次上报但这次没有goID的slot置0,避免残留
+	slots := s.reported
+	if len(depths) > slots {
+		slots = len(depths)
+	}
+	s.reported = len(depths)
+	s.mu.Unlock()
+
+	metrics.ReportPoolQueueDepth(s.reporters, s.Name(), s.queued.Load())
+	for slot := 0; slot < slots; slot++ {
+		depth := 0
+		if slot < len(depths) {
+			depth = depths[slot]
+		}
+		metrics.ReportPoolGoIDQueueDepth(s.reporters, s.Name(), slot, depth)
+	}
+}
@@ -0,0 +1,176 @@
+package co
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	"github.com/alkaid/goerrors/errors"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"github.com/topfreegames/pitaya/v2/metrics"
+	"go.uber.org/zap"
+)
+
+// 任务panic后的处理策略 config.PanicConfig.Policy config.GoPool.PanicPolicy
+const (
+	PanicLog         = "log"         // 记录日志、指标并上报后继续执行后续任务
+	PanicKillSession = "killSession" // 同 PanicLog,并踢下任务所属的session,ctx中没有session时同 PanicLog
+	PanicCrash       = "crash"       // 记录日志、指标并上报后退出进程
+)
+
+// StatelessGoPoolName 无状态线程池 Go 的名称,仅用于 PanicInfo 和指标
+const StatelessGoPoolName = "stateless"
+
+// PanicInfo 任务panic的现场
+type PanicInfo struct {
+	Pool   string // 线程池名,无状态线程池为 StatelessGoPoolName
+	GoID   int
+	Key    string // 字符串key,为空时按goID派发
+	Task   string // 任务名,依次取 WithTaskName、路由、任务函数名
+	Route  string // 提交任务的请求路由
+	UID    string // ctx中session的uid
+	Policy string // 处理策略
+	Value  any    // recover()的返回值
+	Stack  string // panic时的调用栈
+}
+
+// CrashReporter 任务panic的上报,如上报到sentry.在执行任务的goroutine中同步调用,不应阻塞
+type CrashReporter interface {
+	ReportPanic(ctx context.Context, info PanicInfo)
+}
+
+// SessionKiller 踢下任务所属的session, PanicKillSession 策略时在新的goroutine中调用, pitaya.App 启动时设置
+//
+//	@param ctx 提交任务的ctx,包含 constants.SessionCtxKey
+//	@param info
+//	@return error
+type SessionKiller func(ctx context.Context, info PanicInfo) error
+
+var recovery = struct {
+	mu        sync.RWMutex
+	policy    string
+	reporters []CrashReporter
+	killer    SessionKiller
+}{policy: PanicLog}
+
+// exit PanicCrash 策略退出进程,退出码与未恢复的panic一致
+var exit = func() {
+	_ = logger.Zap.Sync()
+	os.Exit(2)
+}
+
+func checkPanicPolicy(policy string) error {
+	switch policy {
+	case PanicLog, PanicKillSession, PanicCrash:
+		return nil
+	}
+	return errors.NewWithStack(fmt.Sprintf("unknown panic policy %s", policy))
+}
+
+// SetPanicPolicy 设置任务panic后的默认处理策略,线程池配置了 config.GoPool.PanicPolicy 时以线程池配置为准
+//
+//	@param policy PanicLog PanicKillSession PanicCrash
+//	@return error
+func SetPanicPolicy(policy string) error {
+	if err := checkPanicPolicy(policy); err != nil {
+		return err
+	}
+	recovery.mu.Lock()
+	recovery.policy = policy
+	recovery.mu.Unlock()
+	return nil
+}
+
+// AddCrashReporter 添加任务panic的上报
+//
+//	@param reporter
+func AddCrashReporter(reporter CrashReporter) {
+	recovery.mu.Lock()
+	recovery.reporters = append(recovery.reporters, reporter)
+	recovery.mu.Unlock()
+}
+
+// SetSessionKiller 设置 PanicKillSession 策略踢下session的方式
+//
+//	@param killer
+func SetSessionKiller(killer SessionKiller) {
+	recovery.mu.Lock()
+	recovery.killer = killer
+	recovery.mu.Unlock()
+}
+
+// handlePanic 按策略处理任务的panic,须在recover后调用
+//
+//	@param ctx 提交任务的ctx
+//	@param logg
+//	@param reporters 指标上报
+//	@param policy 为空时使用 SetPanicPolicy 设置的策略
+//	@param info
+func handlePanic(ctx context.Context, logg *zap.Logger, reporters []metrics.Reporter, policy string, info PanicInfo) {
+	recovery.mu.RLock()
+	if policy == "" {
+		policy = recovery.policy
+	}
+	crashReporters, killer := recovery.reporters, recovery.killer
+	recovery.mu.RUnlock()
+	info.Policy = policy
+	sess := ctx.Value(constants.SessionCtxKey)
+	if s, ok := sess.(interface{ UID() string }); ok {
+		info.UID = s.UID()
+	}
+	logg.Error("goroutine task panic", zap.Any("panic", info.Value), zap.String("policy", policy),
+		zap.String("task", info.Task), zap.String("route", info.Route), zap.String("uid", info.UID), zap.String("stack", info.Stack))
+	metrics.ReportPoolPanic(reporters, info.Pool, policy)
+	for _, r := range crashReporters {
+		reportPanic(ctx, logg, r, info)
+	}
+	switch policy {
+	case PanicKillSession:
+		if sess == nil {
+			return
+		}
+		if killer == nil {
+			logg.Warn("panic policy killSession without session killer")
+			return
+		}
+		// 异步踢下,避免session关闭时等待正在panic的线程
+		Go(func() {
+			if err := killer(ctx, info); err != nil {
+				logg.Error("kill session after panic error", zap.Error(err))
+			}
+		})
+	case PanicCrash:
+		exit()
+	}
+}
+
+func reportPanic(ctx context.Context, logg *zap.Logger, r CrashReporter, info PanicInfo) {
+	defer func() {
+		if e := recover(); e != nil {
+			logg.Error("crash reporter panic", zap.Any("panic", e), zap.Stack("stack"))
+		}
+	}()
+	r.ReportPanic(ctx, info)
+}
+
+// goSafe 执行无状态线程池的任务,panic时按默认策略处理
+func goSafe(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			var reporters []metrics.Reporter
+			if instance != nil {
+				reporters = instance.reporters
+			}
+			handlePanic(context.Background(), logger.Zap, reporters, "", PanicInfo{
+				Pool:  StatelessGoPoolName,
+				Task:  funcName(task),
+				Value: r,
+				Stack: string(debug.Stack()),
+			})
+		}
+	}()
+	task()
+}
@@ -0,0 +1,137 @@
+package co
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/metrics"
+	"github.com/topfreegames/pitaya/v2/metrics/mocks"
+)
+
+type panicRecorder chan PanicInfo
+
+func (r panicRecorder) ReportPanic(ctx context.Context, info PanicInfo) {
+	r <- info
+}
+
+type uidSession string
+
+func (s uidSession) UID() string {
+	return string(s)
+}
+
+func useRecovery(t *testing.T) panicRecorder {
+	recovery.mu.Lock()
+	old := recovery.policy
+	recovery.policy = PanicLog
+	recovery.reporters = nil
+	recovery.killer = nil
+	recovery.mu.Unlock()
+	oldExit := exit
+	t.Cleanup(func() {
+		recovery.mu.Lock()
+		recovery.policy = old
+		recovery.reporters = nil
+		recovery.killer = nil
+		recovery.mu.Unlock()
+		exit = oldExit
+	})
+	recorder := make(panicRecorder, 1)
+	AddCrashReporter(recorder)
+	return recorder
+}
+
+func TestStatefulPoolPanicPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string // 线程池配置
+		global     string // SetPanicPolicy
+		withSess   bool
+		wantPolicy string
+		wantKill   bool
+		wantExit   bool
+	}{
+		{name: "log", wantPolicy: PanicLog},
+		{name: "killSession", policy: PanicKillSession, withSess: true, wantPolicy: PanicKillSession, wantKill: true},
+		{name: "killSessionWithoutSession", policy: PanicKillSession, wantPolicy: PanicKillSession},
+		{name: "crash", global: PanicCrash, wantPolicy: PanicCrash, wantExit: true},
+		{name: "poolOverridesGlobal", policy: PanicLog, global: PanicCrash, wantPolicy: PanicLog},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := useRecovery(t)
+			if tt.global != "" {
+				require.NoError(t, SetPanicPolicy(tt.global))
+			}
+			killed := make(chan string, 1)
+			SetSessionKiller(func(ctx context.Context, info PanicInfo) error {
+				killed <- info.UID
+				return nil
+			})
+			exited := make(chan struct{}, 1)
+			exit = func() { exited <- struct{}{} }
+
+			ctrl := gomock.NewController(t)
+			reporter := mocks.NewMockReporter(ctrl)
+			reporter.EXPECT().ReportHistogram(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			reporter.EXPECT().ReportCount(metrics.PoolPanics, map[string]string{"pool": "panic", "policy": tt.wantPolicy}, float64(1))
+			p, err := NewStatefulPool(config.GoPool{Name: "panic", PanicPolicy: tt.policy, DisableTimeoutWatch: true}, []metrics.Reporter{reporter})
+			require.NoError(t, err)
+
+			ctx := ContextWithTaskName(context.Background(), "boom")
+			if tt.withSess {
+				ctx = context.WithValue(ctx, constants.SessionCtxKey, uidSession("u1"))
+			}
+			<-p.Go(ctx, 1, func(ctx context.Context) { panic("boom") }, true)
+			ran := false
+			<-p.Go(ctx, 1, func(ctx context.Context) { ran = true }, true)
+			assert.True(t, ran)
+
+			info := <-recorder
+			assert.Equal(t, "panic", info.Pool)
+			assert.Equal(t, 1, info.GoID)
+			assert.Equal(t, "boom", info.Task)
+			assert.Equal(t, "boom", info.Value)
+			assert.Equal(t, tt.wantPolicy, info.Policy)
+			assert.Contains(t, info.Stack, "TestStatefulPoolPanicPolicy")
+			if tt.withSess {
+				assert.Equal(t, "u1", info.UID)
+			}
+			if tt.wantKill {
+				assert.Equal(t, "u1", <-killed)
+			} else {
+				assert.Empty(t, killed)
+			}
+			if tt.wantExit {
+				assert.Len(t, exited, 1)
+			} else {
+				assert.Empty(t, exited)
+			}
+		})
+	}
+}
+
+func TestGoPanic(t *testing.T) {
+	recorder := useRecovery(t)
+	old := instance
+	instance = nil
+	t.Cleanup(func() { instance = old })
+
+	Go(func() { panic("boom") })
+	info := <-recorder
+	assert.Equal(t, StatelessGoPoolName, info.Pool)
+	assert.Equal(t, PanicLog, info.Policy)
+	assert.Contains(t, info.Task, "TestGoPanic.func")
+}
+
+func TestPanicPolicyValidation(t *testing.T) {
+	useRecovery(t)
+	assert.Error(t, SetPanicPolicy("ignore"))
+	_, err := NewStatefulPool(config.GoPool{Name: "panic", PanicPolicy: "ignore"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+package co
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/topfreegames/pitaya/v2/clock"
+	"github.com/topfreegames/pitaya/v2/constants"
+	pcontext "github.com/topfreegames/pitaya/v2/context"
+	"go.uber.org/zap"
+)
+
+type taskNameKey struct{}
+
+// ContextWithTaskName 为之后提交的任务命名,用于超时日志、任务列表和耗时指标.
+// 适用于不接受 Option 的入口,如 session.SessPublic.Go 和 GoWithUser
+//
+//	@param ctx
+//	@param name
+//	@return context.Context
+func ContextWithTaskName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, taskNameKey{}, name)
+}
+
+// TaskInfo 排队中或执行中的任务
+type TaskInfo struct {
+	Pool       string        `json:"pool"`
+	GoID       int           `json:"goID"`
+	Key        string        `json:"key"`        // 字符串key,为空时按goID派发
+	Name       string        `json:"name"`       // 任务名 WithTaskName
+	Route      string        `json:"route"`      // 提交任务的请求路由
+	Caller     string        `json:"caller"`     // 任务函数名
+	Running    bool          `json:"running"`    // 是否执行中,否则为排队中
+	EnqueuedAt time.Time     `json:"enqueuedAt"` // 提交时间
+	Wait       time.Duration `json:"wait"`       // 排队时长,执行中的任务为开始执行前的排队时长
+	Elapsed    time.Duration `json:"elapsed"`    // 执行时长,排队中为0
+}
+
+func newPoolTask(ctx context.Context, goID int, key string, task func(ctx context.Context), logg *zap.Logger) *poolTask {
+	t := &poolTask{
+		ctx:        ctx,
+		run:        func() { task(ctx) },
+		done:       make(chan struct{}),
+		logg:       logg,
+		goID:       goID,
+		key:        key,
+		caller:     funcName(task),
+		enqueuedAt: clock.Now(),
+	}
+	t.name, _ = ctx.Value(taskNameKey{}).(string)
+	t.route, _ = pcontext.GetFromPropagateCtx(ctx, constants.RouteKey).(string)
+	return t
+}
+
+// funcName 函数的完整名称,如 github.com/topfreegames/pitaya/v2/co.TestXxx.func1
+func funcName(f any) string {
+	if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return ""
+}
+
+func (t *poolTask) info(pool string, now time.Time) TaskInfo {
+	info := TaskInfo{
+		Pool:       pool,
+		GoID:       t.goID,
+		Key:        t.key,
+		Name:       t.name,
+		Route:      t.route,
+		Caller:     t.caller,
+		EnqueuedAt: t.enqueuedAt,
+		Wait:       now.Sub(t.enqueuedAt),
+	}
+	if started := t.startedAt.Load(); started > 0 {
+		startedAt := time.Unix(0, started)
+		info.Running = true
+		info.Wait = startedAt.Sub(t.enqueuedAt)
+		info.Elapsed = now.Sub(startedAt)
+	}
+	return info
+}
+
+// Tasks 执行中和排队中的任务
+//
+//	@receiver s
+//	@return []TaskInfo 按提交时间排序,最早提交的在前
+func (s *StatefulPool) Tasks() []TaskInfo {
+	now := clock.Now()
+	s.mu.Lock()
+	tasks := make([]TaskInfo, 0, len(s.queues)+int(s.queued.Load()))
+	for _, q := range s.queues {
+		if q.current != nil {
+			tasks = append(tasks, q.current.info(s.name, now))
+		}
+		for _, t := range q.tasks {
+			tasks = append(tasks, t.info(s.name, now))
+		}
+	}
+	s.mu.Unlock()
+	sortTasks(tasks)
+	return tasks
+}
+
+func sortTasks(tasks []TaskInfo) {
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].EnqueuedAt.Before(tasks[j].EnqueuedAt) })
+}
+
+// Tasks 所有线程池执行中和排队中的任务
+//
+//	@receiver p
+//	@return []TaskInfo 按提交时间排序,最早提交的在前
+func (p *StatefulPoolsModule) Tasks() []TaskInfo {
+	p.mu.RLock()
+	pools := make([]*StatefulPool, 0, len(p.pools))
+	for _, pool := range p.pools {
+		pools = append(pools, pool)
+	}
+	p.mu.RUnlock()
+	tasks := make([]TaskInfo, 0)
+	for _, pool := range pools {
+		tasks = append(tasks, pool.Tasks()...)
+	}
+	sortTasks(tasks)
+	return tasks
+}
+
+// Dump 输出所有线程池的任务列表和所有goroutine的调用栈,用于排查卡住的任务
+//
+//	@receiver p
+//	@param w
+func (p *StatefulPoolsModule) Dump(w io.Writer) {
+	tasks := p.Tasks()
+	fmt.Fprintf(w, "=== goroutine pool tasks (%d) ===\n", len(tasks))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "POOL\tGOID\tKEY\tSTATE\tWAIT\tELAPSED\tNAME\tROUTE\tCALLER")
+	for _, t := range tasks {
+		state := "queued"
+		if t.Running {
+			state = "running"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.Pool, t.GoID, t.Key, state, t.Wait, t.Elapsed, t.Name, t.Route, t.Caller)
+	}
+	_ = tw.Flush()
+	fmt.Fprintln(w, "=== goroutine stacks ===")
+	_ = pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// Dump 输出所有线程池的任务列表和所有goroutine的调用栈,线程池模块未创建时只输出调用栈
+//
+//	@param w
+func Dump(w io.Writer) {
+	if instance == nil {
+		fmt.Fprintln(w, "=== goroutine stacks ===")
+		_ = pprof.Lookup("goroutine").WriteTo(w, 2)
+		return
+	}
+	instance.Dump(w)
+}
@@ -0,0 +1,66 @@
+package co
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	pcontext "github.com/topfreegames/pitaya/v2/context"
+	"github.com/topfreegames/pitaya/v2/helpers"
+	"github.com/topfreegames/pitaya/v2/metrics"
+	"github.com/topfreegames/pitaya/v2/metrics/mocks"
+)
+
+func TestStatefulPoolTasks(t *testing.T) {
+	c := helpers.UseFakeClock(t, time.Now())
+	ctrl := gomock.NewController(t)
+	reporter := mocks.NewMockReporter(ctrl)
+	p, err := NewStatefulPool(config.GoPool{Name: "trace", DisableTimeoutWatch: true}, []metrics.Reporter{reporter})
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ctx := pcontext.AddToPropagateCtx(context.Background(), constants.RouteKey, "room.room.join")
+	first := p.Go(ContextWithTaskName(ctx, "join"), 1, func(ctx context.Context) {
+		close(started)
+		<-release
+	}, true)
+	<-started
+	second := p.Go(ContextWithTaskName(context.Background(), "save"), 1, func(ctx context.Context) {}, true)
+	c.Advance(2 * time.Second)
+
+	tasks := p.Tasks()
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "join", tasks[0].Name)
+	assert.Equal(t, "room.room.join", tasks[0].Route)
+	assert.Contains(t, tasks[0].Caller, "TestStatefulPoolTasks.func1")
+	assert.True(t, tasks[0].Running)
+	assert.Equal(t, 2*time.Second, tasks[0].Elapsed)
+	assert.Equal(t, "save", tasks[1].Name)
+	assert.False(t, tasks[1].Running)
+	assert.Equal(t, 2*time.Second, tasks[1].Wait)
+
+	var buf bytes.Buffer
+	old := instance
+	instance = &StatefulPoolsModule{pools: map[string]*StatefulPool{"trace": p}}
+	t.Cleanup(func() { instance = old })
+	Dump(&buf)
+	assert.Contains(t, buf.String(), "running")
+	assert.Contains(t, buf.String(), "room.room.join")
+	assert.Contains(t, buf.String(), "=== goroutine stacks ===")
+
+	reporter.EXPECT().ReportHistogram(metrics.PoolTaskWait, map[string]string{"pool": "trace", "task": "join"}, float64(0))
+	reporter.EXPECT().ReportHistogram(metrics.PoolTaskExec, map[string]string{"pool": "trace", "task": "join"}, float64(2000))
+	reporter.EXPECT().ReportHistogram(metrics.PoolTaskWait, map[string]string{"pool": "trace", "task": "save"}, float64(2000))
+	reporter.EXPECT().ReportHistogram(metrics.PoolTaskExec, map[string]string{"pool": "trace", "task": "save"}, float64(0))
+	close(release)
+	<-first
+	<-second
+	helpers.ShouldEventuallyReturn(t, func() int { return len(p.Tasks()) }, 0)
+}
@@ -30,6 +30,8 @@ type (
 		SubscriberGroup  string                                                    // 订阅消费组
 		ReceiverProvider func(ctx context.Context) Component                       // 延迟绑定的receiver实例
 		TaskGoProvider   func(ctx context.Context, task func(ctx context.Context)) // 异步任务派发线程提供者
+		APIVersion       [2]int                                                    // 组件所有路由的接口版本区间 [min, max]
+		MethodAPIVersion map[string][2]int                                         // 单个方法的接口版本区间,优先于 APIVersion
 	}
 
 	// Option used to customize handler
@@ -90,3 +92,29 @@ func WithTaskGoProvider(taskGoProvider func(ctx context.Context, task func(ctx c
 		opt.TaskGoProvider = taskGoProvider
 	}
 }
+
+// WithAPIVersion 声明组件所有路由的接口版本区间,调用方只会路由到版本有交集的服务
+//
+//	@param min
+//	@param max 为0表示没有上限
+//	@return Option
+func WithAPIVersion(min, max int) Option {
+	return func(opt *options) {
+		opt.APIVersion = [2]int{min, max}
+	}
+}
+
+// WithMethodAPIVersion 声明单个方法的接口版本区间,优先于 WithAPIVersion
+//
+//	@param method 注册后的方法名
+//	@param min
+//	@param max 为0表示没有上限
+//	@return Option
+func WithMethodAPIVersion(method string, min, max int) Option {
+	return func(opt *options) {
+		if opt.MethodAPIVersion == nil {
+			opt.MethodAPIVersion = make(map[string][2]int)
+		}
+		opt.MethodAPIVersion[method] = [2]int{min, max}
+	}
+}
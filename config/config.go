@@ -11,8 +11,10 @@ import (
 type PitayaAll struct {
 	PitayaConfig `mapstructure:",squash"`
 	Cluster      struct {
-		Info InfoRetrieverConfig
-		Rpc  struct {
+		Info           InfoRetrieverConfig
+		ConsistentHash ConsistentHashConfig
+		APIVersion     APIVersionConfig
+		Rpc            struct {
 			Client struct {
 				Grpc GRPCClientConfig
 				Nats NatsRPCClientConfig
@@ -23,7 +25,11 @@ type PitayaAll struct {
 			}
 		}
 		Sd struct {
-			Etcd EtcdServiceDiscoveryConfig
+			Type   string // 服务发现类型 etcd|static|dns,默认etcd
+			Etcd   EtcdServiceDiscoveryConfig
+			Static StaticServiceDiscoveryConfig
+			Dns    DNSServiceDiscoveryConfig
+			Nats   NatsServiceDiscoveryConfig
 		}
 	}
 	DefaultPipelines struct {
@@ -49,6 +55,8 @@ type PitayaAll struct {
 		BindingStorage struct {
 			Etcd ETCDBindingConfig
 		}
+		SessionEvents SessionEventsConfig
+		Admin         AdminConfig
 	}
 	Conn struct {
 		RateLimiting RateLimitingConfig
@@ -91,19 +99,59 @@ type PitayaConfig struct {
 		Unique bool
 		// CacheTTL 缓存过期时间
 		CacheTTL time.Duration
+		// MigrateTimeout 迁移到其他网关时,等待客户端重连的最长时间,超时后源网关强制踢出session
+		MigrateTimeout time.Duration
 	}
 	Metrics struct {
 		Period time.Duration
 	}
+	Push struct {
+		// AckTimeout PushWithAck 等待客户端确认的超时时间,超时后重发
+		AckTimeout time.Duration
+		// AckRetries PushWithAck 最大重发次数
+		AckRetries int
+	}
 	Acceptor struct {
 		ProxyProtocol bool
 	}
+	HealthCheck struct {
+		// Interval 模块健康检查间隔,结果决定服务状态 ready 或 unhealthy,<=0时只在启动时检查
+		Interval time.Duration
+	}
 	ConfSource ConfSource // 配置源
 	Log        struct {
 		Development bool   // 是否开发模式
 		Level       string // 日志等级
 	}
-	GoPools map[string]GoPool // 有状态线程池配置
+	GoPools   map[string]GoPool // 有状态线程池配置
+	Inbox     InboxConfig       // 离线消息收件箱
+	DelayTask DelayTaskConfig   // 持久化延迟任务
+	Panic     PanicConfig       // 线程池任务panic处理
+}
+
+// InboxConfig 离线消息收件箱配置
+type InboxConfig struct {
+	Enabled   bool          // 是否启用,启用后持久化推送在用户离线时存入收件箱,Bind时重放
+	Retention time.Duration // 消息保留时长
+	MaxSize   int           // 每个用户最多保留的消息数,超出时丢弃最早的消息,<=0不限制
+}
+
+// PanicConfig 线程池任务panic处理配置
+type PanicConfig struct {
+	Policy string // panic后的处理策略 log killSession crash,默认log,可被 GoPool.PanicPolicy 覆盖
+}
+
+// DelayTaskConfig 持久化延迟任务配置
+type DelayTaskConfig struct {
+	Enabled       bool          // 是否启用,启用后任务存储在redis,重启不丢失
+	Namespace     string        // 任务队列的命名空间,同一命名空间的服务共同消费,为空时使用服务类型
+	PollInterval  time.Duration // 拉取到期任务的间隔
+	BatchSize     int           // 每次拉取的最大任务数
+	Lease         time.Duration // 任务领取后的租约,超时未确认时由其他消费者重新执行,也是单次执行的超时时间
+	Concurrency   int           // 同时执行的最大任务数
+	MaxAttempts   int           // 最大尝试次数,超过后丢弃,<=0不限制
+	RetryMinDelay time.Duration // 失败重试的最小间隔,按指数递增
+	RetryMaxDelay time.Duration // 失败重试的最大间隔
 }
 
 type ConfSource struct {
@@ -121,10 +169,14 @@ type GoPool struct {
 	Name                string          // 线程池名
 	Expire              time.Duration   // 线程池回收不使用的goroutine的间隔时长
 	DisablePurge        bool            // 是否禁止超时回收
-	DisablePurgeRunning bool            // 是否禁止回收有任务运行中的线程(即使超时)
+	DisablePurgeRunning bool            // 是否禁止回收有任务运行中的线程(即使超时),StatefulPool 的runner执行中或空闲未超过Expire时不会被回收
 	TaskBuffer          int             // 每个worker的队列长度
+	Shards              int             // 分片数,大于0时固定使用Shards个线程,goID按哈希分到各分片,同一goID的任务仍按顺序执行,修改需重启
+	Overflow            string          // 队列满时的策略 block reject dropOldest,默认block
+	BlockTimeout        time.Duration   // block策略等待队列空位的最长时间,0为一直等待
 	DisableTimeoutWatch bool            // 是否禁用监控超时
 	TimeoutBuckets      []time.Duration // 监控项
+	PanicPolicy         string          // 任务panic后的处理策略 log killSession crash,为空时使用 PanicConfig.Policy
 }
 
 // NewDefaultPitayaConfig provides default configuration for Pitaya App
@@ -178,22 +230,36 @@ func NewDefaultPitayaConfig() *PitayaConfig {
 			},
 		},
 		Session: struct {
-			Unique   bool
-			CacheTTL time.Duration
+			Unique         bool
+			CacheTTL       time.Duration
+			MigrateTimeout time.Duration
 		}{
-			Unique:   true,
-			CacheTTL: time.Hour * 24 * 3,
+			Unique:         true,
+			CacheTTL:       time.Hour * 24 * 3,
+			MigrateTimeout: 30 * time.Second,
 		},
 		Metrics: struct {
 			Period time.Duration
 		}{
 			Period: 15 * time.Second,
 		},
+		Push: struct {
+			AckTimeout time.Duration
+			AckRetries int
+		}{
+			AckTimeout: 5 * time.Second,
+			AckRetries: 3,
+		},
 		Acceptor: struct {
 			ProxyProtocol bool
 		}{
 			ProxyProtocol: false,
 		},
+		HealthCheck: struct {
+			Interval time.Duration
+		}{
+			Interval: 5 * time.Second,
+		},
 		ConfSource: ConfSource{
 			Interval: 5 * time.Minute,
 		},
@@ -202,6 +268,22 @@ func NewDefaultPitayaConfig() *PitayaConfig {
 			Level       string
 		}{Development: false, Level: "ERROR"},
 		GoPools: map[string]GoPool{},
+		Inbox: InboxConfig{
+			Retention: 7 * 24 * time.Hour,
+			MaxSize:   100,
+		},
+		DelayTask: DelayTaskConfig{
+			PollInterval:  time.Second,
+			BatchSize:     100,
+			Lease:         30 * time.Second,
+			Concurrency:   16,
+			MaxAttempts:   10,
+			RetryMinDelay: time.Second,
+			RetryMaxDelay: 10 * time.Minute,
+		},
+		Panic: PanicConfig{
+			Policy: "log",
+		},
 	}
 }
 
@@ -443,6 +525,12 @@ type EtcdServiceDiscoveryConfig struct {
 		Enable bool   // 是否开启
 		Name   string // 岗位名称
 	}
+	// 服务列表快照,etcd不可用时冻结拓扑沿用最后一次同步成功的服务列表
+	Snapshot struct {
+		Enable       bool          // 是否开启,关闭时etcd租约丢失后按原逻辑退出
+		File         string        // 快照落盘路径,为空时不落盘,启动时etcd不可用则从该文件加载
+		MaxStaleness time.Duration // 冻结拓扑的最长时间,超过后退出,<=0时不限制
+	}
 }
 
 type RedisConfig struct {
@@ -613,6 +701,13 @@ func NewDefaultEtcdServiceDiscoveryConfig() *EtcdServiceDiscoveryConfig {
 			Enable bool
 			Name   string
 		}{},
+		Snapshot: struct {
+			Enable       bool
+			File         string
+			MaxStaleness time.Duration
+		}{
+			MaxStaleness: time.Duration(10 * time.Minute),
+		},
 	}
 }
 
@@ -625,6 +720,188 @@ func NewEtcdServiceDiscoveryConfig(config *Config) *EtcdServiceDiscoveryConfig {
 	return conf
 }
 
+// HashRingConfig 一致性哈希环配置
+type HashRingConfig struct {
+	Replicas   int           // 每个节点的虚拟节点数,按节点权重缩放
+	LoadFactor float64       // 有界负载系数,>1时开启,节点分配的key数不超过平均值的 LoadFactor 倍.负载按进程统计,各服务的选择可能不同,session粘性的类型不支持
+	LoadTTL    time.Duration // 有界负载下key无访问后保留分配的时长
+}
+
+// ConsistentHashConfig 一致性哈希配置,Types 中未配置的服务类型使用 Default
+type ConsistentHashConfig struct {
+	Default HashRingConfig
+	Types   map[string]HashRingConfig
+}
+
+// APIVersionConfig 当前服务支持的接口版本区间 [Min, Max],用于滚动升级时只路由到版本兼容的服务
+//
+//	均为0表示未声明,与任意版本兼容;Max为0表示没有上限
+type APIVersionConfig struct {
+	Min int
+	Max int
+}
+
+// NewAPIVersionConfig api version config with default config paths
+func NewAPIVersionConfig(config *Config) *APIVersionConfig {
+	conf := &APIVersionConfig{}
+	if err := config.UnmarshalKey("pitaya.cluster.apiversion", &conf); err != nil {
+		panic(err)
+	}
+	return conf
+}
+
+// NewDefaultConsistentHashConfig consistent hash default config
+func NewDefaultConsistentHashConfig() *ConsistentHashConfig {
+	return &ConsistentHashConfig{
+		Default: HashRingConfig{
+			Replicas: 100,
+			LoadTTL:  time.Duration(10 * time.Minute),
+		},
+		Types: map[string]HashRingConfig{},
+	}
+}
+
+// NewConsistentHashConfig consistent hash config with default config paths
+func NewConsistentHashConfig(config *Config) *ConsistentHashConfig {
+	conf := NewDefaultConsistentHashConfig()
+	if err := config.UnmarshalKey("pitaya.cluster.consistenthash", &conf); err != nil {
+		panic(err)
+	}
+	return conf
+}
+
+// Ring 获取服务类型的哈希环配置
+//
+//	@receiver c
+//	@param serverType
+//	@return HashRingConfig
+func (c *ConsistentHashConfig) Ring(serverType string) HashRingConfig {
+	if conf, ok := c.Types[serverType]; ok {
+		return conf
+	}
+	return c.Default
+}
+
+// 服务发现类型
+const (
+	ServiceDiscoveryEtcd   = "etcd"
+	ServiceDiscoveryStatic = "static"
+	ServiceDiscoveryDNS    = "dns"
+	ServiceDiscoveryNats   = "nats"
+)
+
+// NewServiceDiscoveryType reads the service discovery type from config
+func NewServiceDiscoveryType(config *Config) string {
+	t := config.GetString("pitaya.cluster.sd.type")
+	if t == "" {
+		return ServiceDiscoveryEtcd
+	}
+	return t
+}
+
+// StaticServiceDiscoveryConfig static file service discovery config
+type StaticServiceDiscoveryConfig struct {
+	File          string        // 服务列表文件,按扩展名解析 yaml/yml/json
+	WatchInterval time.Duration // 检查文件变更的间隔,<=0时不监听变更
+}
+
+// NewDefaultStaticServiceDiscoveryConfig static file service discovery default config
+func NewDefaultStaticServiceDiscoveryConfig() *StaticServiceDiscoveryConfig {
+	return &StaticServiceDiscoveryConfig{
+		File:          "./servers.yaml",
+		WatchInterval: time.Duration(5 * time.Second),
+	}
+}
+
+// NewStaticServiceDiscoveryConfig static file service discovery config with default config paths
+func NewStaticServiceDiscoveryConfig(config *Config) *StaticServiceDiscoveryConfig {
+	conf := NewDefaultStaticServiceDiscoveryConfig()
+	if err := config.UnmarshalKey("pitaya.cluster.sd.static", &conf); err != nil {
+		panic(err)
+	}
+	return conf
+}
+
+// DNSService 一个服务类型对应的SRV记录
+type DNSService struct {
+	Type              string            // 服务类型
+	Name              string            // SRV记录名 如 _game._tcp.example.com
+	Frontend          bool              // 是否为前端服务
+	SessionStickiness bool              // 是否开启session粘性
+	Metadata          map[string]string // 附加到该类型所有服务的metadata
+}
+
+// DNSServiceDiscoveryConfig dns srv service discovery config
+type DNSServiceDiscoveryConfig struct {
+	Services []DNSService
+	Interval time.Duration // 轮询SRV记录的间隔
+	Timeout  time.Duration // 单次查询超时
+	Resolver string        // 自定义DNS服务器 host:port,为空时使用系统默认
+	// ServerID 当前服务在SRV记录中的ID target:port,如 game-0.game.default.svc.cluster.local:3434,配置后替换生成的服务ID;
+	// 为空时保留生成的ID,按 metadata 中的 grpcHost、grpcPort 识别当前服务的记录
+	ServerID string
+}
+
+// NewDefaultDNSServiceDiscoveryConfig dns srv service discovery default config
+func NewDefaultDNSServiceDiscoveryConfig() *DNSServiceDiscoveryConfig {
+	return &DNSServiceDiscoveryConfig{
+		Services: []DNSService{},
+		Interval: time.Duration(10 * time.Second),
+		Timeout:  time.Duration(3 * time.Second),
+	}
+}
+
+// NewDNSServiceDiscoveryConfig dns srv service discovery config with default config paths
+func NewDNSServiceDiscoveryConfig(config *Config) *DNSServiceDiscoveryConfig {
+	conf := NewDefaultDNSServiceDiscoveryConfig()
+	if err := config.UnmarshalKey("pitaya.cluster.sd.dns", &conf); err != nil {
+		panic(err)
+	}
+	return conf
+}
+
+// NatsServiceDiscoveryConfig nats jetstream kv service discovery config
+type NatsServiceDiscoveryConfig struct {
+	Connect                string
+	MaxReconnectionRetries int
+	ConnectionTimeout      time.Duration
+	Bucket                 string // kv bucket名
+	Replicas               int    // bucket副本数
+	Heartbeat              struct {
+		TTL time.Duration // 服务记录有效期,超时未续期视为下线
+	}
+	SyncServers struct {
+		Interval time.Duration // 全量同步间隔,<=0时不全量同步,过期的服务由心跳时清理
+	}
+	Election struct {
+		Enable bool   // 是否开启选举
+		Name   string // 竞选名,为空时为server.Type
+	}
+}
+
+// NewDefaultNatsServiceDiscoveryConfig nats jetstream kv service discovery default config
+func NewDefaultNatsServiceDiscoveryConfig() *NatsServiceDiscoveryConfig {
+	conf := &NatsServiceDiscoveryConfig{
+		Connect:                "nats://localhost:4222",
+		MaxReconnectionRetries: 15,
+		ConnectionTimeout:      time.Duration(2 * time.Second),
+		Bucket:                 "pitaya_sd",
+		Replicas:               1,
+	}
+	conf.Heartbeat.TTL = time.Duration(30 * time.Second)
+	conf.SyncServers.Interval = time.Duration(30 * time.Second)
+	return conf
+}
+
+// NewNatsServiceDiscoveryConfig nats jetstream kv service discovery config with default config paths
+func NewNatsServiceDiscoveryConfig(config *Config) *NatsServiceDiscoveryConfig {
+	conf := NewDefaultNatsServiceDiscoveryConfig()
+	if err := config.UnmarshalKey("pitaya.cluster.sd.nats", &conf); err != nil {
+		panic(err)
+	}
+	return conf
+}
+
 // NewDefaultCustomMetricsSpec returns an empty *CustomMetricsSpec
 func NewDefaultCustomMetricsSpec() *models.CustomMetricsSpec {
 	return &models.CustomMetricsSpec{
@@ -852,6 +1129,41 @@ func NewETCDBindingConfig(config *Config) *ETCDBindingConfig {
 	return conf
 }
 
+// SessionEventsConfig provides configuration for SessionEventEmitter
+type SessionEventsConfig struct {
+	BufferSize     int           // 待发布事件的缓冲区大小
+	EnqueueTimeout time.Duration // 缓冲区满时session回调等待的最长时间,超时后丢弃事件,为0时立即丢弃
+	RetryInterval  time.Duration // 发布失败后的重试间隔
+	Nats           struct {
+		Connect        string        // nats地址
+		Subject        string        // 发布的subject,实际subject为 Subject.事件类型
+		JetStream      bool          // 是否使用JetStream发布,启用后由服务端确认并按事件ID去重
+		PublishTimeout time.Duration // 单次发布等待确认的超时时间
+	}
+}
+
+// NewDefaultSessionEventsConfig provides default configuration for SessionEventEmitter
+func NewDefaultSessionEventsConfig() *SessionEventsConfig {
+	conf := &SessionEventsConfig{
+		BufferSize:     4096,
+		EnqueueTimeout: 100 * time.Millisecond,
+		RetryInterval:  time.Second,
+	}
+	conf.Nats.Connect = "nats://localhost:4222"
+	conf.Nats.Subject = "pitaya.sessionevents"
+	conf.Nats.PublishTimeout = 5 * time.Second
+	return conf
+}
+
+// NewSessionEventsConfig reads from config to build SessionEventEmitter configuration
+func NewSessionEventsConfig(config *Config) *SessionEventsConfig {
+	conf := NewDefaultSessionEventsConfig()
+	if err := config.UnmarshalKey("pitaya.modules.sessionevents", &conf); err != nil {
+		panic(err)
+	}
+	return conf
+}
+
 // RateLimitingConfig rate limits config
 type RateLimitingConfig struct {
 	Limit        int
@@ -876,3 +1188,30 @@ func NewRateLimitingConfig(config *Config) *RateLimitingConfig {
 	}
 	return conf
 }
+
+// AdminConfig provides configuration for the admin HTTP module
+type AdminConfig struct {
+	Enable       bool          // 是否启用
+	Addr         string        // 监听地址,与业务端口分开
+	Token        string        // 鉴权token,请求需携带 Authorization: Bearer {Token},为空时拒绝所有请求
+	ReadTimeout  time.Duration // 读取请求超时
+	WriteTimeout time.Duration // 写入响应超时
+}
+
+// NewDefaultAdminConfig provides default configuration for the admin HTTP module
+func NewDefaultAdminConfig() *AdminConfig {
+	return &AdminConfig{
+		Addr:         "127.0.0.1:8090",
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+}
+
+// NewAdminConfig reads from config to build the admin HTTP module configuration
+func NewAdminConfig(config *Config) *AdminConfig {
+	conf := NewDefaultAdminConfig()
+	if err := config.UnmarshalKey("pitaya.modules.admin", &conf); err != nil {
+		panic(err)
+	}
+	return conf
+}
@@ -100,6 +100,10 @@ func (c *Config) fillDefaultValues() {
 	prometheusConfig := NewDefaultPrometheusConfig()
 	statsdConfig := NewDefaultStatsdConfig()
 	etcdSDConfig := NewDefaultEtcdServiceDiscoveryConfig()
+	staticSDConfig := NewDefaultStaticServiceDiscoveryConfig()
+	dnsSDConfig := NewDefaultDNSServiceDiscoveryConfig()
+	natsSDConfig := NewDefaultNatsServiceDiscoveryConfig()
+	consistentHashConfig := NewDefaultConsistentHashConfig()
 	natsRPCServerConfig := NewDefaultNatsRPCServerConfig()
 	natsRPCClientConfig := NewDefaultNatsRPCClientConfig()
 	grpcRPCClientConfig := NewDefaultGRPCClientConfig()
@@ -111,6 +115,8 @@ func (c *Config) fillDefaultValues() {
 	rateLimitingConfig := NewDefaultRateLimitingConfig()
 	infoRetrieverConfig := NewDefaultInfoRetrieverConfig()
 	etcdBindingConfig := NewDefaultETCDBindingConfig()
+	sessionEventsConfig := NewDefaultSessionEventsConfig()
+	adminConfig := NewDefaultAdminConfig()
 	redisConfig := NewDefaultRedisConfig()
 
 	defaultsMap := map[string]interface{}{
@@ -134,6 +140,26 @@ func (c *Config) fillDefaultValues() {
 		"pitaya.cluster.rpc.server.nats.buffer.messages":        natsRPCServerConfig.Buffer.Messages,
 		"pitaya.cluster.rpc.server.nats.buffer.push":            natsRPCServerConfig.Buffer.Push,
 		"pitaya.cluster.rpc.server.nats.requesttimeout":         natsRPCServerConfig.RequestTimeout,
+		"pitaya.cluster.apiversion.min":                         0,
+		"pitaya.cluster.apiversion.max":                         0,
+		"pitaya.cluster.consistenthash.default.replicas":        consistentHashConfig.Default.Replicas,
+		"pitaya.cluster.consistenthash.default.loadfactor":      consistentHashConfig.Default.LoadFactor,
+		"pitaya.cluster.consistenthash.default.loadttl":         consistentHashConfig.Default.LoadTTL,
+		"pitaya.cluster.sd.type":                                ServiceDiscoveryEtcd,
+		"pitaya.cluster.sd.static.file":                         staticSDConfig.File,
+		"pitaya.cluster.sd.static.watchinterval":                staticSDConfig.WatchInterval,
+		"pitaya.cluster.sd.dns.interval":                        dnsSDConfig.Interval,
+		"pitaya.cluster.sd.dns.timeout":                         dnsSDConfig.Timeout,
+		"pitaya.cluster.sd.dns.serverid":                        dnsSDConfig.ServerID,
+		"pitaya.cluster.sd.nats.connect":                        natsSDConfig.Connect,
+		"pitaya.cluster.sd.nats.maxreconnectionretries":         natsSDConfig.MaxReconnectionRetries,
+		"pitaya.cluster.sd.nats.connectiontimeout":              natsSDConfig.ConnectionTimeout,
+		"pitaya.cluster.sd.nats.bucket":                         natsSDConfig.Bucket,
+		"pitaya.cluster.sd.nats.replicas":                       natsSDConfig.Replicas,
+		"pitaya.cluster.sd.nats.heartbeat.ttl":                  natsSDConfig.Heartbeat.TTL,
+		"pitaya.cluster.sd.nats.syncservers.interval":           natsSDConfig.SyncServers.Interval,
+		"pitaya.cluster.sd.nats.election.enable":                natsSDConfig.Election.Enable,
+		"pitaya.cluster.sd.nats.election.name":                  natsSDConfig.Election.Name,
 		"pitaya.cluster.sd.etcd.dialtimeout":                    etcdSDConfig.DialTimeout,
 		"pitaya.cluster.sd.etcd.endpoints":                      etcdSDConfig.Endpoints,
 		"pitaya.cluster.sd.etcd.prefix":                         etcdSDConfig.Prefix,
@@ -148,6 +174,9 @@ func (c *Config) fillDefaultValues() {
 		"pitaya.cluster.sd.etcd.shutdown.delay":                 etcdSDConfig.Shutdown.Delay,
 		"pitaya.cluster.sd.etcd.election.enable":                etcdSDConfig.Election.Enable,
 		"pitaya.cluster.sd.etcd.election.name":                  etcdSDConfig.Election.Name,
+		"pitaya.cluster.sd.etcd.snapshot.enable":                etcdSDConfig.Snapshot.Enable,
+		"pitaya.cluster.sd.etcd.snapshot.file":                  etcdSDConfig.Snapshot.File,
+		"pitaya.cluster.sd.etcd.snapshot.maxstaleness":          etcdSDConfig.Snapshot.MaxStaleness,
 		// the sum of this config among all the frontend servers should always be less than
 		// the sum of pitaya.buffer.cluster.rpc.server.nats.messages, for covering the worst case scenario
 		// a single backend server should have the config pitaya.buffer.cluster.rpc.server.nats.messages bigger
@@ -176,11 +205,40 @@ func (c *Config) fillDefaultValues() {
 		"pitaya.modules.bindingstorage.etcd.endpoints":     etcdBindingConfig.Endpoints,
 		"pitaya.modules.bindingstorage.etcd.leasettl":      etcdBindingConfig.LeaseTTL,
 		"pitaya.modules.bindingstorage.etcd.prefix":        etcdBindingConfig.Prefix,
+		"pitaya.modules.sessionevents.buffersize":          sessionEventsConfig.BufferSize,
+		"pitaya.modules.sessionevents.enqueuetimeout":      sessionEventsConfig.EnqueueTimeout,
+		"pitaya.modules.sessionevents.retryinterval":       sessionEventsConfig.RetryInterval,
+		"pitaya.modules.sessionevents.nats.connect":        sessionEventsConfig.Nats.Connect,
+		"pitaya.modules.sessionevents.nats.subject":        sessionEventsConfig.Nats.Subject,
+		"pitaya.modules.sessionevents.nats.jetstream":      sessionEventsConfig.Nats.JetStream,
+		"pitaya.modules.sessionevents.nats.publishtimeout": sessionEventsConfig.Nats.PublishTimeout,
+		"pitaya.modules.admin.enable":                      adminConfig.Enable,
+		"pitaya.modules.admin.addr":                        adminConfig.Addr,
+		"pitaya.modules.admin.token":                       adminConfig.Token,
+		"pitaya.modules.admin.readtimeout":                 adminConfig.ReadTimeout,
+		"pitaya.modules.admin.writetimeout":                adminConfig.WriteTimeout,
 		"pitaya.conn.ratelimiting.limit":                   rateLimitingConfig.Limit,
 		"pitaya.conn.ratelimiting.interval":                rateLimitingConfig.Interval,
 		"pitaya.conn.ratelimiting.forcedisable":            rateLimitingConfig.ForceDisable,
 		"pitaya.session.unique":                            pitayaConfig.Session.Unique,
 		"pitaya.session.cachettl":                          pitayaConfig.Session.CacheTTL,
+		"pitaya.session.migratetimeout":                    pitayaConfig.Session.MigrateTimeout,
+		"pitaya.healthcheck.interval":                      pitayaConfig.HealthCheck.Interval,
+		"pitaya.push.acktimeout":                           pitayaConfig.Push.AckTimeout,
+		"pitaya.push.ackretries":                           pitayaConfig.Push.AckRetries,
+		"pitaya.inbox.enabled":                             pitayaConfig.Inbox.Enabled,
+		"pitaya.inbox.retention":                           pitayaConfig.Inbox.Retention,
+		"pitaya.inbox.maxsize":                             pitayaConfig.Inbox.MaxSize,
+		"pitaya.delaytask.enabled":                         pitayaConfig.DelayTask.Enabled,
+		"pitaya.delaytask.namespace":                       pitayaConfig.DelayTask.Namespace,
+		"pitaya.delaytask.pollinterval":                    pitayaConfig.DelayTask.PollInterval,
+		"pitaya.delaytask.batchsize":                       pitayaConfig.DelayTask.BatchSize,
+		"pitaya.delaytask.lease":                           pitayaConfig.DelayTask.Lease,
+		"pitaya.delaytask.concurrency":                     pitayaConfig.DelayTask.Concurrency,
+		"pitaya.delaytask.maxattempts":                     pitayaConfig.DelayTask.MaxAttempts,
+		"pitaya.delaytask.retrymindelay":                   pitayaConfig.DelayTask.RetryMinDelay,
+		"pitaya.delaytask.retrymaxdelay":                   pitayaConfig.DelayTask.RetryMaxDelay,
+		"pitaya.panic.policy":                              pitayaConfig.Panic.Policy,
 		"pitaya.worker.concurrency":                        workerConfig.Concurrency,
 		"pitaya.worker.redis.pool":                         workerConfig.Redis.Pool,
 		"pitaya.worker.redis.url":                          workerConfig.Redis.ServerURL,
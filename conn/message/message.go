@@ -36,6 +36,7 @@ const (
 	Notify   Type = 0x01
 	Response Type = 0x02
 	Push     Type = 0x03
+	AckPush  Type = 0x04 // 需要客户端确认的推送,携带ID,客户端以 packet.PushAck 确认
 )
 
 const (
@@ -52,6 +53,7 @@ var types = map[Type]string{
 	Notify:   "Notify",
 	Response: "Response",
 	Push:     "Push",
+	AckPush:  "AckPush",
 }
 
 var (
@@ -99,11 +101,16 @@ func (m *Message) String() string {
 }
 
 func routable(t Type) bool {
-	return t == Request || t == Notify || t == Push
+	return t == Request || t == Notify || t == Push || t == AckPush
+}
+
+// hasID 消息头中是否包含消息ID
+func hasID(t Type) bool {
+	return t == Request || t == Response || t == AckPush
 }
 
 func invalidType(t Type) bool {
-	return t < Request || t > Push
+	return t < Request || t > AckPush
 
 }
 
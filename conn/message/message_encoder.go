@@ -82,7 +82,7 @@ func (me *MessagesEncoder) Encode(message *Message) ([]byte, error) {
 
 	buf = append(buf, flag)
 
-	if message.Type == Request || message.Type == Response {
+	if hasID(message.Type) {
 		n := message.ID
 		// variant length encode
 		for {
@@ -143,7 +143,7 @@ func Decode(data []byte) (*Message, error) {
 		return nil, ErrWrongMessageType
 	}
 
-	if m.Type == Request || m.Type == Response {
+	if hasID(m.Type) {
 		id := uint(0)
 		// little end byte order
 		// WARNING: must can be stored in 64 bits integer
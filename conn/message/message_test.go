@@ -209,3 +209,17 @@ func TestGetDictionary(t *testing.T) {
 	// make sure we're copying the routes maps
 	assert.NotEqual(t, fmt.Sprintf("%p", routes), fmt.Sprintf("%p", dict))
 }
+
+func TestAckPushEncodeDecode(t *testing.T) {
+	message := &Message{Type: AckPush, ID: 300, Route: "a.b.c", Data: []byte("hello")}
+	encoder := NewMessagesEncoder(false)
+	encoded, err := encoder.Encode(message)
+	assert.NoError(t, err)
+
+	decoded, err := Decode(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, AckPush, decoded.Type)
+	assert.Equal(t, uint(300), decoded.ID)
+	assert.Equal(t, "a.b.c", decoded.Route)
+	assert.Equal(t, []byte("hello"), decoded.Data)
+}
@@ -43,6 +43,9 @@ const (
 	Kick = 0x05 // disconnect message from server
 
 	HeartbeatAck = 0x06
+
+	// PushAck 客户端对 message.AckPush 的确认,数据为8字节大端序的消息ID
+	PushAck = 0x07
 )
 
 // ErrWrongPomeloPacketType represents a wrong packet type.
@@ -64,6 +64,27 @@ const (
 	// SessionKickedBackendRoute session与sessionsticky backend解绑后的路由
 	SessionKickedBackendRoute = "sys.sessionkickedbackend"
 
+	// SessionMigrateRoute 迁移session到其他网关的路由,由session所在网关处理
+	SessionMigrateRoute = "sys.migratesession"
+
+	// SessionMigratedRoute session迁移到新网关后通知已绑定backend的路由
+	SessionMigratedRoute = "sys.sessionmigrated"
+
+	// SessionRedirectRoute 迁移session时推送给客户端的重定向路由
+	SessionRedirectRoute = "sys.redirect"
+
+	// SessionQueryRoute 查询网关本地session的fork路由
+	SessionQueryRoute = "sys.querysessions"
+
+	// SessionQueryReplyRoute 网关将本地session查询结果回复给查询方的路由
+	SessionQueryReplyRoute = "sys.querysessionsreply"
+
+	// InboxReplayedRoute 收件箱消息重放结束后推送给客户端的路由
+	InboxReplayedRoute = "sys.inboxreplayed"
+
+	// InboxAckRoute 客户端确认收件箱消息的路由
+	InboxAckRoute = "inbox.ack"
+
 	// ServerInternalErrorToClientRoute 服务器内部错误时若不是request类型消息引起的,以该路由回应客户端
 	ServerInternalErrorToClientRoute = "internal.error"
 )
@@ -109,6 +130,9 @@ var MetricTagsKey = "metric-tags"
 // GRPCHostKey is the key for grpc host on server metadata
 var GRPCHostKey = "grpcHost"
 
+// ServerWeightKey 服务metadata中一致性哈希权重的key,默认100
+var ServerWeightKey = "weight"
+
 // GRPCExternalHostKey is the key for grpc external host on server metadata
 var GRPCExternalHostKey = "grpc-external-host"
 
@@ -118,6 +142,9 @@ var GRPCPortKey = "grpcPort"
 // GRPCExternalPortKey is the key for grpc external port on server metadata
 var GRPCExternalPortKey = "grpc-external-port"
 
+// ClientAddrKey is the key for the address clients use to connect to a frontend on server metadata
+var ClientAddrKey = "clientAddr"
+
 // RegionKey is the key to save the region server is on
 var RegionKey = "region"
 
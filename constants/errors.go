@@ -53,6 +53,14 @@ var (
 	ErrNatsNoRequestTimeout           = errors.New("pitaya.cluster.rpc.client.nats.requesttimeout cant be empty")
 	ErrNatsPushBufferSizeZero         = errors.New("pitaya.buffer.cluster.rpc.server.nats.push cant be zero")
 	ErrNilCondition                   = errors.New("pitaya/timer: nil condition")
+	ErrInvalidCronSpec                = errors.New("pitaya/timer: invalid cron spec")
+	ErrNilTimerFunc                   = errors.New("pitaya/timer: nil timer function")
+	ErrTimerScopeClosed               = errors.New("pitaya/timer: timer scope closed")
+	ErrPoolOverflow                   = errors.New("pitaya/co: goroutine task queue is full")
+	ErrPoolTaskDropped                = errors.New("pitaya/co: task dropped for newer tasks")
+	ErrPoolClosed                     = errors.New("pitaya/co: goroutine pool closed")
+	ErrPoolNotFound                   = errors.New("pitaya/co: goroutine pool not found")
+	ErrEmptyGoKey                     = errors.New("pitaya/co: empty goroutine key")
 	ErrNoBindingStorageModule         = errors.New("for sending remote pushes or using unique session module while using grpc you need to pass it a BindingStorage")
 	ErrNoConnectionToServer           = errors.New("rpc client has no connection to the chosen server")
 	ErrNoContextFound                 = errors.New("no context found")
@@ -89,11 +97,27 @@ var (
 	ErrReceivedMsgBiggerThanExpected  = errors.New("received more data than expected")
 	ErrConnectionClosed               = errors.New("client connection closed")
 
-	ErrDeveloperLogicFatal     = errors.New("developer logic fatal!!") // 不应该发生的致命错误,一定是开发者逻辑有误
-	ErrRPCTimeout              = errors.New("RPC timeout")
-	ErrIllegalBindBackendID    = errors.New("illegal backend id when bind in remote")
-	ErrSessionNotBoundBackend  = errors.New("session have not bound to backend")
-	ErrNotifyAllSvTypeNotEmpty = errors.New("NotifyAll must be to an empty server type")
-	ErrLooperAsyncInCoroutine  = errors.New("don't call Async() inside coroutine")
-	ErrConvertGenericType      = errors.New("convert generic type error")
+	ErrDeveloperLogicFatal          = errors.New("developer logic fatal!!") // 不应该发生的致命错误,一定是开发者逻辑有误
+	ErrRPCTimeout                   = errors.New("RPC timeout")
+	ErrIllegalBindBackendID         = errors.New("illegal backend id when bind in remote")
+	ErrSessionNotBoundBackend       = errors.New("session have not bound to backend")
+	ErrNotifyAllSvTypeNotEmpty      = errors.New("NotifyAll must be to an empty server type")
+	ErrLooperAsyncInCoroutine       = errors.New("don't call Async() inside coroutine")
+	ErrConvertGenericType           = errors.New("convert generic type error")
+	ErrMigrateTargetIllegal         = errors.New("migrate target must be another frontend")
+	ErrMigrateTargetNoAddr          = errors.New("migrate target has no client address in metadata")
+	ErrActorMailboxFull             = errors.New("actor mailbox is full")
+	ErrActorRequestTimeout          = errors.New("actor request timeout")
+	ErrActorKindClosed              = errors.New("actor kind is closed")
+	ErrActorPanic                   = errors.New("actor panic")
+	ErrActorRemoteInvalid           = errors.New("actor remote message has no entity of this kind")
+	ErrNoCompatibleServers          = errors.New("no servers with compatible api version")
+	ErrHashRingNotSupported         = errors.New("service discovery does not support hash ring listener")
+	ErrElectionListenerNotSupported = errors.New("service discovery does not support election listener")
+	ErrDelayTaskHandlerNotFound     = errors.New("delay task handler not found")
+	ErrDelayTaskRPCJobNotSet        = errors.New("delay task rpc job not set, call RegisterRPCJob first")
+	ErrDelayTaskNotEnabled          = errors.New("delay task is not enabled, set pitaya.delaytask.enabled")
+	ErrCoordinationNotSupported     = errors.New("service discovery does not support elections and locks")
+	ErrCoordinationNotReady         = errors.New("service discovery is not initialized")
+	ErrLockLost                     = errors.New("lock lost")
 )
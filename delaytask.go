@@ -0,0 +1,24 @@
+package pitaya
+
+import (
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/delaytask"
+)
+
+// SetDelayTaskStore
+//
+//	@implement Pitaya.SetDelayTaskStore
+func (app *App) SetDelayTaskStore(store delaytask.Store) {
+	app.delayTasks = delaytask.NewQueue(store, app.config.DelayTask)
+}
+
+// DelayTasks
+//
+//	@implement Pitaya.DelayTasks
+func (app *App) DelayTasks() (*delaytask.Queue, error) {
+	if app.delayTasks == nil {
+		return nil, errors.WithStack(constants.ErrDelayTaskNotEnabled)
+	}
+	return app.delayTasks, nil
+}
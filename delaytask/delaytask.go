@@ -0,0 +1,77 @@
+// Package delaytask 持久化的延迟任务,任务存储在外部存储中,进程重启不丢失
+//
+//	同一命名空间的多个服务共同消费,每个到期任务只会被一个消费者领取;
+//	领取后在租约内未确认的任务会再次到期,由其他消费者重试,保证至少执行一次
+package delaytask
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// Task 延迟任务
+	Task struct {
+		Key       string                 `json:"key"`                // 业务唯一key,用于取消和重新调度,重复添加时覆盖
+		Handler   string                 `json:"handler,omitempty"`  // 注册的处理器名,与Route二选一
+		Route     string                 `json:"route,omitempty"`    // RPC路由,通过 worker.RPCJob 执行
+		Metadata  map[string]interface{} `json:"metadata,omitempty"` // RPC路由时传给 worker.RPCJob.ServerDiscovery 的数据
+		Data      []byte                 `json:"data,omitempty"`     // 处理器参数,RPC路由时为proto序列化后的参数
+		ExecuteAt int64                  `json:"-"`                  // 执行时间 unix毫秒
+		Version   int64                  `json:"-"`                  // 每次添加、重新调度和领取时递增,作为领取的fencing token,确认时用于识别任务是否被修改或重新领取
+		Attempts  int                    `json:"-"`                  // 已领取次数,包含本次
+	}
+
+	// Handler 任务处理器,返回error时按重试策略重试
+	Handler func(ctx context.Context, task *Task) error
+
+	// Store 延迟任务存储
+	Store interface {
+		// Put 添加任务,key已存在时覆盖
+		//  @param ctx
+		//  @param task
+		//  @return int64 版本号
+		//  @return error
+		Put(ctx context.Context, task *Task) (int64, error)
+		// Reschedule 修改执行时间
+		//  @param ctx
+		//  @param key
+		//  @param at
+		//  @return bool 任务是否存在
+		//  @return error
+		Reschedule(ctx context.Context, key string, at time.Time) (bool, error)
+		// Cancel 删除任务
+		//  @param ctx
+		//  @param key
+		//  @return bool 任务是否存在
+		//  @return error
+		Cancel(ctx context.Context, key string) (bool, error)
+		// Get 获取任务,不存在时返回nil
+		//  @param ctx
+		//  @param key
+		//  @return *Task
+		//  @return error
+		Get(ctx context.Context, key string) (*Task, error)
+		// Claim 领取最多limit个到期任务,领取的任务在 now+lease 时再次到期,每次领取递增任务的版本号
+		//  @param ctx
+		//  @param now
+		//  @param limit
+		//  @param lease
+		//  @return []*Task
+		//  @return error
+		Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Task, error)
+		// Ack 执行完成后删除任务,版本号不一致时说明任务已被重新调度或被其他消费者重新领取,不删除
+		//  @param ctx
+		//  @param key
+		//  @param version 领取时的版本号
+		//  @return error
+		Ack(ctx context.Context, key string, version int64) error
+		// Retry 执行失败后在at重试,版本号不一致时忽略
+		//  @param ctx
+		//  @param key
+		//  @param version 领取时的版本号
+		//  @param at
+		//  @return error
+		Retry(ctx context.Context, key string, version int64, at time.Time) error
+	}
+)
@@ -0,0 +1,107 @@
+package delaytask
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore 基于本地内存的延迟任务存储,重启后丢失,仅用于单机模式和测试
+type MemoryStore struct {
+	mu    sync.Mutex
+	tasks map[string]*Task
+	seq   int64
+}
+
+// NewMemoryStore returns a new memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tasks: map[string]*Task{}}
+}
+
+func (m *MemoryStore) Put(ctx context.Context, task *Task) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	t := *task
+	t.Version = m.seq
+	t.Attempts = 0
+	m.tasks[task.Key] = &t
+	return t.Version, nil
+}
+
+func (m *MemoryStore) Reschedule(ctx context.Context, key string, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tasks[key]
+	if !ok {
+		return false, nil
+	}
+	m.seq++
+	t.Version = m.seq
+	t.ExecuteAt = at.UnixMilli()
+	return true, nil
+}
+
+func (m *MemoryStore) Cancel(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.tasks[key]
+	delete(m.tasks, key)
+	return ok, nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) (*Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tasks[key]
+	if !ok {
+		return nil, nil
+	}
+	ret := *t
+	return &ret, nil
+}
+
+func (m *MemoryStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nowMs := now.UnixMilli()
+	due := make([]*Task, 0)
+	for _, t := range m.tasks {
+		if t.ExecuteAt <= nowMs {
+			due = append(due, t)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ExecuteAt < due[j].ExecuteAt })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	ret := make([]*Task, 0, len(due))
+	for _, t := range due {
+		m.seq++
+		t.Version = m.seq
+		t.Attempts++
+		claimed := *t
+		ret = append(ret, &claimed)
+		t.ExecuteAt = now.Add(lease).UnixMilli()
+	}
+	return ret, nil
+}
+
+func (m *MemoryStore) Ack(ctx context.Context, key string, version int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.tasks[key]; ok && t.Version == version {
+		delete(m.tasks, key)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Retry(ctx context.Context, key string, version int64, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.tasks[key]; ok && t.Version == version {
+		t.ExecuteAt = at.UnixMilli()
+	}
+	return nil
+}
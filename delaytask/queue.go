@@ -0,0 +1,271 @@
+package delaytask
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/clock"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"github.com/topfreegames/pitaya/v2/worker"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// Queue 延迟任务队列,定时从 Store 领取到期任务,派发给注册的处理器或通过 worker.RPCJob 执行RPC
+//
+//	实现了 interfaces.Module,AfterInit 后开始消费,BeforeShutdown 时停止领取并等待执行中的任务结束
+type Queue struct {
+	store    Store
+	conf     config.DelayTaskConfig
+	lock     sync.RWMutex
+	handlers map[string]Handler
+	rpcJob   worker.RPCJob
+	sem      chan struct{}
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewQueue ctor
+//
+//	@param store
+//	@param conf
+//	@return *Queue
+func NewQueue(store Store, conf config.DelayTaskConfig) *Queue {
+	if conf.Concurrency <= 0 {
+		conf.Concurrency = 1
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = conf.Concurrency
+	}
+	return &Queue{
+		store:    store,
+		conf:     conf,
+		handlers: map[string]Handler{},
+		sem:      make(chan struct{}, conf.Concurrency),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Register 注册处理器,需在 AfterInit 前注册,否则已到期的任务会因找不到处理器而重试
+//
+//	@receiver q
+//	@param name
+//	@param handler
+func (q *Queue) Register(name string, handler Handler) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.handlers[name] = handler
+}
+
+// SetRPCJob 设置执行RPC路由任务的 worker.RPCJob, App.RegisterRPCJob 时会自动设置
+//
+//	@receiver q
+//	@param rpcJob
+func (q *Queue) SetRPCJob(rpcJob worker.RPCJob) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.rpcJob = rpcJob
+}
+
+// Schedule 在at时刻执行注册的处理器,key已存在时覆盖
+//
+//	@receiver q
+//	@param ctx
+//	@param key
+//	@param at
+//	@param handler 处理器名
+//	@param data 处理器参数
+//	@return error
+func (q *Queue) Schedule(ctx context.Context, key string, at time.Time, handler string, data []byte) error {
+	_, err := q.store.Put(ctx, &Task{Key: key, Handler: handler, Data: data, ExecuteAt: at.UnixMilli()})
+	return err
+}
+
+// ScheduleRPC 在at时刻执行RPC,key已存在时覆盖
+//
+//	@receiver q
+//	@param ctx
+//	@param key
+//	@param at
+//	@param route
+//	@param arg
+//	@param metadata 传给 worker.RPCJob.ServerDiscovery
+//	@return error
+func (q *Queue) ScheduleRPC(ctx context.Context, key string, at time.Time, route string, arg proto.Message, metadata map[string]interface{}) error {
+	var data []byte
+	if arg != nil {
+		var err error
+		if data, err = proto.Marshal(arg); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	_, err := q.store.Put(ctx, &Task{Key: key, Route: route, Metadata: metadata, Data: data, ExecuteAt: at.UnixMilli()})
+	return err
+}
+
+// Reschedule 修改任务的执行时间
+//
+//	@receiver q
+//	@param ctx
+//	@param key
+//	@param at
+//	@return bool 任务是否存在
+//	@return error
+func (q *Queue) Reschedule(ctx context.Context, key string, at time.Time) (bool, error) {
+	return q.store.Reschedule(ctx, key, at)
+}
+
+// Cancel 取消任务,执行中的任务无法中断,但执行失败后不会再重试
+//
+//	@receiver q
+//	@param ctx
+//	@param key
+//	@return bool 任务是否存在
+//	@return error
+func (q *Queue) Cancel(ctx context.Context, key string) (bool, error) {
+	return q.store.Cancel(ctx, key)
+}
+
+// Get 获取任务,不存在时返回nil
+//
+//	@receiver q
+//	@param ctx
+//	@param key
+//	@return *Task
+//	@return error
+func (q *Queue) Get(ctx context.Context, key string) (*Task, error) {
+	return q.store.Get(ctx, key)
+}
+
+// Init was called to initialize the component.
+func (q *Queue) Init() error {
+	return nil
+}
+
+// AfterInit 开始消费
+func (q *Queue) AfterInit() {
+	q.wg.Add(1)
+	go q.poll()
+}
+
+// BeforeShutdown 停止领取任务并等待执行中的任务结束,未确认的任务在租约到期后由其他消费者执行
+func (q *Queue) BeforeShutdown() {
+	close(q.stopChan)
+	q.wg.Wait()
+}
+
+// Shutdown was called when the component is shutting down.
+func (q *Queue) Shutdown() error {
+	return nil
+}
+
+func (q *Queue) poll() {
+	defer q.wg.Done()
+	ticker := clock.NewTicker(q.conf.PollInterval)
+	defer ticker.Stop()
+	for {
+		q.claim()
+		select {
+		case <-ticker.C():
+		case <-q.stopChan:
+			return
+		}
+	}
+}
+
+// claim 领取到期任务并派发,并发数达到上限时等待
+func (q *Queue) claim() {
+	tasks, err := q.store.Claim(context.Background(), clock.Now(), q.conf.BatchSize, q.conf.Lease)
+	if err != nil {
+		logger.Zap.Error("failed to claim delay tasks", zap.Error(err))
+	}
+	for _, task := range tasks {
+		q.sem <- struct{}{}
+		q.wg.Add(1)
+		task := task
+		co.Go(func() {
+			defer func() {
+				<-q.sem
+				q.wg.Done()
+			}()
+			q.execute(task)
+		})
+	}
+}
+
+func (q *Queue) execute(task *Task) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.conf.Lease)
+	defer cancel()
+	err := q.dispatch(ctx, task)
+	if err == nil {
+		if err = q.store.Ack(ctx, task.Key, task.Version); err != nil {
+			logger.Zap.Error("failed to ack delay task", zap.String("key", task.Key), zap.Error(err))
+		}
+		return
+	}
+	if q.conf.MaxAttempts > 0 && task.Attempts >= q.conf.MaxAttempts {
+		logger.Zap.Error("delay task failed too many times, dropped", zap.String("key", task.Key), zap.Int("attempts", task.Attempts), zap.Error(err))
+		if err = q.store.Ack(ctx, task.Key, task.Version); err != nil {
+			logger.Zap.Error("failed to drop delay task", zap.String("key", task.Key), zap.Error(err))
+		}
+		return
+	}
+	logger.Zap.Warn("delay task failed, will retry", zap.String("key", task.Key), zap.Int("attempts", task.Attempts), zap.Error(err))
+	if err = q.store.Retry(ctx, task.Key, task.Version, clock.Now().Add(q.retryDelay(task.Attempts))); err != nil {
+		logger.Zap.Error("failed to retry delay task", zap.String("key", task.Key), zap.Error(err))
+	}
+}
+
+// dispatch 派发给处理器或执行RPC,处理器panic视为失败
+func (q *Queue) dispatch(ctx context.Context, task *Task) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.Zap.Error("delay task panic", zap.String("key", task.Key), zap.Any("recover", rec), zap.Stack("stack"))
+			err = errors.Errorf("delay task panic: %v", rec)
+		}
+	}()
+	q.lock.RLock()
+	handler, rpcJob := q.handlers[task.Handler], q.rpcJob
+	q.lock.RUnlock()
+
+	if task.Route == "" {
+		if handler == nil {
+			return errors.WithStack(constants.ErrDelayTaskHandlerNotFound)
+		}
+		return handler(ctx, task)
+	}
+	if rpcJob == nil {
+		return errors.WithStack(constants.ErrDelayTaskRPCJobNotSet)
+	}
+	arg, reply, err := rpcJob.GetArgReply(task.Route)
+	if err != nil {
+		return err
+	}
+	if arg != nil && len(task.Data) > 0 {
+		if err = proto.Unmarshal(task.Data, arg); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	serverID, err := rpcJob.ServerDiscovery(task.Route, task.Metadata)
+	if err != nil {
+		return err
+	}
+	return rpcJob.RPC(ctx, serverID, task.Route, reply, arg)
+}
+
+// retryDelay 按尝试次数指数递增的重试间隔
+func (q *Queue) retryDelay(attempts int) time.Duration {
+	delay := q.conf.RetryMinDelay
+	for i := 1; i < attempts && delay < q.conf.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if q.conf.RetryMaxDelay > 0 && delay > q.conf.RetryMaxDelay {
+		delay = q.conf.RetryMaxDelay
+	}
+	return delay
+}
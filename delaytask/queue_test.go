@@ -0,0 +1,233 @@
+package delaytask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newTestConf() config.DelayTaskConfig {
+	return config.DelayTaskConfig{
+		PollInterval:  10 * time.Millisecond,
+		BatchSize:     10,
+		Lease:         time.Second,
+		Concurrency:   4,
+		MaxAttempts:   3,
+		RetryMinDelay: 10 * time.Millisecond,
+		RetryMaxDelay: 50 * time.Millisecond,
+	}
+}
+
+func startQueue(t *testing.T, q *Queue) {
+	require.NoError(t, q.Init())
+	q.AfterInit()
+	t.Cleanup(q.BeforeShutdown)
+}
+
+func TestQueueExecutesOnceAndAcks(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryStore()
+	ctx := context.Background()
+	var mu sync.Mutex
+	executed := map[string]int{}
+	handler := func(ctx context.Context, task *Task) error {
+		mu.Lock()
+		defer mu.Unlock()
+		executed[task.Key]++
+		return nil
+	}
+	// 两个消费者共享存储,每个任务只执行一次
+	for i := 0; i < 2; i++ {
+		q := NewQueue(store, newTestConf())
+		q.Register("energy", handler)
+		startQueue(t, q)
+	}
+
+	q := NewQueue(store, newTestConf())
+	for i := 0; i < 50; i++ {
+		require.NoError(t, q.Schedule(ctx, fmt.Sprintf("task-%d", i), time.Now().Add(20*time.Millisecond), "energy", []byte("1")))
+	}
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(executed) == 50
+	}, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	for key, n := range executed {
+		assert.Equal(t, 1, n, key)
+	}
+	mu.Unlock()
+	task, err := q.Get(ctx, "task-0")
+	require.NoError(t, err)
+	assert.Nil(t, task)
+}
+
+func TestQueueCancelAndReschedule(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	q := NewQueue(NewMemoryStore(), newTestConf())
+	var executed atomic.Int32
+	q.Register("h", func(ctx context.Context, task *Task) error {
+		executed.Add(1)
+		return nil
+	})
+	startQueue(t, q)
+
+	require.NoError(t, q.Schedule(ctx, "cancel", time.Now().Add(50*time.Millisecond), "h", nil))
+	ok, err := q.Cancel(ctx, "cancel")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, q.Schedule(ctx, "later", time.Now().Add(time.Hour), "h", nil))
+	ok, err = q.Reschedule(ctx, "later", time.Now())
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = q.Reschedule(ctx, "missing", time.Now())
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Eventually(t, func() bool { return executed.Load() == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), executed.Load())
+}
+
+func TestQueueRetry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := NewMemoryStore()
+	q := NewQueue(store, newTestConf())
+	var attempts sync.Map
+	q.Register("flaky", func(ctx context.Context, task *Task) error {
+		attempts.Store(task.Key, task.Attempts)
+		if task.Key == "ok-on-2" && task.Attempts == 2 {
+			return nil
+		}
+		if task.Key == "panic" {
+			panic("boom")
+		}
+		return errors.New("fail")
+	})
+	startQueue(t, q)
+
+	for _, key := range []string{"ok-on-2", "always", "panic"} {
+		require.NoError(t, q.Schedule(ctx, key, time.Now(), "flaky", nil))
+	}
+	require.NoError(t, q.Schedule(ctx, "no-handler", time.Now(), "missing", nil))
+
+	assert.Eventually(t, func() bool {
+		for _, key := range []string{"ok-on-2", "always", "panic", "no-handler"} {
+			if task, _ := store.Get(ctx, key); task != nil {
+				return false
+			}
+		}
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+	n, _ := attempts.Load("ok-on-2")
+	assert.Equal(t, 2, n)
+	n, _ = attempts.Load("always")
+	assert.Equal(t, 3, n)
+	n, _ = attempts.Load("panic")
+	assert.Equal(t, 3, n)
+}
+
+func TestQueueRescheduledWhileRunning(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := NewMemoryStore()
+	q := NewQueue(store, newTestConf())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var runs atomic.Int32
+	q.Register("h", func(ctx context.Context, task *Task) error {
+		if runs.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+	startQueue(t, q)
+
+	require.NoError(t, q.Schedule(ctx, "key", time.Now(), "h", nil))
+	<-started
+	ok, err := q.Reschedule(ctx, "key", time.Now().Add(30*time.Millisecond))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	close(release)
+	// 执行期间被重新调度的任务不会被确认删除
+	assert.Eventually(t, func() bool { return runs.Load() == 2 }, time.Second, 10*time.Millisecond)
+}
+
+type fakeRPCJob struct {
+	mu       sync.Mutex
+	serverID string
+	route    string
+	arg      proto.Message
+	metadata map[string]interface{}
+}
+
+func (f *fakeRPCJob) ServerDiscovery(route string, rpcMetadata map[string]interface{}) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.metadata = rpcMetadata
+	return "server-1", nil
+}
+
+func (f *fakeRPCJob) RPC(ctx context.Context, serverID, routeStr string, reply, arg proto.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.serverID, f.route, f.arg = serverID, routeStr, arg
+	return nil
+}
+
+func (f *fakeRPCJob) GetArgReply(route string) (arg, reply proto.Message, err error) {
+	return &wrapperspb.StringValue{}, &wrapperspb.StringValue{}, nil
+}
+
+func TestQueueScheduleRPC(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conf := newTestConf()
+	conf.MaxAttempts = 0
+	q := NewQueue(NewMemoryStore(), conf)
+	job := &fakeRPCJob{}
+	startQueue(t, q)
+
+	require.NoError(t, q.ScheduleRPC(ctx, "auction-1", time.Now(), "auction.auction.close", wrapperspb.String("auction-1"), map[string]interface{}{"uid": "u1"}))
+	assert.Eventually(t, func() bool {
+		task, _ := q.Get(ctx, "auction-1")
+		return task != nil && task.Attempts > 0
+	}, time.Second, 10*time.Millisecond, "should fail without rpc job")
+
+	q.SetRPCJob(job)
+	assert.Eventually(t, func() bool {
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		return job.route != ""
+	}, 2*time.Second, 10*time.Millisecond)
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	assert.Equal(t, "server-1", job.serverID)
+	assert.Equal(t, "auction.auction.close", job.route)
+	assert.Equal(t, "auction-1", job.arg.(*wrapperspb.StringValue).GetValue())
+	assert.Equal(t, "u1", job.metadata["uid"])
+}
+
+func TestQueueRetryDelay(t *testing.T) {
+	t.Parallel()
+	q := NewQueue(NewMemoryStore(), config.DelayTaskConfig{RetryMinDelay: time.Second, RetryMaxDelay: 5 * time.Second})
+	assert.Equal(t, time.Second, q.retryDelay(1))
+	assert.Equal(t, 2*time.Second, q.retryDelay(2))
+	assert.Equal(t, 4*time.Second, q.retryDelay(3))
+	assert.Equal(t, 5*time.Second, q.retryDelay(10))
+}
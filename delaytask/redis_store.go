@@ -0,0 +1,192 @@
+package delaytask
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+const (
+	// 使用hash tag保证同一命名空间的key在redis cluster的同一个slot
+	redisDueKey      = "pit:dt:{%s}:due"  // zset 任务key -> 到期时间
+	redisDataKey     = "pit:dt:{%s}:data" // hash 任务key -> 任务json
+	redisVersionKey  = "pit:dt:{%s}:ver"  // hash 任务key -> 版本号
+	redisAttemptKey  = "pit:dt:{%s}:att"  // hash 任务key -> 领取次数
+	redisSequenceKey = "pit:dt:{%s}:seq"  // 版本号生成器
+)
+
+var (
+	// KEYS: due data ver att seq; ARGV: key json at
+	putScript = redis.NewScript(`
+local ver = redis.call('INCR', KEYS[5])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+redis.call('HSET', KEYS[3], ARGV[1], ver)
+redis.call('HSET', KEYS[4], ARGV[1], 0)
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return ver
+`)
+	// KEYS: due data ver seq; ARGV: key at
+	rescheduleScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[2], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[3], ARGV[1], redis.call('INCR', KEYS[4]))
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return 1
+`)
+	// KEYS: due data ver att; ARGV: key
+	cancelScript = redis.NewScript(`
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+return redis.call('HDEL', KEYS[2], ARGV[1])
+`)
+	// KEYS: due data ver att seq; ARGV: now limit leaseUntil
+	// 每次领取递增版本号作为fencing token,租约过期被重新领取后旧的领取无法 Ack 或 Retry
+	claimScript = redis.NewScript(`
+local keys = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'WITHSCORES', 'LIMIT', 0, ARGV[2])
+local ret = {}
+for i = 1, #keys, 2 do
+	local key = keys[i]
+	local data = redis.call('HGET', KEYS[2], key)
+	if data then
+		redis.call('ZADD', KEYS[1], ARGV[3], key)
+		local att = redis.call('HINCRBY', KEYS[4], key, 1)
+		local ver = redis.call('INCR', KEYS[5])
+		redis.call('HSET', KEYS[3], key, ver)
+		table.insert(ret, data)
+		table.insert(ret, tostring(ver))
+		table.insert(ret, tostring(att))
+		table.insert(ret, keys[i + 1])
+	else
+		redis.call('ZREM', KEYS[1], key)
+	end
+end
+return ret
+`)
+	// KEYS: due data ver att; ARGV: key version
+	ackScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+return 1
+`)
+	// KEYS: due ver; ARGV: key version at
+	retryScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+)
+
+// RedisStore 基于redis有序集合的延迟任务存储,score为到期时间,领取、确认等操作使用lua脚本保证原子性
+type RedisStore struct {
+	conn        redis.Cmdable
+	dueKey      string
+	dataKey     string
+	versionKey  string
+	attemptKey  string
+	sequenceKey string
+}
+
+// NewRedisStore returns a new redis store
+func NewRedisStore(client redis.Cmdable, namespace string) *RedisStore {
+	return &RedisStore{
+		conn:        client,
+		dueKey:      fmt.Sprintf(redisDueKey, namespace),
+		dataKey:     fmt.Sprintf(redisDataKey, namespace),
+		versionKey:  fmt.Sprintf(redisVersionKey, namespace),
+		attemptKey:  fmt.Sprintf(redisAttemptKey, namespace),
+		sequenceKey: fmt.Sprintf(redisSequenceKey, namespace),
+	}
+}
+
+func (r *RedisStore) Put(ctx context.Context, task *Task) (int64, error) {
+	b, err := json.Marshal(task)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	ver, err := putScript.Run(ctx, r.conn, []string{r.dueKey, r.dataKey, r.versionKey, r.attemptKey, r.sequenceKey},
+		task.Key, b, task.ExecuteAt).Int64()
+	return ver, errors.WithStack(err)
+}
+
+func (r *RedisStore) Reschedule(ctx context.Context, key string, at time.Time) (bool, error) {
+	n, err := rescheduleScript.Run(ctx, r.conn, []string{r.dueKey, r.dataKey, r.versionKey, r.sequenceKey},
+		key, at.UnixMilli()).Int()
+	return n == 1, errors.WithStack(err)
+}
+
+func (r *RedisStore) Cancel(ctx context.Context, key string) (bool, error) {
+	n, err := cancelScript.Run(ctx, r.conn, []string{r.dueKey, r.dataKey, r.versionKey, r.attemptKey}, key).Int()
+	return n == 1, errors.WithStack(err)
+}
+
+func (r *RedisStore) Get(ctx context.Context, key string) (*Task, error) {
+	var data *redis.StringCmd
+	var ver, att *redis.StringCmd
+	var score *redis.FloatCmd
+	_, err := r.conn.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		data = pipe.HGet(ctx, r.dataKey, key)
+		ver = pipe.HGet(ctx, r.versionKey, key)
+		att = pipe.HGet(ctx, r.attemptKey, key)
+		score = pipe.ZScore(ctx, r.dueKey, key)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, errors.WithStack(err)
+	}
+	if data.Err() == redis.Nil {
+		return nil, nil
+	}
+	return decodeTask(data.Val(), ver.Val(), att.Val(), strconv.FormatFloat(score.Val(), 'f', 0, 64))
+}
+
+func (r *RedisStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Task, error) {
+	ret, err := claimScript.Run(ctx, r.conn, []string{r.dueKey, r.dataKey, r.versionKey, r.attemptKey, r.sequenceKey},
+		now.UnixMilli(), limit, now.Add(lease).UnixMilli()).StringSlice()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	tasks := make([]*Task, 0, len(ret)/4)
+	for i := 0; i+3 < len(ret); i += 4 {
+		task, err := decodeTask(ret[i], ret[i+1], ret[i+2], ret[i+3])
+		if err != nil {
+			return tasks, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (r *RedisStore) Ack(ctx context.Context, key string, version int64) error {
+	return errors.WithStack(ackScript.Run(ctx, r.conn, []string{r.dueKey, r.dataKey, r.versionKey, r.attemptKey}, key, version).Err())
+}
+
+func (r *RedisStore) Retry(ctx context.Context, key string, version int64, at time.Time) error {
+	return errors.WithStack(retryScript.Run(ctx, r.conn, []string{r.dueKey, r.versionKey}, key, version, at.UnixMilli()).Err())
+}
+
+// decodeTask 由redis中存储的各字段还原任务
+func decodeTask(data, ver, att, score string) (*Task, error) {
+	task := &Task{}
+	if err := json.Unmarshal([]byte(data), task); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	task.Version, _ = strconv.ParseInt(ver, 10, 64)
+	task.Attempts, _ = strconv.Atoi(att)
+	executeAt, _ := strconv.ParseFloat(score, 64)
+	task.ExecuteAt = int64(executeAt)
+	return task, nil
+}
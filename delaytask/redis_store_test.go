@@ -0,0 +1,186 @@
+package delaytask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *redis.Client) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client, "test"), client
+}
+
+// TestStore 内存和redis存储的行为一致
+func TestStore(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"Memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"Redis": func(t *testing.T) Store {
+			store, _ := newTestRedisStore(t)
+			return store
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Run("PutAndGet", func(t *testing.T) { testStorePutAndGet(t, newStore(t)) })
+			t.Run("ClaimFencing", func(t *testing.T) { testStoreClaimFencing(t, newStore(t)) })
+			t.Run("RescheduleWhileRunning", func(t *testing.T) { testStoreRescheduleWhileRunning(t, newStore(t)) })
+			t.Run("Cancel", func(t *testing.T) { testStoreCancel(t, newStore(t)) })
+			t.Run("ClaimLimit", func(t *testing.T) { testStoreClaimLimit(t, newStore(t)) })
+		})
+	}
+}
+
+func testStorePutAndGet(t *testing.T, store Store) {
+	ctx := context.Background()
+	at := time.UnixMilli(time.Now().UnixMilli())
+	v1, err := store.Put(ctx, &Task{Key: "k", Handler: "h", Data: []byte("d1"), ExecuteAt: at.UnixMilli()})
+	require.NoError(t, err)
+	task, err := store.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, &Task{Key: "k", Handler: "h", Data: []byte("d1"), ExecuteAt: at.UnixMilli(), Version: v1}, task)
+
+	// 覆盖时版本号递增,领取次数清零
+	_, err = store.Claim(ctx, at, 10, time.Minute)
+	require.NoError(t, err)
+	v2, err := store.Put(ctx, &Task{Key: "k", Handler: "h", Data: []byte("d2"), ExecuteAt: at.UnixMilli()})
+	require.NoError(t, err)
+	task, err = store.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Greater(t, v2, v1)
+	assert.Equal(t, []byte("d2"), task.Data)
+	assert.Equal(t, 0, task.Attempts)
+
+	task, err = store.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, task)
+}
+
+func testStoreClaimFencing(t *testing.T, store Store) {
+	ctx := context.Background()
+	now := time.UnixMilli(time.Now().UnixMilli())
+	v0, err := store.Put(ctx, &Task{Key: "k", Handler: "h", ExecuteAt: now.UnixMilli()})
+	require.NoError(t, err)
+
+	tasks, err := store.Claim(ctx, now.Add(-time.Millisecond), 10, time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, tasks, "not due yet")
+
+	tasks, err = store.Claim(ctx, now, 10, time.Second)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	first := tasks[0]
+	assert.Equal(t, 1, first.Attempts)
+	assert.Greater(t, first.Version, v0, "claim issues a new token")
+	assert.Equal(t, now.UnixMilli(), first.ExecuteAt)
+
+	// 租约内不会被再次领取
+	tasks, err = store.Claim(ctx, now.Add(time.Second-time.Millisecond), 10, time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	// 租约过期后被重新领取,旧的领取无法确认或重试
+	later := now.Add(time.Second)
+	tasks, err = store.Claim(ctx, later, 10, time.Second)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	second := tasks[0]
+	assert.Equal(t, 2, second.Attempts)
+	assert.Greater(t, second.Version, first.Version)
+
+	require.NoError(t, store.Ack(ctx, "k", first.Version))
+	require.NoError(t, store.Retry(ctx, "k", first.Version, now.Add(time.Hour)))
+	task, err := store.Get(ctx, "k")
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, later.Add(time.Second).UnixMilli(), task.ExecuteAt)
+
+	retryAt := later.Add(time.Minute)
+	require.NoError(t, store.Retry(ctx, "k", second.Version, retryAt))
+	task, err = store.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, retryAt.UnixMilli(), task.ExecuteAt)
+	assert.Equal(t, second.Version, task.Version)
+
+	require.NoError(t, store.Ack(ctx, "k", second.Version))
+	task, err = store.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Nil(t, task)
+}
+
+func testStoreRescheduleWhileRunning(t *testing.T, store Store) {
+	ctx := context.Background()
+	now := time.UnixMilli(time.Now().UnixMilli())
+	_, err := store.Put(ctx, &Task{Key: "k", Handler: "h", ExecuteAt: now.UnixMilli()})
+	require.NoError(t, err)
+	tasks, err := store.Claim(ctx, now, 10, time.Second)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+
+	at := now.Add(time.Hour)
+	ok, err := store.Reschedule(ctx, "k", at)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	// 执行期间被重新调度,确认不删除任务
+	require.NoError(t, store.Ack(ctx, "k", tasks[0].Version))
+	task, err := store.Get(ctx, "k")
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, at.UnixMilli(), task.ExecuteAt)
+	assert.Greater(t, task.Version, tasks[0].Version)
+
+	ok, err = store.Reschedule(ctx, "missing", at)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func testStoreCancel(t *testing.T, store Store) {
+	ctx := context.Background()
+	now := time.UnixMilli(time.Now().UnixMilli())
+	_, err := store.Put(ctx, &Task{Key: "k", Handler: "h", ExecuteAt: now.UnixMilli()})
+	require.NoError(t, err)
+	ok, err := store.Cancel(ctx, "k")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = store.Cancel(ctx, "k")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	tasks, err := store.Claim(ctx, now, 10, time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func testStoreClaimLimit(t *testing.T, store Store) {
+	ctx := context.Background()
+	now := time.UnixMilli(time.Now().UnixMilli())
+	for i, key := range []string{"c", "a", "b"} {
+		_, err := store.Put(ctx, &Task{Key: key, Handler: "h", ExecuteAt: now.Add(time.Duration(i) * time.Millisecond).UnixMilli()})
+		require.NoError(t, err)
+	}
+	tasks, err := store.Claim(ctx, now.Add(time.Second), 2, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "c", tasks[0].Key)
+	assert.Equal(t, "a", tasks[1].Key)
+}
+
+func TestRedisStoreClaimDropsOrphanKey(t *testing.T) {
+	store, client := newTestRedisStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	// 只有到期时间没有数据的key在领取时被清理
+	require.NoError(t, client.ZAdd(ctx, store.dueKey, &redis.Z{Score: float64(now.UnixMilli()), Member: "orphan"}).Err())
+	tasks, err := store.Claim(ctx, now, 10, time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+	n, err := client.ZCard(ctx, store.dueKey).Result()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
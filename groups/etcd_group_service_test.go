@@ -21,6 +21,7 @@
 package groups
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/topfreegames/pitaya/v2/config"
@@ -28,9 +29,12 @@ import (
 )
 
 func setup(t *testing.T) (*integration.ClusterV3, GroupService) {
-	integration.BeforeTest(t)
+	// 包初始化时创建的常驻goroutine(如无状态线程池)在首个测试结束时仍存在,泄漏检测会误报
+	integration.BeforeTest(t, integration.WithoutGoLeakDetection())
 	cluster := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
 	cli := cluster.RandClient()
+	// 每个测试使用独立的etcd集群,需重置单例client
+	etcdOnce = sync.Once{}
 	etcdGroupService, err := NewEtcdGroupService(*config.NewDefaultEtcdGroupServiceConfig(), cli)
 	if err != nil {
 		panic(err)
@@ -5,7 +5,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/clock"
 	"github.com/topfreegames/pitaya/v2/config"
 	"github.com/topfreegames/pitaya/v2/constants"
 )
@@ -31,13 +31,19 @@ type MemoryGroup struct {
 func NewMemoryGroupService(config config.MemoryGroupConfig) *MemoryGroupService {
 	memoryOnce.Do(func() {
 		memoryGroups = make(map[string]*MemoryGroup)
-		co.Go(func() { groupTTLCleanup(config.TickDuration) })
+		groupTTLCleanup(config.TickDuration)
 	})
 	return &MemoryGroupService{}
 }
 
+// groupTTLCleanup 每隔duration清理过期的group,使用 clock.AfterFunc 定时,测试时可由虚拟时钟触发.
+// duration<=0时不清理,与 time.Tick 一致
 func groupTTLCleanup(duration time.Duration) {
-	for now := range time.Tick(duration) {
+	if duration <= 0 {
+		return
+	}
+	clock.AfterFunc(duration, func() {
+		now := clock.Now()
 		memoryGroupsMu.Lock()
 		for groupName, mg := range memoryGroups {
 			if mg.TTL != 0 && now.UnixNano()-mg.LastRefresh > mg.TTL {
@@ -45,7 +51,8 @@ func groupTTLCleanup(duration time.Duration) {
 			}
 		}
 		memoryGroupsMu.Unlock()
-	}
+		groupTTLCleanup(duration)
+	})
 }
 
 // GroupCreate creates a group without TTL
@@ -70,7 +77,7 @@ func (c *MemoryGroupService) GroupCreateWithTTL(ctx context.Context, groupName s
 		return constants.ErrGroupAlreadyExists
 	}
 
-	memoryGroups[groupName] = &MemoryGroup{LastRefresh: time.Now().UnixNano(), TTL: ttlTime.Nanoseconds()}
+	memoryGroups[groupName] = &MemoryGroup{LastRefresh: clock.Now().UnixNano(), TTL: ttlTime.Nanoseconds()}
 	return nil
 }
 
@@ -196,7 +203,7 @@ func (c *MemoryGroupService) GroupRenewTTL(ctx context.Context, groupName string
 	}
 
 	if mg.TTL != 0 {
-		mg.LastRefresh = time.Now().UnixNano()
+		mg.LastRefresh = clock.Now().UnixNano()
 		return nil
 	}
 	return constants.ErrMemoryTTLNotFound
@@ -21,10 +21,15 @@
 package groups
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/helpers"
 )
 
 var memoryGroupService *MemoryGroupService
@@ -78,3 +83,21 @@ func TestMemoryCount(t *testing.T) {
 func TestMemoryMembers(t *testing.T) {
 	testMembers(memoryGroupService, t)
 }
+
+func TestMemoryGroupTTLCleanup(t *testing.T) {
+	c := helpers.UseFakeClock(t, time.Now())
+	groupTTLCleanup(0)
+	assert.Equal(t, 0, c.Waiters())
+	groupTTLCleanup(time.Second)
+	ctx := context.Background()
+	assert.NoError(t, memoryGroupService.GroupCreateWithTTL(ctx, "testMemoryGroupTTLCleanup", 3*time.Second))
+
+	c.Advance(2 * time.Second)
+	assert.NoError(t, memoryGroupService.GroupRenewTTL(ctx, "testMemoryGroupTTLCleanup"))
+	c.Advance(3 * time.Second)
+	_, err := memoryGroupService.GroupCountMembers(ctx, "testMemoryGroupTTLCleanup")
+	assert.NoError(t, err)
+	c.Advance(time.Second)
+	_, err = memoryGroupService.GroupCountMembers(ctx, "testMemoryGroupTTLCleanup")
+	assert.ErrorIs(t, err, constants.ErrGroupNotFound)
+}
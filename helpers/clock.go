@@ -0,0 +1,185 @@
+package helpers
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/topfreegames/pitaya/v2/clock"
+)
+
+// FakeClock 虚拟时钟,时间只在 Advance 时前进
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	seq     int64
+	waiters []*fakeWaiter
+}
+
+var _ clock.Clock = (*FakeClock)(nil)
+
+// fakeWaiter Ticker Timer AfterFunc 的统一实现
+type fakeWaiter struct {
+	clock  *FakeClock
+	seq    int64 // 同一时刻到期时按创建顺序触发
+	when   time.Time
+	period time.Duration // 大于0时为Ticker
+	ch     chan time.Time
+	fn     func()
+}
+
+// NewFakeClock returns a fake clock starting at now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// UseFakeClock 创建虚拟时钟并设置为 clock.Default,测试结束时恢复
+func UseFakeClock(t testing.TB, now time.Time) *FakeClock {
+	t.Helper()
+	c := NewFakeClock(now)
+	t.Cleanup(clock.SetDefault(c))
+	return c
+}
+
+// Advance 推进时间d,按到期时间依次触发到期的 Ticker Timer AfterFunc
+//
+//	AfterFunc 的回调在当前goroutine执行,回调中创建的已到期定时器也会在本次触发
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for {
+		w := c.nextDueLocked(target)
+		if w == nil {
+			break
+		}
+		c.now = w.when
+		if w.period > 0 {
+			w.when = w.when.Add(w.period)
+		} else {
+			c.removeLocked(w)
+		}
+		if w.fn != nil {
+			c.mu.Unlock()
+			w.fn()
+			c.mu.Lock()
+			continue
+		}
+		select {
+		case w.ch <- c.now:
+		default:
+		}
+	}
+	if target.After(c.now) {
+		c.now = target
+	}
+	c.mu.Unlock()
+}
+
+// Waiters 未到期的 Ticker Timer AfterFunc 数量,可用于等待其他goroutine开始等待
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Sleep 阻塞到其他goroutine调用 Advance 推进了d
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return fakeTicker{c.add(d, d, make(chan time.Time, 1), nil)}
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	return c.add(d, 0, make(chan time.Time, 1), nil)
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	return c.add(d, 0, nil, f)
+}
+
+func (c *FakeClock) add(d, period time.Duration, ch chan time.Time, fn func()) *fakeWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{clock: c, period: period, ch: ch, fn: fn}
+	c.scheduleLocked(w, d)
+	return w
+}
+
+func (c *FakeClock) scheduleLocked(w *fakeWaiter, d time.Duration) {
+	c.seq++
+	w.seq = c.seq
+	w.when = c.now.Add(d)
+	c.waiters = append(c.waiters, w)
+}
+
+// nextDueLocked 最早到期且不晚于target的waiter
+func (c *FakeClock) nextDueLocked(target time.Time) *fakeWaiter {
+	sort.SliceStable(c.waiters, func(i, j int) bool {
+		if c.waiters[i].when.Equal(c.waiters[j].when) {
+			return c.waiters[i].seq < c.waiters[j].seq
+		}
+		return c.waiters[i].when.Before(c.waiters[j].when)
+	})
+	if len(c.waiters) == 0 || c.waiters[0].when.After(target) {
+		return nil
+	}
+	return c.waiters[0]
+}
+
+func (c *FakeClock) removeLocked(w *fakeWaiter) bool {
+	for i, v := range c.waiters {
+		if v == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	return w.clock.removeLocked(w)
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	active := w.clock.removeLocked(w)
+	if w.period > 0 {
+		w.period = d
+	}
+	w.clock.scheduleLocked(w, d)
+	return active
+}
+
+// fakeTicker Stop Reset 没有返回值
+type fakeTicker struct{ *fakeWaiter }
+
+func (t fakeTicker) Stop() { t.fakeWaiter.Stop() }
+
+func (t fakeTicker) Reset(d time.Duration) { t.fakeWaiter.Reset(d) }
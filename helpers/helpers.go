@@ -61,6 +61,16 @@ func GetTestNatsServer(t *testing.T) *server.Server {
 	return s
 }
 
+// GetTestJetStreamServer gets a test nats server with jetstream enabled
+func GetTestJetStreamServer(t *testing.T) *server.Server {
+	opts := gnatsd.DefaultTestOptions
+	opts.Port = GetFreePort(t)
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	s := gnatsd.RunServer(&opts)
+	return s
+}
+
 // GetTestEtcd gets a test in memory etcd server
 func GetTestEtcd(t *testing.T) (*integration.ClusterV3, *clientv3.Client) {
 	t.Helper()
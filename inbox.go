@@ -0,0 +1,151 @@
+package pitaya
+
+import (
+	"context"
+	"strings"
+
+	agent2 "github.com/topfreegames/pitaya/v2/agent"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/component"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/inbox"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"github.com/topfreegames/pitaya/v2/session"
+	"github.com/topfreegames/pitaya/v2/util"
+	"go.uber.org/zap"
+)
+
+// inboxHandler 处理客户端对收件箱消息的确认
+//
+//	@see constants.InboxAckRoute
+type inboxHandler struct {
+	component.Base
+	inbox inbox.Inbox
+}
+
+// Ack 删除客户端已确认的消息
+//
+//	@receiver h
+//	@param ctx
+//	@param req
+//	@return *inbox.AckReq
+//	@return error
+func (h *inboxHandler) Ack(ctx context.Context, req *inbox.AckReq) (*inbox.AckReq, error) {
+	s, ok := ctx.Value(constants.SessionCtxKey).(session.Session)
+	if !ok || s.UID() == "" {
+		return nil, constants.ErrNoUIDBind
+	}
+	err := h.inbox.Ack(ctx, s.UID(), req.Seq)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// SetInbox
+//
+//	@implement Pitaya.SetInbox
+func (app *App) SetInbox(ib inbox.Inbox) {
+	app.inbox = ib
+}
+
+// initInbox frontend上注册确认handler,在session绑定后重放离线消息,并转存断线时未确认的推送
+func (app *App) initInbox() {
+	if app.inbox == nil || !app.server.Frontend {
+		return
+	}
+	app.Register(&inboxHandler{inbox: app.inbox},
+		component.WithName(strings.Split(constants.InboxAckRoute, ".")[0]),
+		component.WithNameFunc(strings.ToLower),
+	)
+	app.sessionPool.OnAfterSessionBind(func(ctx context.Context, s session.Session, callback map[string]string) error {
+		// 异步重放,保证客户端先收到Bind的响应
+		co.Go(func() { app.replayInbox(context.Background(), s) })
+		return nil
+	})
+	// 断线时未确认的推送转存收件箱,下次Bind时重放
+	app.sessionPool.OnUnackedPush(func(s session.Session, pushes []*session.UnackedPush) {
+		if s.UID() == "" {
+			return
+		}
+		for _, p := range pushes {
+			if _, err := app.inbox.Put(context.Background(), s.UID(), p.Route, p.Data); err != nil {
+				logger.Zap.Error("inbox put unacked push error", zap.String("uid", s.UID()), zap.String("route", p.Route), zap.Error(err))
+			}
+		}
+	})
+}
+
+func (app *App) replayInbox(ctx context.Context, s session.Session) {
+	logW := logger.Zap.With(zap.Int64("sid", s.ID()), zap.String("uid", s.UID()))
+	items, err := app.inbox.List(ctx, s.UID())
+	if err != nil {
+		logW.Error("inbox list error", zap.Error(err))
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+	// 未确认的消息保留在收件箱,下次Bind时再次重放
+	for _, item := range items {
+		if err = s.Push(item.Route, item.Data); err != nil {
+			logW.Warn("inbox replay push error", zap.Int64("seq", item.Seq), zap.Error(err))
+			return
+		}
+	}
+	err = s.Push(constants.InboxReplayedRoute, &inbox.ReplayedPush{Seq: items[len(items)-1].Seq})
+	if err != nil {
+		logW.Warn("inbox replay push error", zap.Error(err))
+	}
+}
+
+// SendPersistentPushToUsers
+//
+//	@implement Pitaya.SendPersistentPushToUsers
+func (app *App) SendPersistentPushToUsers(route string, v interface{}, uids []string, frontendType string) ([]string, error) {
+	if app.inbox == nil {
+		return app.SendPushToUsers(route, v, uids, frontendType)
+	}
+	data, err := util.SerializeOrRaw(app.serializer, v)
+	if err != nil {
+		return uids, err
+	}
+	var online, offline []string
+	for _, uid := range uids {
+		if app.isUserOnline(uid) {
+			online = append(online, uid)
+		} else {
+			offline = append(offline, uid)
+		}
+	}
+	if len(online) > 0 {
+		notPushed, _ := app.SendPushToUsers(route, data, online, frontendType)
+		offline = append(offline, notPushed...)
+	}
+	var failed []string
+	for _, uid := range offline {
+		if _, err := app.inbox.Put(context.Background(), uid, route, data); err != nil {
+			failed = append(failed, uid)
+			logger.Zap.Error("inbox put error", zap.String("uid", uid), zap.String("route", route), zap.Error(err))
+		}
+	}
+	if len(failed) != 0 {
+		return failed, constants.ErrPushingToUsers
+	}
+	return nil, nil
+}
+
+// isUserOnline 本服session或cluster缓存中的在线状态
+func (app *App) isUserOnline(uid string) bool {
+	if s := app.sessionPool.GetSessionByUID(uid); s != nil {
+		return true
+	}
+	if app.rpcClient == nil {
+		return false
+	}
+	a, err := agent2.NewCluster(uid, app.sessionPool, app.rpcClient, app.serializer, app.serviceDiscovery)
+	if err != nil {
+		return false
+	}
+	return a.Session.Online()
+}
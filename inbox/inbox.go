@@ -0,0 +1,53 @@
+package inbox
+
+import (
+	"context"
+)
+
+type (
+	// Item 收件箱中的一条离线推送
+	Item struct {
+		Seq       int64  `json:"seq"`       // 用户内单调递增的序号,决定重放顺序
+		Route     string `json:"route"`     // 推送路由
+		Data      []byte `json:"data"`      // 已序列化的推送数据
+		CreatedAt int64  `json:"createdAt"` // 存入时间 unix秒
+	}
+
+	// Inbox 以uid为key的离线消息收件箱
+	Inbox interface {
+		// Put 存入一条推送,超出单用户上限时丢弃最早的消息
+		//  @param ctx
+		//  @param uid
+		//  @param route
+		//  @param data
+		//  @return int64 消息序号
+		//  @return error
+		Put(ctx context.Context, uid, route string, data []byte) (int64, error)
+		// List 按序号顺序返回未过期的消息
+		//  @param ctx
+		//  @param uid
+		//  @return []*Item
+		//  @return error
+		List(ctx context.Context, uid string) ([]*Item, error)
+		// Ack 客户端确认收到,删除序号小于等于seq的消息
+		//  @param ctx
+		//  @param uid
+		//  @param seq
+		//  @return error
+		Ack(ctx context.Context, uid string, seq int64) error
+	}
+)
+
+// AckReq 客户端确认收件箱消息的请求
+//
+//	@see constants.InboxAckRoute
+type AckReq struct {
+	Seq int64 `json:"seq"` // 已收到的最大序号
+}
+
+// ReplayedPush 重放结束后推送给客户端的数据
+//
+//	@see constants.InboxReplayedRoute
+type ReplayedPush struct {
+	Seq int64 `json:"seq"` // 本次重放的最大序号,客户端处理完后以此序号确认
+}
@@ -0,0 +1,74 @@
+package inbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/pitaya/v2/config"
+)
+
+// MemoryInbox 基于本地内存的收件箱,仅用于单机模式和测试
+type MemoryInbox struct {
+	mu        sync.Mutex
+	items     map[string][]*Item
+	seqs      map[string]int64
+	retention time.Duration
+	maxSize   int
+}
+
+// NewMemoryInbox returns a new memory inbox
+func NewMemoryInbox(conf config.InboxConfig) *MemoryInbox {
+	return &MemoryInbox{
+		items:     map[string][]*Item{},
+		seqs:      map[string]int64{},
+		retention: conf.Retention,
+		maxSize:   conf.MaxSize,
+	}
+}
+
+func (m *MemoryInbox) Put(ctx context.Context, uid, route string, data []byte) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seqs[uid]++
+	seq := m.seqs[uid]
+	items := append(m.items[uid], &Item{Seq: seq, Route: route, Data: data, CreatedAt: time.Now().Unix()})
+	if m.maxSize > 0 && len(items) > m.maxSize {
+		items = items[len(items)-m.maxSize:]
+	}
+	m.items[uid] = items
+	return seq, nil
+}
+
+func (m *MemoryInbox) List(ctx context.Context, uid string) ([]*Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deadline := time.Now().Add(-m.retention).Unix()
+	items := m.items[uid][:0]
+	for _, item := range m.items[uid] {
+		if m.retention > 0 && item.CreatedAt < deadline {
+			continue
+		}
+		items = append(items, item)
+	}
+	m.items[uid] = items
+	ret := make([]*Item, len(items))
+	copy(ret, items)
+	return ret, nil
+}
+
+func (m *MemoryInbox) Ack(ctx context.Context, uid string, seq int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	items := m.items[uid]
+	i := 0
+	for i < len(items) && items[i].Seq <= seq {
+		i++
+	}
+	if i == len(items) {
+		delete(m.items, uid)
+		return nil
+	}
+	m.items[uid] = items[i:]
+	return nil
+}
@@ -0,0 +1,82 @@
+package inbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya/v2/config"
+)
+
+func TestMemoryInboxPutList(t *testing.T) {
+	ib := NewMemoryInbox(config.InboxConfig{Retention: time.Hour, MaxSize: 10})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		seq, err := ib.Put(ctx, "u1", "mail.new", []byte{byte(i)})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(i+1), seq)
+	}
+	items, err := ib.List(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Len(t, items, 3)
+	for i, item := range items {
+		assert.Equal(t, int64(i+1), item.Seq)
+		assert.Equal(t, []byte{byte(i)}, item.Data)
+	}
+	items, err = ib.List(ctx, "u2")
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestMemoryInboxMaxSize(t *testing.T) {
+	ib := NewMemoryInbox(config.InboxConfig{Retention: time.Hour, MaxSize: 2})
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := ib.Put(ctx, "u1", "mail.new", nil)
+		assert.NoError(t, err)
+	}
+	items, err := ib.List(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, int64(4), items[0].Seq)
+	assert.Equal(t, int64(5), items[1].Seq)
+}
+
+func TestMemoryInboxAck(t *testing.T) {
+	ib := NewMemoryInbox(config.InboxConfig{Retention: time.Hour})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := ib.Put(ctx, "u1", "mail.new", nil)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, ib.Ack(ctx, "u1", 2))
+	items, err := ib.List(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, int64(3), items[0].Seq)
+
+	assert.NoError(t, ib.Ack(ctx, "u1", 3))
+	items, err = ib.List(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+
+	// 确认后序号继续递增
+	seq, err := ib.Put(ctx, "u1", "mail.new", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), seq)
+}
+
+func TestMemoryInboxRetention(t *testing.T) {
+	ib := NewMemoryInbox(config.InboxConfig{Retention: time.Hour})
+	ctx := context.Background()
+	_, err := ib.Put(ctx, "u1", "mail.new", nil)
+	assert.NoError(t, err)
+	ib.items["u1"][0].CreatedAt = time.Now().Add(-2 * time.Hour).Unix()
+	_, err = ib.Put(ctx, "u1", "mail.new", nil)
+	assert.NoError(t, err)
+	items, err := ib.List(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, int64(2), items[0].Seq)
+}
@@ -0,0 +1,96 @@
+package inbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/config"
+)
+
+const (
+	// 使用hash tag保证同一用户的key在redis cluster的同一个slot
+	redisInboxKey    = "pit:ib:{%s}"
+	redisInboxSeqKey = "pit:ibs:{%s}"
+)
+
+// RedisInbox 基于redis有序集合的收件箱,score为消息序号
+type RedisInbox struct {
+	conn      redis.Cmdable
+	retention time.Duration
+	maxSize   int
+}
+
+// NewRedisInbox returns a new redis inbox
+func NewRedisInbox(client redis.Cmdable, conf config.InboxConfig) *RedisInbox {
+	return &RedisInbox{
+		conn:      client,
+		retention: conf.Retention,
+		maxSize:   conf.MaxSize,
+	}
+}
+
+func (r *RedisInbox) Put(ctx context.Context, uid, route string, data []byte) (int64, error) {
+	seq, err := r.conn.Incr(ctx, fmt.Sprintf(redisInboxSeqKey, uid)).Result()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	b, err := json.Marshal(&Item{Seq: seq, Route: route, Data: data, CreatedAt: time.Now().Unix()})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	key := fmt.Sprintf(redisInboxKey, uid)
+	_, err = r.conn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, &redis.Z{Score: float64(seq), Member: b})
+		if r.maxSize > 0 {
+			pipe.ZRemRangeByRank(ctx, key, 0, int64(-r.maxSize-1))
+		}
+		if r.retention > 0 {
+			pipe.Expire(ctx, key, r.retention)
+			pipe.Expire(ctx, fmt.Sprintf(redisInboxSeqKey, uid), r.retention)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return seq, nil
+}
+
+func (r *RedisInbox) List(ctx context.Context, uid string) ([]*Item, error) {
+	key := fmt.Sprintf(redisInboxKey, uid)
+	members, err := r.conn.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	items := make([]*Item, 0, len(members))
+	var expired []interface{}
+	deadline := time.Now().Add(-r.retention).Unix()
+	for _, m := range members {
+		item := &Item{}
+		if err = json.Unmarshal([]byte(m), item); err != nil {
+			expired = append(expired, m)
+			continue
+		}
+		if r.retention > 0 && item.CreatedAt < deadline {
+			expired = append(expired, m)
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(expired) > 0 {
+		if err = r.conn.ZRem(ctx, key, expired...).Err(); err != nil {
+			return items, errors.WithStack(err)
+		}
+	}
+	return items, nil
+}
+
+func (r *RedisInbox) Ack(ctx context.Context, uid string, seq int64) error {
+	err := r.conn.ZRemRangeByScore(ctx, fmt.Sprintf(redisInboxKey, uid), "-inf", strconv.FormatInt(seq, 10)).Err()
+	return errors.WithStack(err)
+}
@@ -0,0 +1,122 @@
+package inbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/config"
+)
+
+func newTestRedisInbox(t *testing.T, conf config.InboxConfig) (*RedisInbox, *miniredis.Miniredis, *redis.Client) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisInbox(client, conf), mr, client
+}
+
+func TestRedisInboxPutList(t *testing.T) {
+	ib, mr, _ := newTestRedisInbox(t, config.InboxConfig{Retention: time.Hour, MaxSize: 10})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		seq, err := ib.Put(ctx, "u1", "mail.new", []byte{byte(i)})
+		require.NoError(t, err)
+		assert.Equal(t, int64(i+1), seq)
+	}
+	items, err := ib.List(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	for i, item := range items {
+		assert.Equal(t, int64(i+1), item.Seq)
+		assert.Equal(t, "mail.new", item.Route)
+		assert.Equal(t, []byte{byte(i)}, item.Data)
+	}
+	items, err = ib.List(ctx, "u2")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	// 消息和序号的key都带过期时间
+	assert.Equal(t, time.Hour, mr.TTL(fmt.Sprintf(redisInboxKey, "u1")))
+	assert.Equal(t, time.Hour, mr.TTL(fmt.Sprintf(redisInboxSeqKey, "u1")))
+	seq, err := mr.Get(fmt.Sprintf(redisInboxSeqKey, "u1"))
+	require.NoError(t, err)
+	assert.Equal(t, "3", seq)
+}
+
+func TestRedisInboxMaxSize(t *testing.T) {
+	ib, mr, _ := newTestRedisInbox(t, config.InboxConfig{MaxSize: 2})
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := ib.Put(ctx, "u1", "mail.new", nil)
+		require.NoError(t, err)
+	}
+	members, err := mr.ZMembers(fmt.Sprintf(redisInboxKey, "u1"))
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+	items, err := ib.List(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, int64(4), items[0].Seq)
+	assert.Equal(t, int64(5), items[1].Seq)
+	// 未配置保留时长时不设置过期
+	assert.Zero(t, mr.TTL(fmt.Sprintf(redisInboxKey, "u1")))
+}
+
+func TestRedisInboxAck(t *testing.T) {
+	ib, _, _ := newTestRedisInbox(t, config.InboxConfig{Retention: time.Hour})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := ib.Put(ctx, "u1", "mail.new", nil)
+		require.NoError(t, err)
+	}
+	require.NoError(t, ib.Ack(ctx, "u1", 2))
+	items, err := ib.List(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, int64(3), items[0].Seq)
+
+	require.NoError(t, ib.Ack(ctx, "u1", 3))
+	items, err = ib.List(ctx, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	// 确认后序号继续递增
+	seq, err := ib.Put(ctx, "u1", "mail.new", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), seq)
+}
+
+func TestRedisInboxRetention(t *testing.T) {
+	ib, mr, client := newTestRedisInbox(t, config.InboxConfig{Retention: time.Hour})
+	ctx := context.Background()
+	key := fmt.Sprintf(redisInboxKey, "u1")
+	old, err := json.Marshal(&Item{Seq: 1, Route: "mail.new", CreatedAt: time.Now().Add(-2 * time.Hour).Unix()})
+	require.NoError(t, err)
+	require.NoError(t, client.ZAdd(ctx, key, &redis.Z{Score: 1, Member: old}).Err())
+	require.NoError(t, client.ZAdd(ctx, key, &redis.Z{Score: 2, Member: "invalid"}).Err())
+	require.NoError(t, client.Set(ctx, fmt.Sprintf(redisInboxSeqKey, "u1"), 2, 0).Err())
+	_, err = ib.Put(ctx, "u1", "mail.new", nil)
+	require.NoError(t, err)
+
+	// 过期和无法解析的消息在读取时删除
+	items, err := ib.List(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, int64(3), items[0].Seq)
+	members, err := mr.ZMembers(key)
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+
+	// 保留时长内没有新消息时整个收件箱过期
+	mr.FastForward(2 * time.Hour)
+	items, err = ib.List(ctx, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.False(t, mr.Exists(fmt.Sprintf(redisInboxSeqKey, "u1")))
+}
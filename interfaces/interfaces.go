@@ -28,6 +28,11 @@ type Module interface {
 	Shutdown() error
 }
 
+// HealthChecker 模块健康检查,实现该接口的模块参与服务就绪判断,返回错误时服务状态为 unhealthy
+type HealthChecker interface {
+	HealthCheck() error
+}
+
 // BindingStorage interface
 type BindingStorage interface {
 	GetUserFrontendID(uid, frontendType string) (string, error)
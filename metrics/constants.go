@@ -7,6 +7,10 @@ var (
 	ConnectedClients = "connected_clients"
 	// CountServers counts the number of servers of different types
 	CountServers = "count_servers"
+	// SDFrozen 服务发现是否处于冻结拓扑状态 1:冻结 0:正常
+	SDFrozen = "frozen"
+	// SDStaleness 服务发现距最后一次同步成功的秒数
+	SDStaleness = "staleness_seconds"
 	// ChannelCapacity represents the capacity of a channel (available slots)
 	ChannelCapacity = "channel_capacity"
 	// DroppedMessages reports the number of dropped messages in rpc server (messages that will not be handled)
@@ -30,4 +34,20 @@ var (
 	ExceededRateLimiting = "exceeded_rate_limiting"
 	// PoolGoDeadlines 线程池中超时goroutine的数量
 	PoolGoDeadlines = "pool_go_deadlines"
+	// PoolOverflows 线程池队列满的次数
+	PoolOverflows = "pool_overflows"
+	// PoolQueueDepth 线程池排队中的任务数
+	PoolQueueDepth = "pool_queue_depth"
+	// PoolGoIDQueueDepth 线程池中排队最多的几个goID的任务数,按排名以slot标签区分
+	PoolGoIDQueueDepth = "pool_goid_queue_depth"
+	// PoolTaskWait 线程池任务排队等待的毫秒数
+	PoolTaskWait = "pool_task_wait"
+	// PoolTaskExec 线程池任务执行的毫秒数
+	PoolTaskExec = "pool_task_exec"
+	// PoolPanics 线程池任务panic的次数
+	PoolPanics = "pool_panics"
+	// ScopedTimers 各类 timer.Scope 中未结束的定时器数量
+	ScopedTimers = "scoped_timers"
+	// SessionEventsDropped 缓冲区满时丢弃的会话事件数
+	SessionEventsDropped = "session_events_dropped"
 )
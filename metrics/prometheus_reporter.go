@@ -164,6 +164,63 @@ func (p *PrometheusReporter) registerMetrics(
 		append([]string{"route", "pool", "second"}, additionalLabelsKeys...),
 	)
 
+	p.countReportersMap[PoolOverflows] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "gopool",
+			Name:        PoolOverflows,
+			Help:        "the number of tasks submitted to a full goroutine queue",
+			ConstLabels: constLabels,
+		},
+		append([]string{"pool", "policy"}, additionalLabelsKeys...),
+	)
+
+	p.countReportersMap[PoolPanics] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "gopool",
+			Name:        PoolPanics,
+			Help:        "the number of goroutine tasks that panicked",
+			ConstLabels: constLabels,
+		},
+		append([]string{"pool", "policy"}, additionalLabelsKeys...),
+	)
+
+	p.countReportersMap[SessionEventsDropped] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "sessionevents",
+			Name:        SessionEventsDropped,
+			Help:        "the number of session events dropped because the emitter buffer is full",
+			ConstLabels: constLabels,
+		},
+		append([]string{"type"}, additionalLabelsKeys...),
+	)
+
+	p.histogramReportersMap[PoolTaskWait] = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "gopool",
+			Name:        PoolTaskWait,
+			Help:        "the time a task waits in the goroutine queue in milliseconds",
+			Buckets:     []float64{1, 5, 10, 50, 100, 500, 1000, 5000, 10000, 60000},
+			ConstLabels: constLabels,
+		},
+		append([]string{"pool", "task"}, additionalLabelsKeys...),
+	)
+
+	p.histogramReportersMap[PoolTaskExec] = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "gopool",
+			Name:        PoolTaskExec,
+			Help:        "the time to execute a goroutine task in milliseconds",
+			Buckets:     []float64{1, 5, 10, 50, 100, 500, 1000, 5000, 10000, 60000},
+			ConstLabels: constLabels,
+		},
+		append([]string{"pool", "task"}, additionalLabelsKeys...),
+	)
+
 	// ProcessDelay summary
 	p.summaryReportersMap[ProcessDelay] = prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
@@ -200,6 +257,61 @@ func (p *PrometheusReporter) registerMetrics(
 		append([]string{"type"}, additionalLabelsKeys...),
 	)
 
+	p.gaugeReportersMap[SDFrozen] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "service_discovery",
+			Name:        SDFrozen,
+			Help:        "whether service discovery is in frozen topology mode",
+			ConstLabels: constLabels,
+		},
+		additionalLabelsKeys,
+	)
+
+	p.gaugeReportersMap[SDStaleness] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "service_discovery",
+			Name:        SDStaleness,
+			Help:        "seconds since the last successful service discovery sync",
+			ConstLabels: constLabels,
+		},
+		additionalLabelsKeys,
+	)
+
+	p.gaugeReportersMap[PoolQueueDepth] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "gopool",
+			Name:        PoolQueueDepth,
+			Help:        "the number of queued tasks of the goroutine pool",
+			ConstLabels: constLabels,
+		},
+		append([]string{"pool"}, additionalLabelsKeys...),
+	)
+
+	p.gaugeReportersMap[PoolGoIDQueueDepth] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "gopool",
+			Name:        PoolGoIDQueueDepth,
+			Help:        "the number of queued tasks of the most backlogged goroutines",
+			ConstLabels: constLabels,
+		},
+		append([]string{"pool", "slot"}, additionalLabelsKeys...),
+	)
+
+	p.gaugeReportersMap[ScopedTimers] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   "pitaya",
+			Subsystem:   "timer",
+			Name:        ScopedTimers,
+			Help:        "the number of pending timers owned by sessions or entities",
+			ConstLabels: constLabels,
+		},
+		append([]string{"scope"}, additionalLabelsKeys...),
+	)
+
 	p.gaugeReportersMap[ChannelCapacity] = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace:   "pitaya",
@@ -312,6 +424,14 @@ func (p *PrometheusReporter) registerMetrics(
 		toRegister = append(toRegister, c)
 	}
 
+	for name, c := range p.histogramReportersMap {
+		// ResponseTime 已注册为同名summary,重复注册会panic
+		if _, ok := p.summaryReportersMap[name]; ok {
+			continue
+		}
+		toRegister = append(toRegister, c)
+	}
+
 	prometheus.MustRegister(toRegister...)
 }
 
@@ -87,6 +87,51 @@ func ReportPoolGoDeadlines(ctx context.Context, poolName string, second int, rep
 	}
 }
 
+// ReportPoolOverflow reports a task submitted to a full goroutine queue
+func ReportPoolOverflow(reporters []Reporter, poolName, policy string) {
+	for _, r := range reporters {
+		r.ReportCount(PoolOverflows, map[string]string{"pool": poolName, "policy": policy}, 1)
+	}
+}
+
+// ReportPoolQueueDepth reports the number of queued tasks of a pool
+func ReportPoolQueueDepth(reporters []Reporter, poolName string, depth int64) {
+	for _, r := range reporters {
+		r.ReportGauge(PoolQueueDepth, map[string]string{"pool": poolName}, float64(depth))
+	}
+}
+
+// ReportPoolGoIDQueueDepth reports the number of queued tasks of the goID ranked at slot,
+// the slot label keeps the series bounded no matter how many goIDs there are
+func ReportPoolGoIDQueueDepth(reporters []Reporter, poolName string, slot, depth int) {
+	for _, r := range reporters {
+		r.ReportGauge(PoolGoIDQueueDepth, map[string]string{"pool": poolName, "slot": strconv.Itoa(slot)}, float64(depth))
+	}
+}
+
+// ReportPoolTaskTiming reports the queue wait and execution time of a goroutine task
+func ReportPoolTaskTiming(reporters []Reporter, poolName, task string, wait, exec time.Duration) {
+	tags := map[string]string{"pool": poolName, "task": task}
+	for _, r := range reporters {
+		r.ReportHistogram(PoolTaskWait, tags, float64(wait.Milliseconds()))
+		r.ReportHistogram(PoolTaskExec, tags, float64(exec.Milliseconds()))
+	}
+}
+
+// ReportSessionEventDropped reports a session event dropped because the emitter buffer is full
+func ReportSessionEventDropped(reporters []Reporter, eventType string) {
+	for _, r := range reporters {
+		r.ReportCount(SessionEventsDropped, map[string]string{"type": eventType}, 1)
+	}
+}
+
+// ReportPoolPanic reports a goroutine task panic handled with the given policy
+func ReportPoolPanic(reporters []Reporter, poolName, policy string) {
+	for _, r := range reporters {
+		r.ReportCount(PoolPanics, map[string]string{"pool": poolName, "policy": policy}, 1)
+	}
+}
+
 // ReportMessageProcessDelayFromCtx reports the delay to process the messages
 func ReportMessageProcessDelayFromCtx(ctx context.Context, reporters []Reporter, typ string) {
 	if len(reporters) > 0 {
@@ -127,6 +172,27 @@ func ReportSysMetrics(reporters []Reporter, period time.Duration) {
 	}
 }
 
+// ReportServiceDiscoveryFrozen reports the frozen topology state of service discovery
+func ReportServiceDiscoveryFrozen(reporters []Reporter, frozen bool, staleness time.Duration) {
+	value := 0.0
+	if frozen {
+		value = 1
+	}
+	for _, r := range reporters {
+		r.ReportGauge(SDFrozen, map[string]string{}, value)
+		r.ReportGauge(SDStaleness, map[string]string{}, staleness.Seconds())
+	}
+}
+
+// ReportScopedTimers reports the number of pending timers of each timer.Scope kind
+func ReportScopedTimers(reporters []Reporter, counts map[string]int64) {
+	for _, r := range reporters {
+		for kind, n := range counts {
+			r.ReportGauge(ScopedTimers, map[string]string{"scope": kind}, float64(n))
+		}
+	}
+}
+
 // ReportExceededRateLimiting reports the number of requests made
 // after exceeded rate limiting in a connection
 func ReportExceededRateLimiting(reporters []Reporter) {
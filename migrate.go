@@ -0,0 +1,43 @@
+package pitaya
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/protos"
+)
+
+// MigrateSession
+//
+//	@implement Pitaya.MigrateSession
+//	session在本服时直接处理,否则转发给session所在的frontend处理
+func (app *App) MigrateSession(ctx context.Context, uid string, targetFrontendID string) error {
+	if app.rpcServer == nil {
+		return constants.ErrRPCServerNotInitialized
+	}
+	target, err := app.serviceDiscovery.GetServer(targetFrontendID)
+	if err != nil {
+		return err
+	}
+	if !target.Frontend {
+		return errors.WithStack(constants.ErrMigrateTargetIllegal)
+	}
+	msg := &protos.BindMsg{
+		Uid: uid,
+		Fid: targetFrontendID,
+	}
+	if app.server.Frontend && app.sessionPool.GetSessionByUID(uid) != nil {
+		_, err = app.sys.MigrateSession(ctx, msg)
+		return err
+	}
+	sess, err := app.imperfectSessionForRPC(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if !sess.Online() {
+		return errors.WithStack(constants.ErrSessionNotFound)
+	}
+	_, err = sess.SendRequestToFrontend(ctx, constants.SessionMigrateRoute, msg)
+	return err
+}
@@ -0,0 +1,237 @@
+package modules
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"go.uber.org/zap"
+)
+
+// RouteLister 已注册路由的提供者,如 service.HandlerService 和 service.RemoteService
+type RouteLister interface {
+	Routes() []string
+}
+
+// PoolStatsProvider co线程池状态的提供者,如 co.StatefulPoolsModule
+type PoolStatsProvider interface {
+	Stats() []co.PoolStats
+}
+
+// PoolTasksProvider 可选实现,提供co线程池中执行中和排队中的任务,如 co.StatefulPoolsModule
+type PoolTasksProvider interface {
+	Tasks() []co.TaskInfo
+}
+
+// redactedKeys 配置快照中包含这些关键字的key会被隐藏
+var redactedKeys = []string{"password", "token", "secret", "credential"}
+
+// Admin 集群拓扑管理HTTP接口,供值班和看板查询当前服务视角的集群状态
+//
+//	绑定独立的管理地址,所有请求需携带 Authorization: Bearer {token},均为只读的GET接口:
+//	/servers 服务列表 /hashring 一致性哈希环成员 /leaders 选举主节点
+//	/routes 已注册的handler和remote路由 /pools co线程池状态 /pools/tasks co线程池中执行中和排队中的任务
+//	/config 配置快照(敏感字段已隐藏)
+type Admin struct {
+	Base
+	conf     config.AdminConfig
+	sd       cluster.ServiceDiscovery
+	handlers RouteLister
+	remotes  RouteLister
+	pools    PoolStatsProvider
+	appConf  *config.Config
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewAdmin returns a new instance of Admin
+//
+//	@param conf
+//	@param sd
+//	@param handlers handler路由
+//	@param remotes remote路由
+//	@param pools co线程池
+//	@param appConf 应用配置
+//	@return *Admin
+func NewAdmin(conf config.AdminConfig, sd cluster.ServiceDiscovery, handlers, remotes RouteLister, pools PoolStatsProvider, appConf *config.Config) *Admin {
+	a := &Admin{
+		conf:     conf,
+		sd:       sd,
+		handlers: handlers,
+		remotes:  remotes,
+		pools:    pools,
+		appConf:  appConf,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/servers", a.handle(a.servers))
+	mux.HandleFunc("/hashring", a.handle(a.hashRing))
+	mux.HandleFunc("/leaders", a.handle(a.leaders))
+	mux.HandleFunc("/routes", a.handle(a.routes))
+	mux.HandleFunc("/pools", a.handle(a.poolStats))
+	mux.HandleFunc("/pools/tasks", a.handle(a.poolTasks))
+	mux.HandleFunc("/config", a.handle(a.configSnapshot))
+	a.server = &http.Server{
+		Addr:         conf.Addr,
+		Handler:      mux,
+		ReadTimeout:  conf.ReadTimeout,
+		WriteTimeout: conf.WriteTimeout,
+	}
+	return a
+}
+
+// Init 监听管理地址
+//
+//	@implement interfaces.Module.Init
+//	@receiver a
+//	@return error
+func (a *Admin) Init() error {
+	if a.conf.Token == "" {
+		logger.Zap.Warn("admin token is empty, all admin requests will be rejected")
+	}
+	listener, err := net.Listen("tcp", a.conf.Addr)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	a.listener = listener
+	co.Go(func() {
+		if err := a.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Zap.Error("admin server stopped", zap.Error(err))
+		}
+	})
+	logger.Zap.Info("admin server listening", zap.String("addr", listener.Addr().String()))
+	return nil
+}
+
+// Addr 实际监听的地址
+//
+//	@receiver a
+//	@return string
+func (a *Admin) Addr() string {
+	if a.listener == nil {
+		return a.conf.Addr
+	}
+	return a.listener.Addr().String()
+}
+
+// Shutdown 关闭管理接口
+//
+//	@implement interfaces.Module.Shutdown
+//	@receiver a
+//	@return error
+func (a *Admin) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.conf.WriteTimeout)
+	defer cancel()
+	return errors.WithStack(a.server.Shutdown(ctx))
+}
+
+// authorized 校验token,token为空时拒绝所有请求
+func (a *Admin) authorized(r *http.Request) bool {
+	if a.conf.Token == "" {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(a.conf.Token)) == 1
+}
+
+func (a *Admin) handle(f func() any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if !a.authorized(r) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(f()); err != nil {
+			logger.Zap.Warn("failed to write admin response", zap.String("path", r.URL.Path), zap.Error(err))
+		}
+	}
+}
+
+func (a *Admin) servers() any {
+	if a.sd == nil {
+		return []*cluster.Server{}
+	}
+	return a.sd.GetServers()
+}
+
+func (a *Admin) hashRing() any {
+	ring, ok := a.sd.(cluster.ConsistentHashRing)
+	if !ok {
+		return map[string]map[string]int{}
+	}
+	return ring.HashRingMembers()
+}
+
+func (a *Admin) leaders() any {
+	if a.sd == nil {
+		return map[string]any{"leader": "", "elections": map[string]string{}}
+	}
+	elections := map[string]string{}
+	if coordinator, ok := a.sd.(cluster.Coordinator); ok {
+		elections = coordinator.Leaders()
+	}
+	return map[string]any{
+		"leader":    a.sd.LeaderID(),
+		"elections": elections,
+	}
+}
+
+func (a *Admin) routes() any {
+	routes := map[string][]string{"handlers": {}, "remotes": {}}
+	if a.handlers != nil {
+		routes["handlers"] = a.handlers.Routes()
+	}
+	if a.remotes != nil {
+		routes["remotes"] = a.remotes.Routes()
+	}
+	return routes
+}
+
+func (a *Admin) poolStats() any {
+	if a.pools == nil {
+		return []co.PoolStats{}
+	}
+	return a.pools.Stats()
+}
+
+func (a *Admin) poolTasks() any {
+	if provider, ok := a.pools.(PoolTasksProvider); ok {
+		return provider.Tasks()
+	}
+	return []co.TaskInfo{}
+}
+
+func (a *Admin) configSnapshot() any {
+	if a.appConf == nil {
+		return map[string]any{}
+	}
+	return redactSettings(a.appConf.Viper().AllSettings())
+}
+
+// redactSettings 隐藏敏感配置
+func redactSettings(settings map[string]any) map[string]any {
+	ret := make(map[string]any, len(settings))
+	for k, v := range settings {
+		lower := strings.ToLower(k)
+		if lo.ContainsBy(redactedKeys, func(key string) bool { return strings.Contains(lower, key) }) {
+			ret[k] = "******"
+		} else if m, ok := v.(map[string]any); ok {
+			ret[k] = redactSettings(m)
+		} else {
+			ret[k] = v
+		}
+	}
+	return ret
+}
@@ -0,0 +1,122 @@
+package modules
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/config"
+)
+
+type staticRoutes []string
+
+func (r staticRoutes) Routes() []string { return r }
+
+type staticPools []co.PoolStats
+
+func (p staticPools) Stats() []co.PoolStats { return p }
+
+func (p staticPools) Tasks() []co.TaskInfo {
+	return []co.TaskInfo{{Pool: "default", GoID: 1, Name: "save", Running: true}}
+}
+
+func newTestAdmin(t *testing.T, token string) *Admin {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "servers.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`servers:
+  - id: game-1
+    type: game
+    status: ready
+`), 0o644))
+	sdConf := config.NewDefaultStaticServiceDiscoveryConfig()
+	sdConf.File = file
+	sd, err := cluster.NewStaticServiceDiscovery(*sdConf, cluster.NewServer("connector-1", "connector", true))
+	require.NoError(t, err)
+	require.NoError(t, sd.Init())
+
+	appConf := config.NewConfig()
+	conf := config.NewDefaultAdminConfig()
+	conf.Addr = "127.0.0.1:0"
+	conf.Token = token
+	admin := NewAdmin(*conf, sd, staticRoutes{"connector.entry.login"}, staticRoutes{}, staticPools{{Name: "default", Cap: 10}}, appConf)
+	require.NoError(t, admin.Init())
+	t.Cleanup(func() {
+		admin.Shutdown()
+		sd.Shutdown()
+	})
+	return admin
+}
+
+func adminGet(t *testing.T, admin *Admin, path, token string, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "http://"+admin.Addr()+path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && v != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestAdminAuth(t *testing.T) {
+	admin := newTestAdmin(t, "secret")
+	assert.Equal(t, http.StatusUnauthorized, adminGet(t, admin, "/servers", "wrong", nil))
+	assert.Equal(t, http.StatusOK, adminGet(t, admin, "/servers", "secret", nil))
+
+	noToken := newTestAdmin(t, "")
+	assert.Equal(t, http.StatusUnauthorized, adminGet(t, noToken, "/servers", "", nil))
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	admin := newTestAdmin(t, "secret")
+
+	var servers []*cluster.Server
+	assert.Equal(t, http.StatusOK, adminGet(t, admin, "/servers", "secret", &servers))
+	require.Len(t, servers, 2)
+
+	var ring map[string]map[string]int
+	adminGet(t, admin, "/hashring", "secret", &ring)
+	assert.Equal(t, map[string]int{"game-1": 100}, ring["game"])
+
+	var routes map[string][]string
+	adminGet(t, admin, "/routes", "secret", &routes)
+	assert.Equal(t, []string{"connector.entry.login"}, routes["handlers"])
+	assert.Empty(t, routes["remotes"])
+
+	var pools []co.PoolStats
+	adminGet(t, admin, "/pools", "secret", &pools)
+	assert.Equal(t, []co.PoolStats{{Name: "default", Cap: 10}}, pools)
+
+	var tasks []co.TaskInfo
+	adminGet(t, admin, "/pools/tasks", "secret", &tasks)
+	assert.Equal(t, []co.TaskInfo{{Pool: "default", GoID: 1, Name: "save", Running: true}}, tasks)
+
+	var leaders map[string]any
+	adminGet(t, admin, "/leaders", "secret", &leaders)
+	assert.Equal(t, "connector-1", leaders["leader"])
+}
+
+func TestRedactSettings(t *testing.T) {
+	settings := redactSettings(map[string]any{
+		"addr": "localhost",
+		"redis": map[string]any{
+			"password": "p",
+			"db":       1,
+		},
+		"admin": map[string]any{"token": "t"},
+	})
+	assert.Equal(t, map[string]any{
+		"addr":  "localhost",
+		"redis": map[string]any{"password": "******", "db": 1},
+		"admin": map[string]any{"token": "******"},
+	}, settings)
+}
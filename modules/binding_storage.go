@@ -95,7 +95,20 @@ func (b *ETCDBindingStorage) GetUserFrontendID(uid, frontendType string) (string
 
 func (b *ETCDBindingStorage) setupOnSessionCloseCB() {
 	b.sessionPool.OnSessionClose(func(s session.Session, callback map[string]string, reason session.CloseReason) {
-		if s.UID() != "" && reason != session.CloseReasonKickRebind {
+		// 迁移时绑定关系已由目标网关接管,不能删除
+		if s.UID() != "" && reason != session.CloseReasonKickRebind && reason != session.CloseReasonKickMigrate {
+			err := b.removeBinding(s.UID())
+			if err != nil {
+				logger.Zap.Error("error removing binding info from storage", zap.Error(err))
+			}
+		}
+	})
+}
+
+func (b *ETCDBindingStorage) setupOnMigrationAbandonedCB() {
+	// 迁移未被目标网关接管,关闭时保留的绑定关系需要删除
+	b.sessionPool.OnMigrationAbandoned(func(s session.Session) {
+		if s.UID() != "" {
 			err := b.removeBinding(s.UID())
 			if err != nil {
 				logger.Zap.Error("error removing binding info from storage", zap.Error(err))
@@ -176,6 +189,7 @@ func (b *ETCDBindingStorage) Init() error {
 
 	if b.thisServer.Frontend {
 		b.setupOnSessionCloseCB()
+		b.setupOnMigrationAbandonedCB()
 		b.setupOnAfterSessionBindCB()
 	}
 
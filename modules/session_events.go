@@ -0,0 +1,189 @@
+package modules
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nuid"
+	"github.com/topfreegames/pitaya/v2/clock"
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"github.com/topfreegames/pitaya/v2/metrics"
+	"github.com/topfreegames/pitaya/v2/session"
+	"github.com/topfreegames/pitaya/v2/sessionevent"
+	"go.uber.org/zap"
+)
+
+// SessionEventEmitter 将会话生命周期事件发布到外部 sessionevent.Sink
+//
+//	frontend发布bind和close事件,backend发布bindbackend和kickbackend事件.
+//	发布失败时按 RetryInterval 重试直到成功,投递语义为至少一次;
+//	sink持续故障导致缓冲区满时,session回调最多等待 EnqueueTimeout,超时后丢弃事件并记录日志和指标,不阻塞session的绑定和关闭
+type SessionEventEmitter struct {
+	Base
+	thisServer     *cluster.Server
+	sessionPool    session.SessionPool
+	sink           sessionevent.Sink
+	events         chan *sessionevent.Event
+	enqueueTimeout time.Duration
+	retryInterval  time.Duration
+	reporters      []metrics.Reporter
+	stopChan       chan struct{}
+	doneChan       chan struct{}
+}
+
+// NewSessionEventEmitter returns a new instance of SessionEventEmitter
+func NewSessionEventEmitter(server *cluster.Server, sessionPool session.SessionPool, sink sessionevent.Sink, conf config.SessionEventsConfig, reporters []metrics.Reporter) *SessionEventEmitter {
+	return &SessionEventEmitter{
+		thisServer:     server,
+		sessionPool:    sessionPool,
+		sink:           sink,
+		events:         make(chan *sessionevent.Event, conf.BufferSize),
+		enqueueTimeout: conf.EnqueueTimeout,
+		retryInterval:  conf.RetryInterval,
+		reporters:      reporters,
+		stopChan:       make(chan struct{}),
+		doneChan:       make(chan struct{}),
+	}
+}
+
+func (e *SessionEventEmitter) newEvent(typ sessionevent.Type, s session.Session, callback map[string]string) *sessionevent.Event {
+	return &sessionevent.Event{
+		ID:         nuid.Next(),
+		Type:       typ,
+		Uid:        s.UID(),
+		Sid:        s.GetFrontendSessionID(),
+		FrontendID: s.GetFrontendID(),
+		ServerID:   e.thisServer.ID,
+		Timestamp:  time.Now().UnixMilli(),
+		Metadata:   callback,
+	}
+}
+
+func (e *SessionEventEmitter) newFrontendEvent(typ sessionevent.Type, s session.Session, callback map[string]string) *sessionevent.Event {
+	event := e.newEvent(typ, s, callback)
+	event.Sid = s.ID()
+	event.FrontendID = e.thisServer.ID
+	event.IP = s.RemoteIPText()
+	event.ConnectedAt = s.CreatedAt()
+	return event
+}
+
+// emit 事件进入缓冲区,缓冲区满时最多等待 enqueueTimeout,超时后丢弃
+func (e *SessionEventEmitter) emit(event *sessionevent.Event) {
+	select {
+	case e.events <- event:
+		return
+	default:
+	}
+	timer := clock.NewTimer(e.enqueueTimeout)
+	defer timer.Stop()
+	select {
+	case e.events <- event:
+	case <-timer.C():
+		logger.Zap.Error("session event dropped,buffer is full", zap.String("id", event.ID), zap.String("type", string(event.Type)), zap.String("uid", event.Uid))
+		metrics.ReportSessionEventDropped(e.reporters, string(event.Type))
+	case <-e.stopChan:
+		logger.Zap.Warn("session event dropped after shutdown", zap.String("type", string(event.Type)), zap.String("uid", event.Uid))
+	}
+}
+
+func (e *SessionEventEmitter) setupCallbacks() {
+	if e.thisServer.Frontend {
+		e.sessionPool.OnAfterSessionBind(func(ctx context.Context, s session.Session, callback map[string]string) error {
+			e.emit(e.newFrontendEvent(sessionevent.TypeBind, s, callback))
+			return nil
+		})
+		e.sessionPool.OnSessionClose(func(s session.Session, callback map[string]string, reason session.CloseReason) {
+			if s.UID() == "" {
+				return
+			}
+			event := e.newFrontendEvent(sessionevent.TypeClose, s, callback)
+			event.Reason = reason
+			e.emit(event)
+		})
+		return
+	}
+	e.sessionPool.OnAfterBindBackend(func(ctx context.Context, s session.Session, serverType, serverId string, callback map[string]string) error {
+		event := e.newEvent(sessionevent.TypeBindBackend, s, callback)
+		event.BackendType = serverType
+		event.BackendID = serverId
+		e.emit(event)
+		return nil
+	})
+	e.sessionPool.OnAfterKickBackend(func(ctx context.Context, s session.Session, serverType, serverId string, callback map[string]string, reason session.CloseReason) error {
+		event := e.newEvent(sessionevent.TypeKickBackend, s, callback)
+		event.BackendType = serverType
+		event.BackendID = serverId
+		event.Reason = reason
+		e.emit(event)
+		return nil
+	})
+}
+
+// publish 发布失败时重试直到成功或停止
+func (e *SessionEventEmitter) publish(event *sessionevent.Event) bool {
+	for {
+		err := e.sink.Publish(context.Background(), event)
+		if err == nil {
+			return true
+		}
+		logger.Zap.Warn("publish session event error,will retry", zap.String("id", event.ID), zap.String("type", string(event.Type)), zap.String("uid", event.Uid), zap.Error(err))
+		select {
+		case <-clock.After(e.retryInterval):
+		case <-e.stopChan:
+			return false
+		}
+	}
+}
+
+func (e *SessionEventEmitter) run() {
+	defer close(e.doneChan)
+	for {
+		select {
+		case event := <-e.events:
+			if !e.publish(event) {
+				e.drain(event)
+				return
+			}
+		case <-e.stopChan:
+			e.drain(nil)
+			return
+		}
+	}
+}
+
+// drain 停止时尽力发布缓冲区中剩余的事件,每个事件只尝试一次
+func (e *SessionEventEmitter) drain(pending *sessionevent.Event) {
+	if pending != nil {
+		if err := e.sink.Publish(context.Background(), pending); err != nil {
+			logger.Zap.Error("session event lost on shutdown", zap.String("id", pending.ID), zap.String("type", string(pending.Type)), zap.String("uid", pending.Uid), zap.Error(err))
+		}
+	}
+	for {
+		select {
+		case event := <-e.events:
+			if err := e.sink.Publish(context.Background(), event); err != nil {
+				logger.Zap.Error("session event lost on shutdown", zap.String("id", event.ID), zap.String("type", string(event.Type)), zap.String("uid", event.Uid), zap.Error(err))
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Init starts the session event emitter module
+func (e *SessionEventEmitter) Init() error {
+	e.setupCallbacks()
+	co.Go(func() { e.run() })
+	return nil
+}
+
+// Shutdown stops publishing and closes the sink
+func (e *SessionEventEmitter) Shutdown() error {
+	close(e.stopChan)
+	<-e.doneChan
+	return e.sink.Close()
+}
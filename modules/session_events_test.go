@@ -0,0 +1,138 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/metrics"
+	metricsmocks "github.com/topfreegames/pitaya/v2/metrics/mocks"
+	"github.com/topfreegames/pitaya/v2/session"
+	sessionmocks "github.com/topfreegames/pitaya/v2/session/mocks"
+	"github.com/topfreegames/pitaya/v2/sessionevent"
+)
+
+type flakySink struct {
+	mu        sync.Mutex
+	failTimes int
+	attempts  int
+	published []*sessionevent.Event
+	closed    bool
+}
+
+func (s *flakySink) Publish(ctx context.Context, event *sessionevent.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.attempts <= s.failTimes {
+		return errors.New("publish failed")
+	}
+	s.published = append(s.published, event)
+	return nil
+}
+
+func (s *flakySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *flakySink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.published)
+}
+
+func newTestEmitter(sink sessionevent.Sink) *SessionEventEmitter {
+	conf := config.NewDefaultSessionEventsConfig()
+	conf.RetryInterval = 10 * time.Millisecond
+	server := cluster.NewServer("connector-1", "connector", true, nil)
+	return NewSessionEventEmitter(server, session.NewSessionPool(), sink, *conf, nil)
+}
+
+func TestSessionEventEmitterRetry(t *testing.T) {
+	sink := &flakySink{failTimes: 3}
+	e := newTestEmitter(sink)
+	assert.NoError(t, e.Init())
+	e.emit(&sessionevent.Event{ID: "1", Type: sessionevent.TypeBind, Uid: "u1"})
+	e.emit(&sessionevent.Event{ID: "2", Type: sessionevent.TypeClose, Uid: "u1"})
+	assert.Eventually(t, func() bool { return sink.count() == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "1", sink.published[0].ID)
+	assert.Equal(t, "2", sink.published[1].ID)
+	assert.Equal(t, 5, sink.attempts)
+	assert.NoError(t, e.Shutdown())
+	assert.True(t, sink.closed)
+}
+
+func TestSessionEventEmitterDrainOnShutdown(t *testing.T) {
+	sink := &flakySink{}
+	e := newTestEmitter(sink)
+	// 未启动发布循环,事件都留在缓冲区
+	for i := 0; i < 3; i++ {
+		e.emit(&sessionevent.Event{Type: sessionevent.TypeBind, Uid: "u1"})
+	}
+	exited := make(chan struct{})
+	go func() {
+		e.run()
+		close(exited)
+	}()
+	assert.NoError(t, e.Shutdown())
+	<-exited
+	assert.Equal(t, 3, sink.count())
+}
+
+func TestSessionEventEmitterSinkOutage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	reporter := metricsmocks.NewMockReporter(ctrl)
+	sink := &flakySink{failTimes: 1 << 30}
+	conf := config.NewDefaultSessionEventsConfig()
+	conf.BufferSize = 1
+	conf.EnqueueTimeout = 10 * time.Millisecond
+	conf.RetryInterval = 10 * time.Millisecond
+	server := cluster.NewServer("connector-1", "connector", true, nil)
+	pool := session.NewSessionPool()
+	e := NewSessionEventEmitter(server, pool, sink, *conf, []metrics.Reporter{reporter})
+	assert.NoError(t, e.Init())
+
+	sess := sessionmocks.NewMockSession(ctrl)
+	sess.EXPECT().UID().Return("u1").AnyTimes()
+	sess.EXPECT().ID().Return(int64(1)).AnyTimes()
+	sess.EXPECT().GetFrontendSessionID().Return(int64(1)).AnyTimes()
+	sess.EXPECT().GetFrontendID().Return("connector-1").AnyTimes()
+	sess.EXPECT().RemoteIPText().Return("127.0.0.1").AnyTimes()
+	sess.EXPECT().CreatedAt().Return(int64(0)).AnyTimes()
+	// 一个事件在发布重试中,一个在缓冲区,其余超时丢弃
+	reporter.EXPECT().ReportCount(metrics.SessionEventsDropped, map[string]string{"type": string(sessionevent.TypeClose)}, float64(1)).Times(3)
+
+	closeSession := func() {
+		for _, cb := range pool.GetSessionCloseCallbacks() {
+			cb(sess, nil, session.CloseReasonNormal)
+		}
+	}
+	closeSession()
+	assert.Eventually(t, func() bool {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		return sink.attempts > 0
+	}, time.Second, time.Millisecond)
+
+	// sink故障时关闭回调不会一直阻塞
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for i := 0; i < 4; i++ {
+			closeSession()
+		}
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("session close callbacks blocked by sink outage")
+	}
+	assert.NoError(t, e.Shutdown())
+}
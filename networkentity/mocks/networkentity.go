@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	net "net"
+	netip "net/netip"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -37,10 +38,10 @@ func (m *MockNetworkEntity) EXPECT() *MockNetworkEntityMockRecorder {
 }
 
 // Close mocks base method.
-func (m *MockNetworkEntity) Close(arg0 ...int) error {
+func (m *MockNetworkEntity) Close(arg0 map[string]string, arg1 ...int) error {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{}
-	for _, a := range arg0 {
+	varargs := []interface{}{arg0}
+	for _, a := range arg1 {
 		varargs = append(varargs, a)
 	}
 	ret := m.ctrl.Call(m, "Close", varargs...)
@@ -49,23 +50,43 @@ func (m *MockNetworkEntity) Close(arg0 ...int) error {
 }
 
 // Close indicates an expected call of Close.
-func (mr *MockNetworkEntityMockRecorder) Close(arg0 ...interface{}) *gomock.Call {
+func (mr *MockNetworkEntityMockRecorder) Close(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockNetworkEntity)(nil).Close), arg0...)
+	varargs := append([]interface{}{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockNetworkEntity)(nil).Close), varargs...)
 }
 
 // Kick mocks base method.
-func (m *MockNetworkEntity) Kick(arg0 context.Context) error {
+func (m *MockNetworkEntity) Kick(arg0 context.Context, arg1 ...int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Kick", arg0)
+	varargs := []interface{}{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Kick", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Kick indicates an expected call of Kick.
-func (mr *MockNetworkEntityMockRecorder) Kick(arg0 interface{}) *gomock.Call {
+func (mr *MockNetworkEntityMockRecorder) Kick(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Kick", reflect.TypeOf((*MockNetworkEntity)(nil).Kick), varargs...)
+}
+
+// NetworkEntityName mocks base method.
+func (m *MockNetworkEntity) NetworkEntityName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NetworkEntityName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// NetworkEntityName indicates an expected call of NetworkEntityName.
+func (mr *MockNetworkEntityMockRecorder) NetworkEntityName() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Kick", reflect.TypeOf((*MockNetworkEntity)(nil).Kick), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NetworkEntityName", reflect.TypeOf((*MockNetworkEntity)(nil).NetworkEntityName))
 }
 
 // Push mocks base method.
@@ -96,6 +117,20 @@ func (mr *MockNetworkEntityMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockNetworkEntity)(nil).RemoteAddr))
 }
 
+// RemoteIP mocks base method.
+func (m *MockNetworkEntity) RemoteIP() netip.Addr {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoteIP")
+	ret0, _ := ret[0].(netip.Addr)
+	return ret0
+}
+
+// RemoteIP indicates an expected call of RemoteIP.
+func (mr *MockNetworkEntityMockRecorder) RemoteIP() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteIP", reflect.TypeOf((*MockNetworkEntity)(nil).RemoteIP))
+}
+
 // ResponseMID mocks base method.
 func (m *MockNetworkEntity) ResponseMID(arg0 context.Context, arg1 uint, arg2 interface{}, arg3 ...bool) error {
 	m.ctrl.T.Helper()
@@ -22,12 +22,17 @@ package remote
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/topfreegames/pitaya/v2/util"
 
+	"github.com/topfreegames/pitaya/v2/clock"
 	"github.com/topfreegames/pitaya/v2/co"
 
 	"github.com/topfreegames/pitaya/v2/cluster"
@@ -49,11 +54,17 @@ type Sys struct {
 	serverDiscovery cluster.ServiceDiscovery
 	rpcClient       cluster.RPCClient
 	remote          *service.RemoteService
+	migrateTimeout  time.Duration // 迁移时等待客户端重连到目标网关的最长时间
+	queries         sync.Map      // 进行中的session查询 key:查询ID value:chan *protos.Msg 接收各网关的回复
+	querySeq        atomic.Uint64 // session查询ID
 }
 
+// defaultQueryTimeout ctx未设置超时时session查询等待各网关回复的最长时间
+const defaultQueryTimeout = 5 * time.Second
+
 // NewSys returns a new Sys instance
-func NewSys(sessionPool session.SessionPool, server *cluster.Server, serverDiscovery cluster.ServiceDiscovery, client cluster.RPCClient, remoteService *service.RemoteService) *Sys {
-	return &Sys{sessionPool: sessionPool, server: server, serverDiscovery: serverDiscovery, rpcClient: client, remote: remoteService}
+func NewSys(sessionPool session.SessionPool, server *cluster.Server, serverDiscovery cluster.ServiceDiscovery, client cluster.RPCClient, remoteService *service.RemoteService, migrateTimeout time.Duration) *Sys {
+	return &Sys{sessionPool: sessionPool, server: server, serverDiscovery: serverDiscovery, rpcClient: client, remote: remoteService, migrateTimeout: migrateTimeout}
 }
 
 // Init initializes the module
@@ -76,6 +87,15 @@ func (sys *Sys) Init() {
 			}
 		}
 		logW := logger.Zap.With(zap.Int64("sid", s.ID()), zap.String("uid", s.UID()))
+		// 从其他网关迁移过来的session 接管交接数据,不广播绑定
+		ticket, err := s.TakeMigration(sys.server.ID)
+		if err != nil {
+			logW.Error("session binding error", zap.Error(err))
+			return err
+		}
+		if ticket != nil && !ticket.Expired() {
+			return sys.acceptMigration(ctx, s, ticket)
+		}
 		// 从redis同步backend bind数据到本地
 		err = s.InitialFromCluster()
 		if err != nil {
@@ -134,31 +154,15 @@ func (sys *Sys) Init() {
 		if reason == session.CloseReasonKickRebind {
 			return
 		}
-		logW := logger.Zap.With(zap.Int64("sid", s.ID()), zap.String("uid", s.UID()))
-		// 与stateful backend不同,frontend的绑定数据无须清除
-		if s.UID() != "" {
-			err := s.FlushOnline()
-			if err != nil {
-				logW.Error("session on close error", zap.Error(err))
-				return
-			}
-		}
-		// 通知所有 server
-		r, err := route.Decode(constants.SessionClosedRoute)
-		if err != nil {
-			logW.Error("session on close error", zap.Error(err))
+		// 迁移到其他网关的session已由目标网关接管,不做处理
+		if reason == session.CloseReasonKickMigrate {
 			return
 		}
-		msg := &protos.KickMsg{
-			UserId:   s.UID(),
-			Metadata: callback,
-		}
-		err = sys.remote.NotifyAll(context.Background(), r, sys.server, msg, s)
-		if err != nil {
-			logW.Error("session on close error", zap.Error(err))
-			return
-		}
-		// 这里只可能是frontend 不再考虑stateful backend的处理
+		sys.notifySessionClosed(s, callback)
+	})
+	// 迁移未被接管的session补做下线处理
+	sys.sessionPool.OnMigrationAbandoned(func(s session.Session) {
+		sys.notifySessionClosed(s, nil)
 	})
 	sys.sessionPool.OnBindBackend(func(ctx context.Context, s session.Session, serverType, serverId string, callback map[string]string) error {
 		msg := &protos.BindBackendMsg{
@@ -579,6 +583,232 @@ func (s *Sys) SessionKickedBackend(ctx context.Context, msg *protos.BindBackendM
 	})
 	return &protos.Response{Data: []byte("ack")}, nil
 }
+
+// notifySessionClosed 网关本地session下线,刷新在线状态并通知所有服务器
+//
+//	@receiver s
+//	@param sess
+//	@param callback
+func (s *Sys) notifySessionClosed(sess session.Session, callback map[string]string) {
+	logW := logger.Zap.With(zap.Int64("sid", sess.ID()), zap.String("uid", sess.UID()))
+	// 与stateful backend不同,frontend的绑定数据无须清除
+	if sess.UID() != "" {
+		err := sess.FlushOnline()
+		if err != nil {
+			logW.Error("session on close error", zap.Error(err))
+			return
+		}
+	}
+	// 通知所有 server
+	r, err := route.Decode(constants.SessionClosedRoute)
+	if err != nil {
+		logW.Error("session on close error", zap.Error(err))
+		return
+	}
+	msg := &protos.KickMsg{
+		UserId:   sess.UID(),
+		Metadata: callback,
+	}
+	err = s.remote.NotifyAll(context.Background(), r, s.server, msg, sess)
+	if err != nil {
+		logW.Error("session on close error", zap.Error(err))
+		return
+	}
+	// 这里只可能是frontend 不再考虑stateful backend的处理
+}
+
+// MigrateSession 将本网关的session迁移到其他网关
+//
+//	@see constants.SessionMigrateRoute
+//	推送重定向消息给客户端,并将session数据和backend绑定关系经由cluster cache交接给目标网关,
+//	超过 migrateTimeout 后由 settleMigration 按目标网关是否接管结算源网关的session
+//	@receiver s
+//	@param ctx
+//	@param msg Fid为目标网关ID
+//	@return *protos.Response
+//	@return error
+func (s *Sys) MigrateSession(ctx context.Context, msg *protos.BindMsg) (*protos.Response, error) {
+	if !s.server.Frontend {
+		return nil, errors.WithStack(constants.ErrDeveloperLogicFatal)
+	}
+	sess := s.sessionPool.GetSessionByUID(msg.Uid)
+	if sess == nil {
+		return nil, protos.ErrSessionNotFound().WithMessage("session is nil on migrate").WithMetadata(map[string]string{"uid": msg.Uid, "fid": msg.Fid}).WithStack()
+	}
+	if msg.Fid == s.server.ID {
+		return nil, errors.WithStack(constants.ErrMigrateTargetIllegal)
+	}
+	target, err := s.serverDiscovery.GetServer(msg.Fid)
+	if err != nil {
+		return nil, err
+	}
+	if !target.Frontend {
+		return nil, errors.WithStack(constants.ErrMigrateTargetIllegal)
+	}
+	addr := target.Metadata[constants.ClientAddrKey]
+	if addr == "" {
+		return nil, errors.WithStack(constants.ErrMigrateTargetNoAddr)
+	}
+	logW := logger.Zap.With(zap.Int64("sid", sess.ID()), zap.String("uid", sess.UID()), zap.String("target", target.ID))
+	redirect, err := json.Marshal(&session.RedirectData{Addr: addr, FrontendID: target.ID})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err = sess.FlushMigration(&session.MigrationTicket{
+		From:     s.server.ID,
+		To:       target.ID,
+		Sid:      sess.ID(),
+		Backends: sess.GetBackends(),
+		Data:     sess.GetDataEncoded(),
+		ExpireAt: clock.Now().Add(s.migrateTimeout).Unix(),
+	})
+	if err != nil {
+		logW.Error("session migrate error", zap.Error(err))
+		return nil, err
+	}
+	sess.SetMigrating(true)
+	err = sess.Push(constants.SessionRedirectRoute, redirect)
+	if err != nil {
+		sess.SetMigrating(false)
+		logW.Error("session migrate push redirect error", zap.Error(err))
+		// 撤回交接数据,避免目标网关在过期前仍可接管
+		if _, revokeErr := sess.TakeMigration(target.ID); revokeErr != nil {
+			logW.Error("session migrate revoke ticket error", zap.Error(revokeErr))
+		}
+		return nil, err
+	}
+	// 客户端应在收到重定向后主动断开并连接目标网关,超时后结算
+	clock.AfterFunc(s.migrateTimeout, func() {
+		s.settleMigration(sess, target.ID, logW)
+	})
+	return &protos.Response{Data: []byte("ack")}, nil
+}
+
+// settleMigration 迁移超时后结算源网关的session.
+// 迁移中的session断开时以 session.CloseReasonKickMigrate 关闭,不做下线处理,等待目标网关接管;
+// 超时后撤回未被接管的交接数据,已断开的session执行 session.SessionPool.OnMigrationAbandoned 回调补做下线处理,
+// 仍连接的session正常踢出,避免用户在集群中一直在线;
+// 已被接管时以 session.CloseReasonKickMigrate 踢出仍连接的session
+//
+//	@receiver s
+//	@param sess 源网关的session
+//	@param to 目标网关ID
+//	@param logW
+func (s *Sys) settleMigration(sess session.Session, to string, logW *zap.Logger) {
+	if !sess.IsMigrating() {
+		return
+	}
+	ticket, err := sess.TakeMigration(to)
+	if err != nil {
+		// 无法确认是否已接管,按未接管处理
+		logW.Error("session migrate revoke ticket error", zap.Error(err))
+	}
+	taken := err == nil && ticket == nil
+	sess.SetMigrating(false)
+	connected := s.sessionPool.GetSessionByID(sess.ID()) != nil
+	switch {
+	case taken && connected:
+		logW.Info("session migrate timeout,kick it")
+		if err := sess.Kick(context.Background(), nil, session.CloseReasonKickMigrate); err != nil {
+			logW.Warn("session migrate kick error", zap.Error(err))
+		}
+	case taken:
+	case connected:
+		logW.Warn("session migrate not taken over by target,kick it")
+		if err := sess.Kick(context.Background(), nil, session.CloseReasonNormal); err != nil {
+			logW.Warn("session migrate kick error", zap.Error(err))
+		}
+	default:
+		// 用户已重新登录本网关时由新session负责在线状态
+		if s.sessionPool.GetSessionByUID(sess.UID()) != nil {
+			return
+		}
+		logW.Warn("session migrate not taken over by target,set it offline")
+		for _, cb := range s.sessionPool.GetMigrationAbandonedCallbacks() {
+			cb(sess)
+		}
+	}
+}
+
+// acceptMigration 目标网关接管迁移过来的session,不触发绑定广播,仅通知已绑定的backend更新网关数据
+//
+//	@receiver s
+//	@param ctx
+//	@param sess
+//	@param ticket
+//	@return error
+func (s *Sys) acceptMigration(ctx context.Context, sess session.Session, ticket *session.MigrationTicket) error {
+	logW := logger.Zap.With(zap.Int64("sid", sess.ID()), zap.String("uid", sess.UID()), zap.String("from", ticket.From))
+	if ticket.Backends != nil {
+		sess.SetBackends(ticket.Backends)
+	}
+	err := sess.SetDataEncoded(ticket.Data)
+	if err != nil {
+		logW.Error("session accept migration error", zap.Error(err))
+		return err
+	}
+	err = sess.FlushFrontendData()
+	if err != nil {
+		logW.Error("session accept migration error", zap.Error(err))
+		return err
+	}
+	r, err := route.Decode(constants.SessionMigratedRoute)
+	if err != nil {
+		logW.Warn("session migrated notify error", zap.Error(err))
+		return nil
+	}
+	msg := &protos.BindMsg{
+		Uid: sess.UID(),
+		Fid: s.server.ID,
+		Sid: sess.ID(),
+	}
+	err = s.remote.NotifyAll(ctx, r, s.server, msg, sess)
+	if err != nil {
+		logW.Warn("session migrated notify error", zap.Error(err))
+	}
+	// NotifyAll 不包括同类型的网关,单独通知源网关关闭仍连接的session
+	source, err := s.serverDiscovery.GetServer(ticket.From)
+	if err != nil {
+		logW.Warn("session migrated notify source error", zap.Error(err))
+		return nil
+	}
+	r, err = route.Decode(source.Type + "." + constants.SessionMigratedRoute)
+	if err != nil {
+		logW.Warn("session migrated notify source error", zap.Error(err))
+		return nil
+	}
+	err = s.remote.RPC(ctx, source.ID, r, &protos.Response{}, msg, sess)
+	if err != nil {
+		logW.Warn("session migrated notify source error", zap.Error(err))
+	}
+	return nil
+}
+
+// SessionMigrated 收到session已迁移到新网关的通知,backend更新本地session的网关数据,源网关关闭仍连接的session
+//
+//	@see constants.SessionMigratedRoute
+//	@receiver s
+//	@param ctx
+//	@param msg
+//	@return *protos.Response
+//	@return error
+func (s *Sys) SessionMigrated(ctx context.Context, msg *protos.BindMsg) (*protos.Response, error) {
+	sess := s.sessionPool.GetSessionByUID(msg.Uid)
+	if sess == nil {
+		return &protos.Response{Data: []byte("ack")}, nil
+	}
+	if !s.server.Frontend {
+		sess.SetFrontendData(msg.Fid, msg.Sid)
+	} else if msg.Fid != s.server.ID && sess.IsMigrating() {
+		// 目标网关已接管,交接数据已被消费
+		sess.SetMigrating(false)
+		if err := sess.Kick(ctx, nil, session.CloseReasonKickMigrate); err != nil {
+			logger.Zap.Warn("session migrated kick error", zap.String("uid", msg.Uid), zap.Error(err))
+		}
+	}
+	return &protos.Response{Data: []byte("ack")}, nil
+}
+
 func (s *Sys) getSessionFromCtx(ctx context.Context) session.Session {
 	sessionVal := ctx.Value(constants.SessionCtxKey)
 	if sessionVal == nil {
@@ -587,3 +817,156 @@ func (s *Sys) getSessionFromCtx(ctx context.Context) session.Session {
 	}
 	return sessionVal.(session.Session)
 }
+
+// ForkQuerySessions 经 Fork 将查询广播给各frontend实例并等待其回复,合并结果后统一分页
+//
+//	各frontend返回前 Offset+Limit 条,超时或失败的frontend记录在 QueryResult.Errors 中.
+//	Fork 仅支持nats,ctx未设置超时时最多等待 defaultQueryTimeout
+//	@receiver s
+//	@param ctx
+//	@param q
+//	@param frontends 需要回复的frontend
+//	@return *session.QueryResult
+//	@return error
+func (s *Sys) ForkQuerySessions(ctx context.Context, q *session.SessionQuery, frontends []*cluster.Server) (*session.QueryResult, error) {
+	data, err := json.Marshal(q)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultQueryTimeout)
+		defer cancel()
+	}
+	id := s.querySeq.Add(1)
+	replies := make(chan *protos.Msg, len(frontends))
+	s.queries.Store(id, replies)
+	defer s.queries.Delete(id)
+
+	errs := map[string]string{}
+	pending := map[string]bool{}
+	types := map[string][]string{}
+	for _, sv := range frontends {
+		pending[sv.ID] = true
+		types[sv.Type] = append(types[sv.Type], sv.ID)
+	}
+	msg := &protos.Msg{Id: id, Reply: s.server.ID, Data: data}
+	for svType, ids := range types {
+		var r *route.Route
+		r, err = route.Decode(svType + "." + constants.SessionQueryRoute)
+		if err == nil {
+			err = s.remote.Fork(ctx, r, msg, nil)
+		}
+		if err != nil {
+			for _, fid := range ids {
+				errs[fid] = err.Error()
+				delete(pending, fid)
+			}
+		}
+	}
+	var (
+		total    int
+		sessions []*session.SessionInfo
+	)
+	for len(pending) > 0 {
+		select {
+		case reply := <-replies:
+			if !pending[reply.Reply] {
+				continue
+			}
+			delete(pending, reply.Reply)
+			ret := &session.QueryResult{}
+			if err = json.Unmarshal(reply.Data, ret); err != nil {
+				errs[reply.Reply] = err.Error()
+				continue
+			}
+			for fid, e := range ret.Errors {
+				errs[fid] = e
+			}
+			total += ret.Total
+			sessions = append(sessions, ret.Sessions...)
+		case <-ctx.Done():
+			for fid := range pending {
+				errs[fid] = ctx.Err().Error()
+			}
+			pending = nil
+		}
+	}
+	ret := q.Paginate(sessions)
+	ret.Total = total
+	if len(errs) > 0 {
+		ret.Errors = errs
+	}
+	return ret, nil
+}
+
+// QuerySessions 按条件查询本网关的session,并将结果回复给查询方
+//
+//	@see constants.SessionQueryRoute
+//	经 Fork 广播给同类型的所有网关,返回匹配总数和前 Offset+Limit 条数据,由查询方合并各网关结果后再统一分页
+//	@receiver s
+//	@param ctx
+//	@param msg Id为查询ID,Reply为查询方serverID,Data为json编码的 session.SessionQuery
+//	@return *protos.Response
+//	@return error
+func (s *Sys) QuerySessions(ctx context.Context, msg *protos.Msg) (*protos.Response, error) {
+	if !s.server.Frontend {
+		return nil, errors.WithStack(constants.ErrDeveloperLogicFatal)
+	}
+	q := &session.SessionQuery{}
+	if len(msg.Data) > 0 {
+		if err := json.Unmarshal(msg.Data, q); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	// 指定了其他网关时不回复
+	if q.FrontendID != "" && q.FrontendID != s.server.ID {
+		return &protos.Response{Data: []byte("ack")}, nil
+	}
+	local := *q
+	local.FrontendID = ""
+	local.Offset = 0
+	if q.Limit > 0 {
+		local.Limit = q.Offset + q.Limit
+	}
+	ret := s.sessionPool.QuerySessions(&local)
+	for _, info := range ret.Sessions {
+		info.FrontendID = s.server.ID
+	}
+	data, err := json.Marshal(ret)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	caller, err := s.serverDiscovery.GetServer(msg.Reply)
+	if err != nil {
+		return nil, err
+	}
+	r, err := route.Decode(caller.Type + "." + constants.SessionQueryReplyRoute)
+	if err != nil {
+		return nil, err
+	}
+	err = s.remote.RPC(ctx, caller.ID, r, &protos.Response{}, &protos.Msg{Id: msg.Id, Reply: s.server.ID, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &protos.Response{Data: []byte("ack")}, nil
+}
+
+// QuerySessionsReply 收到网关回复的session查询结果
+//
+//	@see constants.SessionQueryReplyRoute
+//	@receiver s
+//	@param ctx
+//	@param msg Id为查询ID,Reply为回复的网关ID,Data为json编码的 session.QueryResult
+//	@return *protos.Response
+//	@return error
+func (s *Sys) QuerySessionsReply(ctx context.Context, msg *protos.Msg) (*protos.Response, error) {
+	if v, ok := s.queries.Load(msg.Id); ok {
+		select {
+		case v.(chan *protos.Msg) <- msg:
+		default:
+			// 重复的回复
+		}
+	}
+	return &protos.Response{Data: []byte("ack")}, nil
+}
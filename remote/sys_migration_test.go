@@ -0,0 +1,120 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya/v2/cluster"
+	clustermocks "github.com/topfreegames/pitaya/v2/cluster/mocks"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/protos"
+	"github.com/topfreegames/pitaya/v2/session"
+	"github.com/topfreegames/pitaya/v2/session/mocks"
+	"go.uber.org/zap"
+)
+
+func TestSysSettleMigration(t *testing.T) {
+	tables := []struct {
+		name      string
+		migrating bool
+		ticket    *session.MigrationTicket // 超时后仍未被取走的交接数据
+		takeErr   error
+		connected bool
+		relogin   bool // 用户已重新登录本网关
+		kick      *session.CloseReason
+		closed    bool // 补做下线处理
+	}{
+		{name: "NotMigrating"},
+		{name: "TakenConnected", migrating: true, connected: true, kick: reason(session.CloseReasonKickMigrate)},
+		{name: "TakenDisconnected", migrating: true},
+		{name: "NotTakenConnected", migrating: true, ticket: &session.MigrationTicket{To: "connector-2"}, connected: true, kick: reason(session.CloseReasonNormal)},
+		{name: "NotTakenDisconnected", migrating: true, ticket: &session.MigrationTicket{To: "connector-2"}, closed: true},
+		{name: "NotTakenRelogin", migrating: true, ticket: &session.MigrationTicket{To: "connector-2"}, relogin: true},
+		{name: "TakeError", migrating: true, takeErr: errors.New("redis down"), closed: true},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			sess := mocks.NewMockSession(ctrl)
+			pool := mocks.NewMockSessionPool(ctrl)
+			sys := &Sys{sessionPool: pool}
+
+			sess.EXPECT().IsMigrating().Return(table.migrating)
+			if table.migrating {
+				sess.EXPECT().TakeMigration("connector-2").Return(table.ticket, table.takeErr)
+				sess.EXPECT().SetMigrating(false)
+				sess.EXPECT().ID().Return(int64(1)).AnyTimes()
+				sess.EXPECT().UID().Return("1001").AnyTimes()
+				if table.connected {
+					pool.EXPECT().GetSessionByID(int64(1)).Return(sess)
+				} else {
+					pool.EXPECT().GetSessionByID(int64(1)).Return(nil)
+				}
+			}
+			if table.kick != nil {
+				sess.EXPECT().Kick(gomock.Any(), gomock.Nil(), *table.kick)
+			}
+			if !table.connected && table.migrating && (table.ticket != nil || table.takeErr != nil) {
+				if table.relogin {
+					pool.EXPECT().GetSessionByUID("1001").Return(mocks.NewMockSession(ctrl))
+				} else {
+					pool.EXPECT().GetSessionByUID("1001").Return(nil)
+				}
+			}
+			closed := false
+			if table.closed {
+				// 只执行迁移未接管的回调,不重复执行关闭回调
+				pool.EXPECT().GetMigrationAbandonedCallbacks().Return([]session.OnMigrationAbandonedFunc{
+					func(s session.Session) {
+						assert.Equal(t, sess, s)
+						closed = true
+					},
+				})
+			}
+
+			sys.settleMigration(sess, "connector-2", zap.NewNop())
+			assert.Equal(t, table.closed, closed)
+		})
+	}
+}
+
+func TestSysMigrateSessionRedirectFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sess := mocks.NewMockSession(ctrl)
+	pool := mocks.NewMockSessionPool(ctrl)
+	sd := clustermocks.NewMockServiceDiscovery(ctrl)
+	sys := &Sys{
+		server:          cluster.NewServer("connector-1", "connector", true),
+		serverDiscovery: sd,
+		sessionPool:     pool,
+		migrateTimeout:  time.Minute,
+	}
+	target := cluster.NewServer("connector-2", "connector", true, map[string]string{constants.ClientAddrKey: "127.0.0.1:3250"})
+
+	pool.EXPECT().GetSessionByUID("1001").Return(sess)
+	sd.EXPECT().GetServer("connector-2").Return(target, nil)
+	sess.EXPECT().ID().Return(int64(1)).AnyTimes()
+	sess.EXPECT().UID().Return("1001").AnyTimes()
+	sess.EXPECT().GetBackends().Return(nil)
+	sess.EXPECT().GetDataEncoded().Return(nil)
+	pushErr := errors.New("connection closed")
+	gomock.InOrder(
+		sess.EXPECT().FlushMigration(gomock.Any()),
+		sess.EXPECT().SetMigrating(true),
+		sess.EXPECT().Push(constants.SessionRedirectRoute, gomock.Any()).Return(pushErr),
+		sess.EXPECT().SetMigrating(false),
+		// 重定向失败时撤回交接数据
+		sess.EXPECT().TakeMigration("connector-2"),
+	)
+
+	_, err := sys.MigrateSession(context.Background(), &protos.BindMsg{Uid: "1001", Fid: "connector-2"})
+	assert.ErrorIs(t, err, pushErr)
+}
+
+func reason(r session.CloseReason) *session.CloseReason {
+	return &r
+}
@@ -0,0 +1,108 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/cluster/mocks"
+	"github.com/topfreegames/pitaya/v2/config"
+	"github.com/topfreegames/pitaya/v2/route"
+	sessionmocks "github.com/topfreegames/pitaya/v2/session/mocks"
+)
+
+func versionedServer(id string, v cluster.VersionRange, routes map[string]cluster.VersionRange) *cluster.Server {
+	sv := cluster.NewServer(id, "game", false)
+	sv.APIVersion = v
+	sv.RouteVersions = routes
+	return sv
+}
+
+func TestCompatibleServers(t *testing.T) {
+	t.Parallel()
+	rt := route.NewRoute("game", "room", "join")
+	servers := map[string]*cluster.Server{
+		"undeclared": versionedServer("undeclared", cluster.VersionRange{}, nil),
+		"v1":         versionedServer("v1", cluster.VersionRange{Min: 1, Max: 1}, nil),
+		"v3":         versionedServer("v3", cluster.VersionRange{Min: 3, Max: 3}, nil),
+		"v1Method3":  versionedServer("v1Method3", cluster.VersionRange{Min: 1, Max: 1}, map[string]cluster.VersionRange{"room.join": {Min: 3, Max: 3}}),
+		"v3Service2": versionedServer("v3Service2", cluster.VersionRange{Min: 3, Max: 3}, map[string]cluster.VersionRange{"room": {Min: 2, Max: 2}}),
+	}
+	tables := []struct {
+		name string
+		self cluster.VersionRange
+		rt   *route.Route
+		want []string
+	}{
+		{"SelfUndeclared", cluster.VersionRange{}, rt, []string{"undeclared", "v1", "v3", "v1Method3", "v3Service2"}},
+		{"RouteVersionFirst", cluster.VersionRange{Min: 1, Max: 2}, rt, []string{"undeclared", "v1", "v3Service2"}},
+		{"OtherRoute", cluster.VersionRange{Min: 1, Max: 2}, route.NewRoute("game", "bag", "list"), []string{"undeclared", "v1", "v1Method3"}},
+		{"OpenMax", cluster.VersionRange{Min: 3}, rt, []string{"undeclared", "v3", "v1Method3"}},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			sd := mocks.NewMockServiceDiscovery(ctrl)
+			sd.EXPECT().GetSelfServer().Return(versionedServer("connector-1", table.self, nil))
+			r := New()
+			r.SetServiceDiscovery(sd)
+
+			compatible := r.compatibleServers(servers, table.rt)
+			ids := make([]string, 0, len(compatible))
+			for id := range compatible {
+				ids = append(ids, id)
+			}
+			assert.ElementsMatch(t, table.want, ids)
+		})
+	}
+}
+
+// ringServiceDiscovery 按固定顺序模拟哈希环
+type ringServiceDiscovery struct {
+	*mocks.MockServiceDiscovery
+	ring []string
+}
+
+func (sd *ringServiceDiscovery) SetConsistentHashConfig(conf config.ConsistentHashConfig) {}
+func (sd *ringServiceDiscovery) AddHashRingListener(listener cluster.HashRingListener)    {}
+func (sd *ringServiceDiscovery) HashRingMembers() map[string]map[string]int               { return nil }
+func (sd *ringServiceDiscovery) GetRingNode(serverType string, key string) (string, error) {
+	return sd.ring[0], nil
+}
+
+func (sd *ringServiceDiscovery) GetRingNodeMatch(serverType string, key string, match func(serverID string) bool) (string, error) {
+	for _, id := range sd.ring {
+		if match(id) {
+			return id, nil
+		}
+	}
+	return "", assert.AnError
+}
+
+func TestDefaultRouteNextCompatibleNode(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	mockSD := mocks.NewMockServiceDiscovery(ctrl)
+	mockSD.EXPECT().GetConsistentHashNode("game", "u1").Return("game-1", nil).AnyTimes()
+	sess := sessionmocks.NewMockSession(ctrl)
+	sess.EXPECT().UID().Return("u1").AnyTimes()
+	sess.EXPECT().GetFrontendID().Return("connector-1").AnyTimes()
+	sess.EXPECT().GetFrontendSessionID().Return(int64(1)).AnyTimes()
+
+	servers := make(map[string]*cluster.Server)
+	for _, id := range []string{"game-2", "game-3", "game-4"} {
+		servers[id] = cluster.NewServer(id, "game", false)
+		servers[id].Status = cluster.ServerStatusReady
+	}
+	// game-1 与路由不兼容已被过滤,下线中的 game-3 不分配新的请求
+	servers["game-3"].Status = cluster.ServerStatusDraining
+	r := New()
+	r.SetServiceDiscovery(&ringServiceDiscovery{MockServiceDiscovery: mockSD, ring: []string{"game-1", "game-3", "game-4", "game-2"}})
+	for i := 0; i < 10; i++ {
+		sv, err := r.defaultRoute("game", servers, sess)
+		require.NoError(t, err)
+		assert.Equal(t, "game-4", sv.ID)
+	}
+}
@@ -74,43 +74,65 @@ func (r *Router) SetServiceDiscovery(sd cluster.ServiceDiscovery) {
 //
 //	-payload with session: 路由到session绑定的backend
 //	-payload without session: 随机
+//
+// 随机和一致性哈希只选择 Available 的服务,已绑定session的路由不受服务状态影响
 func (r *Router) defaultRoute(
 	svType string,
 	servers map[string]*cluster.Server,
 	session session.Session,
 ) (*cluster.Server, error) {
-	srvList := make([]*cluster.Server, 0)
-	s := rand.NewSource(time.Now().Unix())
-	rnd := rand.New(s)
+	srvList := make([]*cluster.Server, 0, len(servers))
+	// 同类型服务的 Frontend 和 SessionStickiness 一致,任取一个用于判断路由方式
+	var sample *cluster.Server
 	for _, v := range servers {
-		srvList = append(srvList, v)
+		sample = v
+		if v.Available() {
+			srvList = append(srvList, v)
+		}
+	}
+	randomRoute := func() (*cluster.Server, error) {
+		if len(srvList) == 0 {
+			return nil, errors.WithStack(fmt.Errorf("%w svType=%s", constants.ErrNoServersAvailableOfType, svType))
+		}
+		s := rand.NewSource(time.Now().Unix())
+		rnd := rand.New(s)
+		return srvList[rnd.Intn(len(srvList))], nil
 	}
-	server := srvList[rnd.Intn(len(srvList))]
 	var err error
 	if session != nil {
 		logW := logger.Zap.With(zap.String("uid", session.UID()), zap.String("frontend", session.GetFrontendID()), zap.Int64("frontSessID", session.GetFrontendSessionID()), zap.String("sv", svType))
 		svId := ""
-		if server.Frontend {
+		if sample.Frontend {
 			svId = session.GetFrontendID()
 			// logW.Debug("frontend route request by session's frontendID", zap.String("svID", svId))
-		} else if server.SessionStickiness {
-			svId = session.GetBackendID(server.Type)
+		} else if sample.SessionStickiness {
+			svId = session.GetBackendID(sample.Type)
 			// logW.Debug("stickiness backend route request by session's backendID", zap.String("svID", svId))
 		} else {
 			// logW.Debug("normal backend route request by consist hash")
 			// 尝试hash一致性路由
+			var hashKey string
 			if session.UID() != "" {
-				svId, err = r.serviceDiscovery.GetConsistentHashNode(svType, session.UID())
+				hashKey = session.UID()
 			} else if session.GetFrontendID() != "" && session.GetFrontendSessionID() > 0 {
-				svId, err = r.serviceDiscovery.GetConsistentHashNode(svType, fmt.Sprintf("%s-%d", session.GetFrontendID(), session.GetFrontendSessionID()))
+				hashKey = fmt.Sprintf("%s-%d", session.GetFrontendID(), session.GetFrontendSessionID())
 			} else {
 				logW.Debug("normal backend route request try consist hash failed,change by random")
-				return server, nil
+				return randomRoute()
 			}
+			svId, err = r.serviceDiscovery.GetConsistentHashNode(svType, hashKey)
 			// 获取不到hash node则随机路由
 			if err != nil {
-				logW.Error("route by consist hash error,will route random", zap.Error(err), zap.String("svID", server.ID))
-				return server, nil
+				logW.Error("route by consist hash error,will route random", zap.Error(err))
+				return randomRoute()
+			}
+			// hash node 的该路由版本不兼容时沿哈希环选择下一个兼容的节点,同一key的路由保持稳定
+			if _, ok := servers[svId]; !ok {
+				svId, err = r.nextCompatibleNode(svType, hashKey, servers)
+				if err != nil {
+					logW.Debug("no consist hash node is compatible with route,will route random", zap.Error(err))
+					return randomRoute()
+				}
 			}
 		}
 		if svId != "" {
@@ -124,13 +146,12 @@ func (r *Router) defaultRoute(
 		// return nil,constants.ErrNoServersAvailableOfType
 		// 需要路由到绑定session的服务,但是找不到，报错
 		return nil, protos.ErrForbiddenServerOfSession().WithMetadata(map[string]string{
-			"svType": server.Type,
-			"svID":   server.ID,
+			"svType": sample.Type,
 			"uid":    session.UID(),
 		}).WithStack()
 	}
 	// logger.Zap.Debug("session is nil,route random", zap.String("svType", svType))
-	return server, nil
+	return randomRoute()
 }
 
 // Route gets the right server to use in the call
@@ -156,6 +177,10 @@ func (r *Router) Route(
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	serversOfType = r.compatibleServers(serversOfType, route)
+	if len(serversOfType) == 0 {
+		return nil, errors.WithStack(fmt.Errorf("%w svType=%s,route=%s", constants.ErrNoCompatibleServers, svType, route.String()))
+	}
 	// RPCType_Usser类型的route改成也允许使用自定义route
 	// if rpcType == protos.RPCType_User {
 	// 	server := r.defaultRoute(serversOfType)
@@ -173,6 +198,45 @@ func (r *Router) Route(
 	return routeFunc(ctx, route, msg.Data, serversOfType, session)
 }
 
+// nextCompatibleNode 沿哈希环查找第一个在 servers 中的可用节点
+//
+//	@receiver r
+//	@param svType
+//	@param hashKey
+//	@param servers 与路由兼容的服务
+//	@return string
+//	@return error
+func (r *Router) nextCompatibleNode(svType, hashKey string, servers map[string]*cluster.Server) (string, error) {
+	ring, ok := r.serviceDiscovery.(cluster.ConsistentHashRing)
+	if !ok {
+		return "", errors.WithStack(fmt.Errorf("%w svType=%s", constants.ErrNoCompatibleServers, svType))
+	}
+	return ring.GetRingNodeMatch(svType, hashKey, func(serverID string) bool {
+		sv, ok := servers[serverID]
+		return ok && sv.Available()
+	})
+}
+
+// compatibleServers 过滤出与当前服务接口版本兼容的服务,自定义路由函数也只会收到兼容的服务
+//
+//	@receiver r
+//	@param servers
+//	@param rt
+//	@return map[string]*cluster.Server
+func (r *Router) compatibleServers(servers map[string]*cluster.Server, rt *route.Route) map[string]*cluster.Server {
+	self := r.serviceDiscovery.GetSelfServer()
+	if self == nil || !self.APIVersion.Declared() {
+		return servers
+	}
+	compatible := make(map[string]*cluster.Server, len(servers))
+	for id, sv := range servers {
+		if self.CompatibleWith(sv, rt) {
+			compatible[id] = sv
+		}
+	}
+	return compatible
+}
+
 // AddRoute adds a routing function to a server type
 func (r *Router) AddRoute(
 	serverType string,
@@ -0,0 +1,96 @@
+package pitaya
+
+import (
+	"time"
+
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/interfaces"
+	"github.com/topfreegames/pitaya/v2/logger"
+	"go.uber.org/zap"
+)
+
+// SetServerStatus
+//
+//	@implement Pitaya.SetServerStatus
+//	@receiver app
+//	@param status
+//	@return error
+func (app *App) SetServerStatus(status cluster.ServerStatus) error {
+	app.statusLock.Lock()
+	defer app.statusLock.Unlock()
+	return app.setServerStatus(status)
+}
+
+// setServerStatus 调用方需持有 statusLock
+//
+//	app.server 被服务发现、路由等并发读取,这里不原地修改,而是把带新状态的副本同步到集群
+//	@receiver app
+//	@param status
+//	@return error
+func (app *App) setServerStatus(status cluster.ServerStatus) error {
+	if app.status == status {
+		return nil
+	}
+	logger.Zap.Info("server status changed", zap.String("from", string(app.status)), zap.String("to", string(status)))
+	app.status = status
+	if app.serverMode != Cluster {
+		return nil
+	}
+	server := *app.server
+	server.Status = status
+	return app.serviceDiscovery.FlushServer2Cluster(&server)
+}
+
+// checkHealth 检查所有实现了 interfaces.HealthChecker 的模块
+//
+//	@receiver app
+//	@return cluster.ServerStatus
+func (app *App) checkHealth() cluster.ServerStatus {
+	status := cluster.ServerStatusReady
+	for _, modWrapper := range app.modulesArr {
+		checker, ok := modWrapper.module.(interfaces.HealthChecker)
+		if !ok {
+			continue
+		}
+		if err := checker.HealthCheck(); err != nil {
+			logger.Zap.Warn("module health check failed", zap.String("module", modWrapper.name), zap.Error(err))
+			status = cluster.ServerStatusUnhealthy
+		}
+	}
+	return status
+}
+
+// updateHealthStatus 按健康检查结果更新服务状态,draining 状态不受健康检查影响
+func (app *App) updateHealthStatus() {
+	status := app.checkHealth()
+	app.statusLock.Lock()
+	defer app.statusLock.Unlock()
+	if app.status == cluster.ServerStatusDraining {
+		return
+	}
+	if err := app.setServerStatus(status); err != nil {
+		logger.Zap.Error("failed to flush server status", zap.String("status", string(status)), zap.Error(err))
+	}
+}
+
+// initHealthCheck 模块启动后确定初始状态,并定时检查
+func (app *App) initHealthCheck() {
+	app.updateHealthStatus()
+	interval := app.config.HealthCheck.Interval
+	if interval <= 0 {
+		return
+	}
+	co.Go(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				app.updateHealthStatus()
+			case <-app.stopChan:
+				return
+			}
+		}
+	})
+}
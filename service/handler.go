@@ -26,6 +26,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/nats-io/nuid"
@@ -120,7 +121,7 @@ func (h *HandlerService) Dispatch(thread int) {
 	for {
 		// Calls to remote servers block calls to local server
 		select {
-		case <-timer.GlobalTicker.C: // execute cron task
+		case <-timer.GlobalTicker.C(): // execute cron task
 			timer.Cron()
 
 		case t := <-timer.Manager.ChCreatedTimer: // new Timers
@@ -149,6 +150,7 @@ func (h *HandlerService) Register(comp component.Component, opts []component.Opt
 	for name, handler := range s.Handlers {
 		h.handlerPool.Register(s.Name, name, handler)
 	}
+	declareRouteVersions(h.server, s)
 	return nil
 }
 
@@ -263,6 +265,11 @@ func (h *HandlerService) processPacket(a agent.Agent, p *packet.Packet) error {
 			return err
 		}
 
+	case packet.PushAck:
+		if len(p.Data) >= 8 {
+			a.AckPush(uint(binary.BigEndian.Uint64(p.Data)))
+		}
+
 	case packet.HeartbeatAck:
 		var rawUnixMillTime int64 = 0
 		if len(p.Data) > 0 {
@@ -358,6 +365,20 @@ func (h *HandlerService) localProcess(ctx context.Context, a agent.Agent, route
 	}
 }
 
+// Routes 所有已注册的handler路由
+//
+//	@receiver h
+//	@return []string 按字典序排序
+func (h *HandlerService) Routes() []string {
+	handlers := h.handlerPool.GetHandlers()
+	routes := make([]string, 0, len(handlers))
+	for name := range handlers {
+		routes = append(routes, name)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
 // DumpServices outputs all registered services
 func (h *HandlerService) DumpServices() {
 	handlers := h.handlerPool.GetHandlers()
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
 	"sync"
 
 	"github.com/alkaid/goerrors/errors"
@@ -500,12 +501,14 @@ func (r *RemoteService) Register(comp component.Component, opts []component.Opti
 		for name, remote := range s.Remotes {
 			r.remotes[fmt.Sprintf("%s.%s", s.Name, name)] = remote
 		}
+		declareRouteVersions(r.server, s)
 		return nil
 	}
 	var groups []string
 	if s.Options.SubscriberGroup != "" {
 		groups = append(groups, s.Options.SubscriberGroup)
 	}
+	declareRouteVersions(r.server, s)
 	// 注册订阅
 	for name, remote := range s.Remotes {
 		// 同一个服务器的不同subscriber之间订阅的topic不能相同
@@ -835,6 +838,22 @@ func (r *RemoteService) remoteCall(
 	return res, err
 }
 
+// Routes 所有已注册的remote路由
+//
+//	@receiver r
+//	@return []string 按字典序排序
+func (r *RemoteService) Routes() []string {
+	if r == nil {
+		return []string{}
+	}
+	routes := make([]string, 0, len(r.remotes))
+	for name := range r.remotes {
+		routes = append(routes, name)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
 // DumpServices outputs all registered services
 func (r *RemoteService) DumpServices() {
 	for name := range r.remotes {
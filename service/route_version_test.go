@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/component"
+	"github.com/topfreegames/pitaya/v2/protos/test"
+)
+
+type VersionedComp struct {
+	component.Base
+}
+
+func (c *VersionedComp) OnKick(ctx context.Context, ss *test.SomeStruct) (*test.SomeStruct, error) {
+	return ss, nil
+}
+
+func (c *VersionedComp) OnLogin(ctx context.Context, ss *test.SomeStruct) (*test.SomeStruct, error) {
+	return ss, nil
+}
+
+func TestDeclareRouteVersions(t *testing.T) {
+	tables := []struct {
+		name       string
+		subscriber bool
+		want       map[string]cluster.VersionRange
+	}{
+		{"Remote", false, map[string]cluster.VersionRange{
+			"VersionedComp":        {Min: 1, Max: 2},
+			"VersionedComp.OnKick": {Min: 2, Max: 3},
+		}},
+		// 订阅者以 publish.topic 路由,按topic声明
+		{"Subscriber", true, map[string]cluster.VersionRange{
+			cluster.PublishServiceName + ".OnKick":  {Min: 2, Max: 3},
+			cluster.PublishServiceName + ".OnLogin": {Min: 1, Max: 2},
+		}},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			opts := []component.Option{component.WithAPIVersion(1, 2), component.WithMethodAPIVersion("OnKick", 2, 3)}
+			if table.subscriber {
+				opts = append(opts, component.WithSubscriber())
+			}
+			s := component.NewService(&VersionedComp{}, opts)
+			require.NoError(t, s.ExtractRemote())
+			require.Len(t, s.Remotes, 2)
+
+			server := cluster.NewServer("game-1", "game", false)
+			declareRouteVersions(server, s)
+			assert.Equal(t, table.want, server.RouteVersions)
+		})
+	}
+}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/alkaid/goerrors/apierrors"
 
+	"github.com/topfreegames/pitaya/v2/cluster"
 	"github.com/topfreegames/pitaya/v2/component"
 	"github.com/topfreegames/pitaya/v2/conn/message"
 	"github.com/topfreegames/pitaya/v2/constants"
@@ -171,3 +172,27 @@ func processHandlerMessage(
 
 	return ret, nil
 }
+
+// declareRouteVersions 将组件声明的接口版本写入当前服务,随服务注册到服务发现
+//
+//	订阅者的remote以 publish.topic 路由,按topic声明
+func declareRouteVersions(server *cluster.Server, s *component.Service) {
+	if server == nil {
+		return
+	}
+	if s.Options.Subscriber {
+		for topic := range s.Remotes {
+			v, ok := s.Options.MethodAPIVersion[topic]
+			if !ok {
+				v = s.Options.APIVersion
+			}
+			server.SetRouteVersion(cluster.PublishServiceName+"."+topic, cluster.VersionRange{Min: v[0], Max: v[1]})
+		}
+		return
+	}
+	v := s.Options.APIVersion
+	server.SetRouteVersion(s.Name, cluster.VersionRange{Min: v[0], Max: v[1]})
+	for method, v := range s.Options.MethodAPIVersion {
+		server.SetRouteVersion(s.Name+"."+method, cluster.VersionRange{Min: v[0], Max: v[1]})
+	}
+}
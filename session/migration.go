@@ -0,0 +1,140 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/clock"
+	"github.com/topfreegames/pitaya/v2/constants"
+)
+
+// takeMigrationScript 原子地取出并删除交接给 ARGV[2] 的迁移交接数据,交接给其他网关的不删除
+//
+//	KEYS: session的cluster storage key; ARGV: field to
+var takeMigrationScript = redis.NewScript(`
+local v = redis.call('HGET', KEYS[1], ARGV[1])
+if not v or v == '' then
+	return false
+end
+local ok, ticket = pcall(cjson.decode, v)
+if ok and type(ticket) == 'table' and ticket['to'] ~= ARGV[2] then
+	return false
+end
+redis.call('HDEL', KEYS[1], ARGV[1])
+return v
+`)
+
+// OnMigrationAbandonedFunc 迁移超时未被目标网关接管且连接已断开时的回调.
+// session断开时已以 CloseReasonKickMigrate 执行过 OnSessionCloseFunc,这里补做下线处理,不会再次执行关闭回调
+type OnMigrationAbandonedFunc func(s Session)
+
+// MigrationTicket 跨网关迁移时源网关交接给目标网关的session数据,经由cluster cache传递
+type MigrationTicket struct {
+	From     string            `json:"from"`     // 源网关ID
+	To       string            `json:"to"`       // 目标网关ID
+	Sid      int64             `json:"sid"`      // 源网关上的session id
+	Backends map[string]string `json:"backends"` // 绑定的backends key:serverType value:serverID
+	Data     []byte            `json:"data"`     // 用户自定义数据
+	ExpireAt int64             `json:"expireAt"` // 过期时间 unix秒
+}
+
+// Expired 交接数据是否已过期
+//
+//	@receiver t
+//	@return bool
+func (t *MigrationTicket) Expired() bool {
+	return clock.Now().Unix() > t.ExpireAt
+}
+
+// RedirectData 迁移时推送给客户端的重定向数据
+//
+//	@see constants.SessionRedirectRoute
+type RedirectData struct {
+	Addr       string `json:"addr"` // 目标网关地址,取自目标网关metadata的 constants.ClientAddrKey
+	FrontendID string `json:"fid"`  // 目标网关ID
+}
+
+func (s *sessionImpl) SetMigrating(migrating bool) {
+	var v int32
+	if migrating {
+		v = 1
+	}
+	atomic.StoreInt32(&s.migrating, v)
+}
+
+func (s *sessionImpl) IsMigrating() bool {
+	return atomic.LoadInt32(&s.migrating) == 1
+}
+
+func (s *sessionImpl) FlushMigration(ticket *MigrationTicket) error {
+	if "" == s.uid {
+		return errors.WithStack(constants.ErrIllegalUID)
+	}
+	b, err := json.Marshal(ticket)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.pool.storage.Hset(s.ClusterStorageKey(), fieldKeyMigration, string(b))
+}
+
+func (s *sessionImpl) TakeMigration(to string) (*MigrationTicket, error) {
+	if "" == s.uid {
+		return nil, errors.WithStack(constants.ErrIllegalUID)
+	}
+	var v string
+	var err error
+	if sc, ok := s.pool.storage.(ScriptCache); ok {
+		var ret interface{}
+		ret, err = sc.EvalCtx(context.Background(), takeMigrationScript, []string{s.ClusterStorageKey()}, fieldKeyMigration, to)
+		v, _ = ret.(string)
+	} else {
+		v, err = s.takeMigrationNonAtomic(to)
+	}
+	if errors.Is(err, redis.Nil) || (err == nil && v == "") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ticket := &MigrationTicket{}
+	err = json.Unmarshal([]byte(v), ticket)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ticket, nil
+}
+
+// takeMigrationNonAtomic cache不支持lua脚本时先读后清除,并发消费时同一份交接数据可能被取出多次
+func (s *sessionImpl) takeMigrationNonAtomic(to string) (string, error) {
+	v, err := s.pool.storage.Hget(s.ClusterStorageKey(), fieldKeyMigration)
+	if err != nil || v == "" {
+		return v, err
+	}
+	ticket := &MigrationTicket{}
+	if json.Unmarshal([]byte(v), ticket) == nil && ticket.To != to {
+		return "", nil
+	}
+	return v, s.pool.storage.Hset(s.ClusterStorageKey(), fieldKeyMigration, "")
+}
+
+// OnMigrationAbandoned
+//
+//	@implement SessionPool.OnMigrationAbandoned
+func (pool *sessionPoolImpl) OnMigrationAbandoned(f OnMigrationAbandonedFunc) {
+	sf1 := reflect.ValueOf(f)
+	for _, fun := range pool.migrationAbandonedCallbacks {
+		sf2 := reflect.ValueOf(fun)
+		if sf1.Pointer() == sf2.Pointer() {
+			return
+		}
+	}
+	pool.migrationAbandonedCallbacks = append(pool.migrationAbandonedCallbacks, f)
+}
+
+func (pool *sessionPoolImpl) GetMigrationAbandonedCallbacks() []OnMigrationAbandonedFunc {
+	return pool.migrationAbandonedCallbacks
+}
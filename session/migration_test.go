@@ -0,0 +1,94 @@
+package session
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/clock"
+)
+
+// plainCache 不支持lua脚本的cache
+type plainCache struct {
+	CacheInterface
+}
+
+func newMigrationTestSession(t *testing.T, script bool) Session {
+	mr := miniredis.RunT(t)
+	var cache CacheInterface = NewRedisCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Hour)
+	if !script {
+		cache = plainCache{cache}
+	}
+	pool := NewSessionPool()
+	pool.SetClusterCache(cache)
+	s, _ := pool.NewSession(nil, true, "1001")
+	return s
+}
+
+func TestTakeMigration(t *testing.T) {
+	for _, script := range []bool{true, false} {
+		tables := []struct {
+			name    string
+			ticket  *MigrationTicket
+			to      string
+			taken   bool
+			expired bool
+		}{
+			{"NoTicket", nil, "connector-2", false, false},
+			{"OtherFrontend", &MigrationTicket{From: "connector-1", To: "connector-3", ExpireAt: clock.Now().Add(time.Minute).Unix()}, "connector-2", false, false},
+			{"Taken", &MigrationTicket{From: "connector-1", To: "connector-2", ExpireAt: clock.Now().Add(time.Minute).Unix()}, "connector-2", true, false},
+			{"Expired", &MigrationTicket{From: "connector-1", To: "connector-2", ExpireAt: clock.Now().Add(-time.Minute).Unix()}, "connector-2", true, true},
+		}
+		for _, table := range tables {
+			t.Run(table.name, func(t *testing.T) {
+				s := newMigrationTestSession(t, script)
+				if table.ticket != nil {
+					require.NoError(t, s.FlushMigration(table.ticket))
+				}
+				ticket, err := s.TakeMigration(table.to)
+				require.NoError(t, err)
+				if !table.taken {
+					assert.Nil(t, ticket)
+					if table.ticket != nil {
+						// 交接给其他网关的数据保留不动
+						ticket, err = s.TakeMigration(table.ticket.To)
+						require.NoError(t, err)
+						assert.Equal(t, table.ticket, ticket)
+					}
+					return
+				}
+				assert.Equal(t, table.ticket, ticket)
+				assert.Equal(t, table.expired, ticket.Expired())
+				// 只能取出一次
+				ticket, err = s.TakeMigration(table.to)
+				require.NoError(t, err)
+				assert.Nil(t, ticket)
+			})
+		}
+	}
+}
+
+func TestTakeMigrationConcurrent(t *testing.T) {
+	s := newMigrationTestSession(t, true)
+	require.NoError(t, s.FlushMigration(&MigrationTicket{From: "connector-1", To: "connector-2", ExpireAt: clock.Now().Add(time.Minute).Unix()}))
+	var taken atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticket, err := s.TakeMigration("connector-2")
+			assert.NoError(t, err)
+			if ticket != nil {
+				taken.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), taken.Load())
+}
@@ -7,275 +7,309 @@ package mocks
 import (
 	context "context"
 	net "net"
+	netip "net/netip"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	nats "github.com/nats-io/nats.go"
 	networkentity "github.com/topfreegames/pitaya/v2/networkentity"
+	protos "github.com/topfreegames/pitaya/v2/protos"
 	session "github.com/topfreegames/pitaya/v2/session"
+	timer "github.com/topfreegames/pitaya/v2/timer"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 )
 
-// MockSessionPool is a mock of SessionPool interface.
-type MockSessionPool struct {
+// MockSession is a mock of Session interface.
+type MockSession struct {
 	ctrl     *gomock.Controller
-	recorder *MockSessionPoolMockRecorder
+	recorder *MockSessionMockRecorder
 }
 
-// MockSessionPoolMockRecorder is the mock recorder for MockSessionPool.
-type MockSessionPoolMockRecorder struct {
-	mock *MockSessionPool
+// MockSessionMockRecorder is the mock recorder for MockSession.
+type MockSessionMockRecorder struct {
+	mock *MockSession
 }
 
-// NewMockSessionPool creates a new mock instance.
-func NewMockSessionPool(ctrl *gomock.Controller) *MockSessionPool {
-	mock := &MockSessionPool{ctrl: ctrl}
-	mock.recorder = &MockSessionPoolMockRecorder{mock}
+// NewMockSession creates a new mock instance.
+func NewMockSession(ctrl *gomock.Controller) *MockSession {
+	mock := &MockSession{ctrl: ctrl}
+	mock.recorder = &MockSessionMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockSessionPool) EXPECT() *MockSessionPoolMockRecorder {
+func (m *MockSession) EXPECT() *MockSessionMockRecorder {
 	return m.recorder
 }
 
-// CloseAll mocks base method.
-func (m *MockSessionPool) CloseAll() {
+// AfterFunc mocks base method.
+func (m *MockSession) AfterFunc(arg0 time.Duration, arg1 timer.Func) (*timer.Timer, error) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "CloseAll")
+	ret := m.ctrl.Call(m, "AfterFunc", arg0, arg1)
+	ret0, _ := ret[0].(*timer.Timer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// CloseAll indicates an expected call of CloseAll.
-func (mr *MockSessionPoolMockRecorder) CloseAll() *gomock.Call {
+// AfterFunc indicates an expected call of AfterFunc.
+func (mr *MockSessionMockRecorder) AfterFunc(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseAll", reflect.TypeOf((*MockSessionPool)(nil).CloseAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AfterFunc", reflect.TypeOf((*MockSession)(nil).AfterFunc), arg0, arg1)
 }
 
-// GetSessionByID mocks base method.
-func (m *MockSessionPool) GetSessionByID(id int64) session.Session {
+// Bind mocks base method.
+func (m *MockSession) Bind(arg0 context.Context, arg1 string, arg2 map[string]string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSessionByID", id)
-	ret0, _ := ret[0].(session.Session)
+	ret := m.ctrl.Call(m, "Bind", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// GetSessionByID indicates an expected call of GetSessionByID.
-func (mr *MockSessionPoolMockRecorder) GetSessionByID(id interface{}) *gomock.Call {
+// Bind indicates an expected call of Bind.
+func (mr *MockSessionMockRecorder) Bind(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByID", reflect.TypeOf((*MockSessionPool)(nil).GetSessionByID), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bind", reflect.TypeOf((*MockSession)(nil).Bind), arg0, arg1, arg2)
 }
 
-// GetSessionByUID mocks base method.
-func (m *MockSessionPool) GetSessionByUID(uid string) session.Session {
+// BindBackend mocks base method.
+func (m *MockSession) BindBackend(arg0 context.Context, arg1, arg2 string, arg3 map[string]string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSessionByUID", uid)
-	ret0, _ := ret[0].(session.Session)
+	ret := m.ctrl.Call(m, "BindBackend", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// GetSessionByUID indicates an expected call of GetSessionByUID.
-func (mr *MockSessionPoolMockRecorder) GetSessionByUID(uid interface{}) *gomock.Call {
+// BindBackend indicates an expected call of BindBackend.
+func (mr *MockSessionMockRecorder) BindBackend(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByUID", reflect.TypeOf((*MockSessionPool)(nil).GetSessionByUID), uid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindBackend", reflect.TypeOf((*MockSession)(nil).BindBackend), arg0, arg1, arg2, arg3)
 }
 
-// GetSessionCloseCallbacks mocks base method.
-func (m *MockSessionPool) GetSessionCloseCallbacks() []func(session.Session) {
+// Clear mocks base method.
+func (m *MockSession) Clear() {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSessionCloseCallbacks")
-	ret0, _ := ret[0].([]func(session.Session))
-	return ret0
+	m.ctrl.Call(m, "Clear")
 }
 
-// GetSessionCloseCallbacks indicates an expected call of GetSessionCloseCallbacks.
-func (mr *MockSessionPoolMockRecorder) GetSessionCloseCallbacks() *gomock.Call {
+// Clear indicates an expected call of Clear.
+func (mr *MockSessionMockRecorder) Clear() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionCloseCallbacks", reflect.TypeOf((*MockSessionPool)(nil).GetSessionCloseCallbacks))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Clear", reflect.TypeOf((*MockSession)(nil).Clear))
 }
 
-// GetSessionCount mocks base method.
-func (m *MockSessionPool) GetSessionCount() int64 {
+// Close mocks base method.
+func (m *MockSession) Close(arg0 map[string]string, arg1 ...int) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSessionCount")
-	ret0, _ := ret[0].(int64)
+	varargs := []interface{}{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "Close", varargs...)
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockSessionMockRecorder) Close(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSession)(nil).Close), varargs...)
+}
+
+// ClusterStorageKey mocks base method.
+func (m *MockSession) ClusterStorageKey() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClusterStorageKey")
+	ret0, _ := ret[0].(string)
 	return ret0
 }
 
-// GetSessionCount indicates an expected call of GetSessionCount.
-func (mr *MockSessionPoolMockRecorder) GetSessionCount() *gomock.Call {
+// ClusterStorageKey indicates an expected call of ClusterStorageKey.
+func (mr *MockSessionMockRecorder) ClusterStorageKey() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionCount", reflect.TypeOf((*MockSessionPool)(nil).GetSessionCount))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClusterStorageKey", reflect.TypeOf((*MockSession)(nil).ClusterStorageKey))
 }
 
-// NewSession mocks base method.
-func (m *MockSessionPool) NewSession(entity networkentity.NetworkEntity, frontend bool, UID ...string) session.Session {
+// CreatedAt mocks base method.
+func (m *MockSession) CreatedAt() int64 {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{entity, frontend}
-	for _, a := range UID {
-		varargs = append(varargs, a)
-	}
-	ret := m.ctrl.Call(m, "NewSession", varargs...)
-	ret0, _ := ret[0].(session.Session)
+	ret := m.ctrl.Call(m, "CreatedAt")
+	ret0, _ := ret[0].(int64)
 	return ret0
 }
 
-// NewSession indicates an expected call of NewSession.
-func (mr *MockSessionPoolMockRecorder) NewSession(entity, frontend interface{}, UID ...interface{}) *gomock.Call {
+// CreatedAt indicates an expected call of CreatedAt.
+func (mr *MockSessionMockRecorder) CreatedAt() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]interface{}{entity, frontend}, UID...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewSession", reflect.TypeOf((*MockSessionPool)(nil).NewSession), varargs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatedAt", reflect.TypeOf((*MockSession)(nil).CreatedAt))
 }
 
-// OnAfterSessionBind mocks base method.
-func (m *MockSessionPool) OnAfterSessionBind(f func(context.Context, session.Session) error) {
+// Every mocks base method.
+func (m *MockSession) Every(arg0 time.Duration, arg1 timer.Func) (*timer.Timer, error) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnAfterSessionBind", f)
+	ret := m.ctrl.Call(m, "Every", arg0, arg1)
+	ret0, _ := ret[0].(*timer.Timer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// OnAfterSessionBind indicates an expected call of OnAfterSessionBind.
-func (mr *MockSessionPoolMockRecorder) OnAfterSessionBind(f interface{}) *gomock.Call {
+// Every indicates an expected call of Every.
+func (mr *MockSessionMockRecorder) Every(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAfterSessionBind", reflect.TypeOf((*MockSessionPool)(nil).OnAfterSessionBind), f)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Every", reflect.TypeOf((*MockSession)(nil).Every), arg0, arg1)
 }
 
-// OnSessionBind mocks base method.
-func (m *MockSessionPool) OnSessionBind(f func(context.Context, session.Session) error) {
+// Float32 mocks base method.
+func (m *MockSession) Float32(arg0 string) float32 {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnSessionBind", f)
+	ret := m.ctrl.Call(m, "Float32", arg0)
+	ret0, _ := ret[0].(float32)
+	return ret0
 }
 
-// OnSessionBind indicates an expected call of OnSessionBind.
-func (mr *MockSessionPoolMockRecorder) OnSessionBind(f interface{}) *gomock.Call {
+// Float32 indicates an expected call of Float32.
+func (mr *MockSessionMockRecorder) Float32(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnSessionBind", reflect.TypeOf((*MockSessionPool)(nil).OnSessionBind), f)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Float32", reflect.TypeOf((*MockSession)(nil).Float32), arg0)
 }
 
-// OnSessionClose mocks base method.
-func (m *MockSessionPool) OnSessionClose(f func(session.Session)) {
+// Float64 mocks base method.
+func (m *MockSession) Float64(arg0 string) float64 {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "OnSessionClose", f)
+	ret := m.ctrl.Call(m, "Float64", arg0)
+	ret0, _ := ret[0].(float64)
+	return ret0
 }
 
-// OnSessionClose indicates an expected call of OnSessionClose.
-func (mr *MockSessionPoolMockRecorder) OnSessionClose(f interface{}) *gomock.Call {
+// Float64 indicates an expected call of Float64.
+func (mr *MockSessionMockRecorder) Float64(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnSessionClose", reflect.TypeOf((*MockSessionPool)(nil).OnSessionClose), f)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Float64", reflect.TypeOf((*MockSession)(nil).Float64), arg0)
 }
 
-// MockSession is a mock of Session interface.
-type MockSession struct {
-	ctrl     *gomock.Controller
-	recorder *MockSessionMockRecorder
+// FlushBackendData mocks base method.
+func (m *MockSession) FlushBackendData() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlushBackendData")
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// MockSessionMockRecorder is the mock recorder for MockSession.
-type MockSessionMockRecorder struct {
-	mock *MockSession
+// FlushBackendData indicates an expected call of FlushBackendData.
+func (mr *MockSessionMockRecorder) FlushBackendData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushBackendData", reflect.TypeOf((*MockSession)(nil).FlushBackendData))
 }
 
-// NewMockSession creates a new mock instance.
-func NewMockSession(ctrl *gomock.Controller) *MockSession {
-	mock := &MockSession{ctrl: ctrl}
-	mock.recorder = &MockSessionMockRecorder{mock}
-	return mock
+// FlushFrontendData mocks base method.
+func (m *MockSession) FlushFrontendData() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlushFrontendData")
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockSession) EXPECT() *MockSessionMockRecorder {
-	return m.recorder
+// FlushFrontendData indicates an expected call of FlushFrontendData.
+func (mr *MockSessionMockRecorder) FlushFrontendData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushFrontendData", reflect.TypeOf((*MockSession)(nil).FlushFrontendData))
 }
 
-// Bind mocks base method.
-func (m *MockSession) Bind(ctx context.Context, uid string) error {
+// FlushMigration mocks base method.
+func (m *MockSession) FlushMigration(arg0 *session.MigrationTicket) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Bind", ctx, uid)
+	ret := m.ctrl.Call(m, "FlushMigration", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Bind indicates an expected call of Bind.
-func (mr *MockSessionMockRecorder) Bind(ctx, uid interface{}) *gomock.Call {
+// FlushMigration indicates an expected call of FlushMigration.
+func (mr *MockSessionMockRecorder) FlushMigration(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bind", reflect.TypeOf((*MockSession)(nil).Bind), ctx, uid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushMigration", reflect.TypeOf((*MockSession)(nil).FlushMigration), arg0)
 }
 
-// Clear mocks base method.
-func (m *MockSession) Clear() {
+// FlushOnline mocks base method.
+func (m *MockSession) FlushOnline() error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Clear")
+	ret := m.ctrl.Call(m, "FlushOnline")
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Clear indicates an expected call of Clear.
-func (mr *MockSessionMockRecorder) Clear() *gomock.Call {
+// FlushOnline indicates an expected call of FlushOnline.
+func (mr *MockSessionMockRecorder) FlushOnline() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Clear", reflect.TypeOf((*MockSession)(nil).Clear))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushOnline", reflect.TypeOf((*MockSession)(nil).FlushOnline))
 }
 
-// Close mocks base method.
-func (m *MockSession) Close() {
+// FlushUserData mocks base method.
+func (m *MockSession) FlushUserData() error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Close")
+	ret := m.ctrl.Call(m, "FlushUserData")
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Close indicates an expected call of Close.
-func (mr *MockSessionMockRecorder) Close() *gomock.Call {
+// FlushUserData indicates an expected call of FlushUserData.
+func (mr *MockSessionMockRecorder) FlushUserData() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSession)(nil).Close))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushUserData", reflect.TypeOf((*MockSession)(nil).FlushUserData))
 }
 
-// Float32 mocks base method.
-func (m *MockSession) Float32(key string) float32 {
+// Get mocks base method.
+func (m *MockSession) Get(arg0 string) interface{} {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Float32", key)
-	ret0, _ := ret[0].(float32)
+	ret := m.ctrl.Call(m, "Get", arg0)
+	ret0, _ := ret[0].(interface{})
 	return ret0
 }
 
-// Float32 indicates an expected call of Float32.
-func (mr *MockSessionMockRecorder) Float32(key interface{}) *gomock.Call {
+// Get indicates an expected call of Get.
+func (mr *MockSessionMockRecorder) Get(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Float32", reflect.TypeOf((*MockSession)(nil).Float32), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockSession)(nil).Get), arg0)
 }
 
-// Float64 mocks base method.
-func (m *MockSession) Float64(key string) float64 {
+// GetBackendID mocks base method.
+func (m *MockSession) GetBackendID(arg0 string) string {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Float64", key)
-	ret0, _ := ret[0].(float64)
+	ret := m.ctrl.Call(m, "GetBackendID", arg0)
+	ret0, _ := ret[0].(string)
 	return ret0
 }
 
-// Float64 indicates an expected call of Float64.
-func (mr *MockSessionMockRecorder) Float64(key interface{}) *gomock.Call {
+// GetBackendID indicates an expected call of GetBackendID.
+func (mr *MockSessionMockRecorder) GetBackendID(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Float64", reflect.TypeOf((*MockSession)(nil).Float64), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBackendID", reflect.TypeOf((*MockSession)(nil).GetBackendID), arg0)
 }
 
-// Get mocks base method.
-func (m *MockSession) Get(key string) interface{} {
+// GetBackends mocks base method.
+func (m *MockSession) GetBackends() map[string]string {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", key)
-	ret0, _ := ret[0].(interface{})
+	ret := m.ctrl.Call(m, "GetBackends")
+	ret0, _ := ret[0].(map[string]string)
 	return ret0
 }
 
-// Get indicates an expected call of Get.
-func (mr *MockSessionMockRecorder) Get(key interface{}) *gomock.Call {
+// GetBackends indicates an expected call of GetBackends.
+func (mr *MockSessionMockRecorder) GetBackends() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockSession)(nil).Get), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBackends", reflect.TypeOf((*MockSession)(nil).GetBackends))
 }
 
-// GetData mocks base method.
-func (m *MockSession) GetData() map[string]interface{} {
+// GetClusterStorage mocks base method.
+func (m *MockSession) GetClusterStorage() session.CacheInterface {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetData")
-	ret0, _ := ret[0].(map[string]interface{})
+	ret := m.ctrl.Call(m, "GetClusterStorage")
+	ret0, _ := ret[0].(session.CacheInterface)
 	return ret0
 }
 
-// GetData indicates an expected call of GetData.
-func (mr *MockSessionMockRecorder) GetData() *gomock.Call {
+// GetClusterStorage indicates an expected call of GetClusterStorage.
+func (mr *MockSessionMockRecorder) GetClusterStorage() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetData", reflect.TypeOf((*MockSession)(nil).GetData))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClusterStorage", reflect.TypeOf((*MockSession)(nil).GetClusterStorage))
 }
 
 // GetDataEncoded mocks base method.
@@ -292,6 +326,34 @@ func (mr *MockSessionMockRecorder) GetDataEncoded() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataEncoded", reflect.TypeOf((*MockSession)(nil).GetDataEncoded))
 }
 
+// GetFrontendID mocks base method.
+func (m *MockSession) GetFrontendID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFrontendID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetFrontendID indicates an expected call of GetFrontendID.
+func (mr *MockSessionMockRecorder) GetFrontendID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFrontendID", reflect.TypeOf((*MockSession)(nil).GetFrontendID))
+}
+
+// GetFrontendSessionID mocks base method.
+func (m *MockSession) GetFrontendSessionID() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFrontendSessionID")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// GetFrontendSessionID indicates an expected call of GetFrontendSessionID.
+func (mr *MockSessionMockRecorder) GetFrontendSessionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFrontendSessionID", reflect.TypeOf((*MockSession)(nil).GetFrontendSessionID))
+}
+
 // GetHandshakeData mocks base method.
 func (m *MockSession) GetHandshakeData() *session.HandshakeData {
 	m.ctrl.T.Helper()
@@ -362,18 +424,30 @@ func (mr *MockSessionMockRecorder) GetSubscriptions() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptions", reflect.TypeOf((*MockSession)(nil).GetSubscriptions))
 }
 
+// Go mocks base method.
+func (m *MockSession) Go(arg0 context.Context, arg1 func(context.Context)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Go", arg0, arg1)
+}
+
+// Go indicates an expected call of Go.
+func (mr *MockSessionMockRecorder) Go(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Go", reflect.TypeOf((*MockSession)(nil).Go), arg0, arg1)
+}
+
 // HasKey mocks base method.
-func (m *MockSession) HasKey(key string) bool {
+func (m *MockSession) HasKey(arg0 string) bool {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HasKey", key)
+	ret := m.ctrl.Call(m, "HasKey", arg0)
 	ret0, _ := ret[0].(bool)
 	return ret0
 }
 
 // HasKey indicates an expected call of HasKey.
-func (mr *MockSessionMockRecorder) HasKey(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) HasKey(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasKey", reflect.TypeOf((*MockSession)(nil).HasKey), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasKey", reflect.TypeOf((*MockSession)(nil).HasKey), arg0)
 }
 
 // HasRequestsInFlight mocks base method.
@@ -404,130 +478,224 @@ func (mr *MockSessionMockRecorder) ID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ID", reflect.TypeOf((*MockSession)(nil).ID))
 }
 
+// InitialFromCluster mocks base method.
+func (m *MockSession) InitialFromCluster() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitialFromCluster")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitialFromCluster indicates an expected call of InitialFromCluster.
+func (mr *MockSessionMockRecorder) InitialFromCluster() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitialFromCluster", reflect.TypeOf((*MockSession)(nil).InitialFromCluster))
+}
+
 // Int mocks base method.
-func (m *MockSession) Int(key string) int {
+func (m *MockSession) Int(arg0 string) int {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Int", key)
+	ret := m.ctrl.Call(m, "Int", arg0)
 	ret0, _ := ret[0].(int)
 	return ret0
 }
 
 // Int indicates an expected call of Int.
-func (mr *MockSessionMockRecorder) Int(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Int(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Int", reflect.TypeOf((*MockSession)(nil).Int), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Int", reflect.TypeOf((*MockSession)(nil).Int), arg0)
 }
 
 // Int16 mocks base method.
-func (m *MockSession) Int16(key string) int16 {
+func (m *MockSession) Int16(arg0 string) int16 {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Int16", key)
+	ret := m.ctrl.Call(m, "Int16", arg0)
 	ret0, _ := ret[0].(int16)
 	return ret0
 }
 
 // Int16 indicates an expected call of Int16.
-func (mr *MockSessionMockRecorder) Int16(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Int16(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Int16", reflect.TypeOf((*MockSession)(nil).Int16), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Int16", reflect.TypeOf((*MockSession)(nil).Int16), arg0)
 }
 
 // Int32 mocks base method.
-func (m *MockSession) Int32(key string) int32 {
+func (m *MockSession) Int32(arg0 string) int32 {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Int32", key)
+	ret := m.ctrl.Call(m, "Int32", arg0)
 	ret0, _ := ret[0].(int32)
 	return ret0
 }
 
 // Int32 indicates an expected call of Int32.
-func (mr *MockSessionMockRecorder) Int32(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Int32(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Int32", reflect.TypeOf((*MockSession)(nil).Int32), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Int32", reflect.TypeOf((*MockSession)(nil).Int32), arg0)
 }
 
 // Int64 mocks base method.
-func (m *MockSession) Int64(key string) int64 {
+func (m *MockSession) Int64(arg0 string) int64 {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Int64", key)
+	ret := m.ctrl.Call(m, "Int64", arg0)
 	ret0, _ := ret[0].(int64)
 	return ret0
 }
 
 // Int64 indicates an expected call of Int64.
-func (mr *MockSessionMockRecorder) Int64(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Int64(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Int64", reflect.TypeOf((*MockSession)(nil).Int64), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Int64", reflect.TypeOf((*MockSession)(nil).Int64), arg0)
 }
 
 // Int8 mocks base method.
-func (m *MockSession) Int8(key string) int8 {
+func (m *MockSession) Int8(arg0 string) int8 {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Int8", key)
+	ret := m.ctrl.Call(m, "Int8", arg0)
 	ret0, _ := ret[0].(int8)
 	return ret0
 }
 
 // Int8 indicates an expected call of Int8.
-func (mr *MockSessionMockRecorder) Int8(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Int8(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Int8", reflect.TypeOf((*MockSession)(nil).Int8), arg0)
+}
+
+// IsMigrating mocks base method.
+func (m *MockSession) IsMigrating() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsMigrating")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsMigrating indicates an expected call of IsMigrating.
+func (mr *MockSessionMockRecorder) IsMigrating() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Int8", reflect.TypeOf((*MockSession)(nil).Int8), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsMigrating", reflect.TypeOf((*MockSession)(nil).IsMigrating))
 }
 
 // Kick mocks base method.
-func (m *MockSession) Kick(ctx context.Context) error {
+func (m *MockSession) Kick(arg0 context.Context, arg1 map[string]string, arg2 ...int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Kick", ctx)
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Kick", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Kick indicates an expected call of Kick.
-func (mr *MockSessionMockRecorder) Kick(ctx interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Kick(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Kick", reflect.TypeOf((*MockSession)(nil).Kick), varargs...)
+}
+
+// KickBackend mocks base method.
+func (m *MockSession) KickBackend(arg0 context.Context, arg1 string, arg2 map[string]string, arg3 ...int) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1, arg2}
+	for _, a := range arg3 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "KickBackend", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// KickBackend indicates an expected call of KickBackend.
+func (mr *MockSessionMockRecorder) KickBackend(arg0, arg1, arg2 interface{}, arg3 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1, arg2}, arg3...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KickBackend", reflect.TypeOf((*MockSession)(nil).KickBackend), varargs...)
+}
+
+// ObtainFromCluster mocks base method.
+func (m *MockSession) ObtainFromCluster() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ObtainFromCluster")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ObtainFromCluster indicates an expected call of ObtainFromCluster.
+func (mr *MockSessionMockRecorder) ObtainFromCluster() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Kick", reflect.TypeOf((*MockSession)(nil).Kick), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObtainFromCluster", reflect.TypeOf((*MockSession)(nil).ObtainFromCluster))
 }
 
 // OnClose mocks base method.
-func (m *MockSession) OnClose(c func()) error {
+func (m *MockSession) OnClose(arg0 func()) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "OnClose", c)
+	ret := m.ctrl.Call(m, "OnClose", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // OnClose indicates an expected call of OnClose.
-func (mr *MockSessionMockRecorder) OnClose(c interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) OnClose(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnClose", reflect.TypeOf((*MockSession)(nil).OnClose), arg0)
+}
+
+// Online mocks base method.
+func (m *MockSession) Online() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Online")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Online indicates an expected call of Online.
+func (mr *MockSessionMockRecorder) Online() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnClose", reflect.TypeOf((*MockSession)(nil).OnClose), c)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Online", reflect.TypeOf((*MockSession)(nil).Online))
 }
 
 // Push mocks base method.
-func (m *MockSession) Push(route string, v interface{}) error {
+func (m *MockSession) Push(arg0 string, arg1 interface{}) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Push", route, v)
+	ret := m.ctrl.Call(m, "Push", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Push indicates an expected call of Push.
-func (mr *MockSessionMockRecorder) Push(route, v interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Push(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Push", reflect.TypeOf((*MockSession)(nil).Push), route, v)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Push", reflect.TypeOf((*MockSession)(nil).Push), arg0, arg1)
 }
 
 // PushToFront mocks base method.
-func (m *MockSession) PushToFront(ctx context.Context) error {
+func (m *MockSession) PushToFront(arg0 context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PushToFront", ctx)
+	ret := m.ctrl.Call(m, "PushToFront", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // PushToFront indicates an expected call of PushToFront.
-func (mr *MockSessionMockRecorder) PushToFront(ctx interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) PushToFront(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PushToFront", reflect.TypeOf((*MockSession)(nil).PushToFront), arg0)
+}
+
+// PushWithAck mocks base method.
+func (m *MockSession) PushWithAck(arg0 string, arg1 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PushWithAck", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PushWithAck indicates an expected call of PushWithAck.
+func (mr *MockSessionMockRecorder) PushWithAck(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PushToFront", reflect.TypeOf((*MockSession)(nil).PushToFront), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PushWithAck", reflect.TypeOf((*MockSession)(nil).PushWithAck), arg0, arg1)
 }
 
 // RemoteAddr mocks base method.
@@ -544,25 +712,79 @@ func (mr *MockSessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockSession)(nil).RemoteAddr))
 }
 
+// RemoteIP mocks base method.
+func (m *MockSession) RemoteIP() netip.Addr {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoteIP")
+	ret0, _ := ret[0].(netip.Addr)
+	return ret0
+}
+
+// RemoteIP indicates an expected call of RemoteIP.
+func (mr *MockSessionMockRecorder) RemoteIP() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteIP", reflect.TypeOf((*MockSession)(nil).RemoteIP))
+}
+
+// RemoteIPText mocks base method.
+func (m *MockSession) RemoteIPText() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoteIPText")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// RemoteIPText indicates an expected call of RemoteIPText.
+func (mr *MockSessionMockRecorder) RemoteIPText() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteIPText", reflect.TypeOf((*MockSession)(nil).RemoteIPText))
+}
+
+// RemoteIPWithoutCache mocks base method.
+func (m *MockSession) RemoteIPWithoutCache() netip.Addr {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoteIPWithoutCache")
+	ret0, _ := ret[0].(netip.Addr)
+	return ret0
+}
+
+// RemoteIPWithoutCache indicates an expected call of RemoteIPWithoutCache.
+func (mr *MockSessionMockRecorder) RemoteIPWithoutCache() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteIPWithoutCache", reflect.TypeOf((*MockSession)(nil).RemoteIPWithoutCache))
+}
+
 // Remove mocks base method.
-func (m *MockSession) Remove(key string) error {
+func (m *MockSession) Remove(arg0 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Remove", key)
+	ret := m.ctrl.Call(m, "Remove", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Remove indicates an expected call of Remove.
-func (mr *MockSessionMockRecorder) Remove(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Remove(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockSession)(nil).Remove), arg0)
+}
+
+// RemoveBackendID mocks base method.
+func (m *MockSession) RemoveBackendID(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemoveBackendID", arg0)
+}
+
+// RemoveBackendID indicates an expected call of RemoveBackendID.
+func (mr *MockSessionMockRecorder) RemoveBackendID(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockSession)(nil).Remove), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveBackendID", reflect.TypeOf((*MockSession)(nil).RemoveBackendID), arg0)
 }
 
 // ResponseMID mocks base method.
-func (m *MockSession) ResponseMID(ctx context.Context, mid uint, v interface{}, err ...bool) error {
+func (m *MockSession) ResponseMID(arg0 context.Context, arg1 uint, arg2 interface{}, arg3 ...bool) error {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{ctx, mid, v}
-	for _, a := range err {
+	varargs := []interface{}{arg0, arg1, arg2}
+	for _, a := range arg3 {
 		varargs = append(varargs, a)
 	}
 	ret := m.ctrl.Call(m, "ResponseMID", varargs...)
@@ -571,138 +793,202 @@ func (m *MockSession) ResponseMID(ctx context.Context, mid uint, v interface{},
 }
 
 // ResponseMID indicates an expected call of ResponseMID.
-func (mr *MockSessionMockRecorder) ResponseMID(ctx, mid, v interface{}, err ...interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) ResponseMID(arg0, arg1, arg2 interface{}, arg3 ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]interface{}{ctx, mid, v}, err...)
+	varargs := append([]interface{}{arg0, arg1, arg2}, arg3...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResponseMID", reflect.TypeOf((*MockSession)(nil).ResponseMID), varargs...)
 }
 
+// SendRequestToFrontend mocks base method.
+func (m *MockSession) SendRequestToFrontend(arg0 context.Context, arg1 string, arg2 protoreflect.ProtoMessage) (*protos.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendRequestToFrontend", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*protos.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendRequestToFrontend indicates an expected call of SendRequestToFrontend.
+func (mr *MockSessionMockRecorder) SendRequestToFrontend(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendRequestToFrontend", reflect.TypeOf((*MockSession)(nil).SendRequestToFrontend), arg0, arg1, arg2)
+}
+
 // Set mocks base method.
-func (m *MockSession) Set(key string, value interface{}) error {
+func (m *MockSession) Set(arg0 string, arg1 interface{}) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Set", key, value)
+	ret := m.ctrl.Call(m, "Set", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Set indicates an expected call of Set.
-func (mr *MockSessionMockRecorder) Set(key, value interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Set(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockSession)(nil).Set), key, value)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockSession)(nil).Set), arg0, arg1)
 }
 
-// SetData mocks base method.
-func (m *MockSession) SetData(data map[string]interface{}) error {
+// SetBackendID mocks base method.
+func (m *MockSession) SetBackendID(arg0, arg1 string) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetData", data)
-	ret0, _ := ret[0].(error)
-	return ret0
+	m.ctrl.Call(m, "SetBackendID", arg0, arg1)
+}
+
+// SetBackendID indicates an expected call of SetBackendID.
+func (mr *MockSessionMockRecorder) SetBackendID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBackendID", reflect.TypeOf((*MockSession)(nil).SetBackendID), arg0, arg1)
+}
+
+// SetBackends mocks base method.
+func (m *MockSession) SetBackends(arg0 map[string]string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBackends", arg0)
 }
 
-// SetData indicates an expected call of SetData.
-func (mr *MockSessionMockRecorder) SetData(data interface{}) *gomock.Call {
+// SetBackends indicates an expected call of SetBackends.
+func (mr *MockSessionMockRecorder) SetBackends(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetData", reflect.TypeOf((*MockSession)(nil).SetData), data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBackends", reflect.TypeOf((*MockSession)(nil).SetBackends), arg0)
 }
 
 // SetDataEncoded mocks base method.
-func (m *MockSession) SetDataEncoded(encodedData []byte) error {
+func (m *MockSession) SetDataEncoded(arg0 []byte) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetDataEncoded", encodedData)
+	ret := m.ctrl.Call(m, "SetDataEncoded", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetDataEncoded indicates an expected call of SetDataEncoded.
-func (mr *MockSessionMockRecorder) SetDataEncoded(encodedData interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) SetDataEncoded(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDataEncoded", reflect.TypeOf((*MockSession)(nil).SetDataEncoded), encodedData)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDataEncoded", reflect.TypeOf((*MockSession)(nil).SetDataEncoded), arg0)
 }
 
 // SetFrontendData mocks base method.
-func (m *MockSession) SetFrontendData(frontendID string, frontendSessionID int64) {
+func (m *MockSession) SetFrontendData(arg0 string, arg1 int64) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SetFrontendData", frontendID, frontendSessionID)
+	m.ctrl.Call(m, "SetFrontendData", arg0, arg1)
 }
 
 // SetFrontendData indicates an expected call of SetFrontendData.
-func (mr *MockSessionMockRecorder) SetFrontendData(frontendID, frontendSessionID interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) SetFrontendData(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFrontendData", reflect.TypeOf((*MockSession)(nil).SetFrontendData), frontendID, frontendSessionID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFrontendData", reflect.TypeOf((*MockSession)(nil).SetFrontendData), arg0, arg1)
 }
 
 // SetHandshakeData mocks base method.
-func (m *MockSession) SetHandshakeData(data *session.HandshakeData) {
+func (m *MockSession) SetHandshakeData(arg0 *session.HandshakeData) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SetHandshakeData", data)
+	m.ctrl.Call(m, "SetHandshakeData", arg0)
 }
 
 // SetHandshakeData indicates an expected call of SetHandshakeData.
-func (mr *MockSessionMockRecorder) SetHandshakeData(data interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) SetHandshakeData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHandshakeData", reflect.TypeOf((*MockSession)(nil).SetHandshakeData), arg0)
+}
+
+// SetIP mocks base method.
+func (m *MockSession) SetIP(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetIP", arg0)
+}
+
+// SetIP indicates an expected call of SetIP.
+func (mr *MockSessionMockRecorder) SetIP(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHandshakeData", reflect.TypeOf((*MockSession)(nil).SetHandshakeData), data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIP", reflect.TypeOf((*MockSession)(nil).SetIP), arg0)
 }
 
 // SetIsFrontend mocks base method.
-func (m *MockSession) SetIsFrontend(isFrontend bool) {
+func (m *MockSession) SetIsFrontend(arg0 bool) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SetIsFrontend", isFrontend)
+	m.ctrl.Call(m, "SetIsFrontend", arg0)
 }
 
 // SetIsFrontend indicates an expected call of SetIsFrontend.
-func (mr *MockSessionMockRecorder) SetIsFrontend(isFrontend interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) SetIsFrontend(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIsFrontend", reflect.TypeOf((*MockSession)(nil).SetIsFrontend), arg0)
+}
+
+// SetMigrating mocks base method.
+func (m *MockSession) SetMigrating(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMigrating", arg0)
+}
+
+// SetMigrating indicates an expected call of SetMigrating.
+func (mr *MockSessionMockRecorder) SetMigrating(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIsFrontend", reflect.TypeOf((*MockSession)(nil).SetIsFrontend), isFrontend)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMigrating", reflect.TypeOf((*MockSession)(nil).SetMigrating), arg0)
 }
 
 // SetOnCloseCallbacks mocks base method.
-func (m *MockSession) SetOnCloseCallbacks(callbacks []func()) {
+func (m *MockSession) SetOnCloseCallbacks(arg0 []func()) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SetOnCloseCallbacks", callbacks)
+	m.ctrl.Call(m, "SetOnCloseCallbacks", arg0)
 }
 
 // SetOnCloseCallbacks indicates an expected call of SetOnCloseCallbacks.
-func (mr *MockSessionMockRecorder) SetOnCloseCallbacks(callbacks interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) SetOnCloseCallbacks(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOnCloseCallbacks", reflect.TypeOf((*MockSession)(nil).SetOnCloseCallbacks), callbacks)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOnCloseCallbacks", reflect.TypeOf((*MockSession)(nil).SetOnCloseCallbacks), arg0)
 }
 
 // SetRequestInFlight mocks base method.
-func (m *MockSession) SetRequestInFlight(reqID, reqData string, inFlight bool) {
+func (m *MockSession) SetRequestInFlight(arg0, arg1 string, arg2 bool) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SetRequestInFlight", reqID, reqData, inFlight)
+	m.ctrl.Call(m, "SetRequestInFlight", arg0, arg1, arg2)
 }
 
 // SetRequestInFlight indicates an expected call of SetRequestInFlight.
-func (mr *MockSessionMockRecorder) SetRequestInFlight(reqID, reqData, inFlight interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) SetRequestInFlight(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRequestInFlight", reflect.TypeOf((*MockSession)(nil).SetRequestInFlight), reqID, reqData, inFlight)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRequestInFlight", reflect.TypeOf((*MockSession)(nil).SetRequestInFlight), arg0, arg1, arg2)
 }
 
 // SetSubscriptions mocks base method.
-func (m *MockSession) SetSubscriptions(subscriptions []*nats.Subscription) {
+func (m *MockSession) SetSubscriptions(arg0 []*nats.Subscription) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SetSubscriptions", subscriptions)
+	m.ctrl.Call(m, "SetSubscriptions", arg0)
 }
 
 // SetSubscriptions indicates an expected call of SetSubscriptions.
-func (mr *MockSessionMockRecorder) SetSubscriptions(subscriptions interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) SetSubscriptions(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSubscriptions", reflect.TypeOf((*MockSession)(nil).SetSubscriptions), subscriptions)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSubscriptions", reflect.TypeOf((*MockSession)(nil).SetSubscriptions), arg0)
 }
 
 // String mocks base method.
-func (m *MockSession) String(key string) string {
+func (m *MockSession) String(arg0 string) string {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "String", key)
+	ret := m.ctrl.Call(m, "String", arg0)
 	ret0, _ := ret[0].(string)
 	return ret0
 }
 
 // String indicates an expected call of String.
-func (mr *MockSessionMockRecorder) String(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) String(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockSession)(nil).String), arg0)
+}
+
+// TakeMigration mocks base method.
+func (m *MockSession) TakeMigration(arg0 string) (*session.MigrationTicket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeMigration", arg0)
+	ret0, _ := ret[0].(*session.MigrationTicket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TakeMigration indicates an expected call of TakeMigration.
+func (mr *MockSessionMockRecorder) TakeMigration(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockSession)(nil).String), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeMigration", reflect.TypeOf((*MockSession)(nil).TakeMigration), arg0)
 }
 
 // UID mocks base method.
@@ -719,86 +1005,467 @@ func (mr *MockSessionMockRecorder) UID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UID", reflect.TypeOf((*MockSession)(nil).UID))
 }
 
+// UIDInt mocks base method.
+func (m *MockSession) UIDInt() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UIDInt")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// UIDInt indicates an expected call of UIDInt.
+func (mr *MockSessionMockRecorder) UIDInt() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UIDInt", reflect.TypeOf((*MockSession)(nil).UIDInt))
+}
+
 // Uint mocks base method.
-func (m *MockSession) Uint(key string) uint {
+func (m *MockSession) Uint(arg0 string) uint {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Uint", key)
+	ret := m.ctrl.Call(m, "Uint", arg0)
 	ret0, _ := ret[0].(uint)
 	return ret0
 }
 
 // Uint indicates an expected call of Uint.
-func (mr *MockSessionMockRecorder) Uint(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Uint(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uint", reflect.TypeOf((*MockSession)(nil).Uint), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uint", reflect.TypeOf((*MockSession)(nil).Uint), arg0)
 }
 
 // Uint16 mocks base method.
-func (m *MockSession) Uint16(key string) uint16 {
+func (m *MockSession) Uint16(arg0 string) uint16 {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Uint16", key)
+	ret := m.ctrl.Call(m, "Uint16", arg0)
 	ret0, _ := ret[0].(uint16)
 	return ret0
 }
 
 // Uint16 indicates an expected call of Uint16.
-func (mr *MockSessionMockRecorder) Uint16(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Uint16(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uint16", reflect.TypeOf((*MockSession)(nil).Uint16), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uint16", reflect.TypeOf((*MockSession)(nil).Uint16), arg0)
 }
 
 // Uint32 mocks base method.
-func (m *MockSession) Uint32(key string) uint32 {
+func (m *MockSession) Uint32(arg0 string) uint32 {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Uint32", key)
+	ret := m.ctrl.Call(m, "Uint32", arg0)
 	ret0, _ := ret[0].(uint32)
 	return ret0
 }
 
 // Uint32 indicates an expected call of Uint32.
-func (mr *MockSessionMockRecorder) Uint32(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Uint32(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uint32", reflect.TypeOf((*MockSession)(nil).Uint32), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uint32", reflect.TypeOf((*MockSession)(nil).Uint32), arg0)
 }
 
 // Uint64 mocks base method.
-func (m *MockSession) Uint64(key string) uint64 {
+func (m *MockSession) Uint64(arg0 string) uint64 {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Uint64", key)
+	ret := m.ctrl.Call(m, "Uint64", arg0)
 	ret0, _ := ret[0].(uint64)
 	return ret0
 }
 
 // Uint64 indicates an expected call of Uint64.
-func (mr *MockSessionMockRecorder) Uint64(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Uint64(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uint64", reflect.TypeOf((*MockSession)(nil).Uint64), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uint64", reflect.TypeOf((*MockSession)(nil).Uint64), arg0)
 }
 
 // Uint8 mocks base method.
-func (m *MockSession) Uint8(key string) uint8 {
+func (m *MockSession) Uint8(arg0 string) byte {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Uint8", key)
-	ret0, _ := ret[0].(uint8)
+	ret := m.ctrl.Call(m, "Uint8", arg0)
+	ret0, _ := ret[0].(byte)
 	return ret0
 }
 
 // Uint8 indicates an expected call of Uint8.
-func (mr *MockSessionMockRecorder) Uint8(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Uint8(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uint8", reflect.TypeOf((*MockSession)(nil).Uint8), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Uint8", reflect.TypeOf((*MockSession)(nil).Uint8), arg0)
 }
 
 // Value mocks base method.
-func (m *MockSession) Value(key string) interface{} {
+func (m *MockSession) Value(arg0 string) interface{} {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Value", key)
+	ret := m.ctrl.Call(m, "Value", arg0)
 	ret0, _ := ret[0].(interface{})
 	return ret0
 }
 
 // Value indicates an expected call of Value.
-func (mr *MockSessionMockRecorder) Value(key interface{}) *gomock.Call {
+func (mr *MockSessionMockRecorder) Value(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Value", reflect.TypeOf((*MockSession)(nil).Value), arg0)
+}
+
+// MockSessionPool is a mock of SessionPool interface.
+type MockSessionPool struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionPoolMockRecorder
+}
+
+// MockSessionPoolMockRecorder is the mock recorder for MockSessionPool.
+type MockSessionPoolMockRecorder struct {
+	mock *MockSessionPool
+}
+
+// NewMockSessionPool creates a new mock instance.
+func NewMockSessionPool(ctrl *gomock.Controller) *MockSessionPool {
+	mock := &MockSessionPool{ctrl: ctrl}
+	mock.recorder = &MockSessionPoolMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessionPool) EXPECT() *MockSessionPoolMockRecorder {
+	return m.recorder
+}
+
+// CloseAll mocks base method.
+func (m *MockSessionPool) CloseAll() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CloseAll")
+}
+
+// CloseAll indicates an expected call of CloseAll.
+func (mr *MockSessionPoolMockRecorder) CloseAll() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseAll", reflect.TypeOf((*MockSessionPool)(nil).CloseAll))
+}
+
+// DecodeSessionData mocks base method.
+func (m *MockSessionPool) DecodeSessionData(arg0 []byte) (map[string]interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecodeSessionData", arg0)
+	ret0, _ := ret[0].(map[string]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecodeSessionData indicates an expected call of DecodeSessionData.
+func (mr *MockSessionPoolMockRecorder) DecodeSessionData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecodeSessionData", reflect.TypeOf((*MockSessionPool)(nil).DecodeSessionData), arg0)
+}
+
+// EncodeSessionData mocks base method.
+func (m *MockSessionPool) EncodeSessionData(arg0 map[string]interface{}) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncodeSessionData", arg0)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncodeSessionData indicates an expected call of EncodeSessionData.
+func (mr *MockSessionPoolMockRecorder) EncodeSessionData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncodeSessionData", reflect.TypeOf((*MockSessionPool)(nil).EncodeSessionData), arg0)
+}
+
+// GetMigrationAbandonedCallbacks mocks base method.
+func (m *MockSessionPool) GetMigrationAbandonedCallbacks() []session.OnMigrationAbandonedFunc {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMigrationAbandonedCallbacks")
+	ret0, _ := ret[0].([]session.OnMigrationAbandonedFunc)
+	return ret0
+}
+
+// GetMigrationAbandonedCallbacks indicates an expected call of GetMigrationAbandonedCallbacks.
+func (mr *MockSessionPoolMockRecorder) GetMigrationAbandonedCallbacks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMigrationAbandonedCallbacks", reflect.TypeOf((*MockSessionPool)(nil).GetMigrationAbandonedCallbacks))
+}
+
+// GetSessionByID mocks base method.
+func (m *MockSessionPool) GetSessionByID(arg0 int64) session.Session {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionByID", arg0)
+	ret0, _ := ret[0].(session.Session)
+	return ret0
+}
+
+// GetSessionByID indicates an expected call of GetSessionByID.
+func (mr *MockSessionPoolMockRecorder) GetSessionByID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByID", reflect.TypeOf((*MockSessionPool)(nil).GetSessionByID), arg0)
+}
+
+// GetSessionByUID mocks base method.
+func (m *MockSessionPool) GetSessionByUID(arg0 string) session.Session {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionByUID", arg0)
+	ret0, _ := ret[0].(session.Session)
+	return ret0
+}
+
+// GetSessionByUID indicates an expected call of GetSessionByUID.
+func (mr *MockSessionPoolMockRecorder) GetSessionByUID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByUID", reflect.TypeOf((*MockSessionPool)(nil).GetSessionByUID), arg0)
+}
+
+// GetSessionCloseCallbacks mocks base method.
+func (m *MockSessionPool) GetSessionCloseCallbacks() []session.OnSessionCloseFunc {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionCloseCallbacks")
+	ret0, _ := ret[0].([]session.OnSessionCloseFunc)
+	return ret0
+}
+
+// GetSessionCloseCallbacks indicates an expected call of GetSessionCloseCallbacks.
+func (mr *MockSessionPoolMockRecorder) GetSessionCloseCallbacks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionCloseCallbacks", reflect.TypeOf((*MockSessionPool)(nil).GetSessionCloseCallbacks))
+}
+
+// GetSessionCount mocks base method.
+func (m *MockSessionPool) GetSessionCount() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionCount")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// GetSessionCount indicates an expected call of GetSessionCount.
+func (mr *MockSessionPoolMockRecorder) GetSessionCount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionCount", reflect.TypeOf((*MockSessionPool)(nil).GetSessionCount))
+}
+
+// GetUnackedPushCallbacks mocks base method.
+func (m *MockSessionPool) GetUnackedPushCallbacks() []session.OnUnackedPushFunc {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUnackedPushCallbacks")
+	ret0, _ := ret[0].([]session.OnUnackedPushFunc)
+	return ret0
+}
+
+// GetUnackedPushCallbacks indicates an expected call of GetUnackedPushCallbacks.
+func (mr *MockSessionPoolMockRecorder) GetUnackedPushCallbacks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUnackedPushCallbacks", reflect.TypeOf((*MockSessionPool)(nil).GetUnackedPushCallbacks))
+}
+
+// GetUserCount mocks base method.
+func (m *MockSessionPool) GetUserCount() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserCount")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// GetUserCount indicates an expected call of GetUserCount.
+func (mr *MockSessionPoolMockRecorder) GetUserCount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserCount", reflect.TypeOf((*MockSessionPool)(nil).GetUserCount))
+}
+
+// NewSession mocks base method.
+func (m *MockSessionPool) NewSession(arg0 networkentity.NetworkEntity, arg1 bool, arg2 ...string) (session.Session, bool) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "NewSession", varargs...)
+	ret0, _ := ret[0].(session.Session)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// NewSession indicates an expected call of NewSession.
+func (mr *MockSessionPoolMockRecorder) NewSession(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewSession", reflect.TypeOf((*MockSessionPool)(nil).NewSession), varargs...)
+}
+
+// OnAfterBindBackend mocks base method.
+func (m *MockSessionPool) OnAfterBindBackend(arg0 session.OnSessionBindBackendFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnAfterBindBackend", arg0)
+}
+
+// OnAfterBindBackend indicates an expected call of OnAfterBindBackend.
+func (mr *MockSessionPoolMockRecorder) OnAfterBindBackend(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAfterBindBackend", reflect.TypeOf((*MockSessionPool)(nil).OnAfterBindBackend), arg0)
+}
+
+// OnAfterKickBackend mocks base method.
+func (m *MockSessionPool) OnAfterKickBackend(arg0 session.OnSessionKickBackendFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnAfterKickBackend", arg0)
+}
+
+// OnAfterKickBackend indicates an expected call of OnAfterKickBackend.
+func (mr *MockSessionPoolMockRecorder) OnAfterKickBackend(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAfterKickBackend", reflect.TypeOf((*MockSessionPool)(nil).OnAfterKickBackend), arg0)
+}
+
+// OnAfterSessionBind mocks base method.
+func (m *MockSessionPool) OnAfterSessionBind(arg0 session.OnSessionBindFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnAfterSessionBind", arg0)
+}
+
+// OnAfterSessionBind indicates an expected call of OnAfterSessionBind.
+func (mr *MockSessionPoolMockRecorder) OnAfterSessionBind(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAfterSessionBind", reflect.TypeOf((*MockSessionPool)(nil).OnAfterSessionBind), arg0)
+}
+
+// OnBindBackend mocks base method.
+func (m *MockSessionPool) OnBindBackend(arg0 session.OnSessionBindBackendFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnBindBackend", arg0)
+}
+
+// OnBindBackend indicates an expected call of OnBindBackend.
+func (mr *MockSessionPoolMockRecorder) OnBindBackend(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnBindBackend", reflect.TypeOf((*MockSessionPool)(nil).OnBindBackend), arg0)
+}
+
+// OnKickBackend mocks base method.
+func (m *MockSessionPool) OnKickBackend(arg0 session.OnSessionKickBackendFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnKickBackend", arg0)
+}
+
+// OnKickBackend indicates an expected call of OnKickBackend.
+func (mr *MockSessionPoolMockRecorder) OnKickBackend(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnKickBackend", reflect.TypeOf((*MockSessionPool)(nil).OnKickBackend), arg0)
+}
+
+// OnMigrationAbandoned mocks base method.
+func (m *MockSessionPool) OnMigrationAbandoned(arg0 session.OnMigrationAbandonedFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnMigrationAbandoned", arg0)
+}
+
+// OnMigrationAbandoned indicates an expected call of OnMigrationAbandoned.
+func (mr *MockSessionPoolMockRecorder) OnMigrationAbandoned(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnMigrationAbandoned", reflect.TypeOf((*MockSessionPool)(nil).OnMigrationAbandoned), arg0)
+}
+
+// OnSessionBind mocks base method.
+func (m *MockSessionPool) OnSessionBind(arg0 session.OnSessionBindFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnSessionBind", arg0)
+}
+
+// OnSessionBind indicates an expected call of OnSessionBind.
+func (mr *MockSessionPoolMockRecorder) OnSessionBind(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnSessionBind", reflect.TypeOf((*MockSessionPool)(nil).OnSessionBind), arg0)
+}
+
+// OnSessionClose mocks base method.
+func (m *MockSessionPool) OnSessionClose(arg0 session.OnSessionCloseFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnSessionClose", arg0)
+}
+
+// OnSessionClose indicates an expected call of OnSessionClose.
+func (mr *MockSessionPoolMockRecorder) OnSessionClose(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnSessionClose", reflect.TypeOf((*MockSessionPool)(nil).OnSessionClose), arg0)
+}
+
+// OnUnackedPush mocks base method.
+func (m *MockSessionPool) OnUnackedPush(arg0 session.OnUnackedPushFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnUnackedPush", arg0)
+}
+
+// OnUnackedPush indicates an expected call of OnUnackedPush.
+func (mr *MockSessionPoolMockRecorder) OnUnackedPush(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnUnackedPush", reflect.TypeOf((*MockSessionPool)(nil).OnUnackedPush), arg0)
+}
+
+// QuerySessions mocks base method.
+func (m *MockSessionPool) QuerySessions(arg0 *session.SessionQuery) *session.QueryResult {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuerySessions", arg0)
+	ret0, _ := ret[0].(*session.QueryResult)
+	return ret0
+}
+
+// QuerySessions indicates an expected call of QuerySessions.
+func (mr *MockSessionPoolMockRecorder) QuerySessions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuerySessions", reflect.TypeOf((*MockSessionPool)(nil).QuerySessions), arg0)
+}
+
+// RangeSessions mocks base method.
+func (m *MockSessionPool) RangeSessions(arg0 func(int64, session.SessPublic) bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RangeSessions", arg0)
+}
+
+// RangeSessions indicates an expected call of RangeSessions.
+func (mr *MockSessionPoolMockRecorder) RangeSessions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RangeSessions", reflect.TypeOf((*MockSessionPool)(nil).RangeSessions), arg0)
+}
+
+// RangeUsers mocks base method.
+func (m *MockSessionPool) RangeUsers(arg0 func(string, session.SessPublic) bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RangeUsers", arg0)
+}
+
+// RangeUsers indicates an expected call of RangeUsers.
+func (mr *MockSessionPoolMockRecorder) RangeUsers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RangeUsers", reflect.TypeOf((*MockSessionPool)(nil).RangeUsers), arg0)
+}
+
+// RemoveSessionLocal mocks base method.
+func (m *MockSessionPool) RemoveSessionLocal(arg0 session.Session) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemoveSessionLocal", arg0)
+}
+
+// RemoveSessionLocal indicates an expected call of RemoveSessionLocal.
+func (mr *MockSessionPoolMockRecorder) RemoveSessionLocal(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveSessionLocal", reflect.TypeOf((*MockSessionPool)(nil).RemoveSessionLocal), arg0)
+}
+
+// SetClusterCache mocks base method.
+func (m *MockSessionPool) SetClusterCache(arg0 session.CacheInterface) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetClusterCache", arg0)
+}
+
+// SetClusterCache indicates an expected call of SetClusterCache.
+func (mr *MockSessionPoolMockRecorder) SetClusterCache(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetClusterCache", reflect.TypeOf((*MockSessionPool)(nil).SetClusterCache), arg0)
+}
+
+// StoreSessionLocal mocks base method.
+func (m *MockSessionPool) StoreSessionLocal(arg0 session.Session) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreSessionLocal", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreSessionLocal indicates an expected call of StoreSessionLocal.
+func (mr *MockSessionPoolMockRecorder) StoreSessionLocal(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Value", reflect.TypeOf((*MockSession)(nil).Value), key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreSessionLocal", reflect.TypeOf((*MockSessionPool)(nil).StoreSessionLocal), arg0)
 }
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/constants"
+)
+
+// UnackedPush 未被客户端确认的推送
+type UnackedPush struct {
+	ID    uint   // 推送消息ID
+	Route string // 推送路由
+	Data  []byte // 已序列化的推送数据
+}
+
+// OnUnackedPushFunc session关闭时仍有未确认推送的回调,pushes按发送顺序排列
+type OnUnackedPushFunc func(s Session, pushes []*UnackedPush)
+
+// ackPusher 支持确认推送的网络实体
+type ackPusher interface {
+	PushWithAck(route string, v interface{}) error
+}
+
+func (s *sessionImpl) PushWithAck(route string, v interface{}) error {
+	if p, ok := s.entity.(ackPusher); ok {
+		return p.PushWithAck(route, v)
+	}
+	return errors.WithStack(constants.ErrNotImplemented)
+}
+
+// OnUnackedPush
+//
+//	@implement SessionPool.OnUnackedPush
+func (pool *sessionPoolImpl) OnUnackedPush(f OnUnackedPushFunc) {
+	sf1 := reflect.ValueOf(f)
+	for _, fun := range pool.unackedPushCallbacks {
+		sf2 := reflect.ValueOf(fun)
+		if sf1.Pointer() == sf2.Pointer() {
+			return
+		}
+	}
+	pool.unackedPushCallbacks = append(pool.unackedPushCallbacks, f)
+}
+
+func (pool *sessionPoolImpl) GetUnackedPushCallbacks() []OnUnackedPushFunc {
+	return pool.unackedPushCallbacks
+}
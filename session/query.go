@@ -0,0 +1,151 @@
+package session
+
+import (
+	"sort"
+	"strings"
+)
+
+// SessionQuery 集群session查询条件,空值的条件不参与过滤
+type SessionQuery struct {
+	Uids            []string `json:"uids,omitempty"`     // 指定uid列表
+	FrontendID      string   `json:"fid,omitempty"`      // 仅查询指定网关
+	BackendType     string   `json:"btype,omitempty"`    // 已绑定指定类型的backend
+	BackendID       string   `json:"bid,omitempty"`      // 已绑定指定的backend,需同时指定 BackendType
+	IP              string   `json:"ip,omitempty"`       // 客户端ip前缀
+	ConnectedAfter  int64    `json:"after,omitempty"`    // 连接时间不早于 unix秒
+	ConnectedBefore int64    `json:"before,omitempty"`   // 连接时间不晚于 unix秒
+	OnlyBound       bool     `json:"bound,omitempty"`    // 仅查询已绑定uid的session
+	OnlyInFlight    bool     `json:"inFlight,omitempty"` // 仅查询有处理中请求的session
+	Offset          int      `json:"offset,omitempty"`   // 分页偏移
+	Limit           int      `json:"limit,omitempty"`    // 分页大小,<=0时不分页
+}
+
+// SessionInfo session查询结果
+type SessionInfo struct {
+	Uid              string            `json:"uid"`
+	FrontendID       string            `json:"fid"`
+	Sid              int64             `json:"sid"`
+	Backends         map[string]string `json:"backends,omitempty"`
+	IP               string            `json:"ip"`
+	ConnectedAt      int64             `json:"connectedAt"`
+	RequestsInFlight int               `json:"inFlight"`
+}
+
+// QueryResult session查询结果集
+type QueryResult struct {
+	Total    int               `json:"total"`            // 分页前的匹配总数
+	Sessions []*SessionInfo    `json:"sessions"`         // 当前页数据
+	Errors   map[string]string `json:"errors,omitempty"` // 查询失败的网关 key:frontendID value:错误信息
+}
+
+// Match 判断session是否满足查询条件
+//
+//	@receiver q
+//	@param s
+//	@return bool
+func (q *SessionQuery) Match(s *SessionInfo) bool {
+	if len(q.Uids) > 0 {
+		found := false
+		for _, uid := range q.Uids {
+			if uid == s.Uid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.FrontendID != "" && q.FrontendID != s.FrontendID {
+		return false
+	}
+	if q.BackendType != "" {
+		bid, ok := s.Backends[q.BackendType]
+		if !ok || (q.BackendID != "" && q.BackendID != bid) {
+			return false
+		}
+	}
+	if q.IP != "" && !strings.HasPrefix(s.IP, q.IP) {
+		return false
+	}
+	if q.ConnectedAfter > 0 && s.ConnectedAt < q.ConnectedAfter {
+		return false
+	}
+	if q.ConnectedBefore > 0 && s.ConnectedAt > q.ConnectedBefore {
+		return false
+	}
+	if q.OnlyBound && s.Uid == "" {
+		return false
+	}
+	if q.OnlyInFlight && s.RequestsInFlight == 0 {
+		return false
+	}
+	return true
+}
+
+// Paginate 对匹配的全部结果排序并分页
+//
+//	@receiver q
+//	@param infos
+//	@return *QueryResult
+func (q *SessionQuery) Paginate(infos []*SessionInfo) *QueryResult {
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].ConnectedAt != infos[j].ConnectedAt {
+			return infos[i].ConnectedAt < infos[j].ConnectedAt
+		}
+		if infos[i].FrontendID != infos[j].FrontendID {
+			return infos[i].FrontendID < infos[j].FrontendID
+		}
+		return infos[i].Sid < infos[j].Sid
+	})
+	ret := &QueryResult{Total: len(infos), Sessions: infos}
+	if q.Offset > 0 {
+		if q.Offset >= len(infos) {
+			ret.Sessions = []*SessionInfo{}
+			return ret
+		}
+		ret.Sessions = ret.Sessions[q.Offset:]
+	}
+	if q.Limit > 0 && q.Limit < len(ret.Sessions) {
+		ret.Sessions = ret.Sessions[:q.Limit]
+	}
+	return ret
+}
+
+func (s *sessionImpl) CreatedAt() int64 {
+	return s.createdAt
+}
+
+func (s *sessionImpl) info() *SessionInfo {
+	s.requestsInFlight.mu.RLock()
+	inFlight := len(s.requestsInFlight.m)
+	s.requestsInFlight.mu.RUnlock()
+	s.RLock()
+	info := &SessionInfo{
+		Uid:              s.uid,
+		FrontendID:       s.frontendID,
+		Sid:              s.id,
+		IP:               s.ip,
+		ConnectedAt:      s.createdAt,
+		RequestsInFlight: inFlight,
+	}
+	s.RUnlock()
+	info.Backends = s.GetBackends()
+	return info
+}
+
+// QuerySessions
+//
+//	@implement SessionPool.QuerySessions
+//	仅返回本地frontend session,跨网关的合并分页由调用方处理
+func (pool *sessionPoolImpl) QuerySessions(q *SessionQuery) *QueryResult {
+	var infos []*SessionInfo
+	pool.sessionsByID.Range(func(k, v any) bool {
+		info := v.(*sessionImpl).info()
+		if q.Match(info) {
+			infos = append(infos, info)
+		}
+		return true
+	})
+	return q.Paginate(infos)
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionQueryMatch(t *testing.T) {
+	info := &SessionInfo{
+		Uid:              "1001",
+		FrontendID:       "connector-1",
+		Sid:              1,
+		Backends:         map[string]string{"game": "game-1"},
+		IP:               "10.0.1.2",
+		ConnectedAt:      100,
+		RequestsInFlight: 2,
+	}
+	tables := []struct {
+		name  string
+		query SessionQuery
+		match bool
+	}{
+		{"Empty", SessionQuery{}, true},
+		{"Uids", SessionQuery{Uids: []string{"1000", "1001"}}, true},
+		{"UidsMiss", SessionQuery{Uids: []string{"1000"}}, false},
+		{"Frontend", SessionQuery{FrontendID: "connector-1"}, true},
+		{"FrontendMiss", SessionQuery{FrontendID: "connector-2"}, false},
+		{"BackendType", SessionQuery{BackendType: "game"}, true},
+		{"BackendTypeMiss", SessionQuery{BackendType: "chat"}, false},
+		{"BackendID", SessionQuery{BackendType: "game", BackendID: "game-1"}, true},
+		{"BackendIDMiss", SessionQuery{BackendType: "game", BackendID: "game-2"}, false},
+		{"IPPrefix", SessionQuery{IP: "10.0.1."}, true},
+		{"IPPrefixMiss", SessionQuery{IP: "10.0.2."}, false},
+		{"ConnectedAfter", SessionQuery{ConnectedAfter: 100}, true},
+		{"ConnectedAfterMiss", SessionQuery{ConnectedAfter: 101}, false},
+		{"ConnectedBefore", SessionQuery{ConnectedBefore: 100}, true},
+		{"ConnectedBeforeMiss", SessionQuery{ConnectedBefore: 99}, false},
+		{"OnlyBound", SessionQuery{OnlyBound: true}, true},
+		{"OnlyInFlight", SessionQuery{OnlyInFlight: true}, true},
+		{"All", SessionQuery{Uids: []string{"1001"}, FrontendID: "connector-1", BackendType: "game", BackendID: "game-1", IP: "10.", ConnectedAfter: 50, ConnectedBefore: 150, OnlyBound: true, OnlyInFlight: true}, true},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			assert.Equal(t, table.match, table.query.Match(info))
+		})
+	}
+
+	t.Run("OnlyBoundMiss", func(t *testing.T) {
+		q := SessionQuery{OnlyBound: true}
+		assert.False(t, q.Match(&SessionInfo{}))
+	})
+	t.Run("OnlyInFlightMiss", func(t *testing.T) {
+		q := SessionQuery{OnlyInFlight: true}
+		assert.False(t, q.Match(&SessionInfo{Uid: "1001"}))
+	})
+}
+
+func TestSessionQueryPaginate(t *testing.T) {
+	newInfos := func() []*SessionInfo {
+		return []*SessionInfo{
+			{Uid: "d", FrontendID: "connector-2", Sid: 1, ConnectedAt: 20},
+			{Uid: "b", FrontendID: "connector-1", Sid: 2, ConnectedAt: 10},
+			{Uid: "c", FrontendID: "connector-2", Sid: 3, ConnectedAt: 10},
+			{Uid: "a", FrontendID: "connector-1", Sid: 1, ConnectedAt: 10},
+		}
+	}
+	tables := []struct {
+		name   string
+		offset int
+		limit  int
+		uids   []string
+	}{
+		{"NoPaging", 0, 0, []string{"a", "b", "c", "d"}},
+		{"Limit", 0, 2, []string{"a", "b"}},
+		{"Offset", 1, 0, []string{"b", "c", "d"}},
+		{"OffsetLimit", 1, 2, []string{"b", "c"}},
+		{"LimitBeyond", 2, 10, []string{"c", "d"}},
+		{"OffsetBeyond", 4, 2, []string{}},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			q := &SessionQuery{Offset: table.offset, Limit: table.limit}
+			ret := q.Paginate(newInfos())
+			assert.Equal(t, 4, ret.Total)
+			uids := []string{}
+			for _, info := range ret.Sessions {
+				uids = append(uids, info.Uid)
+			}
+			assert.Equal(t, table.uids, uids)
+		})
+	}
+}
+
+func TestSessionPoolQuerySessions(t *testing.T) {
+	pool := NewSessionPool()
+	s1, _ := pool.NewSession(nil, true, "1001")
+	s1.SetIP("10.0.0.1")
+	s2, _ := pool.NewSession(nil, true)
+	s2.SetIP("10.0.0.2")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// 查询与更新并发
+		s2.SetIP("10.0.1.2")
+	}()
+	ret := pool.QuerySessions(&SessionQuery{OnlyBound: true})
+	wg.Wait()
+	require.Len(t, ret.Sessions, 1)
+	assert.Equal(t, "1001", ret.Sessions[0].Uid)
+	assert.Equal(t, "10.0.0.1", ret.Sessions[0].IP)
+
+	ret = pool.QuerySessions(&SessionQuery{IP: "10.0.1."})
+	require.Len(t, ret.Sessions, 1)
+	assert.Equal(t, s2.ID(), ret.Sessions[0].Sid)
+}
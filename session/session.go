@@ -35,7 +35,9 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
+	"github.com/topfreegames/pitaya/v2/clock"
 	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/timer"
 
 	"github.com/topfreegames/pitaya/v2/util"
 
@@ -56,6 +58,7 @@ const (
 	fieldKeyBackends       = "bs"        // backends id list
 	fieldKeyIP             = "ip"        // ip
 	fieldKeyData           = "u"         // 用户自定义数据 json存储
+	fieldKeyMigration      = "mg"        // 跨网关迁移交接数据
 )
 
 type CloseReason = int // 关闭原因
@@ -64,10 +67,12 @@ const (
 	CloseReasonNormal CloseReason = iota // 默认关闭
 )
 const (
-	CloseReasonKickMin    CloseReason = 100
-	CloseReasonKickRebind             = 101 // 重新绑定,同一session在其他设备登录时发生
-	CloseReasonKickManual             = 102 // 手动被踢(封号)
-	CloseReasonKickMax    CloseReason = 1000
+	CloseReasonKickMin     CloseReason = 100
+	CloseReasonKickRebind              = 101 // 重新绑定,同一session在其他设备登录时发生
+	CloseReasonKickManual              = 102 // 手动被踢(封号)
+	CloseReasonKickMigrate             = 103 // 迁移到其他网关
+	CloseReasonKickPanic               = 104 // 任务panic,线程池panic策略为 co.PanicKillSession 时发生
+	CloseReasonKickMax     CloseReason = 1000
 )
 
 type OnSessionBindFunc func(ctx context.Context, s Session, callback map[string]string) error
@@ -90,13 +95,15 @@ type sessionPoolImpl struct {
 	sessionsByID          sync.Map
 	sessionIDSvc          *sessionIDService
 	// SessionCount keeps the current number of sessions
-	SessionCount              int64
-	UserCount                 int64
-	storage                   CacheInterface
-	bindBackendCallbacks      []OnSessionBindBackendFunc
-	kickBackendCallbacks      []OnSessionKickBackendFunc
-	afterBindBackendCallbacks []OnSessionBindBackendFunc
-	afterKickBackendCallbacks []OnSessionKickBackendFunc
+	SessionCount                int64
+	UserCount                   int64
+	storage                     CacheInterface
+	bindBackendCallbacks        []OnSessionBindBackendFunc
+	kickBackendCallbacks        []OnSessionKickBackendFunc
+	afterBindBackendCallbacks   []OnSessionBindBackendFunc
+	unackedPushCallbacks        []OnUnackedPushFunc
+	migrationAbandonedCallbacks []OnMigrationAbandonedFunc
+	afterKickBackendCallbacks   []OnSessionKickBackendFunc
 }
 
 // SessionPool centralizes all sessions within a Pitaya app
@@ -112,6 +119,14 @@ type SessionPool interface {
 	OnSessionBind(f OnSessionBindFunc)
 	OnAfterSessionBind(f OnSessionBindFunc)
 	OnSessionClose(f OnSessionCloseFunc)
+	// OnUnackedPush 设置session关闭时仍有未确认的 PushWithAck 推送的回调
+	//  @param f
+	OnUnackedPush(f OnUnackedPushFunc)
+	GetUnackedPushCallbacks() []OnUnackedPushFunc
+	// OnMigrationAbandoned 设置迁移超时未被目标网关接管且连接已断开的session的回调
+	//  @param f
+	OnMigrationAbandoned(f OnMigrationAbandonedFunc)
+	GetMigrationAbandonedCallbacks() []OnMigrationAbandonedFunc
 	OnBindBackend(f OnSessionBindBackendFunc)
 	OnKickBackend(f OnSessionKickBackendFunc)
 	OnAfterBindBackend(f OnSessionBindBackendFunc)
@@ -131,6 +146,10 @@ type SessionPool interface {
 	SetClusterCache(storage CacheInterface)
 	RangeUsers(f func(uid string, sess SessPublic) bool)
 	RangeSessions(f func(sid int64, sess SessPublic) bool)
+	// QuerySessions 按条件查询本地frontend session,结果按连接时间排序
+	//  @param q
+	//  @return *QueryResult
+	QuerySessions(q *SessionQuery) *QueryResult
 }
 
 // HandshakeClientData represents information about the client sent on the handshake.
@@ -155,6 +174,7 @@ type sessionImpl struct {
 	uid               string                      // binding user id
 	uidInt            int64                       // uid as number
 	lastTime          int64                       // last heartbeat time
+	createdAt         int64                       // 连接建立时间 unix秒
 	entity            networkentity.NetworkEntity // low-level network entity
 	data              map[string]any              // session data store 用户自定义数据
 	handshakeData     *HandshakeData              // handshake data received by the client
@@ -164,12 +184,15 @@ type sessionImpl struct {
 	frontendID        string                      // the id of the frontend that owns the session
 	frontendSessionID int64                       // the id of the session on the frontend server
 	online            bool                        // 是否在线,仅cluster session有效
+	migrating         int32                       // 是否正在迁移到其他网关,仅frontend session有效
 	backends          map[string]string           // 绑定的backends
 	bsMutex           sync.RWMutex                // backends 的mutex
 	ip                string                      // 远程客户端ip地址
 	Subscriptions     []*nats.Subscription        // subscription created on bind when using nats rpc server  // subscription created on bind when using nats rpc server
 	requestsInFlight  ReqInFlight
 	pool              *sessionPoolImpl
+	timerMu           sync.Mutex   // timers 的mutex
+	timers            *timer.Scope // session定时器,首次使用时创建
 }
 
 type ReqInFlight struct {
@@ -183,6 +206,12 @@ type SessPublic interface {
 	GetFrontendID() string
 	GetFrontendSessionID() int64
 	Push(route string, v interface{}) error
+	// PushWithAck 需要客户端确认的推送,未确认时在同一session上重发,session关闭时仍未确认的推送交给 SessionPool.OnUnackedPush 回调
+	//  仅frontend本地session支持
+	//  @param route
+	//  @param v
+	//  @return error
+	PushWithAck(route string, v interface{}) error
 	ID() int64
 	UID() string
 	UIDInt() int64
@@ -225,6 +254,9 @@ type SessPublic interface {
 	RemoteIPWithoutCache() netip.Addr
 	RemoteIP() netip.Addr
 	RemoteIPText() string
+	// CreatedAt 连接建立时间 unix秒
+	//  @return int64
+	CreatedAt() int64
 	Remove(key string) error
 	// Set 设置用户自定义数据
 	//  @param key
@@ -265,6 +297,20 @@ type SessPublic interface {
 	//  @see co.GoWithUser
 	//  @param task
 	Go(ctx context.Context, task func(ctx context.Context))
+	// AfterFunc d 后在session独立线程执行一次 fn,session关闭或本服被 KickBackend 解绑(session从本服移除)时自动取消
+	//  @see Go
+	//  @param d
+	//  @param fn
+	//  @return *timer.Timer
+	//  @return error session已关闭时返回 constants.ErrTimerScopeClosed
+	AfterFunc(d time.Duration, fn timer.Func) (*timer.Timer, error)
+	// Every 每隔 d 在session独立线程执行一次 fn,session关闭或本服被 KickBackend 解绑(session从本服移除)时自动取消
+	//  @see Go
+	//  @param d
+	//  @param fn
+	//  @return *timer.Timer
+	//  @return error session已关闭时返回 constants.ErrTimerScopeClosed
+	Every(d time.Duration, fn timer.Func) (*timer.Timer, error)
 }
 
 // Session represents a client session, which can store data during the connection.
@@ -350,6 +396,21 @@ type Session interface {
 	SetBackends(bs map[string]string)
 	SetBackendID(svrType string, id string)
 	RemoveBackendID(svrType string)
+	// SetMigrating 标记session正在迁移到其他网关,标记后session关闭时以 CloseReasonKickMigrate 作为关闭原因,超时未被目标网关接管时由源网关补做下线处理
+	//  @param migrating
+	SetMigrating(migrating bool)
+	// IsMigrating 是否正在迁移到其他网关
+	//  @return bool
+	IsMigrating() bool
+	// FlushMigration 迁移交接数据写入cache
+	//  @param ticket
+	//  @return error
+	FlushMigration(ticket *MigrationTicket) error
+	// TakeMigration 从cache原子地取出并清除交接给 to 网关的迁移交接数据,交接给其他网关的数据保留不动
+	//  @param to 目标网关ID
+	//  @return *MigrationTicket 没有时返回nil,已过期的也会返回,由调用方检查 MigrationTicket.Expired
+	//  @return error
+	TakeMigration(to string) (*MigrationTicket, error)
 }
 
 type sessionIDService struct {
@@ -383,7 +444,8 @@ func (pool *sessionPoolImpl) NewSession(entity networkentity.NetworkEntity, fron
 		entity:           entity,
 		data:             make(map[string]any),
 		handshakeData:    nil,
-		lastTime:         time.Now().Unix(),
+		lastTime:         clock.Now().Unix(),
+		createdAt:        clock.Now().Unix(),
 		OnCloseCallbacks: []func(){},
 		IsFrontend:       frontend,
 		pool:             pool,
@@ -598,6 +660,9 @@ func (pool *sessionPoolImpl) StoreSessionLocal(session Session) error {
 //	@receiver pool
 //	@param session
 func (pool *sessionPoolImpl) RemoveSessionLocal(session Session) {
+	if s, ok := session.(*sessionImpl); ok {
+		s.stopTimers(false)
+	}
 	if len(session.UID()) > 0 {
 		pool.sessionsByUID.Delete(session.UID())
 		atomic.AddInt64(&pool.UserCount, -1)
@@ -742,14 +807,29 @@ func (s *sessionImpl) SetDataEncoded(encodedData []byte) error {
 
 // SetFrontendData sets frontend id and session id
 func (s *sessionImpl) SetFrontendData(frontendID string, frontendSessionID int64) {
+	s.Lock()
+	defer s.Unlock()
 	s.frontendID = frontendID
 	s.frontendSessionID = frontendSessionID
 }
 
 func (s *sessionImpl) SetIP(ip string) {
+	s.Lock()
+	defer s.Unlock()
 	s.ip = ip
 }
 
+// setUID 在锁内设置uid,与 info 等并发读取互斥
+//
+//	@receiver s
+//	@param uid 为空时解绑
+func (s *sessionImpl) setUID(uid string) {
+	s.Lock()
+	defer s.Unlock()
+	s.uid = uid
+	s.uidInt = util.ForceIdStrToInt(uid)
+}
+
 // Bind bind UID to current session
 func (s *sessionImpl) Bind(ctx context.Context, uid string, callback map[string]string) error {
 	if uid == "" {
@@ -760,21 +840,18 @@ func (s *sessionImpl) Bind(ctx context.Context, uid string, callback map[string]
 		return errors.WithStack(fmt.Errorf("%w,uid=%s", constants.ErrSessionAlreadyBound, uid))
 	}
 
-	s.uid = uid
-	s.uidInt = util.ForceIdStrToInt(s.uid)
+	s.setUID(uid)
 	for _, cb := range s.pool.sessionBindCallbacks {
 		err = cb(ctx, s, callback)
 		if err != nil {
-			s.uid = ""
-			s.uidInt = 0
+			s.setUID("")
 			return err
 		}
 	}
 	for _, cb := range s.pool.afterBindCallbacks {
 		err = cb(ctx, s, callback)
 		if err != nil {
-			s.uid = ""
-			s.uidInt = 0
+			s.setUID("")
 			return err
 		}
 	}
@@ -789,8 +866,7 @@ func (s *sessionImpl) Bind(ctx context.Context, uid string, callback map[string]
 		err = s.bindInFront(ctx, callback)
 		if err != nil {
 			logger.Zap.Error("error while trying to push session to front", zap.Error(err))
-			s.uid = ""
-			s.uidInt = 0
+			s.setUID("")
 			return err
 		}
 
@@ -850,6 +926,9 @@ func (s *sessionImpl) Kick(ctx context.Context, callback map[string]string, reas
 		return err
 	}
 	s.online = false
+	if len(reason) == 0 && s.IsMigrating() {
+		reason = []CloseReason{CloseReasonKickMigrate}
+	}
 	// 不能调用s.Close(),因为s.entity.Close()后handler.go的Handle()的defer里自然会session.close().会导致重复调用
 	return s.entity.Close(callback, reason...)
 }
@@ -888,6 +967,7 @@ func (s *sessionImpl) KickBackend(ctx context.Context, targetServerType string,
 		s.SetBackendID(targetServerType, backendID)
 		return err
 	}
+	// 定时器只属于本服,由被解绑的目标服在 RemoveSessionLocal 时取消,其他服的定时器不受影响
 	for _, cb := range s.pool.afterKickBackendCallbacks {
 		err := cb(ctx, s, targetServerType, backendID, callback, rea)
 		if err != nil {
@@ -913,6 +993,7 @@ func (s *sessionImpl) Close(callback map[string]string, reason ...CloseReason) {
 	logger.Zap.Debug("session close", zap.Int64("id", s.ID()), zap.String("uid", s.UID()))
 	atomic.AddInt64(&s.pool.SessionCount, -1)
 	s.online = false
+	s.stopTimers(true)
 	s.pool.sessionsByID.Delete(s.ID())
 	// 须校验存的session和要关闭的是否同一个session，相同才清uid-session map中的值。否则互相频繁顶号时会有误删的异步问题
 	oldSession := s.pool.GetSessionByUID(s.UID())
@@ -938,6 +1019,9 @@ func (s *sessionImpl) Close(callback map[string]string, reason ...CloseReason) {
 			}
 		}
 	}
+	if len(reason) == 0 && s.IsMigrating() {
+		reason = []CloseReason{CloseReasonKickMigrate}
+	}
 	// 不能返回error,因为这里可能是kick后重复调用了entity.Close
 	s.entity.Close(callback, reason...)
 }
@@ -1435,7 +1519,9 @@ func (s *sessionImpl) ObtainFromCluster() error {
 	for field, v := range cache {
 		switch field {
 		case fieldKeyFrontendID:
+			s.Lock()
 			s.frontendID = v
+			s.Unlock()
 		case fieldKeyFrontendSessID:
 			s.frontendSessionID, err = strconv.ParseInt(v, 10, 64)
 			if err != nil {
@@ -1458,7 +1544,7 @@ func (s *sessionImpl) ObtainFromCluster() error {
 				return err
 			}
 		case fieldKeyIP:
-			s.ip = v
+			s.SetIP(v)
 		case fieldKeyOnline:
 			s.online = v == "1"
 		}
@@ -1494,17 +1580,78 @@ func (s *sessionImpl) InitialFromCluster() error {
 	}
 	return nil
 }
+
+// FrontendSessHash 网关session的哈希
+//
+// Deprecated: 不同session可能冲突,派发任务请使用 FrontendSessKey
 func FrontendSessHash(frontendID string, sid int64) int64 {
-	return int64(crc32.ChecksumIEEE([]byte(fmt.Sprintf("%s-%d", frontendID, sid))))
+	return int64(crc32.ChecksumIEEE([]byte(FrontendSessKey(frontendID, sid))))
+}
+
+// FrontendSessKey 网关session在所有网关中唯一的key,用于在backend按session派发任务 co.GoWithKey
+//
+//	@param frontendID
+//	@param sid 网关上的sessionID
+//	@return string
+func FrontendSessKey(frontendID string, sid int64) string {
+	return fmt.Sprintf("%s-%d", frontendID, sid)
 }
 
 func (s *sessionImpl) Go(ctx context.Context, task func(ctx context.Context)) {
+	// 任务panic时按 co.PanicKillSession 策略踢下所属的session
+	if ctx.Value(constants.SessionCtxKey) == nil {
+		ctx = context.WithValue(ctx, constants.SessionCtxKey, s)
+	}
 	if s.uidInt != 0 {
 		co.GoWithUser(ctx, s.uidInt, task)
 		return
 	}
-	sid := lo.If(s.IsFrontend, s.id).Else(FrontendSessHash(s.frontendID, s.frontendSessionID))
-	co.GoWithID(ctx, sid, task, co.WithPoolName(co.SessionGoPoolName))
+	if s.IsFrontend {
+		co.GoWithID(ctx, s.id, task, co.WithPoolName(co.SessionGoPoolName))
+		return
+	}
+	co.GoWithKey(ctx, FrontendSessKey(s.frontendID, s.frontendSessionID), task, co.WithPoolName(co.SessionGoPoolName))
+}
+
+// AfterFunc
+// @implement SessPublic.AfterFunc
+func (s *sessionImpl) AfterFunc(d time.Duration, fn timer.Func) (*timer.Timer, error) {
+	return s.timerScope().AfterFunc(d, fn)
+}
+
+// Every
+// @implement SessPublic.Every
+func (s *sessionImpl) Every(d time.Duration, fn timer.Func) (*timer.Timer, error) {
+	return s.timerScope().Every(d, fn)
+}
+
+func (s *sessionImpl) timerScope() *timer.Scope {
+	s.timerMu.Lock()
+	defer s.timerMu.Unlock()
+	if s.timers == nil {
+		s.timers = timer.NewScope("session", func(task func()) {
+			s.Go(context.Background(), func(ctx context.Context) { task() })
+		})
+	}
+	return s.timers
+}
+
+// stopTimers 取消session的所有定时器
+//
+//	@receiver s
+//	@param closing session关闭时为true,之后不能再添加定时器;否则之后添加时重新创建
+func (s *sessionImpl) stopTimers(closing bool) {
+	var scope *timer.Scope
+	if closing {
+		scope = s.timerScope()
+	} else {
+		s.timerMu.Lock()
+		scope, s.timers = s.timers, nil
+		s.timerMu.Unlock()
+	}
+	if scope != nil {
+		scope.Stop()
+	}
 }
 
 func (s *sessionImpl) HasRequestsInFlight() bool {
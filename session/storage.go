@@ -26,6 +26,19 @@ type CacheInterface interface {
 	Hmset(key string, fieldsAndValues map[string]string) error
 	HmsetCtx(ctx context.Context, key string, fieldsAndValues map[string]string) error
 }
+
+// ScriptCache 可选实现,支持lua脚本的 CacheInterface,需要原子读写时使用,未实现时退化为非原子的读写
+type ScriptCache interface {
+	// EvalCtx 执行lua脚本
+	//  @param ctx
+	//  @param script
+	//  @param keys
+	//  @param args
+	//  @return interface{} 脚本返回值,返回false时err为 redis.Nil
+	//  @return error
+	EvalCtx(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error)
+}
+
 type RedisCache struct {
 	conn redis.Cmdable
 	ttl  time.Duration
@@ -94,6 +107,13 @@ func (r RedisCache) HsetnxCtx(ctx context.Context, key, field, value string) (va
 	return
 }
 
+// EvalCtx
+//
+//	@implement ScriptCache.EvalCtx
+func (r RedisCache) EvalCtx(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	return script.Run(ctx, r.conn, keys, args...).Result()
+}
+
 // Hmset is the implementation of redis hmset command.
 func (r RedisCache) Hmset(key string, fieldsAndValues map[string]string) error {
 	return r.HmsetCtx(context.Background(), key, fieldsAndValues)
@@ -0,0 +1,29 @@
+package pitaya
+
+import (
+	"context"
+
+	"github.com/topfreegames/pitaya/v2/cluster"
+	"github.com/topfreegames/pitaya/v2/constants"
+	"github.com/topfreegames/pitaya/v2/session"
+)
+
+// QuerySessions
+//
+//	@implement Pitaya.QuerySessions
+//	经 Fork 查询所有frontend,各frontend返回前 Offset+Limit 条后在本地合并分页
+func (app *App) QuerySessions(ctx context.Context, q *session.SessionQuery) (*session.QueryResult, error) {
+	if app.rpcServer == nil {
+		return nil, constants.ErrRPCServerNotInitialized
+	}
+	if q == nil {
+		q = &session.SessionQuery{}
+	}
+	var frontends []*cluster.Server
+	for _, sv := range app.serviceDiscovery.GetServers() {
+		if sv.Frontend && (q.FrontendID == "" || q.FrontendID == sv.ID) {
+			frontends = append(frontends, sv)
+		}
+	}
+	return app.sys.ForkQuerySessions(ctx, q, frontends)
+}
@@ -0,0 +1,46 @@
+package sessionevent
+
+import (
+	"context"
+)
+
+// Type 会话事件类型
+type Type string
+
+const (
+	TypeBind        Type = "bind"        // session在frontend绑定uid
+	TypeClose       Type = "close"       // frontend上的session关闭
+	TypeBindBackend Type = "bindbackend" // session绑定到有状态backend
+	TypeKickBackend Type = "kickbackend" // session与有状态backend解绑
+)
+
+// Event 会话生命周期事件
+//
+//	投递语义为至少一次,消费方应以ID去重
+type Event struct {
+	ID          string            `json:"id"`                    // 事件唯一ID
+	Type        Type              `json:"type"`                  // 事件类型
+	Uid         string            `json:"uid"`                   // 用户ID
+	Sid         int64             `json:"sid"`                   // session在frontend上的ID
+	FrontendID  string            `json:"fid,omitempty"`         // frontend ID
+	BackendType string            `json:"btype,omitempty"`       // backend类型,仅backend事件
+	BackendID   string            `json:"bid,omitempty"`         // backend ID,仅backend事件
+	Reason      int               `json:"reason,omitempty"`      // 关闭或解绑原因 session.CloseReason
+	IP          string            `json:"ip,omitempty"`          // 客户端ip,仅frontend事件
+	ServerID    string            `json:"server"`                // 产生事件的服务ID
+	ConnectedAt int64             `json:"connectedAt,omitempty"` // 连接建立时间 unix秒,仅frontend事件
+	Timestamp   int64             `json:"ts"`                    // 事件发生时间 unix毫秒
+	Metadata    map[string]string `json:"metadata,omitempty"`    // 调用方透传数据
+}
+
+// Sink 会话事件的发布目标
+type Sink interface {
+	// Publish 发布事件,返回错误时由调用方重试
+	//  @param ctx
+	//  @param event
+	//  @return error
+	Publish(ctx context.Context, event *Event) error
+	// Close 释放资源
+	//  @return error
+	Close() error
+}
@@ -0,0 +1,65 @@
+package sessionevent
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/config"
+)
+
+// NatsSink 发布事件到nats subject,subject为 conf.Nats.Subject + "." + 事件类型
+//
+//	启用JetStream时由服务端确认并以事件ID去重,否则以Flush确认服务端已收到
+type NatsSink struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+	timeout time.Duration
+}
+
+// NewNatsSink returns a new nats sink
+func NewNatsSink(conf config.SessionEventsConfig, options ...nats.Option) (*NatsSink, error) {
+	conn, err := nats.Connect(conf.Nats.Connect, options...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	s := &NatsSink{
+		conn:    conn,
+		subject: conf.Nats.Subject,
+		timeout: conf.Nats.PublishTimeout,
+	}
+	if conf.Nats.JetStream {
+		s.js, err = conn.JetStream()
+		if err != nil {
+			conn.Close()
+			return nil, errors.WithStack(err)
+		}
+	}
+	return s, nil
+}
+
+func (s *NatsSink) Publish(ctx context.Context, event *Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	subject := s.subject + "." + string(event.Type)
+	if s.js != nil {
+		ctx, cancel := context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+		_, err = s.js.Publish(subject, b, nats.MsgId(event.ID), nats.Context(ctx))
+		return errors.WithStack(err)
+	}
+	if err = s.conn.Publish(subject, b); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(s.conn.FlushTimeout(s.timeout))
+}
+
+func (s *NatsSink) Close() error {
+	s.conn.Close()
+	return nil
+}
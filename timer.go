@@ -33,8 +33,9 @@ import (
 // for slow receivers.
 // The duration d must be greater than zero; if not, NewTimer will panic.
 // Stop the timer to release associated resources.
-func NewTimer(interval time.Duration, fn timer.Func) *timer.Timer {
-	return NewCountTimer(interval, timer.LoopForever, fn)
+// Pass timer.WithGoID to run fn on a co pool thread instead of the ticker goroutine.
+func NewTimer(interval time.Duration, fn timer.Func, opts ...timer.Option) *timer.Timer {
+	return NewCountTimer(interval, timer.LoopForever, fn, opts...)
 }
 
 // NewCountTimer returns a new Timer containing a function that will be called
@@ -42,7 +43,7 @@ func NewTimer(interval time.Duration, fn timer.Func) *timer.Timer {
 // will be stopped automatically, It adjusts the intervals for slow receivers.
 // The duration d must be greater than zero; if not, NewCountTimer will panic.
 // Stop the timer to release associated resources.
-func NewCountTimer(interval time.Duration, count int, fn timer.Func) *timer.Timer {
+func NewCountTimer(interval time.Duration, count int, fn timer.Func, opts ...timer.Option) *timer.Timer {
 	if fn == nil {
 		panic("pitaya/timer: nil timer function")
 	}
@@ -50,37 +51,42 @@ func NewCountTimer(interval time.Duration, count int, fn timer.Func) *timer.Time
 		panic("non-positive interval for NewTimer")
 	}
 
-	t := timer.NewTimer(fn, interval, count)
-	// add to manager
-	timer.Manager.ChCreatedTimer <- t
-	return t
+	return timer.NewTimer(fn, interval, count, opts...)
 }
 
 // NewAfterTimer returns a new Timer containing a function that will be called
 // after duration that specified by the duration argument.
 // The duration d must be greater than zero; if not, NewAfterTimer will panic.
 // Stop the timer to release associated resources.
-func NewAfterTimer(duration time.Duration, fn timer.Func) *timer.Timer {
-	return NewCountTimer(duration, 1, fn)
+func NewAfterTimer(duration time.Duration, fn timer.Func, opts ...timer.Option) *timer.Timer {
+	return NewCountTimer(duration, 1, fn, opts...)
 }
 
 // NewCondTimer returns a new Timer containing a function that will be called
 // when condition satisfied that specified by the condition argument.
 // The duration d must be greater than zero; if not, NewCondTimer will panic.
 // Stop the timer to release associated resources.
-func NewCondTimer(condition timer.Condition, fn timer.Func) (*timer.Timer, error) {
+func NewCondTimer(condition timer.Condition, fn timer.Func, opts ...timer.Option) (*timer.Timer, error) {
 	if condition == nil {
 		return nil, constants.ErrNilCondition
 	}
 
-	t := NewCountTimer(time.Duration(math.MaxInt64), timer.LoopForever, fn)
-	t.SetCondition(condition)
-	return t, nil
+	opts = append(opts, timer.WithCondition(condition))
+	return NewCountTimer(time.Duration(math.MaxInt64), timer.LoopForever, fn, opts...), nil
+}
+
+// NewEntityTimers returns a timer.Scope whose timers run on the co pool thread
+// of goID, serialized with the tasks submitted by co.GoWithID for the same
+// entity. Stop the scope when the entity is destroyed to cancel all timers.
+// Sessions have their own scope, see session.SessPublic.AfterFunc.
+func NewEntityTimers(poolName string, goID int64) *timer.Scope {
+	return timer.NewEntityScope(poolName, goID)
 }
 
 // SetTimerPrecision set the ticker precision, and time precision can not less
 // than a Millisecond, and can not change after application running. The default
-// precision is time.Second
+// precision is time.Second. Timers are kept in a timing wheel with millisecond
+// resolution, the precision only decides how often the wheel is advanced
 func SetTimerPrecision(precision time.Duration) {
 	if precision < time.Millisecond {
 		panic("time precision can not less than a Millisecond")
@@ -0,0 +1,321 @@
+package timer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/constants"
+)
+
+// MissedPolicy 错过执行时间(进程卡顿、时钟跳变等导致的延迟超过阈值)时的处理策略
+type MissedPolicy int
+
+const (
+	// MissedRunOnce 补执行一次,之后从当前时间计算下次执行时间,默认策略
+	MissedRunOnce MissedPolicy = iota
+	// MissedSkip 跳过错过的执行,从当前时间计算下次执行时间
+	MissedSkip
+	// MissedCatchUp 每个错过的执行时间都补执行一次
+	MissedCatchUp
+)
+
+// maxSearchYears Next 向后查找的最大年数,超过时视为不会再执行
+const maxSearchYears = 5
+
+// CronSchedule 解析后的cron表达式
+//
+//	格式为 秒 分 时 日 月 周,也支持省略秒的5段格式(秒为0)以及 @yearly @monthly @weekly @daily @hourly
+//	每段支持 * ? , - / 以及月份和星期的英文缩写,星期的0和7都表示周日;日和周同时指定时满足其一即可
+//	表达式前可加 CRON_TZ=Asia/Shanghai 或 TZ=Asia/Shanghai 指定时区,否则使用构造时传入的时区
+//
+//	夏令时:时钟拨快跳过的时间点在跳变后的第一刻执行一次;时钟拨回重复的时间段,
+//	指定了小时的表达式只在第一次经过时执行,小时为 * 的表达式按实际经过的时间执行
+type CronSchedule struct {
+	spec     string
+	second   uint64
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	anyHour  bool
+	anyDom   bool
+	anyDow   bool
+	location *time.Location
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondField = cronField{min: 0, max: 59}
+	minuteField = cronField{min: 0, max: 59}
+	hourField   = cronField{min: 0, max: 23}
+	domField    = cronField{min: 1, max: 31}
+	monthField  = cronField{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = cronField{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 0 1 1 *",
+		"@annually": "0 0 0 1 1 *",
+		"@monthly":  "0 0 0 1 * *",
+		"@weekly":   "0 0 0 * * 0",
+		"@daily":    "0 0 0 * * *",
+		"@midnight": "0 0 0 * * *",
+		"@hourly":   "0 0 * * * *",
+	}
+)
+
+// ParseCron 解析cron表达式
+//
+//	@param spec
+//	@param loc 表达式未指定时区时使用的时区,为nil时使用 time.Local
+//	@return *CronSchedule
+//	@return error
+func ParseCron(spec string, loc *time.Location) (*CronSchedule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	s := &CronSchedule{spec: spec, location: loc}
+	expr := strings.TrimSpace(spec)
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		i := strings.IndexByte(expr, ' ')
+		if i < 0 {
+			return nil, invalidCron(spec, "missing fields after time zone")
+		}
+		name := expr[strings.IndexByte(expr, '=')+1 : i]
+		tz, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, invalidCron(spec, err.Error())
+		}
+		s.location = tz
+		expr = strings.TrimSpace(expr[i+1:])
+	}
+	if d, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, invalidCron(spec, fmt.Sprintf("expected 5 or 6 fields, got %d", len(fields)))
+	}
+
+	var err error
+	if s.second, err = parseCronField(fields[0], secondField); err != nil {
+		return nil, invalidCron(spec, err.Error())
+	}
+	if s.minute, err = parseCronField(fields[1], minuteField); err != nil {
+		return nil, invalidCron(spec, err.Error())
+	}
+	if s.hour, err = parseCronField(fields[2], hourField); err != nil {
+		return nil, invalidCron(spec, err.Error())
+	}
+	if s.dom, err = parseCronField(fields[3], domField); err != nil {
+		return nil, invalidCron(spec, err.Error())
+	}
+	if s.month, err = parseCronField(fields[4], monthField); err != nil {
+		return nil, invalidCron(spec, err.Error())
+	}
+	if s.dow, err = parseCronField(fields[5], dowField); err != nil {
+		return nil, invalidCron(spec, err.Error())
+	}
+	// 7 和 0 都表示周日
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.anyHour = isWildcard(fields[2])
+	s.anyDom = isWildcard(fields[3])
+	s.anyDow = isWildcard(fields[5])
+	return s, nil
+}
+
+func invalidCron(spec, reason string) error {
+	return errors.WithStack(fmt.Errorf("%w: %q %s", constants.ErrInvalidCronSpec, spec, reason))
+}
+
+func isWildcard(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// parseCronField 解析一段表达式为位图
+func parseCronField(field string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := f.min, f.max
+		switch {
+		case part == "*" || part == "?":
+			if f.max == 7 {
+				hi = 6
+			}
+		default:
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = f.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				hi = f.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// String 原始表达式
+func (s *CronSchedule) String() string {
+	return s.spec
+}
+
+// Location 表达式使用的时区
+func (s *CronSchedule) Location() *time.Location {
+	return s.location
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.anyDom || s.anyDow {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next 返回 after 之后(不含)的下一个执行时间,找不到时返回零值
+//
+//	@receiver s
+//	@param after
+//	@return time.Time
+func (s *CronSchedule) Next(after time.Time) time.Time {
+	t := after.In(s.location).Truncate(time.Second).Add(time.Second)
+	yearLimit := t.Year() + maxSearchYears
+
+	for t.Year() <= yearLimit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = s.startOfDay(t.Year(), t.Month()+1, 1)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = s.startOfDay(t.Year(), t.Month(), t.Day()+1)
+			continue
+		}
+		if s.skippedHourMatches(t) {
+			return t
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = nextHour(t)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if s.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		if !s.anyHour && isRepeatedHour(t) {
+			// 时钟拨回后重复的一小时,指定小时的任务已在第一次经过时执行
+			t = nextHour(t)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// startOfDay 某天的零点,零点被夏令时跳过时取跳变后的第一刻
+func (s *CronSchedule) startOfDay(year int, month time.Month, day int) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, s.location)
+	// 不存在的本地时间会被规范化到跳变前,逐小时推进到当天
+	want := time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+	for t.Day() != want.Day() {
+		t = nextHour(t)
+	}
+	return t
+}
+
+// nextHour 按实际经过的时间推进到下一个本地整点,跨越夏令时跳变时不会停在不存在的时间上
+func nextHour(t time.Time) time.Time {
+	return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond())).Add(time.Hour)
+}
+
+// skippedHourMatches t 是夏令时拨快跳变后的第一刻,且被跳过的小时中有需要执行的
+//
+//	@receiver s
+//	@param t
+//	@return bool
+func (s *CronSchedule) skippedHourMatches(t time.Time) bool {
+	if s.anyHour || t.Minute() != 0 || t.Second() != 0 {
+		return false
+	}
+	prev := t.Add(-time.Second)
+	prevHour := prev.Hour()
+	if prev.Day() != t.Day() {
+		prevHour = -1
+	}
+	for h := prevHour + 1; h < t.Hour(); h++ {
+		if s.hour&(1<<uint(h)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isRepeatedHour 时钟拨回后第二次经过的本地时间
+func isRepeatedHour(t time.Time) bool {
+	_, offset := t.Zone()
+	_, prevOffset := t.Add(-time.Hour).Zone()
+	if prevOffset <= offset {
+		return false
+	}
+	// 拨回前同一本地时间的时刻
+	earlier := t.Add(-time.Duration(prevOffset-offset) * time.Second)
+	return earlier.Hour() == t.Hour() && earlier.Day() == t.Day()
+}
@@ -0,0 +1,187 @@
+package timer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/constants"
+)
+
+func TestParseCron(t *testing.T) {
+	t.Parallel()
+	tables := []struct {
+		spec string
+		err  bool
+	}{
+		{"0 0 5 * * *", false},
+		{"0 5 * * *", false},
+		{"*/15 * * * * ?", false},
+		{"0 30 9-18/3 * JAN-MAR mon,wed,FRI", false},
+		{"@daily", false},
+		{"CRON_TZ=Asia/Shanghai 0 0 0 * * *", false},
+		{"TZ=UTC @hourly", false},
+		{"", true},
+		{"* * * *", true},
+		{"60 * * * * *", true},
+		{"0 0 24 * * *", true},
+		{"0 0 0 0 * *", true},
+		{"0 0 0 * * 8", true},
+		{"0 0 5-1 * * *", true},
+		{"*/0 * * * * *", true},
+		{"CRON_TZ=Nowhere/Nothing 0 0 0 * * *", true},
+	}
+	for _, table := range tables {
+		t.Run(table.spec, func(t *testing.T) {
+			_, err := ParseCron(table.spec, time.UTC)
+			if table.err {
+				assert.True(t, errors.Is(err, constants.ErrInvalidCronSpec))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	t.Parallel()
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	tables := []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  []time.Time
+	}{
+		{"daily", "0 0 5 * * *", time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC), []time.Time{
+			time.Date(2024, 1, 2, 5, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 3, 5, 0, 0, 0, time.UTC),
+		}},
+		{"seconds", "*/20 * * * * *", time.Date(2024, 1, 1, 0, 0, 50, 0, time.UTC), []time.Time{
+			time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC),
+			time.Date(2024, 1, 1, 0, 1, 20, 0, time.UTC),
+		}},
+		{"weekly", "0 0 0 * * MON", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), []time.Time{
+			time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+		}},
+		{"dom or dow", "0 0 0 13 * 5", time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC), []time.Time{
+			time.Date(2024, 9, 6, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 9, 13, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 9, 20, 0, 0, 0, 0, time.UTC),
+		}},
+		{"leap day", "0 0 0 29 2 *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), []time.Time{
+			time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		}},
+		{"time zone", "CRON_TZ=Asia/Shanghai 0 0 0 * * *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), []time.Time{
+			time.Date(2024, 1, 2, 0, 0, 0, 0, shanghai),
+		}},
+		{"never", "0 0 0 30 2 *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), []time.Time{{}}},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			s, err := ParseCron(table.spec, time.UTC)
+			require.NoError(t, err)
+			after := table.after
+			for _, want := range table.want {
+				next := s.Next(after)
+				assert.True(t, want.Equal(next), "want %v got %v", want, next)
+				after = next
+			}
+		})
+	}
+}
+
+func TestCronScheduleDST(t *testing.T) {
+	t.Parallel()
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	// 2024-03-10 02:00 拨快到 03:00, 2024-11-03 02:00 拨回到 01:00
+	tables := []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  []time.Time
+	}{
+		{"spring forward skipped hour runs at jump", "0 30 2 * * *", time.Date(2024, 3, 9, 12, 0, 0, 0, ny), []time.Time{
+			time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC), // 跳变后的第一刻 03:00 EDT
+			time.Date(2024, 3, 11, 2, 30, 0, 0, ny),
+		}},
+		{"spring forward wildcard hour", "0 0 * * * *", time.Date(2024, 3, 10, 0, 30, 0, 0, ny), []time.Time{
+			time.Date(2024, 3, 10, 1, 0, 0, 0, ny),
+			time.Date(2024, 3, 10, 3, 0, 0, 0, ny),
+		}},
+		{"fall back fixed hour runs once", "0 30 1 * * *", time.Date(2024, 11, 2, 12, 0, 0, 0, ny), []time.Time{
+			time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+			time.Date(2024, 11, 4, 1, 30, 0, 0, ny),
+		}},
+		{"fall back wildcard hour runs twice", "0 30 * * * *", time.Date(2024, 11, 3, 0, 45, 0, 0, ny), []time.Time{
+			time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+			time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC), // 01:30 EST
+			time.Date(2024, 11, 3, 7, 30, 0, 0, time.UTC), // 02:30 EST
+		}},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			s, err := ParseCron(table.spec, ny)
+			require.NoError(t, err)
+			after := table.after
+			for _, want := range table.want {
+				next := s.Next(after)
+				assert.True(t, want.Equal(next), "want %v got %v", want, next)
+				after = next
+			}
+		})
+	}
+}
+
+func TestCronMissedPolicy(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 1, 0, 10, 30, 0, time.UTC)
+	// 每分钟执行,上次计划在 00:05:00,已错过5分钟
+	scheduled := time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)
+	tables := []struct {
+		name   string
+		policy MissedPolicy
+		due    bool
+		next   time.Time
+	}{
+		{"run once", MissedRunOnce, true, time.Date(2024, 1, 1, 0, 11, 0, 0, time.UTC)},
+		{"skip", MissedSkip, false, time.Date(2024, 1, 1, 0, 11, 0, 0, time.UTC)},
+		{"catch up", MissedCatchUp, true, time.Date(2024, 1, 1, 0, 6, 0, 0, time.UTC)},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			s, err := ParseCron("0 * * * * *", time.UTC)
+			require.NoError(t, err)
+			tm := &Timer{sched: s, missed: table.policy, counter: LoopForever, expires: scheduled.UnixMilli()}
+			assert.Equal(t, table.due, cronDue(tm, now))
+			assert.Equal(t, table.next.UnixMilli(), tm.expires)
+		})
+	}
+
+	s, err := ParseCron("0 * * * * *", time.UTC)
+	require.NoError(t, err)
+	tm := &Timer{sched: s, missed: MissedSkip, counter: LoopForever, expires: now.Add(-time.Millisecond).UnixMilli()}
+	assert.True(t, cronDue(tm, now), "on time run should not be skipped")
+
+	tm = &Timer{sched: s, counter: LoopForever, expires: now.UnixMilli(), end: now.Add(time.Second)}
+	assert.True(t, cronDue(tm, now))
+	assert.Equal(t, 0, tm.counter, "no run left in window")
+}
+
+func TestCronTimerLeaderOnly(t *testing.T) {
+	leader := false
+	runs := 0
+	tm, err := NewCronTimer("* * * * * *", func() { runs++ }, WithLeaderOnly(), WithLeaderChecker(func() bool { return leader }))
+	require.NoError(t, err)
+	tm.run()
+	assert.Equal(t, 0, runs)
+	leader = true
+	tm.run()
+	assert.Equal(t, 1, runs)
+
+	_, err = NewCronTimer("0 0 0 30 2 *", func() {})
+	assert.True(t, errors.Is(err, constants.ErrInvalidCronSpec))
+}
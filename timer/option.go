@@ -0,0 +1,64 @@
+package timer
+
+import "time"
+
+// Option 定时器的配置
+type Option func(t *Timer)
+
+// WithGoID 回调派发到 co 线程池 poolName 的 goID 线程执行,默认在 Cron 所在的线程执行
+func WithGoID(poolName string, goID int64) Option {
+	return func(t *Timer) {
+		t.poolName = poolName
+		t.goID = goID
+	}
+}
+
+// WithDispatcher 回调通过 dispatch 派发执行,如派发到session或实体的有序线程,优先于 WithGoID
+func WithDispatcher(dispatch func(task func())) Option {
+	return func(t *Timer) {
+		t.dispatch = dispatch
+	}
+}
+
+// WithCondition 按条件执行,每次 Cron 检查 Condition.Check,满足时执行
+func WithCondition(condition Condition) Option {
+	return func(t *Timer) {
+		t.condition = condition
+	}
+}
+
+// WithLocation cron表达式未指定 CRON_TZ 时使用的时区,默认 time.Local
+func WithLocation(loc *time.Location) Option {
+	return func(t *Timer) {
+		t.location = loc
+	}
+}
+
+// WithMissedPolicy 错过cron执行时间时的策略,默认 MissedRunOnce
+func WithMissedPolicy(policy MissedPolicy) Option {
+	return func(t *Timer) {
+		t.missed = policy
+	}
+}
+
+// WithWindow cron定时器只在 [start, end) 内执行,零值表示不限制,可用于活动的开始和结束
+func WithWindow(start, end time.Time) Option {
+	return func(t *Timer) {
+		t.start = start
+		t.end = end
+	}
+}
+
+// WithLeaderOnly 只在主节点执行,由 WithLeaderChecker 判断是否主节点,未设置时不执行
+func WithLeaderOnly() Option {
+	return func(t *Timer) {
+		t.leaderOnly = true
+	}
+}
+
+// WithLeaderChecker 判断当前服务是否主节点,App.Schedule 会设置为 App.IsLeader
+func WithLeaderChecker(isLeader func() bool) Option {
+	return func(t *Timer) {
+		t.isLeader = isLeader
+	}
+}
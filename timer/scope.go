@@ -0,0 +1,165 @@
+package timer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/topfreegames/pitaya/v2/co"
+	"github.com/topfreegames/pitaya/v2/constants"
+)
+
+// scopedCounts 各类 Scope 中未结束的定时器数量 kind -> *atomic.Int64
+var scopedCounts sync.Map
+
+// Scope 一组归属于同一实体(如session)的定时器,回调派发到实体的有序线程执行,Stop 时全部取消
+type Scope struct {
+	kind     string
+	dispatch func(task func())
+	count    *atomic.Int64
+	mu       sync.Mutex
+	timers   map[int64]*Timer
+	closed   bool
+}
+
+// NewScope ctor
+//
+//	@param kind 类别,用于统计指标,如 session
+//	@param dispatch 回调的派发函数
+//	@return *Scope
+func NewScope(kind string, dispatch func(task func())) *Scope {
+	count, _ := scopedCounts.LoadOrStore(kind, &atomic.Int64{})
+	return &Scope{
+		kind:     kind,
+		dispatch: dispatch,
+		count:    count.(*atomic.Int64),
+		timers:   map[int64]*Timer{},
+	}
+}
+
+// NewEntityScope 回调派发到 co 线程池 poolName 的 goID 线程执行,与 co.GoWithID 提交的任务串行
+//
+//	@param poolName
+//	@param goID
+//	@return *Scope
+func NewEntityScope(poolName string, goID int64) *Scope {
+	return NewScope("entity", func(task func()) {
+		co.GoWithID(context.Background(), goID, func(ctx context.Context) { task() }, co.WithPoolName(poolName))
+	})
+}
+
+// AfterFunc d 后执行一次 fn
+//
+//	@receiver s
+//	@param d
+//	@param fn
+//	@return *Timer
+//	@return error Scope 已停止时返回 constants.ErrTimerScopeClosed
+func (s *Scope) AfterFunc(d time.Duration, fn Func) (*Timer, error) {
+	return s.add(fn, d, 1)
+}
+
+// Every 每隔 d 执行一次 fn,直到 Timer.Stop 或 Scope 停止
+//
+//	@receiver s
+//	@param d
+//	@param fn
+//	@return *Timer
+//	@return error Scope 已停止时返回 constants.ErrTimerScopeClosed
+func (s *Scope) Every(d time.Duration, fn Func) (*Timer, error) {
+	return s.add(fn, d, LoopForever)
+}
+
+func (s *Scope) add(fn Func, d time.Duration, counter int) (*Timer, error) {
+	if fn == nil {
+		return nil, errors.WithStack(constants.ErrNilTimerFunc)
+	}
+	t := newTimer(nil, d, counter, WithDispatcher(s.dispatch))
+	t.scope = s
+	// 派发后执行前可能已被取消,执行时再检查一次
+	t.fn = func() {
+		if t.Stopped() || s.Closed() {
+			return
+		}
+		if counter == 1 {
+			s.remove(t)
+		}
+		fn()
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, errors.WithStack(constants.ErrTimerScopeClosed)
+	}
+	s.timers[t.ID] = t
+	s.count.Add(1)
+	s.mu.Unlock()
+
+	Manager.ChCreatedTimer <- t
+	return t, nil
+}
+
+// remove 定时器结束或取消时移出
+func (s *Scope) remove(t *Timer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.timers[t.ID]; ok {
+		delete(s.timers, t.ID)
+		s.count.Add(-1)
+	}
+}
+
+// Len 未结束的定时器数量
+//
+//	@receiver s
+//	@return int
+func (s *Scope) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.timers)
+}
+
+// Closed 是否已停止
+//
+//	@receiver s
+//	@return bool
+func (s *Scope) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Stop 取消所有定时器,之后不能再添加,可重复调用
+//
+//	@receiver s
+func (s *Scope) Stop() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	timers := s.timers
+	s.timers = map[int64]*Timer{}
+	s.count.Add(-int64(len(timers)))
+	s.mu.Unlock()
+
+	for _, t := range timers {
+		t.Stop()
+	}
+}
+
+// ScopedTimers 各类 Scope 中未结束的定时器数量,用于上报指标
+//
+//	@return map[string]int64 kind -> 数量
+func ScopedTimers() map[string]int64 {
+	ret := map[string]int64{}
+	scopedCounts.Range(func(key, value interface{}) bool {
+		ret[key.(string)] = value.(*atomic.Int64).Load()
+		return true
+	})
+	return ret
+}
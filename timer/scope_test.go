@@ -0,0 +1,84 @@
+package timer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/constants"
+)
+
+// drainScopeTimers 清空测试中产生的创建和关闭通知,避免影响其他测试
+func drainScopeTimers(t *testing.T) {
+	t.Cleanup(func() {
+		for {
+			select {
+			case <-Manager.ChCreatedTimer:
+			case <-Manager.ChClosingTimer:
+			default:
+				return
+			}
+		}
+	})
+}
+
+func TestScope(t *testing.T) {
+	drainScopeTimers(t)
+	var queued []func()
+	scope := NewScope("test", func(task func()) { queued = append(queued, task) })
+	runQueued := func() {
+		tasks := queued
+		queued = nil
+		for _, task := range tasks {
+			task()
+		}
+	}
+
+	var once, every int
+	t1, err := scope.AfterFunc(time.Millisecond, func() { once++ })
+	require.NoError(t, err)
+	t2, err := scope.Every(time.Millisecond, func() { every++ })
+	require.NoError(t, err)
+	AddTimer(t1)
+	AddTimer(t2)
+	assert.Equal(t, 2, scope.Len())
+	assert.Equal(t, int64(2), ScopedTimers()["test"])
+
+	time.Sleep(2 * time.Millisecond)
+	Cron()
+	// 回调派发后才执行
+	assert.Equal(t, 0, once+every)
+	runQueued()
+	assert.Equal(t, 1, once)
+	assert.Equal(t, 1, every)
+	assert.Equal(t, 1, scope.Len())
+
+	// 派发后执行前被停止的回调不执行
+	time.Sleep(2 * time.Millisecond)
+	Cron()
+	scope.Stop()
+	runQueued()
+	assert.Equal(t, 1, every)
+	assert.True(t, t2.Stopped())
+	assert.Equal(t, 0, scope.Len())
+	assert.Equal(t, int64(0), ScopedTimers()["test"])
+
+	_, err = scope.AfterFunc(time.Millisecond, func() {})
+	assert.ErrorIs(t, err, constants.ErrTimerScopeClosed)
+	scope.Stop()
+}
+
+func TestScopeTimerStop(t *testing.T) {
+	drainScopeTimers(t)
+	scope := NewScope("test-stop", func(task func()) { task() })
+	tm, err := scope.Every(time.Millisecond, func() {})
+	require.NoError(t, err)
+	assert.Equal(t, 1, scope.Len())
+	tm.Stop()
+	assert.Equal(t, 0, scope.Len())
+	assert.Equal(t, int64(0), ScopedTimers()["test-stop"])
+
+	_, err = scope.AfterFunc(time.Millisecond, nil)
+	assert.ErrorIs(t, err, constants.ErrNilTimerFunc)
+}
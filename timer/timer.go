@@ -21,12 +21,15 @@
 package timer
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/topfreegames/pitaya/v2/clock"
+	"github.com/topfreegames/pitaya/v2/co"
 	"github.com/topfreegames/pitaya/v2/logger"
 )
 
@@ -44,6 +47,10 @@ var (
 		timers         sync.Map   // all Timers
 		ChClosingTimer chan int64 // timer for closing
 		ChCreatedTimer chan *Timer
+
+		mu    sync.Mutex
+		wheel *timingWheel     // 按时间执行的定时器
+		conds map[int64]*Timer // 按条件执行的定时器,每次 Cron 检查
 	}{}
 
 	// Precision indicates the precision of timer, default is time.Second
@@ -51,7 +58,7 @@ var (
 
 	// GlobalTicker represents global ticker that all cron job will be executed
 	// in globalTicker.
-	GlobalTicker *time.Ticker
+	GlobalTicker clock.Ticker
 )
 
 type (
@@ -75,6 +82,23 @@ type (
 		elapse    int64         // total elapse time
 		closed    int32         // is timer closed
 		counter   int           // counter
+
+		poolName string            // 非空时回调派发到 co 线程池执行
+		goID     int64             // 派发的线程ID
+		dispatch func(task func()) // 非空时回调通过该函数派发
+		scope    *Scope            // 所属的 Scope
+
+		sched      *CronSchedule  // 非空时按cron表达式执行
+		location   *time.Location // cron表达式的默认时区
+		missed     MissedPolicy   // 错过执行时间时的策略
+		start, end time.Time      // cron执行的时间窗口
+		leaderOnly bool           // 只在主节点执行
+		isLeader   func() bool    // 判断当前服务是否主节点
+
+		expires    int64      // 下次执行的毫秒时间戳
+		added      bool       // 是否已加入 Manager
+		list       *timerList // 所在的时间轮槽
+		prev, next *Timer
 	}
 )
 
@@ -91,55 +115,184 @@ func init() {
 	timerBacklog = 1 << 8
 	Manager.ChClosingTimer = make(chan int64, timerBacklog)
 	Manager.ChCreatedTimer = make(chan *Timer, timerBacklog)
+	Manager.conds = make(map[int64]*Timer)
 }
 
 // AddTimer adds a timer to the manager
 func AddTimer(t *Timer) {
+	Manager.mu.Lock()
+	defer Manager.mu.Unlock()
+	if t.added || atomic.LoadInt32(&t.closed) > 0 {
+		return
+	}
+	t.added = true
 	Manager.timers.Store(t.ID, t)
+	schedule(t)
 }
 
 // RemoveTimer removes a timer to the manager
 func RemoveTimer(id int64) {
-	Manager.timers.Delete(id)
+	Manager.mu.Lock()
+	defer Manager.mu.Unlock()
+	v, ok := Manager.timers.LoadAndDelete(id)
+	if !ok {
+		return
+	}
+	unschedule(v.(*Timer))
+}
+
+// schedule 条件定时器放入 conds,其余按到期时间放入时间轮,调用方持有 Manager.mu
+func schedule(t *Timer) {
+	if t.condition != nil {
+		Manager.conds[t.ID] = t
+		return
+	}
+	if Manager.wheel == nil {
+		Manager.wheel = newTimingWheel(clock.Now().UnixMilli())
+	}
+	Manager.wheel.add(t)
+}
+
+// unschedule 调用方持有 Manager.mu
+func unschedule(t *Timer) {
+	delete(Manager.conds, t.ID)
+	if Manager.wheel != nil {
+		Manager.wheel.remove(t)
+	}
 }
 
 // NewTimer creates a cron job
-func NewTimer(fn Func, interval time.Duration, counter int) *Timer {
+func NewTimer(fn Func, interval time.Duration, counter int, opts ...Option) *Timer {
+	t := newTimer(fn, interval, counter, opts...)
+
+	// add to manager
+	Manager.ChCreatedTimer <- t
+	return t
+}
+
+// newTimer 创建定时器但不加入 Manager
+func newTimer(fn Func, interval time.Duration, counter int, opts ...Option) *Timer {
 	id := atomic.AddInt64(&Manager.incrementID, 1)
+	now := clock.Now()
 	t := &Timer{
 		ID:       id,
 		fn:       fn,
-		createAt: time.Now().UnixNano(),
+		createAt: now.UnixNano(),
 		interval: interval,
 		elapse:   int64(interval), // first execution will be after interval
 		counter:  counter,
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.expires = now.UnixMilli() + intervalMs(interval)
+	return t
+}
+
+// NewCronTimer 按cron表达式执行的定时器,表达式格式见 CronSchedule
+//
+//	@param spec
+//	@param fn
+//	@param opts 可用 WithLocation WithMissedPolicy WithWindow WithLeaderOnly WithGoID
+//	@return *Timer
+//	@return error
+func NewCronTimer(spec string, fn Func, opts ...Option) (*Timer, error) {
+	now := clock.Now()
+	t := &Timer{
+		ID:       atomic.AddInt64(&Manager.incrementID, 1),
+		fn:       fn,
+		createAt: now.UnixNano(),
+		counter:  LoopForever,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	sched, err := ParseCron(spec, t.location)
+	if err != nil {
+		return nil, err
+	}
+	t.sched = sched
+	next := t.nextRun(now)
+	if next.IsZero() {
+		return nil, invalidCron(spec, "never fires")
+	}
+	t.expires = next.UnixMilli()
 
 	// add to manager
 	Manager.ChCreatedTimer <- t
-	return t
+	return t, nil
+}
+
+// nextRun cron定时器在 after 之后的下次执行时间,超出时间窗口时返回零值
+func (t *Timer) nextRun(after time.Time) time.Time {
+	if after.Before(t.start) {
+		after = t.start.Add(-time.Nanosecond)
+	}
+	next := t.sched.Next(after)
+	if !t.end.IsZero() && !next.Before(t.end) {
+		return time.Time{}
+	}
+	return next
+}
+
+// misfireThreshold 执行延迟超过该值视为错过,不小于两个 Precision
+func misfireThreshold() int64 {
+	threshold := 2 * Precision
+	if threshold < time.Second {
+		threshold = time.Second
+	}
+	return threshold.Milliseconds()
+}
+
+// intervalMs 间隔换算为毫秒,不足1毫秒按1毫秒计算
+func intervalMs(interval time.Duration) int64 {
+	ms := int64(interval / time.Millisecond)
+	if interval%time.Millisecond != 0 && ms < maxDelay {
+		ms++
+	}
+	if ms < 1 {
+		ms = 1
+	}
+	return ms
 }
 
 // SetCondition sets the condition used for verifying when the cron job should run
 func (t *Timer) SetCondition(condition Condition) {
+	Manager.mu.Lock()
+	defer Manager.mu.Unlock()
+	if t.added {
+		unschedule(t)
+		t.condition = condition
+		schedule(t)
+		return
+	}
 	t.condition = condition
 }
 
 // Stop turns off a timer. After Stop, fn will not be called forever
 func (t *Timer) Stop() {
-	if atomic.LoadInt32(&t.closed) > 0 {
+	if !atomic.CompareAndSwapInt32(&t.closed, 0, 1) {
 		return
 	}
 
-	// guarantee that logic is not blocked
+	if t.scope != nil {
+		t.scope.remove(t)
+	}
+
+	// guarantee that logic is not blocked, closed timers are dropped in next Cron
 	if len(Manager.ChClosingTimer) < timerBacklog {
 		Manager.ChClosingTimer <- t.ID
-		atomic.StoreInt32(&t.closed, 1)
-	} else {
-		t.counter = 0 // automatically closed in next Cron
 	}
 }
 
+// Stopped 是否已停止
+//
+//	@receiver t
+//	@return bool
+func (t *Timer) Stopped() bool {
+	return atomic.LoadInt32(&t.closed) > 0
+}
+
 // execute job function with protection
 func pexec(id int64, fn Func) {
 	defer func() {
@@ -155,42 +308,109 @@ func pexec(id int64, fn Func) {
 	fn()
 }
 
+// run 在当前线程执行回调,或按 WithDispatcher WithGoID 派发
+func (t *Timer) run() {
+	if t.leaderOnly && (t.isLeader == nil || !t.isLeader()) {
+		return
+	}
+	if t.dispatch != nil {
+		t.dispatch(func() { pexec(t.ID, t.fn) })
+		return
+	}
+	if t.poolName == "" {
+		pexec(t.ID, t.fn)
+		return
+	}
+	co.GoWithID(context.Background(), t.goID, func(ctx context.Context) {
+		pexec(t.ID, t.fn)
+	}, co.WithPoolName(t.poolName))
+}
+
 // Cron executes scheduled tasks
-// TODO: if closing Timers'count in single cron call more than timerBacklog will case problem.
+//
+//	推进时间轮到当前毫秒并执行到期的定时器,开销与到期数量和经过的毫秒数相关,与定时器总数无关;
+//	条件定时器每次调用都会检查
 func Cron() {
-	now := time.Now()
-	unn := now.UnixNano()
-	Manager.timers.Range(func(idInterface, tInterface interface{}) bool {
-		t := tInterface.(*Timer)
-		id := idInterface.(int64)
-		// prevent ChClosingTimer exceed
-		if t.counter == 0 {
-			if len(Manager.ChClosingTimer) < timerBacklog {
-				t.Stop()
-			}
-			return true
-		}
+	now := clock.Now()
+	nowMs := now.UnixMilli()
 
-		// condition timer
-		if t.condition != nil {
-			if t.condition.Check(now) {
-				pexec(id, t.fn)
+	Manager.mu.Lock()
+	var due []*Timer
+	if Manager.wheel != nil {
+		for _, t := range Manager.wheel.advance(nowMs) {
+			if atomic.LoadInt32(&t.closed) > 0 || t.counter == 0 {
+				removeLocked(t)
+				continue
 			}
-			return true
-		}
-
-		// execute job
-		if t.createAt+t.elapse <= unn {
-			pexec(id, t.fn)
+			if t.expires > nowMs {
+				// 超出时间轮范围的定时器,重新放入
+				Manager.wheel.add(t)
+				continue
+			}
+			if t.sched != nil {
+				if cronDue(t, now) {
+					due = append(due, t)
+				}
+				if t.counter == 0 {
+					removeLocked(t)
+				} else {
+					Manager.wheel.add(t)
+				}
+				continue
+			}
+			due = append(due, t)
 			t.elapse += int64(t.interval)
-
-			// update timer counter
+			t.expires += intervalMs(t.interval)
 			if t.counter != LoopForever && t.counter > 0 {
 				t.counter--
 			}
+			if t.counter == 0 {
+				removeLocked(t)
+			} else {
+				Manager.wheel.add(t)
+			}
+		}
+	}
+	conds := make([]*Timer, 0, len(Manager.conds))
+	for _, t := range Manager.conds {
+		if atomic.LoadInt32(&t.closed) > 0 || t.counter == 0 {
+			removeLocked(t)
+			continue
 		}
-		return true
-	})
+		conds = append(conds, t)
+	}
+	Manager.mu.Unlock()
+
+	for _, t := range conds {
+		if t.condition.Check(now) {
+			due = append(due, t)
+		}
+	}
+	for _, t := range due {
+		t.run()
+	}
+}
+
+// cronDue 按错过策略判断本次是否执行并计算下次执行时间,不再执行时 counter 置0
+func cronDue(t *Timer, now time.Time) bool {
+	scheduled := t.expires
+	missed := now.UnixMilli()-scheduled > misfireThreshold()
+	after := now
+	if t.missed == MissedCatchUp {
+		after = time.UnixMilli(scheduled)
+	}
+	if next := t.nextRun(after); next.IsZero() {
+		t.counter = 0
+	} else {
+		t.expires = next.UnixMilli()
+	}
+	return !missed || t.missed != MissedSkip
+}
+
+// removeLocked 调用方持有 Manager.mu
+func removeLocked(t *Timer) {
+	unschedule(t)
+	Manager.timers.Delete(t.ID)
 }
 
 // SetTimerBacklog set the timer created/closing channel backlog, A small backlog
@@ -21,10 +21,13 @@
 package timer
 
 import (
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/topfreegames/pitaya/v2/co"
 	"github.com/topfreegames/pitaya/v2/helpers"
 )
 
@@ -204,3 +207,108 @@ func TestCron(t *testing.T) {
 	// after more 25ms j should be still 2 because of the counter
 	assert.Equal(t, 2, j)
 }
+
+func TestTimingWheel(t *testing.T) {
+	t.Parallel()
+	w := newTimingWheel(0)
+	delays := []int64{0, 1, 255, 256, 300, 1 << 14, 20000, 1<<20 + 7, 1<<26 + 3}
+	timers := make(map[*Timer]int64, len(delays))
+	for i, d := range delays {
+		tm := &Timer{ID: int64(i), expires: d}
+		timers[tm] = d
+		w.add(tm)
+	}
+
+	// 超出范围的定时器挂在最高层的最远槽
+	far := &Timer{ID: 99, expires: maxDelay + 1000}
+	w.add(far)
+	assert.Equal(t, &w.tvn[tvnLevels-1][(maxDelay>>(tvrBits+(tvnLevels-1)*tvnBits))&tvnMask], far.list)
+	w.remove(far)
+
+	canceled := &Timer{ID: 100, expires: 500}
+	w.add(canceled)
+	w.remove(canceled)
+	assert.Nil(t, canceled.list)
+
+	fired := make(map[*Timer]int64, len(delays))
+	// 前段逐毫秒推进,之后大步推进
+	for now, step := int64(0), int64(1); len(fired) < len(delays); now += step {
+		for _, tm := range w.advance(now) {
+			fired[tm] = now
+		}
+		if now > 1<<16 {
+			step = 997
+		}
+	}
+	for tm, d := range timers {
+		assert.GreaterOrEqual(t, fired[tm], d, "timer %d", tm.ID)
+		if d <= 1<<16 {
+			assert.Equal(t, d, fired[tm], "timer %d", tm.ID)
+		} else {
+			assert.Less(t, fired[tm]-d, int64(997), "timer %d", tm.ID)
+		}
+	}
+	_, ok := fired[canceled]
+	assert.False(t, ok)
+}
+
+func TestCronStopAndRemove(t *testing.T) {
+	var i, j int32
+	tmi := NewTimer(func() { atomic.AddInt32(&i, 1) }, time.Millisecond, LoopForever)
+	tmj := NewTimer(func() { atomic.AddInt32(&j, 1) }, time.Millisecond, LoopForever)
+	AddTimer(tmi)
+	AddTimer(tmj)
+
+	time.Sleep(5 * time.Millisecond)
+	Cron()
+	assert.Greater(t, atomic.LoadInt32(&i), int32(0))
+	assert.Greater(t, atomic.LoadInt32(&j), int32(0))
+
+	tmi.Stop()
+	RemoveTimer(tmj.ID)
+	ci, cj := atomic.LoadInt32(&i), atomic.LoadInt32(&j)
+	time.Sleep(5 * time.Millisecond)
+	Cron()
+	assert.Equal(t, ci, atomic.LoadInt32(&i))
+	assert.Equal(t, cj, atomic.LoadInt32(&j))
+	_, ok := Manager.timers.Load(tmi.ID)
+	assert.False(t, ok)
+}
+
+func TestCronWithGoID(t *testing.T) {
+	require.NoError(t, co.NewStatefulPoolsModule(nil, nil).Init())
+	done := make(chan struct{})
+	tm := NewTimer(func() { close(done) }, time.Millisecond, 1, WithGoID(co.DefaultGoPoolName, 7))
+	AddTimer(tm)
+	time.Sleep(2 * time.Millisecond)
+	Cron()
+	helpers.ShouldEventuallyReturn(t, func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, true)
+}
+
+func TestCronWithFakeClock(t *testing.T) {
+	c := helpers.UseFakeClock(t, time.Now())
+	var fired int32
+	tm := NewTimer(func() { atomic.AddInt32(&fired, 1) }, time.Minute, 2)
+	<-Manager.ChCreatedTimer
+	AddTimer(tm)
+
+	c.Advance(time.Minute - time.Millisecond)
+	Cron()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&fired))
+	c.Advance(time.Millisecond)
+	Cron()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fired))
+	c.Advance(time.Minute)
+	Cron()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fired))
+	c.Advance(time.Minute)
+	Cron()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fired))
+}
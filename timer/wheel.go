@@ -0,0 +1,139 @@
+package timer
+
+// 分层时间轮,刻度为1毫秒
+//
+//	第一层256个槽,每槽1毫秒;后四层各64个槽,每槽跨度依次为上一层的总跨度
+//	可表示的最大延迟约为49天,更远的定时器先挂在最高层,到期时重新计算位置
+//	添加和删除为O(1),推进时每毫秒处理一个槽,低层转满一圈时从高层下放一个槽
+const (
+	tvrBits   = 8
+	tvnBits   = 6
+	tvrSize   = 1 << tvrBits
+	tvnSize   = 1 << tvnBits
+	tvrMask   = tvrSize - 1
+	tvnMask   = tvnSize - 1
+	tvnLevels = 4
+	maxDelay  = 1<<(tvrBits+tvnLevels*tvnBits) - 1
+)
+
+// timerList 槽内的侵入式双向链表
+type timerList struct {
+	head *Timer
+}
+
+func (l *timerList) push(t *Timer) {
+	t.list = l
+	t.prev = nil
+	t.next = l.head
+	if l.head != nil {
+		l.head.prev = t
+	}
+	l.head = t
+}
+
+func (l *timerList) remove(t *Timer) {
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		l.head = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	t.list, t.prev, t.next = nil, nil, nil
+}
+
+// take 取出槽内全部定时器并清空槽
+func (l *timerList) take() *Timer {
+	head := l.head
+	l.head = nil
+	for t := head; t != nil; t = t.next {
+		t.list = nil
+	}
+	return head
+}
+
+type timingWheel struct {
+	jiffies int64 // 下一个待处理的毫秒
+	tv1     [tvrSize]timerList
+	tvn     [tvnLevels][tvnSize]timerList
+}
+
+func newTimingWheel(nowMs int64) *timingWheel {
+	return &timingWheel{jiffies: nowMs}
+}
+
+// add 按到期时间放入对应层级的槽
+//
+//	@receiver w
+//	@param t
+func (w *timingWheel) add(t *Timer) {
+	expires := t.expires
+	idx := expires - w.jiffies
+	var l *timerList
+	switch {
+	case idx < 0:
+		// 已过期,下一毫秒执行
+		l = &w.tv1[w.jiffies&tvrMask]
+	case idx < tvrSize:
+		l = &w.tv1[expires&tvrMask]
+	default:
+		if idx > maxDelay {
+			expires = w.jiffies + maxDelay
+			idx = maxDelay
+		}
+		level := 0
+		for idx >= 1<<(tvrBits+(level+1)*tvnBits) {
+			level++
+		}
+		l = &w.tvn[level][(expires>>(tvrBits+level*tvnBits))&tvnMask]
+	}
+	l.push(t)
+}
+
+// remove 从所在槽中移除
+//
+//	@receiver w
+//	@param t
+func (w *timingWheel) remove(t *Timer) {
+	if t.list != nil {
+		t.list.remove(t)
+	}
+}
+
+// cascade 把高层一个槽的定时器重新放入更低的层级
+//
+//	@return int 槽下标,为0时需要继续下放更高一层
+func (w *timingWheel) cascade(level int) int {
+	index := int((w.jiffies >> (tvrBits + level*tvnBits)) & tvnMask)
+	for t := w.tvn[level][index].take(); t != nil; {
+		next := t.next
+		w.add(t)
+		t = next
+	}
+	return index
+}
+
+// advance 推进到 nowMs,返回期间到期的定时器
+//
+//	@receiver w
+//	@param nowMs
+//	@return []*Timer
+func (w *timingWheel) advance(nowMs int64) []*Timer {
+	var expired []*Timer
+	for w.jiffies <= nowMs {
+		index := w.jiffies & tvrMask
+		if index == 0 {
+			for level := 0; level < tvnLevels && w.cascade(level) == 0; level++ {
+			}
+		}
+		for t := w.tv1[index].take(); t != nil; {
+			next := t.next
+			t.prev, t.next = nil, nil
+			expired = append(expired, t)
+			t = next
+		}
+		w.jiffies++
+	}
+	return expired
+}